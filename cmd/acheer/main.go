@@ -44,6 +44,15 @@ func main() {
 		log.Fatalf(err.Error())
 	}
 	s := services.NewService(rep)
+	go s.Scheduler.Run(context.Background())
+	go s.Notification.RunCleanup(context.Background())
+	go s.Stream.Run(context.Background())
+	go s.Webhook.Run(context.Background())
+	go s.Outbox.Run(context.Background())
+	go s.Step.RunCode(context.Background())
+	if err := s.Digest.Schedule(context.Background()); err != nil {
+		log.Printf("can not schedule digest: %s", err)
+	}
 	h := handlers.NewHandler(s)
 
 	srv := new(server.Server)
@@ -0,0 +1,81 @@
+// Package chatops reads slash commands of chat platforms and writes messages to them.
+// Every platform has its own adapter, so payloads of each are handled in one place.
+package chatops
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/miprokop/fication/internal/models"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Adapter converts requests and messages of a chat platform. Commands come as form-encoded bodies,
+// responses and incoming-webhook messages go as JSON.
+type Adapter interface {
+	Platform() models.ChatPlatform
+	ParseCommand(body []byte) (*models.ChatCommand, error)
+	// Verify checks that the command is sent by the chat team with the secret of its integration.
+	Verify(header http.Header, body []byte, secret string, now time.Time) error
+	// ParseMention returns the user mentioned by a word of the command text.
+	ParseMention(word string) (models.ChatUser, bool)
+	// UserEmail reads the email of the chat user from the chat API with the bot token of the integration.
+	UserEmail(ctx context.Context, integration *models.ChatIntegration, user models.ChatUser) (string, error)
+	EncodeResponse(response models.ChatResponse) ([]byte, error)
+	EncodeMessage(text string) ([]byte, error)
+}
+
+// commandForm reads fields sent with slash commands by both Slack and Mattermost.
+func commandForm(body []byte) (url.Values, *models.ChatCommand, error) {
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, nil, fmt.Errorf("can not parse command: %s", err)
+	}
+	command := &models.ChatCommand{
+		TeamID:    form.Get("team_id"),
+		ChannelID: form.Get("channel_id"),
+		User:      models.ChatUser{ID: form.Get("user_id"), Name: form.Get("user_name")},
+		Command:   form.Get("command"),
+		Text:      form.Get("text"),
+	}
+	if command.TeamID == "" || command.User.ID == "" {
+		return nil, nil, fmt.Errorf("can not parse command; team_id and user_id are required")
+	}
+	return form, command, nil
+}
+
+// commandResponse is the response of a slash command understood by both Slack and Mattermost.
+type commandResponse struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+func encodeResponse(response models.ChatResponse) ([]byte, error) {
+	responseType := "in_channel"
+	if response.Ephemeral {
+		responseType = "ephemeral"
+	}
+	return json.Marshal(commandResponse{ResponseType: responseType, Text: response.Text})
+}
+
+// getJSON reads a JSON response of the chat API authorized with the bot token.
+func getJSON(ctx context.Context, client *http.Client, address, token string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, address, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("chat api responded %d: %s", resp.StatusCode, body)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,160 @@
+package chatops
+
+import (
+	"github.com/miprokop/fication/internal/models"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Recorded slash command of Slack with the signature from "Verifying requests from Slack".
+const (
+	slackSecret    = "8f742231b10e8888abcd99yyyzzz85a5"
+	slackTimestamp = "1531420618"
+	slackSignature = "v0=a2114d57b48eac39b9ad189dd8316235a7b4a8d21a10bd27519666489c69b503"
+	slackBody      = "token=xyzz0WbapA4vBCDEFasx0q6G&team_id=T1DC2JH3J&team_domain=testteamnow&channel_id=G8PSS9T3V" +
+		"&channel_name=foobar&user_id=U2CERLKJA&user_name=roadrunner&command=%2Fwebhook-collect&text=" +
+		"&response_url=https%3A%2F%2Fhooks.slack.com%2Fcommands%2FT1DC2JH3J%2F397700885554%2F96rGlfmibIGlgcZRskXaIFfN" +
+		"&trigger_id=398738663015.47445629121.803a0bc887a14d10d2c447fce8b6703c"
+)
+
+// Recorded slash command of Mattermost.
+const (
+	mattermostToken = "xr3j5x3p4pfk7kk1ck5dgo7u9a"
+	mattermostBody  = "channel_id=ndxnyfyaqtfwmbd1uhudtgcxcc&channel_name=town-square&command=%2Facheer" +
+		"&response_url=https%3A%2F%2Fchat.example.com%2Fhooks%2Fcommands%2Fqjc8ff1ditdf9xgsqdmpmxw1tr" +
+		"&team_domain=acme&team_id=rdc9bgriktyx9p4kowh3dmgqyc&text=kudos+%40jane.doe%2C+thanks+for+the+review" +
+		"&token=xr3j5x3p4pfk7kk1ck5dgo7u9a&trigger_id=YmF6cmU0cXl5N2Jwemcyb2lkdHd5&user_id=9ze4ozmhtjg4zq6ou1m3t3sxqo" +
+		"&user_name=john.smith"
+)
+
+func slackHeader(timestamp, signature string) http.Header {
+	header := http.Header{}
+	header.Set("X-Slack-Request-Timestamp", timestamp)
+	header.Set("X-Slack-Signature", signature)
+	return header
+}
+
+func TestSlackVerify(t *testing.T) {
+	slack := NewSlack(http.DefaultClient, SlackAPI)
+	sent := time.Unix(1531420618, 0)
+	tests := []struct {
+		name    string
+		header  http.Header
+		body    string
+		secret  string
+		now     time.Time
+		refused string
+	}{
+		{"signed", slackHeader(slackTimestamp, slackSignature), slackBody, slackSecret, sent.Add(time.Minute), ""},
+		{"early clock", slackHeader(slackTimestamp, slackSignature), slackBody, slackSecret, sent.Add(-time.Minute), ""},
+		{"other secret", slackHeader(slackTimestamp, slackSignature), slackBody, "other", sent, "signature"},
+		{"changed body", slackHeader(slackTimestamp, slackSignature), slackBody + "x", slackSecret, sent, "signature"},
+		{"changed timestamp", slackHeader("1531420619", slackSignature), slackBody, slackSecret, sent, "signature"},
+		{"no signature", slackHeader(slackTimestamp, ""), slackBody, slackSecret, sent, "signature"},
+		{"replayed", slackHeader(slackTimestamp, slackSignature), slackBody, slackSecret, sent.Add(slackMaxSkew + time.Second), "too old"},
+		{"from the future", slackHeader(slackTimestamp, slackSignature), slackBody, slackSecret, sent.Add(-slackMaxSkew - time.Second), "too old"},
+		{"no timestamp", slackHeader("", slackSignature), slackBody, slackSecret, sent, "timestamp"},
+	}
+	for _, test := range tests {
+		err := slack.Verify(test.header, []byte(test.body), test.secret, test.now)
+		if test.refused == "" && err != nil {
+			t.Errorf("%s: got error %s", test.name, err)
+		}
+		if test.refused != "" && (err == nil || !strings.Contains(err.Error(), test.refused)) {
+			t.Errorf("%s: got error %v; want error about %s", test.name, err, test.refused)
+		}
+	}
+}
+
+func TestMattermostVerify(t *testing.T) {
+	mattermost := NewMattermost(http.DefaultClient)
+	withoutToken := strings.Replace(mattermostBody, "&token="+mattermostToken, "", 1)
+	authorized := http.Header{}
+	authorized.Set("Authorization", "Token "+mattermostToken)
+	tests := []struct {
+		name    string
+		header  http.Header
+		body    string
+		secret  string
+		refused bool
+	}{
+		{"token in body", http.Header{}, mattermostBody, mattermostToken, false},
+		{"token in header", authorized, withoutToken, mattermostToken, false},
+		{"other token", http.Header{}, mattermostBody, "other", true},
+		{"no token", http.Header{}, withoutToken, mattermostToken, true},
+		{"no secret", http.Header{}, withoutToken, "", true},
+		{"no team", http.Header{}, strings.Replace(mattermostBody, "team_id=", "team=", 1), mattermostToken, true},
+	}
+	for _, test := range tests {
+		err := mattermost.Verify(test.header, []byte(test.body), test.secret, time.Now())
+		if test.refused != (err != nil) {
+			t.Errorf("%s: got error %v; want refused %t", test.name, err, test.refused)
+		}
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		adapter Adapter
+		body    string
+		want    models.ChatCommand
+	}{
+		{NewSlack(http.DefaultClient, SlackAPI), slackBody, models.ChatCommand{
+			TeamID:    "T1DC2JH3J",
+			ChannelID: "G8PSS9T3V",
+			User:      models.ChatUser{ID: "U2CERLKJA", Name: "roadrunner"},
+			Command:   "/webhook-collect",
+		}},
+		{NewMattermost(http.DefaultClient), mattermostBody, models.ChatCommand{
+			TeamID:    "rdc9bgriktyx9p4kowh3dmgqyc",
+			ChannelID: "ndxnyfyaqtfwmbd1uhudtgcxcc",
+			User:      models.ChatUser{ID: "9ze4ozmhtjg4zq6ou1m3t3sxqo", Name: "john.smith"},
+			Command:   "/acheer",
+			Text:      "kudos @jane.doe, thanks for the review",
+		}},
+	}
+	for _, test := range tests {
+		command, err := test.adapter.ParseCommand([]byte(test.body))
+		if err != nil {
+			t.Errorf("%s: got error %s", test.adapter.Platform(), err)
+			continue
+		}
+		if *command != test.want {
+			t.Errorf("%s: got command %+v; want %+v", test.adapter.Platform(), *command, test.want)
+		}
+	}
+
+	for _, body := range []string{"team_id=T1&user_name=roadrunner", "user_id=U1&text=score", "%zz"} {
+		if _, err := NewSlack(http.DefaultClient, SlackAPI).ParseCommand([]byte(body)); err == nil {
+			t.Errorf("got no error for command %q", body)
+		}
+	}
+}
+
+func TestParseMention(t *testing.T) {
+	tests := []struct {
+		adapter Adapter
+		word    string
+		want    models.ChatUser
+		ok      bool
+	}{
+		{NewSlack(http.DefaultClient, SlackAPI), "<@U024BE7LH>", models.ChatUser{ID: "U024BE7LH"}, true},
+		{NewSlack(http.DefaultClient, SlackAPI), "<@U024BE7LH|jane>", models.ChatUser{ID: "U024BE7LH", Name: "jane"}, true},
+		{NewSlack(http.DefaultClient, SlackAPI), "@jane", models.ChatUser{}, false},
+		{NewSlack(http.DefaultClient, SlackAPI), "<#C024BE7LR|general>", models.ChatUser{}, false},
+		{NewSlack(http.DefaultClient, SlackAPI), "<@U024BE7LH>,", models.ChatUser{}, false},
+		{NewMattermost(http.DefaultClient), "@jane.doe,", models.ChatUser{Name: "jane.doe"}, true},
+		{NewMattermost(http.DefaultClient), "@jane", models.ChatUser{Name: "jane"}, true},
+		{NewMattermost(http.DefaultClient), "jane", models.ChatUser{}, false},
+		{NewMattermost(http.DefaultClient), "@", models.ChatUser{}, false},
+	}
+	for _, test := range tests {
+		user, ok := test.adapter.ParseMention(test.word)
+		if ok != test.ok || user != test.want {
+			t.Errorf("%s: ParseMention(%q) = %+v, %t; want %+v, %t",
+				test.adapter.Platform(), test.word, user, ok, test.want, test.ok)
+		}
+	}
+}
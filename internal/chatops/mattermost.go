@@ -0,0 +1,88 @@
+package chatops
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"github.com/miprokop/fication/internal/models"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Mattermost is the adapter of Mattermost slash commands. Commands carry the token of the command,
+// mentions are plain @username.
+type Mattermost struct {
+	client *http.Client
+}
+
+func (m *Mattermost) Platform() models.ChatPlatform {
+	return models.ChatMattermost
+}
+
+func (m *Mattermost) ParseCommand(body []byte) (*models.ChatCommand, error) {
+	_, command, err := commandForm(body)
+	return command, err
+}
+
+// Verify compares the token of the command, sent in the body and in the Authorization header.
+func (m *Mattermost) Verify(header http.Header, body []byte, secret string, now time.Time) error {
+	form, _, err := commandForm(body)
+	if err != nil {
+		return err
+	}
+	token := form.Get("token")
+	if token == "" {
+		token = strings.TrimPrefix(header.Get("Authorization"), "Token ")
+	}
+	if secret == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+		return fmt.Errorf("incorrent mattermost command token")
+	}
+	return nil
+}
+
+func (m *Mattermost) ParseMention(word string) (models.ChatUser, bool) {
+	name := strings.TrimPrefix(word, "@")
+	if name == word || name == "" {
+		return models.ChatUser{}, false
+	}
+	return models.ChatUser{Name: strings.TrimRight(name, ".,:;!?")}, true
+}
+
+type mattermostUser struct {
+	Email string `json:"email"`
+}
+
+// UserEmail reads the email of the user by id, or by name for mentions.
+// The bot account needs to see emails, see ShowEmailAddress of the server privacy settings.
+func (m *Mattermost) UserEmail(ctx context.Context, integration *models.ChatIntegration,
+	user models.ChatUser) (string, error) {
+	address := strings.TrimRight(integration.ServerURL, "/") + "/api/v4/users/"
+	if user.ID != "" {
+		address += url.PathEscape(user.ID)
+	} else {
+		address += "username/" + url.PathEscape(user.Name)
+	}
+	var found mattermostUser
+	if err := getJSON(ctx, m.client, address, integration.BotToken, &found); err != nil {
+		return "", err
+	}
+	if found.Email == "" {
+		return "", fmt.Errorf("mattermost user %s%s has no visible email", user.ID, user.Name)
+	}
+	return found.Email, nil
+}
+
+func (m *Mattermost) EncodeResponse(response models.ChatResponse) ([]byte, error) {
+	return encodeResponse(response)
+}
+
+func (m *Mattermost) EncodeMessage(text string) ([]byte, error) {
+	return json.Marshal(map[string]string{"text": text, "username": "acheer"})
+}
+
+func NewMattermost(client *http.Client) *Mattermost {
+	return &Mattermost{client: client}
+}
@@ -0,0 +1,111 @@
+package chatops
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/miprokop/fication/internal/models"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const (
+	SlackAPI = "https://slack.com/api"
+	// slackMaxSkew is how old a signed request can be, older ones are refused as replays.
+	slackMaxSkew = 5 * time.Minute
+)
+
+// slackMention is a mention escaped by Slack: <@U024BE7LH> or <@U024BE7LH|name>.
+var slackMention = regexp.MustCompile(`^<@([A-Z0-9]+)(?:\|([^>]*))?>$`)
+
+// Slack is the adapter of Slack apps. Commands are signed with the signing secret of the app,
+// mentions must be escaped to be resolved, see "Escape channels, users, and links" of the command.
+type Slack struct {
+	client *http.Client
+	apiURL string
+}
+
+func (s *Slack) Platform() models.ChatPlatform {
+	return models.ChatSlack
+}
+
+func (s *Slack) ParseCommand(body []byte) (*models.ChatCommand, error) {
+	_, command, err := commandForm(body)
+	return command, err
+}
+
+// Verify checks the X-Slack-Signature header: v0= and the hex HMAC-SHA256 of "v0:timestamp:body".
+func (s *Slack) Verify(header http.Header, body []byte, secret string, now time.Time) error {
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("incorrent slack request timestamp: %s", timestamp)
+	}
+	if skew := now.Sub(time.Unix(ts, 0)); skew > slackMaxSkew || skew < -slackMaxSkew {
+		return fmt.Errorf("slack request timestamp %s is too old", timestamp)
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:", timestamp)
+	mac.Write(body)
+	want := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(want), []byte(header.Get("X-Slack-Signature"))) {
+		return fmt.Errorf("incorrent slack request signature")
+	}
+	return nil
+}
+
+func (s *Slack) ParseMention(word string) (models.ChatUser, bool) {
+	match := slackMention.FindStringSubmatch(word)
+	if match == nil {
+		return models.ChatUser{}, false
+	}
+	return models.ChatUser{ID: match[1], Name: match[2]}, true
+}
+
+type slackUserInfo struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error"`
+	User  struct {
+		Profile struct {
+			Email string `json:"email"`
+		} `json:"profile"`
+	} `json:"user"`
+}
+
+// UserEmail reads the email with users.info, the bot token needs the users:read.email scope.
+func (s *Slack) UserEmail(ctx context.Context, integration *models.ChatIntegration, user models.ChatUser) (string, error) {
+	if user.ID == "" {
+		return "", fmt.Errorf("can not find slack user %s; mention users with escaping enabled", user.Name)
+	}
+	var info slackUserInfo
+	address := s.apiURL + "/users.info?user=" + url.QueryEscape(user.ID)
+	if err := getJSON(ctx, s.client, address, integration.BotToken, &info); err != nil {
+		return "", err
+	}
+	if !info.OK {
+		return "", fmt.Errorf("can not get slack user %s: %s", user.ID, info.Error)
+	}
+	if info.User.Profile.Email == "" {
+		return "", fmt.Errorf("slack user %s has no email", user.ID)
+	}
+	return info.User.Profile.Email, nil
+}
+
+func (s *Slack) EncodeResponse(response models.ChatResponse) ([]byte, error) {
+	return encodeResponse(response)
+}
+
+func (s *Slack) EncodeMessage(text string) ([]byte, error) {
+	return json.Marshal(map[string]string{"text": text})
+}
+
+// NewSlack creates a Slack adapter, apiURL is SlackAPI or the address of a recorded API in tests.
+func NewSlack(client *http.Client, apiURL string) *Slack {
+	return &Slack{client: client, apiURL: apiURL}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/miprokop/fication/internal/models"
+	"github.com/miprokop/fication/internal/services"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"net/http"
@@ -64,7 +65,7 @@ func (h *Handler) signUp(c *gin.Context) {
 	var input models.StaffSignUp
 
 	if err := json.Unmarshal(reqData, &input); err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse sign up input: %s", err).Error())
 		return
 	}
 	id := uuid.New()
@@ -87,7 +88,7 @@ func (h *Handler) signUp(c *gin.Context) {
 		if err != nil {
 			log.Error(err)
 			_ = os.Remove(dst)
-			newErrorResponse(c, http.StatusInternalServerError, err.Error())
+			newServiceErrorResponse(c, "can not save image", err)
 			return
 		}
 		input.CurrentImage = dst
@@ -107,7 +108,7 @@ func (h *Handler) signUp(c *gin.Context) {
 	if input.TeamID == (uuid.UUID{}) && input.OrganizationID != (uuid.UUID{}) {
 		defaultTeam, err := h.Service.Team.GetTeamByName(ctx, input.OrganizationID, models.DefaultTeamName)
 		if err != nil {
-			newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create defaul team: %s", err).Error())
+			newServiceErrorResponse(c, "can not create defaul team", err)
 			return
 		}
 		input.TeamID = defaultTeam.ID
@@ -116,7 +117,7 @@ func (h *Handler) signUp(c *gin.Context) {
 	if input.PositionID == (uuid.UUID{}) && input.OrganizationID != (uuid.UUID{}) {
 		positions, err := h.Service.Staff.GetAllPositions(ctx, input.OrganizationID)
 		if err != nil {
-			newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not get positions: %s", err).Error())
+			newServiceErrorResponse(c, "can not get positions", err)
 			return
 		}
 		for _, p := range positions {
@@ -137,7 +138,7 @@ func (h *Handler) signUp(c *gin.Context) {
 	input.BackgroundColor = "#fffff"
 	err := h.Service.Staff.CreateStaffUser(c.Request.Context(), &input)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newServiceErrorResponse(c, "can not create staff", err)
 		return
 	}
 
@@ -153,7 +154,7 @@ func (h *Handler) signUp(c *gin.Context) {
 // @Produce  json
 // @Param input body models.StaffLogin true "staff account log in info"
 // @Success 200 {string} token
-// @Failure 400 {object} errorResponse
+// @Failure 400,401 {object} errorResponse
 // @Failure 500 {object} errorResponse
 // @Failure default {object} errorResponse
 // @Router /sign-in [post]
@@ -165,8 +166,12 @@ func (h *Handler) signIn(c *gin.Context) {
 	}
 
 	token, id, orgID, err := h.Service.Auth.GenerateToken(input.Email, input.Password)
+	if domain := services.ErrorOf(err); domain != nil && domain.Code == services.CodeNotFound {
+		newErrorResponse(c, http.StatusUnauthorized, "incorrent email or password")
+		return
+	}
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newServiceErrorResponse(c, "can not sign in", err)
 		return
 	}
 
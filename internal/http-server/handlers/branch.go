@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"net/http"
+)
+
+// ChooseBranch
+// @Summary Choose branch of fork
+// @Security ApiKeyAuth
+// @Tags steps
+// @Description current staff picks a branch of a fork in event by event ID
+// @Description staff is assigned to the first available step of the branch
+// @Description steps of other branches of the fork are skipped, the choice can not be changed
+// @ID choose-branch
+// @Accept  json
+// @Produce  json
+// @Param input body models.BranchChoiceRequest true "fork and branch"
+// @Success 200 {object} boolean
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/step/branch/:id [put]
+func (h *Handler) ChooseBranch(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.StepGetByID, models.EventGetByID) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in choosing branch: %s", err).Error())
+		return
+	}
+
+	var choice models.BranchChoiceRequest
+
+	if err := c.Bind(&choice); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get input model in choosing branch: %s", err).Error())
+		return
+	}
+
+	err = h.Service.Step.ChooseBranch(ctx, id, staff.ID, choice.Fork, choice.Branch)
+	if err != nil {
+		newServiceErrorResponse(c, "can not choose branch", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"chosen": true,
+	})
+}
+
+// GetStepGraph
+// @Summary Get event step graph
+// @Security ApiKeyAuth
+// @Tags steps
+// @Description get all steps of event by event ID with prerequisites between them and forks with their branches
+// @ID get-step-graph
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} models.StepGraph
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/step/graph/:id [get]
+func (h *Handler) GetStepGraph(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.StepGetByID, models.EventGetByID) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in getting step graph: %s", err).Error())
+		return
+	}
+
+	graph, err := h.Service.Step.GetStepGraph(ctx, id)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get step graph", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"graph": graph,
+	})
+}
+
+// GetStaffPath
+// @Summary Get staff path in event
+// @Security ApiKeyAuth
+// @Tags steps
+// @Description get branch choices and steps taken by staff in event by event ID
+// @Description current staff path is returned unless staff_id is passed by staff who can read all steps
+// @ID get-staff-path
+// @Accept  json
+// @Produce  json
+// @Param staff_id query string false "staff ID"
+// @Success 200 {object} models.StaffPath
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/step/path/:id [get]
+func (h *Handler) GetStaffPath(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in getting path: %s", err).Error())
+		return
+	}
+
+	staffID := userID.(uuid.UUID)
+	if param := c.Query("staff_id"); param != "" {
+		staffID, err = uuid.Parse(param)
+		if err != nil {
+			newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse staff id: %s", err).Error())
+			return
+		}
+	}
+	if staffID != userID.(uuid.UUID) {
+		staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+		if err != nil {
+			newServiceErrorResponse(c, "can not get staff by id", err)
+			return
+		}
+		if !staff.HasOneOfPermissions(models.StepGetAll, models.EventGetByID, models.EventGetAll) {
+			newErrorResponse(c, http.StatusForbidden,
+				"no access to this action")
+			return
+		}
+	}
+
+	path, err := h.Service.Step.GetStaffPath(ctx, id, staffID)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff path", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"path": path,
+	})
+}
@@ -0,0 +1,342 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"io"
+	"net/http"
+)
+
+// chatCommandSize limits the body of a slash command.
+const chatCommandSize = 64 << 10
+
+// ChatCommand
+// @Summary Run chat slash command
+// @Tags chat
+// @Description slash command of Slack or Mattermost: /acheer score, /acheer kudos @user message, /acheer events
+// @Description platform is slack or mattermost, the command is verified with the signing secret of the team integration
+// @Description chat users are staff of the organization with the same email
+// @ID chat-command
+// @Accept  x-www-form-urlencoded
+// @Produce  json
+// @Param platform path string true "slack or mattermost"
+// @Success 200 {object} map[string]string
+// @Failure 400,403,404 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /chat/command/{platform} [post]
+func (h *Handler) ChatCommand(c *gin.Context) {
+	ctx := context.Background()
+
+	platform, err := models.NewChatPlatform(c.Param("platform"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, chatCommandSize))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not read chat command: %s", err).Error())
+		return
+	}
+
+	integration, command, err := h.Service.Chat.VerifyCommand(ctx, platform, c.Request.Header, body)
+	if err != nil {
+		newServiceErrorResponse(c, "can not verify chat command", err)
+		return
+	}
+	response, err := h.Service.Chat.EncodeResponse(platform, h.Service.Chat.RunCommand(ctx, integration, command))
+	if err != nil {
+		newServiceErrorResponse(c, "can not encode chat response", err)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json", response)
+}
+
+// CreateChatIntegration
+// @Summary Create chat integration
+// @Security ApiKeyAuth
+// @Tags chat
+// @Description connect a Slack or Mattermost team to organization of current staff
+// @Description signing_secret is the signing secret of the Slack app or the token of the Mattermost slash command
+// @Description bot_token reads emails of chat users, server_url is required for mattermost
+// @Description prize awards and leaderboards of finished events are posted to webhook_url
+// @ID create-chat-integration
+// @Accept  json
+// @Produce  json
+// @Param input body models.ChatIntegrationRequest true "chat integration info"
+// @Success 200 {object} models.ChatIntegration
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/chat/ [post]
+func (h *Handler) CreateChatIntegration(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.OrganizationUpdate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	var input models.ChatIntegrationRequest
+	if err := c.Bind(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not bind chat integration: %s", err).Error())
+		return
+	}
+
+	integration, err := h.Service.Chat.CreateChatIntegration(ctx, staff.OrganizationID, staff.ID, input)
+	if err != nil {
+		newServiceErrorResponse(c, "can not create chat integration", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, integration)
+}
+
+// GetChatIntegrations
+// @Summary Get chat integrations
+// @Security ApiKeyAuth
+// @Tags chat
+// @Description get chat integrations of organization of current staff
+// @ID get-chat-integrations
+// @Accept  json
+// @Produce  json
+// @Success 200 {array} models.ChatIntegration
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/chat/ [get]
+func (h *Handler) GetChatIntegrations(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.OrganizationUpdate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	integrations, err := h.Service.Chat.GetChatIntegrations(ctx, staff.OrganizationID)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get chat integrations", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, integrations)
+}
+
+// GetChatIntegration
+// @Summary Get chat integration
+// @Security ApiKeyAuth
+// @Tags chat
+// @Description get chat integration by id
+// @ID get-chat-integration
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} models.ChatIntegration
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/chat/{id} [get]
+func (h *Handler) GetChatIntegration(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.OrganizationUpdate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in chat integration: %s", err).Error())
+		return
+	}
+
+	integration, err := h.Service.Chat.GetChatIntegration(ctx, id, staff.OrganizationID)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get chat integration", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, integration)
+}
+
+// UpdateChatIntegration
+// @Summary Update chat integration
+// @Security ApiKeyAuth
+// @Tags chat
+// @Description update chat integration, empty secrets are not changed
+// @Description platform can not be changed
+// @ID update-chat-integration
+// @Accept  json
+// @Produce  json
+// @Param input body models.ChatIntegrationRequest true "chat integration info"
+// @Success 200 {object} models.ChatIntegration
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/chat/{id} [put]
+func (h *Handler) UpdateChatIntegration(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.OrganizationUpdate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in chat integration: %s", err).Error())
+		return
+	}
+
+	var input models.ChatIntegrationRequest
+	if err := c.Bind(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not bind chat integration: %s", err).Error())
+		return
+	}
+
+	integration, err := h.Service.Chat.UpdateChatIntegration(ctx, id, staff.OrganizationID, input)
+	if err != nil {
+		newServiceErrorResponse(c, "can not update chat integration", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, integration)
+}
+
+// DeleteChatIntegration
+// @Summary Delete chat integration
+// @Security ApiKeyAuth
+// @Tags chat
+// @Description delete chat integration
+// @ID delete-chat-integration
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} boolean
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/chat/{id} [delete]
+func (h *Handler) DeleteChatIntegration(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.OrganizationUpdate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in chat integration: %s", err).Error())
+		return
+	}
+
+	if err := h.Service.Chat.DeleteChatIntegration(ctx, id, staff.OrganizationID); err != nil {
+		newServiceErrorResponse(c, "can not delete chat integration", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, true)
+}
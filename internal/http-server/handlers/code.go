@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"net/http"
+)
+
+// GetCodeLanguages
+// @Summary Get code languages
+// @Security ApiKeyAuth
+// @Tags steps
+// @Description get languages supported by code runner
+// @ID get-code-languages
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []string
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/step/code/languages [get]
+func (h *Handler) GetCodeLanguages(c *gin.Context) {
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"languages": h.Service.Step.GetCodeLanguages(),
+	})
+}
+
+// SubmitCode
+// @Summary Submit code
+// @Security ApiKeyAuth
+// @Tags steps
+// @Description submit source code for code step by step ID
+// @Description code is run against step test cases in background
+// @Description result is returned by get code submissions
+// @ID submit-code
+// @Accept  json
+// @Produce  json
+// @Param input body models.CodeSubmissionRequest true "language and source"
+// @Success 200 {object} models.CodeSubmission
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/step/code/:id [post]
+func (h *Handler) SubmitCode(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.StepGetByID, models.EventGetByID) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in submitting code: %s", err).Error())
+		return
+	}
+
+	var input models.CodeSubmissionRequest
+
+	if err := c.Bind(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get input model in submitting code: %s", err).Error())
+		return
+	}
+
+	submission, err := h.Service.Step.SubmitCode(ctx, id, staff.ID, input)
+	if err != nil {
+		newServiceErrorResponse(c, "can not submit code", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"submission": submission,
+	})
+}
+
+// GetCodeSubmissions
+// @Summary Get code submissions
+// @Security ApiKeyAuth
+// @Tags steps
+// @Description get code submissions of current staff with test results by step ID
+// @Description output of hidden tests is not returned
+// @ID get-code-submissions
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []models.CodeSubmission
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/step/code/:id [get]
+func (h *Handler) GetCodeSubmissions(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.StepGetByID, models.EventGetByID) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in getting code submissions: %s", err).Error())
+		return
+	}
+
+	submissions, err := h.Service.Step.GetCodeSubmissions(ctx, id, staff.ID)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get code submissions", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"submissions": submissions,
+	})
+}
+
+// GetCodeTestCases
+// @Summary Get code test cases
+// @Security ApiKeyAuth
+// @Tags steps
+// @Description get test cases with expected output by step ID
+// @Description only creator or admin of the event may get them
+// @ID get-code-test-cases
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []models.CodeTestCase
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/step/code/tests/:id [get]
+func (h *Handler) GetCodeTestCases(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.StepUpdate, models.EventCreate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in getting test cases: %s", err).Error())
+		return
+	}
+
+	tests, err := h.Service.Step.GetCodeTestCases(ctx, id, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get test cases", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"tests": tests,
+	})
+}
+
+// UpdateCodeTestCases
+// @Summary Update code test cases
+// @Security ApiKeyAuth
+// @Tags steps
+// @Description replace test cases by step ID, only creator or admin of the event may replace them
+// @Description tests can not be changed after staff submitted code
+// @ID update-code-test-cases
+// @Accept  json
+// @Produce  json
+// @Param input body []models.CodeTestCase true "test cases"
+// @Success 200 {object} boolean
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/step/code/tests/:id [put]
+func (h *Handler) UpdateCodeTestCases(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.StepUpdate, models.EventCreate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in updating test cases: %s", err).Error())
+		return
+	}
+
+	var tests []*models.CodeTestCase
+
+	if err := c.Bind(&tests); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get input model in updating test cases: %s", err).Error())
+		return
+	}
+
+	err = h.Service.Step.UpdateCodeTestCases(ctx, id, userID.(uuid.UUID), tests)
+	if err != nil {
+		newServiceErrorResponse(c, "can not update test cases", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"updated": true,
+	})
+}
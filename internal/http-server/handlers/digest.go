@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"net/http"
+)
+
+// DigestUnsubscribe
+// @Summary Unsubscribe from weekly digest
+// @Tags digest
+// @Description link of weekly digest emails, turns the digest off without signing in
+// @Description POST is the one-click unsubscribe of mail clients
+// @ID digest-unsubscribe
+// @Produce  html
+// @Param staff query string true "staff id"
+// @Param kind query string true "staff or team"
+// @Param token query string true "token of the link"
+// @Success 200 {string} string
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /digest/unsubscribe [get]
+func (h *Handler) DigestUnsubscribe(c *gin.Context) {
+	ctx := context.Background()
+
+	staffID, err := uuid.Parse(c.Query("staff"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("incorrent staff id: %s", err).Error())
+		return
+	}
+	kind, err := models.NewDigestKind(c.Query("kind"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.Service.Digest.Unsubscribe(ctx, staffID, kind, c.Query("token")); err != nil {
+		newServiceErrorResponse(c, "can not unsubscribe from digest", err)
+		return
+	}
+
+	c.Data(http.StatusOK, "text/html; charset=utf-8",
+		[]byte("<p>You are unsubscribed from weekly digests. Turn them on again in your settings.</p>"))
+}
+
+// GetDigestPreferences
+// @Summary Get digest preferences
+// @Security ApiKeyAuth
+// @Tags digest
+// @Description get preferences of current staff for every weekly digest: staff and team
+// @Description digests are on by default
+// @ID get-digest-preferences
+// @Accept  json
+// @Produce  json
+// @Success 200 {array} models.DigestPreference
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/digest/preferences [get]
+func (h *Handler) GetDigestPreferences(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	preferences, err := h.Service.Digest.GetPreferences(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get digest preferences", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"preferences": preferences,
+	})
+}
+
+// UpdateDigestPreferences
+// @Summary Update digest preferences
+// @Security ApiKeyAuth
+// @Tags digest
+// @Description turn weekly digests on or off for current staff
+// @Description kind can be only models.DigestKind, kinds not in the list are left as they are
+// @ID update-digest-preferences
+// @Accept  json
+// @Produce  json
+// @Param input body []models.DigestPreference true "preferences"
+// @Success 200 {array} models.DigestPreference
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/digest/preferences [put]
+func (h *Handler) UpdateDigestPreferences(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	var preferences []*models.DigestPreference
+
+	if err := c.Bind(&preferences); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get input model in updating digest preferences: %s", err).Error())
+		return
+	}
+
+	preferences, err := h.Service.Digest.UpdatePreferences(ctx, userID.(uuid.UUID), preferences)
+	if err != nil {
+		newServiceErrorResponse(c, "can not update digest preferences", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"preferences": preferences,
+	})
+}
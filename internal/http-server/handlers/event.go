@@ -21,7 +21,7 @@ func (h *Handler) GetEvents(c *gin.Context) {
 
 	user, err := h.Service.Staff.GetStaff(ctx, id.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Sprintf("can not get staff by id; err: %s;", err.Error()))
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 	if !user.Position.HasPermission(models.EventGetAll) {
@@ -29,15 +29,20 @@ func (h *Handler) GetEvents(c *gin.Context) {
 		return
 	}
 
-	events, err := h.Service.Event.GetStaffsEvents(ctx, id.(uuid.UUID))
+	query, err := models.NewListQuery(c.Request.URL.Query(), models.EventList)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError,
-			fmt.Errorf("can not get events by staff role: %s", err).Error())
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse list query: %s", err).Error())
+		return
+	}
+	events, next, err := h.Service.Event.GetStaffsEvents(ctx, id.(uuid.UUID), query)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get events by staff role", err)
 		return
 	}
 
 	c.JSON(http.StatusOK, map[string]interface{}{
-		"events": events,
+		"events":      events,
+		"next_cursor": next,
 	})
 }
 
@@ -46,9 +51,14 @@ func (h *Handler) GetEvents(c *gin.Context) {
 // @Security ApiKeyAuth
 // @Tags events
 // @Description Get Events where staff takes a part by role
+// @Description filters: status, type, from and to of end date
+// @Description sort: end_date by default, name or creation_date, - prefix for descending order
 // @ID get-staff-events
 // @Accept  json
 // @Produce  json
+// @Param limit query int false "page size, 50 by default, 200 at most"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param sort query string false "sort field"
 // @Success 200 {object} eventsResponse
 // @Failure 400,403 {} errorResponse
 // @Failure 500 {object} errorResponse
@@ -65,7 +75,7 @@ func (h *Handler) GetUserEvents(c *gin.Context) {
 
 	user, err := h.Service.Staff.GetStaff(ctx, id.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Sprintf("can not get staff by id; err: %s;", err.Error()))
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 	if !user.Position.HasPermission(models.EventGetAll) {
@@ -75,15 +85,20 @@ func (h *Handler) GetUserEvents(c *gin.Context) {
 
 	staffRole := c.Param("role")
 
-	events, err := h.Service.Event.GetStaffsEventsByRole(ctx, id.(uuid.UUID), staffRole)
+	query, err := models.NewListQuery(c.Request.URL.Query(), models.EventList)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError,
-			fmt.Errorf("can not get events by staff role: %s", err).Error())
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse list query: %s", err).Error())
+		return
+	}
+	events, next, err := h.Service.Event.GetStaffsEventsByRole(ctx, id.(uuid.UUID), staffRole, query)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get events by staff role", err)
 		return
 	}
 
 	c.JSON(http.StatusOK, map[string]interface{}{
-		"events": events,
+		"events":      events,
+		"next_cursor": next,
 	})
 }
 
@@ -95,9 +110,12 @@ func (h *Handler) GetUserEvents(c *gin.Context) {
 // @Description if no org id in request
 // @Description use a default org id
 // @Description if no type use public type
-// @Description event status by default process
+// @Description event status by default scheduled when it starts in the future, else running
+// @Description draft events are not started until they are scheduled
 // @Description add a user who created this event to a member of this event
 // @Description if some steps there creates it
+// @Description max_participants caps accepted staff, 0 means unlimited
+// @Description invitation_ttl is the invite lifetime in seconds, 0 means invites do not expire
 // @ID create-event
 // @Accept  json
 // @Produce  json
@@ -124,7 +142,7 @@ func (h *Handler) CreateEvent(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -148,9 +166,6 @@ func (h *Handler) CreateEvent(c *gin.Context) {
 	if event.EventType == "" {
 		event.EventType = "public"
 	}
-	if event.EventStatus == "" {
-		event.EventStatus = "process"
-	}
 	event.ID = uuid.New()
 	for i := range event.StaffEvents {
 		event.StaffEvents[i].EventID = event.ID
@@ -164,7 +179,7 @@ func (h *Handler) CreateEvent(c *gin.Context) {
 	})
 	err = h.Service.Event.CreateEvent(ctx, event)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create event: %s", err).Error())
+		newServiceErrorResponse(c, "can not create event", err)
 		return
 	}
 	if len(event.Steps) != 0 {
@@ -199,7 +214,7 @@ func (h *Handler) CreateEvent(c *gin.Context) {
 
 			err = h.Service.Step.CreateStep(ctx, &step, creationTime, endTime)
 			if err != nil {
-				newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model: %s", err).Error())
+				newServiceErrorResponse(c, "can not create model", err)
 				return
 			}
 		}
@@ -214,6 +229,7 @@ func (h *Handler) CreateEvent(c *gin.Context) {
 // @Security ApiKeyAuth
 // @Tags events
 // @Description Assign staff array
+// @Description staff can be invited only to running events before the registration deadline
 // @ID assign-staff-to-event
 // @Accept  json
 // @Produce  json
@@ -241,7 +257,7 @@ func (h *Handler) AssignStaffToEvent(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -272,7 +288,7 @@ func (h *Handler) AssignStaffToEvent(c *gin.Context) {
 	}
 	err = h.Service.Event.AssignStaff(ctx, staffEvents, id)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model: %s", err).Error())
+		newServiceErrorResponse(c, "can not create model", err)
 		return
 	}
 
@@ -286,7 +302,9 @@ func (h *Handler) AssignStaffToEvent(c *gin.Context) {
 // @Security ApiKeyAuth
 // @Tags events
 // @Description Answer invite by ID
-// @Description status can be only models.InviteStatus
+// @Description status can be only accepted or declared
+// @Description accepted invite is waitlisted when the event is full, declining gives the place to the waitlist
+// @Description expired invites and invites after the registration deadline can not be accepted
 // @ID answer-invite
 // @Accept  json
 // @Produce  json
@@ -326,14 +344,15 @@ func (h *Handler) AnswerInvitation(c *gin.Context) {
 	}
 	staffEvents.StaffID = userID.(uuid.UUID)
 	staffEvents.ID = id
-	err = h.Service.Event.AnswerInvitation(ctx, staffEvents)
+	status, err := h.Service.Event.AnswerInvitation(ctx, staffEvents)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model: %s", err).Error())
+		newServiceErrorResponse(c, "can not create model", err)
 		return
 	}
 
 	c.JSON(http.StatusOK, map[string]interface{}{
 		"answered": true,
+		"status":   status,
 	})
 }
 
@@ -342,9 +361,13 @@ func (h *Handler) AnswerInvitation(c *gin.Context) {
 // @Security ApiKeyAuth
 // @Tags events
 // @Description Get all invitations by current user
+// @Description sort: -invited_at by default or expires_at, - prefix for descending order
 // @ID get-invites
 // @Accept  json
 // @Produce  json
+// @Param limit query int false "page size, 50 by default, 200 at most"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param sort query string false "sort field"
 // @Success 200 {object} []staffEvents
 // @Failure 400,403 {object} errorResponse
 // @Failure 500 {object} errorResponse
@@ -366,14 +389,20 @@ func (h *Handler) GetInvitations(c *gin.Context) {
 		return
 	}
 
-	invites, err := h.Service.Event.GetInvites(ctx, userID.(uuid.UUID))
+	query, err := models.NewListQuery(c.Request.URL.Query(), models.InvitationList)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse list query: %s", err).Error())
+		return
+	}
+	invites, next, err := h.Service.Event.GetInvites(ctx, userID.(uuid.UUID), query)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model: %s", err).Error())
+		newServiceErrorResponse(c, "can not create model", err)
 		return
 	}
 
 	c.JSON(http.StatusOK, map[string]interface{}{
-		"invites": invites,
+		"invites":     invites,
+		"next_cursor": next,
 	})
 }
 
@@ -407,7 +436,7 @@ func (h *Handler) GetEventByID(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -424,7 +453,7 @@ func (h *Handler) GetEventByID(c *gin.Context) {
 
 	event, err := h.Service.Event.GetEvent(ctx, id)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model: %s", err).Error())
+		newServiceErrorResponse(c, "can not create model", err)
 		return
 	}
 	c.JSON(http.StatusOK, map[string]interface{}{
@@ -437,6 +466,8 @@ func (h *Handler) GetEventByID(c *gin.Context) {
 // @Security ApiKeyAuth
 // @Tags events
 // @Description Update event by ID
+// @Description max_participants changes the cap only when it is sent, 0 removes the cap
+// @Description staff on the waitlist fills places freed by a raised or removed cap
 // @ID update-event
 // @Accept  json
 // @Produce  json
@@ -464,7 +495,7 @@ func (h *Handler) UpdateEvent(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -480,20 +511,92 @@ func (h *Handler) UpdateEvent(c *gin.Context) {
 		return
 	}
 
-	var event *models.Event
+	// max_participants is a pointer, so a cap removed with 0 differs from a cap not sent.
+	var input struct {
+		*models.Event
+		MaxParticipants *uint `json:"max_participants"`
+	}
 
-	if err := c.Bind(&event); err != nil {
+	if err := c.Bind(&input); err != nil {
 		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get input update model in updating team: %s", err).Error())
 		return
 	}
+	event := input.Event
+	if event == nil {
+		event = new(models.Event)
+	}
 
 	event.ID = id
 	for i := range event.StaffEvents {
 		event.StaffEvents[i].EventID = id
 	}
-	err = h.Service.Event.UpdateEvent(ctx, event)
+	err = h.Service.Event.UpdateEvent(ctx, event, input.MaxParticipants)
+	if err != nil {
+		newServiceErrorResponse(c, "can not update model in updating team", err)
+		return
+	}
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"updated": true,
+	})
+}
+
+// UpdateEventStatus
+// @Summary Change event status
+// @Security ApiKeyAuth
+// @Tags events
+// @Description draft -> scheduled -> running -> finished, not finished event can be canceled
+// @Description scheduled event starts at creation date, running event finishes at end date
+// @Description open steps are closed when event is finished or canceled
+// @ID update-event-status
+// @Accept  json
+// @Produce  json
+// @Param input body models.EventStatusRequest true "new status"
+// @Success 200 {object} boolean
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/status/:id [put]
+func (h *Handler) UpdateEventStatus(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not update model in updating team: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.EventUpdate, models.OrganizationUpdate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in changing event status: %s", err).Error())
+		return
+	}
+
+	var input models.EventStatusRequest
+	if err := c.Bind(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get input model in changing event status: %s", err).Error())
+		return
+	}
+	if err := h.Service.Event.ChangeEventStatus(ctx, id, input.Status); err != nil {
+		newServiceErrorResponse(c, "can not change event status", err)
 		return
 	}
 	c.JSON(http.StatusOK, map[string]interface{}{
@@ -532,7 +635,7 @@ func (h *Handler) DeleteEvent(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -549,7 +652,7 @@ func (h *Handler) DeleteEvent(c *gin.Context) {
 
 	err = h.Service.Event.DeleteEvent(ctx, id)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not delete team: %s", err).Error())
+		newServiceErrorResponse(c, "can not delete team", err)
 		return
 	}
 	c.JSON(http.StatusOK, map[string]interface{}{
@@ -562,9 +665,14 @@ func (h *Handler) DeleteEvent(c *gin.Context) {
 // @Security ApiKeyAuth
 // @Tags events
 // @Description Get all team's events by ID
+// @Description filters: status, type, from and to of end date
+// @Description sort: end_date by default, name or creation_date, - prefix for descending order
 // @ID team-events
 // @Accept  json
 // @Produce  json
+// @Param limit query int false "page size, 50 by default, 200 at most"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param sort query string false "sort field"
 // @Success 200 {object} []eventAllData
 // @Failure 400,403 {object} errorResponse
 // @Failure 500 {object} errorResponse
@@ -587,7 +695,7 @@ func (h *Handler) GetTeamEvents(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -602,13 +710,19 @@ func (h *Handler) GetTeamEvents(c *gin.Context) {
 		return
 	}
 
-	events, err := h.Service.Event.GetEventsByTeamID(ctx, id)
+	query, err := models.NewListQuery(c.Request.URL.Query(), models.EventList)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model: %s", err).Error())
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse list query: %s", err).Error())
+		return
+	}
+	events, next, err := h.Service.Event.GetEventsByTeamID(ctx, id, query)
+	if err != nil {
+		newServiceErrorResponse(c, "can not create model", err)
 		return
 	}
 	c.JSON(http.StatusOK, map[string]interface{}{
-		"events": events,
+		"events":      events,
+		"next_cursor": next,
 	})
 }
 
@@ -617,6 +731,7 @@ func (h *Handler) GetTeamEvents(c *gin.Context) {
 // @Security ApiKeyAuth
 // @Tags events
 // @Description Remove staff from event and connected steps
+// @Description the freed place goes to the first staff on the waitlist
 // @ID event-remove-staff
 // @Accept  json
 // @Param input body models.StaffID true "staffID"
@@ -643,7 +758,7 @@ func (h *Handler) RemoveStaffFromEvent(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -670,7 +785,7 @@ func (h *Handler) RemoveStaffFromEvent(c *gin.Context) {
 
 	err = h.Service.Event.RemoveStaffFromEvent(ctx, event)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model: %s", err).Error())
+		newServiceErrorResponse(c, "can not create model", err)
 		return
 	}
 	c.JSON(http.StatusOK, map[string]interface{}{
@@ -714,7 +829,7 @@ func (h *Handler) GetStaffScore(c *gin.Context) {
 
 	events, err := h.Service.Event.GetStaffScore(ctx, id, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model: %s", err).Error())
+		newServiceErrorResponse(c, "can not create model", err)
 		return
 	}
 	c.JSON(http.StatusOK, map[string]interface{}{
@@ -25,7 +25,7 @@ func (h *Handler) InitRoutes() *gin.Engine {
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{http.MethodPost, http.MethodGet, http.MethodPut, http.MethodDelete, http.MethodOptions},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Access-Control-Allow-Origin"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Access-Control-Allow-Origin", "Last-Event-ID"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
@@ -36,6 +36,15 @@ func (h *Handler) InitRoutes() *gin.Engine {
 		auth.POST("/sign-up", h.signUp)
 		auth.POST("/sign-in", h.signIn)
 	}
+	chat := router.Group("/chat")
+	{
+		chat.POST("/command/:platform", h.ChatCommand)
+	}
+	digest := router.Group("/digest")
+	{
+		digest.GET("/unsubscribe", h.DigestUnsubscribe)
+		digest.POST("/unsubscribe", h.DigestUnsubscribe)
+	}
 	api := router.Group("/api", h.identity)
 	{
 		organization := api.Group("/org")
@@ -106,6 +115,27 @@ func (h *Handler) InitRoutes() *gin.Engine {
 			event.GET("/staff/:role", h.GetUserEvents)        // ads
 			event.GET("/team/:id", h.GetTeamEvents)           // ads
 			event.PUT("/:id", h.UpdateEvent)
+			event.PUT("/status/:id", h.UpdateEventStatus)
+			event.POST("/template/:id", h.SaveTemplate)
+			event.GET("/template/:id", h.GetTemplate)
+			event.DELETE("/template/:id", h.DeleteTemplate)
+			event.GET("/templates/:id", h.GetTemplates)
+			event.POST("/template/instantiate/:id", h.InstantiateTemplate)
+			event.POST("/clone/:id", h.CloneEvent)
+			event.POST("/series/", h.CreateSeries)
+			event.GET("/series/:id", h.GetSeries)
+			event.DELETE("/series/:id", h.DeleteSeries)
+			event.GET("/series/org/:id", h.GetOrganizationSeries)
+			event.GET("/series/occurrences/:id", h.GetOccurrences)
+			event.PUT("/series/occurrence/:id", h.UpdateOccurrence)
+			event.POST("/join/:id", h.JoinEvent)
+			event.POST("/join/code/:code", h.JoinByCode)
+			event.POST("/join/request/:id", h.RequestJoin)
+			event.PUT("/join/request/:id", h.AnswerJoinRequest)
+			event.GET("/join/requests/:id", h.GetJoinRequests)
+			event.POST("/join/codes/:id", h.CreateJoinCode)
+			event.GET("/join/codes/:id", h.GetJoinCodes)
+			event.DELETE("/join/codes/:id", h.DeleteJoinCode)
 			event.GET("/score/:id", h.GetStaffScore) // ads
 			event.DELETE("/remove/:id", h.RemoveStaffFromEvent)
 			event.DELETE("/:id", h.DeleteEvent)
@@ -120,16 +150,94 @@ func (h *Handler) InitRoutes() *gin.Engine {
 				step.GET("/prizes/:id", h.GetStepPrizes)
 				step.PUT("/status/:id", h.PassStaff)
 				step.PUT("/assign/:id", h.AssignStaff)
+				step.POST("/submit/:id", h.SubmitStep)
+				step.GET("/queue/:id", h.GetSubmissionQueue)
+				step.GET("/submissions/:id", h.GetStaffSubmissions)
+				step.GET("/reviews", h.GetReviewAssignments)
+				step.PUT("/review/:id", h.SubmitReview)
+				step.PUT("/rubric/:id", h.UpdateRubric)
+				step.GET("/result/:id", h.GetStepResult)
+				step.PUT("/prerequisites/:id", h.UpdatePrerequisites)
+				step.GET("/progress/:id", h.GetStepProgress)
+				step.PUT("/branch/:id", h.ChooseBranch)
+				step.GET("/graph/:id", h.GetStepGraph)
+				step.GET("/path/:id", h.GetStaffPath)
+				step.GET("/quiz/:id", h.StartQuiz)
+				step.POST("/quiz/:id", h.SubmitQuiz)
+				step.GET("/quiz/attempts/:id", h.GetQuizAttempts)
+				step.GET("/quiz/key/:id", h.GetQuizKey)
+				step.PUT("/quiz/key/:id", h.UpdateQuiz)
+				step.GET("/code/languages", h.GetCodeLanguages)
+				step.POST("/code/:id", h.SubmitCode)
+				step.GET("/code/:id", h.GetCodeSubmissions)
+				step.GET("/code/tests/:id", h.GetCodeTestCases)
+				step.PUT("/code/tests/:id", h.UpdateCodeTestCases)
+				step.GET("/jobs/:id", h.GetStepJobs)
+				step.GET("/job/:id", h.GetJob)
+
+				submission := step.Group("/submission")
+				{
+					submission.GET("/:id", h.GetSubmission)
+					submission.GET("/file/:id/:name", h.GetSubmissionFile)
+					submission.PUT("/approve/:id", h.ApproveSubmission)
+					submission.PUT("/reject/:id", h.RejectSubmission)
+					submission.POST("/comment/:id", h.CommentSubmission)
+					submission.PUT("/reviewer/:id", h.AssignReviewer)
+				}
 			}
 		}
+		stream := api.Group("/stream")
+		{
+			stream.GET("/event/:id", h.StreamEvent)
+			stream.GET("/org/:id", h.StreamOrganization)
+		}
+		notification := api.Group("/notification")
+		{
+			notification.GET("/", h.GetNotifications)
+			notification.GET("/unread", h.CountUnreadNotifications)
+			notification.PUT("/read", h.MarkNotificationsRead)
+			notification.GET("/preferences", h.GetNotificationPreferences)
+			notification.PUT("/preferences", h.UpdateNotificationPreferences)
+		}
+		api.GET("/search", h.Search)
+		digestPreferences := api.Group("/digest")
+		{
+			digestPreferences.GET("/preferences", h.GetDigestPreferences)
+			digestPreferences.PUT("/preferences", h.UpdateDigestPreferences)
+		}
+		webhook := api.Group("/webhook")
+		{
+			webhook.POST("/", h.CreateWebhook)
+			webhook.GET("/", h.GetWebhooks)
+			webhook.GET("/:id", h.GetWebhook)
+			webhook.PUT("/:id", h.UpdateWebhook)
+			webhook.DELETE("/:id", h.DeleteWebhook)
+			webhook.GET("/deliveries/:id", h.GetWebhookDeliveries)
+			webhook.POST("/redeliver/:id", h.RedeliverWebhook)
+		}
+		chatIntegration := api.Group("/chat")
+		{
+			chatIntegration.POST("/", h.CreateChatIntegration)
+			chatIntegration.GET("/", h.GetChatIntegrations)
+			chatIntegration.GET("/:id", h.GetChatIntegration)
+			chatIntegration.PUT("/:id", h.UpdateChatIntegration)
+			chatIntegration.DELETE("/:id", h.DeleteChatIntegration)
+		}
 		prize := api.Group("/prize")
 		{
 			prize.POST("/", h.CreatePrize)
 			prize.GET("/:id", h.GetPrize)
 			prize.GET("/", h.GetPrizes)
 			prize.GET("/user/:type", h.GetPrizesByType)
+			prize.GET("/org/:id", h.GetOrganizationPrizes)
 			prize.PUT("/:id", h.UpdatePrize)
+			prize.DELETE("/:id", h.DeletePrize)
+			prize.PUT("/restore/:id", h.RestorePrize)
 			prize.POST("/give/:id", h.GivePrize)
+			prize.POST("/open/:id", h.OpenLootBox)
+			prize.GET("/draws/:id", h.GetLootBoxDraws)
+			prize.GET("/drop/:id", h.GetDropTable)
+			prize.PUT("/drop/:id", h.UpdateDropTable)
 		}
 	}
 
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"net/http"
+)
+
+// GetStepJobs
+// @Summary Get step jobs
+// @Security ApiKeyAuth
+// @Tags steps
+// @Description get scheduled jobs of step by step ID
+// @Description shows when the step is created and finished and whether the job failed
+// @ID get-step-jobs
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []models.Job
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/step/jobs/:id [get]
+func (h *Handler) GetStepJobs(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.StepGetAll, models.EventGetByID, models.EventGetAll) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in getting step jobs: %s", err).Error())
+		return
+	}
+
+	jobs, err := h.Service.Scheduler.GetStepJobs(ctx, id)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get step jobs", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"jobs": jobs,
+	})
+}
+
+// GetJob
+// @Summary Get job
+// @Security ApiKeyAuth
+// @Tags steps
+// @Description get scheduled job status by job ID
+// @ID get-job
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} models.Job
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/step/job/:id [get]
+func (h *Handler) GetJob(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.StepGetAll, models.EventGetByID, models.EventGetAll) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in getting job: %s", err).Error())
+		return
+	}
+
+	job, err := h.Service.Scheduler.GetJob(ctx, id)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get job", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"job": job,
+	})
+}
@@ -0,0 +1,462 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"net/http"
+)
+
+// JoinEvent
+// @Summary Join public event
+// @Security ApiKeyAuth
+// @Tags events
+// @Description join current staff to a public event by event ID
+// @Description staff is waitlisted when the event is full
+// @Description private and team-only events are joined with a join code or a join request
+// @ID join-event
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} boolean
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/join/:id [post]
+func (h *Handler) JoinEvent(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in joining event: %s", err).Error())
+		return
+	}
+
+	status, err := h.Service.Join.JoinEvent(ctx, id, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not join event", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"joined": true,
+		"status": status,
+	})
+}
+
+// JoinByCode
+// @Summary Join event by code
+// @Security ApiKeyAuth
+// @Tags events
+// @Description join current staff to the event of the join code, the code is a part of the join link
+// @Description team and organization checks of the event still apply
+// @Description staff is waitlisted when the event is full
+// @ID join-by-code
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} models.StaffEvents
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/join/code/:code [post]
+func (h *Handler) JoinByCode(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	invite, err := h.Service.Join.JoinByCode(ctx, c.Param("code"), userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not join event by code", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"invite": invite,
+	})
+}
+
+// RequestJoin
+// @Summary Request to join event
+// @Security ApiKeyAuth
+// @Tags events
+// @Description request to join an event by event ID
+// @Description the request waits for approval of the event creator or admins
+// @ID request-join
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} models.StaffEvents
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/join/request/:id [post]
+func (h *Handler) RequestJoin(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in requesting join: %s", err).Error())
+		return
+	}
+
+	request, err := h.Service.Join.RequestJoin(ctx, id, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not request join", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"request": request,
+	})
+}
+
+// AnswerJoinRequest
+// @Summary Answer join request
+// @Security ApiKeyAuth
+// @Tags events
+// @Description approve or decline join request by ID, status can be only accepted or declared
+// @Description only creator and admins of the event can answer
+// @Description approved staff is waitlisted when the event is full
+// @ID answer-join-request
+// @Accept  json
+// @Produce  json
+// @Param input body models.JoinRequestAnswer true "answer"
+// @Success 200 {object} boolean
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/join/request/:id [put]
+func (h *Handler) AnswerJoinRequest(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.EventCreate, models.EventUpdate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in answering join request: %s", err).Error())
+		return
+	}
+
+	var answer models.JoinRequestAnswer
+
+	if err := c.Bind(&answer); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get input model in answering join request: %s", err).Error())
+		return
+	}
+
+	status, err := h.Service.Join.AnswerJoinRequest(ctx, id, staff.ID, answer.Status)
+	if err != nil {
+		newServiceErrorResponse(c, "can not answer join request", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"answered": true,
+		"status":   status,
+	})
+}
+
+// GetJoinRequests
+// @Summary Get join requests
+// @Security ApiKeyAuth
+// @Tags events
+// @Description get waiting join requests of the event by event ID
+// @Description only creator and admins of the event can see them
+// @ID get-join-requests
+// @Accept  json
+// @Produce  json
+// @Success 200 {array} models.StaffEvents
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/join/requests/:id [get]
+func (h *Handler) GetJoinRequests(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.EventCreate, models.EventUpdate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in getting join requests: %s", err).Error())
+		return
+	}
+
+	requests, err := h.Service.Join.GetJoinRequests(ctx, id, staff.ID)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get join requests", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"requests": requests,
+	})
+}
+
+// CreateJoinCode
+// @Summary Create join code
+// @Security ApiKeyAuth
+// @Tags events
+// @Description create a join code of the event by event ID
+// @Description ttl is the code lifetime in seconds, 0 means the code does not expire
+// @Description max_uses limits joins with the code, 0 means unlimited
+// @Description only creator and admins of the event can create codes
+// @ID create-join-code
+// @Accept  json
+// @Produce  json
+// @Param input body models.JoinCodeRequest true "join code info"
+// @Success 200 {object} models.JoinCode
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/join/codes/:id [post]
+func (h *Handler) CreateJoinCode(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.EventCreate, models.EventUpdate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in creating join code: %s", err).Error())
+		return
+	}
+
+	var request models.JoinCodeRequest
+
+	if err := c.Bind(&request); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get input model in creating join code: %s", err).Error())
+		return
+	}
+
+	code, err := h.Service.Join.CreateJoinCode(ctx, id, staff.ID, request)
+	if err != nil {
+		newServiceErrorResponse(c, "can not create join code", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"code": code,
+	})
+}
+
+// GetJoinCodes
+// @Summary Get join codes
+// @Security ApiKeyAuth
+// @Tags events
+// @Description get join codes of the event by event ID
+// @Description only creator and admins of the event can see them
+// @ID get-join-codes
+// @Accept  json
+// @Produce  json
+// @Success 200 {array} models.JoinCode
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/join/codes/:id [get]
+func (h *Handler) GetJoinCodes(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.EventCreate, models.EventUpdate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in getting join codes: %s", err).Error())
+		return
+	}
+
+	codes, err := h.Service.Join.GetJoinCodes(ctx, id, staff.ID)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get join codes", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"codes": codes,
+	})
+}
+
+// DeleteJoinCode
+// @Summary Delete join code
+// @Security ApiKeyAuth
+// @Tags events
+// @Description delete join code by ID, staff can not join with it anymore
+// @Description only creator and admins of the event can delete codes
+// @ID delete-join-code
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} boolean
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/join/codes/:id [delete]
+func (h *Handler) DeleteJoinCode(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.EventCreate, models.EventUpdate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in deleting join code: %s", err).Error())
+		return
+	}
+
+	if err := h.Service.Join.DeleteJoinCode(ctx, id, staff.ID); err != nil {
+		newServiceErrorResponse(c, "can not delete join code", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"deleted": true,
+	})
+}
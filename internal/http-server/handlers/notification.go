@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"net/http"
+	"strconv"
+)
+
+// GetNotifications
+// @Summary Get notifications
+// @Security ApiKeyAuth
+// @Tags notifications
+// @Description get notifications of current staff, newest first
+// @Description unread=true returns only unread notifications
+// @Description limit is 50 by default and 200 at most
+// @ID get-notifications
+// @Accept  json
+// @Produce  json
+// @Success 200 {array} models.Notification
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/notification/ [get]
+func (h *Handler) GetNotifications(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	var filter models.NotificationFilter
+	var err error
+	if unread := c.Query("unread"); unread != "" {
+		filter.Unread, err = strconv.ParseBool(unread)
+		if err != nil {
+			newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse unread filter: %s", err).Error())
+			return
+		}
+	}
+	if limit := c.Query("limit"); limit != "" {
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil {
+			newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse limit: %s", err).Error())
+			return
+		}
+	}
+
+	notifications, err := h.Service.Notification.GetNotifications(ctx, userID.(uuid.UUID), filter)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get notifications", err)
+		return
+	}
+	unread, err := h.Service.Notification.CountUnread(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not count unread notifications", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"notifications": notifications,
+		"unread":        unread,
+	})
+}
+
+// CountUnreadNotifications
+// @Summary Count unread notifications
+// @Security ApiKeyAuth
+// @Tags notifications
+// @Description get the number of unread notifications of current staff
+// @ID count-unread-notifications
+// @Accept  json
+// @Produce  json
+// @Success 200 {integer} integer
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/notification/unread [get]
+func (h *Handler) CountUnreadNotifications(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	unread, err := h.Service.Notification.CountUnread(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not count unread notifications", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"unread": unread,
+	})
+}
+
+// MarkNotificationsRead
+// @Summary Mark notifications as read
+// @Security ApiKeyAuth
+// @Tags notifications
+// @Description mark notifications of current staff as read by IDs
+// @Description all unread notifications are marked when ids are empty
+// @ID mark-notifications-read
+// @Accept  json
+// @Produce  json
+// @Param input body models.MarkReadRequest true "notification ids"
+// @Success 200 {integer} integer
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/notification/read [put]
+func (h *Handler) MarkNotificationsRead(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	var request models.MarkReadRequest
+
+	if err := c.Bind(&request); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get input model in marking notifications: %s", err).Error())
+		return
+	}
+
+	marked, err := h.Service.Notification.MarkRead(ctx, userID.(uuid.UUID), request.IDs)
+	if err != nil {
+		newServiceErrorResponse(c, "can not mark notifications as read", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"marked": marked,
+	})
+}
+
+// GetNotificationPreferences
+// @Summary Get notification preferences
+// @Security ApiKeyAuth
+// @Tags notifications
+// @Description get preferences of current staff for every notification type
+// @Description types are on by default
+// @ID get-notification-preferences
+// @Accept  json
+// @Produce  json
+// @Success 200 {array} models.NotificationPreference
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/notification/preferences [get]
+func (h *Handler) GetNotificationPreferences(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	preferences, err := h.Service.Notification.GetPreferences(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get notification preferences", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"preferences": preferences,
+	})
+}
+
+// UpdateNotificationPreferences
+// @Summary Update notification preferences
+// @Security ApiKeyAuth
+// @Tags notifications
+// @Description turn notification types on or off for current staff
+// @Description type can be only models.NotificationType, types not in the list are left as they are
+// @ID update-notification-preferences
+// @Accept  json
+// @Produce  json
+// @Param input body []models.NotificationPreference true "preferences"
+// @Success 200 {array} models.NotificationPreference
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/notification/preferences [put]
+func (h *Handler) UpdateNotificationPreferences(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	var preferences []*models.NotificationPreference
+
+	if err := c.Bind(&preferences); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get input model in updating notification preferences: %s", err).Error())
+		return
+	}
+
+	preferences, err := h.Service.Notification.UpdatePreferences(ctx, userID.(uuid.UUID), preferences)
+	if err != nil {
+		newServiceErrorResponse(c, "can not update notification preferences", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"preferences": preferences,
+	})
+}
@@ -15,9 +15,13 @@ import (
 // @Security ApiKeyAuth
 // @Tags organizations
 // @Description Get All Organizations In Service
+// @Description sort: name or -name for descending order
 // @ID get-all-organizations
 // @Accept  json
 // @Produce  json
+// @Param limit query int false "page size, 50 by default, 200 at most"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param sort query string false "sort field"
 // @Success 200 {object} []organizationResponse
 // @Failure 400,403 {} errorResponse
 // @Failure 500 {object} errorResponse
@@ -41,7 +45,7 @@ func (h *Handler) GetAllOrganizations(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -51,14 +55,20 @@ func (h *Handler) GetAllOrganizations(c *gin.Context) {
 		return
 	}
 
-	organizations, err := h.Service.Organization.GetOrganizations(ctx)
+	query, err := models.NewListQuery(c.Request.URL.Query(), models.OrganizationList)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse list query: %s", err).Error())
+		return
+	}
+	organizations, next, err := h.Service.Organization.GetOrganizations(ctx, query)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get organizations", err)
 		return
 	}
 
 	c.JSON(http.StatusOK, map[string]interface{}{
 		"organizations": organizations,
+		"next_cursor":   next,
 	})
 }
 
@@ -67,9 +77,14 @@ func (h *Handler) GetAllOrganizations(c *gin.Context) {
 // @Security ApiKeyAuth
 // @Tags organizations
 // @Description Get All Staff In Organization By Organization ID
+// @Description sort: last_name, first_name, - prefix for descending order
 // @ID get-organization-staff
 // @Accept  json
 // @Produce  json
+// @Param limit query int false "page size, 50 by default, 200 at most"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param sort query string false "sort field"
+// @Param team_id query string false "team of staff"
 // @Success 200 {object} []models.StaffInfo
 // @Failure 400,403 {} errorResponse
 // @Failure 500 {object} errorResponse
@@ -98,7 +113,7 @@ func (h *Handler) GetStaffByOrganizationID(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -108,14 +123,20 @@ func (h *Handler) GetStaffByOrganizationID(c *gin.Context) {
 		return
 	}
 
-	staffs, err := h.Service.Organization.GetOrganizationStaff(ctx, id)
+	query, err := models.NewListQuery(c.Request.URL.Query(), models.StaffList)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse list query: %s", err).Error())
+		return
+	}
+	staffs, next, err := h.Service.Organization.GetOrganizationStaff(ctx, id, query)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get organization staff", err)
 		return
 	}
 
 	c.JSON(http.StatusOK, map[string]interface{}{
-		"staff": staffs,
+		"staff":       staffs,
+		"next_cursor": next,
 	})
 }
 
@@ -155,7 +176,7 @@ func (h *Handler) GetOrganization(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -167,7 +188,7 @@ func (h *Handler) GetOrganization(c *gin.Context) {
 
 	organization, err := h.Service.Organization.GetOrganization(ctx, id)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newServiceErrorResponse(c, "can not get organization", err)
 		return
 	}
 
@@ -213,7 +234,7 @@ func (h *Handler) DeleteOrganization(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -225,7 +246,7 @@ func (h *Handler) DeleteOrganization(c *gin.Context) {
 
 	err = h.Service.Organization.DeleteOrganization(ctx, id)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newServiceErrorResponse(c, "can not delete organization", err)
 		return
 	}
 
@@ -266,7 +287,7 @@ func (h *Handler) CreateOrganization(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -283,7 +304,7 @@ func (h *Handler) CreateOrganization(c *gin.Context) {
 	}
 	_, err = url.ParseRequestURI(org.WebsiteURL)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Sprintf("can not url: %s", org.WebsiteURL))
+		newErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("can not url: %s", org.WebsiteURL))
 		return
 	}
 	org.ID = uuid.New()
@@ -311,7 +332,7 @@ func (h *Handler) CreateOrganization(c *gin.Context) {
 	}
 	err = h.Service.Organization.CreateOrganization(ctx, org, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model: %s", err).Error())
+		newServiceErrorResponse(c, "can not create model", err)
 		return
 	}
 
@@ -356,7 +377,7 @@ func (h *Handler) UpdateOrganization(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -390,7 +411,7 @@ func (h *Handler) UpdateOrganization(c *gin.Context) {
 
 	err = h.Service.Organization.UpdateOrganization(ctx, org)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("canupdate model in updating org: %s", err).Error())
+		newServiceErrorResponse(c, "canupdate model in updating org", err)
 		return
 	}
 	c.JSON(http.StatusOK, map[string]interface{}{
@@ -403,9 +424,14 @@ func (h *Handler) UpdateOrganization(c *gin.Context) {
 // @Security ApiKeyAuth
 // @Tags organizations
 // @Description Get Organization Events By ID
+// @Description filters: status, type, from and to of end date
+// @Description sort: end_date by default, name or creation_date, - prefix for descending order
 // @ID get-organization-events
 // @Accept  json
 // @Produce  json
+// @Param limit query int false "page size, 50 by default, 200 at most"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param sort query string false "sort field"
 // @Success 200 {object} []eventShortData
 // @Failure 400,403 {} errorResponse
 // @Failure 500 {object} errorResponse
@@ -433,7 +459,7 @@ func (h *Handler) GetOrganizationEvents(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -442,13 +468,19 @@ func (h *Handler) GetOrganizationEvents(c *gin.Context) {
 			"no access to this action")
 		return
 	}
-	events, err := h.Service.Organization.GetOrganizationEvents(ctx, id, staff.ID)
+	query, err := models.NewListQuery(c.Request.URL.Query(), models.EventList)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse list query: %s", err).Error())
+		return
+	}
+	events, next, err := h.Service.Organization.GetOrganizationEvents(ctx, id, staff.ID, query)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not get org events: %s", err).Error())
+		newServiceErrorResponse(c, "can not get org events", err)
 		return
 	}
 	c.JSON(http.StatusOK, map[string]interface{}{
-		"events": events,
+		"events":      events,
+		"next_cursor": next,
 	})
 }
 
@@ -483,7 +515,7 @@ func (h *Handler) AddStaffToOrganization(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -501,13 +533,13 @@ func (h *Handler) AddStaffToOrganization(c *gin.Context) {
 	var requestStaff users
 
 	if err := c.Bind(&requestStaff); err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not bind ids in adding staff into org: %s", err).Error())
+		newServiceErrorResponse(c, "can not bind ids in adding staff into org", err)
 		return
 	}
 
 	err = h.Service.Organization.AddUsersToOrg(ctx, id, requestStaff.Staff)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not bind staff into org: %s", err).Error())
+		newServiceErrorResponse(c, "can not bind staff into org", err)
 		return
 	}
 
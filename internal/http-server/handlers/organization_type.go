@@ -40,7 +40,7 @@ func (h *Handler) CreateOrganizationType(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -60,7 +60,7 @@ func (h *Handler) CreateOrganizationType(c *gin.Context) {
 
 	err = h.Service.Organization.CreateOrganizationType(ctx, orgType)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model org type: %s", err).Error())
+		newServiceErrorResponse(c, "can not create model org type", err)
 		return
 	}
 	c.JSON(http.StatusOK, map[string]interface{}{
@@ -98,7 +98,7 @@ func (h *Handler) GetOrganizationTypes(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -109,7 +109,7 @@ func (h *Handler) GetOrganizationTypes(c *gin.Context) {
 	}
 	organizationTypes, err := h.Service.Organization.GetOrganizationTypes(ctx)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newServiceErrorResponse(c, "can not get organization types", err)
 		return
 	}
 
@@ -148,7 +148,7 @@ func (h *Handler) GetOrganizationTypeByID(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -164,7 +164,7 @@ func (h *Handler) GetOrganizationTypeByID(c *gin.Context) {
 	}
 	organization, err := h.Service.Organization.GetOrganizationTypeByID(ctx, id)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newServiceErrorResponse(c, "can not get organization type", err)
 		return
 	}
 
@@ -203,7 +203,7 @@ func (h *Handler) DeleteOrganizationType(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -219,7 +219,7 @@ func (h *Handler) DeleteOrganizationType(c *gin.Context) {
 	}
 	err = h.Service.Organization.DeleteOrganizationTypeByID(ctx, id)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newServiceErrorResponse(c, "can not delete organization type", err)
 		return
 	}
 
@@ -259,7 +259,7 @@ func (h *Handler) UpdateOrganizationType(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -285,7 +285,7 @@ func (h *Handler) UpdateOrganizationType(c *gin.Context) {
 
 	err = h.Service.Organization.UpdateOrganizationType(ctx, orgType)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("canupdate model in updating org: %s", err).Error())
+		newServiceErrorResponse(c, "canupdate model in updating org", err)
 		return
 	}
 	c.JSON(http.StatusOK, map[string]interface{}{
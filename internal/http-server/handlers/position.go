@@ -40,7 +40,7 @@ func (h *Handler) UpdatePosition(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -69,7 +69,7 @@ func (h *Handler) UpdatePosition(c *gin.Context) {
 	}
 	err = h.Service.Staff.UpdatePosition(ctx, position)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model: %s", err).Error())
+		newServiceErrorResponse(c, "can not create model", err)
 		return
 	}
 
@@ -109,7 +109,7 @@ func (h *Handler) RemovePermissions(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -135,7 +135,7 @@ func (h *Handler) RemovePermissions(c *gin.Context) {
 	}
 	err = h.Service.Staff.RemovePermissionsFromPosition(ctx, *permissions)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model: %s", err).Error())
+		newServiceErrorResponse(c, "can not create model", err)
 		return
 	}
 
@@ -161,7 +161,7 @@ func (h *Handler) DeletePosition(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -177,7 +177,7 @@ func (h *Handler) DeletePosition(c *gin.Context) {
 	}
 	err = h.Service.Staff.DeletePosition(ctx, id)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newServiceErrorResponse(c, "can not delete position", err)
 		return
 	}
 
@@ -217,7 +217,7 @@ func (h *Handler) CreatePosition(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -240,7 +240,7 @@ func (h *Handler) CreatePosition(c *gin.Context) {
 	}
 	err = h.Service.Staff.CreatePosition(ctx, position)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model: %s", err).Error())
+		newServiceErrorResponse(c, "can not create model", err)
 		return
 	}
 
@@ -266,7 +266,7 @@ func (h *Handler) TakePosition(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -285,7 +285,7 @@ func (h *Handler) TakePosition(c *gin.Context) {
 	for _, staffID := range staffIDs {
 		err = h.Service.Staff.RemoveFromPosition(ctx, staffID.StaffID)
 		if err != nil {
-			newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model: %s", err).Error())
+			newServiceErrorResponse(c, "can not create model", err)
 			return
 		}
 	}
@@ -312,7 +312,7 @@ func (h *Handler) GivePosition(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -336,7 +336,7 @@ func (h *Handler) GivePosition(c *gin.Context) {
 	for _, staffID := range staffIDs {
 		err = h.Service.Staff.AssignPosition(ctx, staffID.StaffID, positionID)
 		if err != nil {
-			newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model: %s", err).Error())
+			newServiceErrorResponse(c, "can not create model", err)
 			return
 		}
 	}
@@ -363,7 +363,7 @@ func (h *Handler) GetOrganizationPositions(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -379,7 +379,7 @@ func (h *Handler) GetOrganizationPositions(c *gin.Context) {
 	}
 	positions, err := h.Service.Staff.GetAllPositions(ctx, id)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newServiceErrorResponse(c, "can not get positions", err)
 		return
 	}
 
@@ -405,7 +405,7 @@ func (h *Handler) GetPosition(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -421,7 +421,7 @@ func (h *Handler) GetPosition(c *gin.Context) {
 	}
 	position, err := h.Service.Staff.GetPosition(ctx, id)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newServiceErrorResponse(c, "can not get position", err)
 		return
 	}
 
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"net/http"
+)
+
+// UpdatePrerequisites
+// @Summary Update step prerequisites
+// @Security ApiKeyAuth
+// @Tags steps
+// @Description replace steps which staff has to do before the step unlocks by step ID
+// @Description required steps must be in the same event and must not make a cycle
+// @Description staff already assigned to the step keeps it
+// @ID update-prerequisites
+// @Accept  json
+// @Produce  json
+// @Param input body []models.StepPrerequisite true "prerequisites"
+// @Success 200 {object} boolean
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/step/prerequisites/:id [put]
+func (h *Handler) UpdatePrerequisites(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.StepUpdate, models.EventCreate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in updating prerequisites: %s", err).Error())
+		return
+	}
+
+	var prerequisites []*models.StepPrerequisite
+
+	if err := c.Bind(&prerequisites); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get input model in updating prerequisites: %s", err).Error())
+		return
+	}
+
+	err = h.Service.Step.UpdatePrerequisites(ctx, id, prerequisites)
+	if err != nil {
+		newServiceErrorResponse(c, "can not update prerequisites", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"updated": true,
+	})
+}
+
+// GetStepProgress
+// @Summary Get staff progress in event
+// @Security ApiKeyAuth
+// @Tags steps
+// @Description get steps of event by event ID ordered by level with state for staff: locked, available, in-progress or done
+// @Description locked steps show prerequisites which are not done yet
+// @Description current staff progress is returned unless staff_id is passed by staff who can read all steps
+// @ID get-step-progress
+// @Accept  json
+// @Produce  json
+// @Param staff_id query string false "staff ID"
+// @Success 200 {object} []models.StepProgress
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/step/progress/:id [get]
+func (h *Handler) GetStepProgress(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in getting progress: %s", err).Error())
+		return
+	}
+
+	staffID := userID.(uuid.UUID)
+	if param := c.Query("staff_id"); param != "" {
+		staffID, err = uuid.Parse(param)
+		if err != nil {
+			newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse staff id: %s", err).Error())
+			return
+		}
+	}
+	if staffID != userID.(uuid.UUID) {
+		staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+		if err != nil {
+			newServiceErrorResponse(c, "can not get staff by id", err)
+			return
+		}
+		if !staff.HasOneOfPermissions(models.StepGetAll, models.EventGetByID, models.EventGetAll) {
+			newErrorResponse(c, http.StatusForbidden,
+				"no access to this action")
+			return
+		}
+	}
+
+	progress, err := h.Service.Step.GetStepProgress(ctx, id, staffID)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get step progress", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"steps": progress,
+	})
+}
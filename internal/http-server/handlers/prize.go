@@ -8,6 +8,7 @@ import (
 	"github.com/miprokop/fication/internal/models"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 )
 
@@ -28,7 +29,7 @@ func (h *Handler) CreatePrize(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -52,10 +53,14 @@ func (h *Handler) CreatePrize(c *gin.Context) {
 	if prize.PrizeType == models.Image || prize.PrizeType == models.Medal {
 		_, err = url.ParseRequestURI(prize.Data)
 		if err != nil {
-			newErrorResponse(c, http.StatusInternalServerError, fmt.Sprintf("can not url: %s", prize.Data))
+			newErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("can not url: %s", prize.Data))
 			return
 		}
 	}
+	if prize.PrizeType == models.LootBox && len(prize.Items) == 0 {
+		newErrorResponse(c, http.StatusBadRequest, "loot box must contain at least one prize")
+		return
+	}
 
 	prize.ID = uuid.New()
 	prize.CreatedBy = userID.(uuid.UUID)
@@ -65,7 +70,7 @@ func (h *Handler) CreatePrize(c *gin.Context) {
 	}
 	err = h.Service.Prize.CreatePrize(ctx, prize)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model: %s", err).Error())
+		newServiceErrorResponse(c, "can not create model", err)
 		return
 	}
 	c.JSON(http.StatusOK, map[string]interface{}{
@@ -90,7 +95,7 @@ func (h *Handler) GetPrize(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -107,7 +112,7 @@ func (h *Handler) GetPrize(c *gin.Context) {
 
 	prize, err := h.Service.Prize.GetPrize(ctx, id)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model: %s", err).Error())
+		newServiceErrorResponse(c, "can not create model", err)
 		return
 	}
 	c.JSON(http.StatusOK, map[string]interface{}{
@@ -133,7 +138,7 @@ func (h *Handler) GetPrizesByType(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -151,7 +156,7 @@ func (h *Handler) GetPrizesByType(c *gin.Context) {
 
 	prizes, err := h.Service.Prize.GetPrizesByType(ctx, prizeType)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model: %s", err).Error())
+		newServiceErrorResponse(c, "can not create model", err)
 		return
 	}
 	c.JSON(http.StatusOK, map[string]interface{}{
@@ -176,7 +181,7 @@ func (h *Handler) GetPrizes(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -186,16 +191,22 @@ func (h *Handler) GetPrizes(c *gin.Context) {
 		return
 	}
 
-	prizes, err := h.Service.Prize.GetPrizes(ctx, userID.(uuid.UUID))
+	query, err := models.NewListQuery(c.Request.URL.Query(), models.PrizeList)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse list query: %s", err).Error())
+		return
+	}
+	prizes, next, err := h.Service.Prize.GetPrizes(ctx, userID.(uuid.UUID), query)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model: %s", err).Error())
+		newServiceErrorResponse(c, "can not create model", err)
 		return
 	}
 	if len(prizes) == 0 {
 		c.JSON(http.StatusOK, "no prizes created by this user")
 	} else {
 		c.JSON(http.StatusOK, map[string]interface{}{
-			"prizes": prizes,
+			"prizes":      prizes,
+			"next_cursor": next,
 		})
 	}
 }
@@ -218,7 +229,7 @@ func (h *Handler) UpdatePrize(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -243,7 +254,7 @@ func (h *Handler) UpdatePrize(c *gin.Context) {
 		if prize.Data != "" {
 			_, err = url.ParseRequestURI(prize.Data)
 			if err != nil {
-				newErrorResponse(c, http.StatusInternalServerError, fmt.Sprintf("can not url: %s", prize.Data))
+				newErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("can not url: %s", prize.Data))
 				return
 			}
 		}
@@ -253,7 +264,7 @@ func (h *Handler) UpdatePrize(c *gin.Context) {
 
 	err = h.Service.Prize.UpdatePrize(ctx, prize)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not update model in updating prize: %s", err).Error())
+		newServiceErrorResponse(c, "can not update model in updating prize", err)
 		return
 	}
 	c.JSON(http.StatusOK, map[string]interface{}{
@@ -279,7 +290,7 @@ func (h *Handler) GivePrize(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -303,10 +314,477 @@ func (h *Handler) GivePrize(c *gin.Context) {
 	}
 	err = h.Service.Prize.GivePrize(ctx, staffID.StaffID, id)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not update model in updating prize: %s", err).Error())
+		newServiceErrorResponse(c, "can not update model in updating prize", err)
 		return
 	}
 	c.JSON(http.StatusOK, map[string]interface{}{
 		"updated": true,
 	})
 }
+
+// OpenLootBox
+// @Summary Open loot box
+// @Security ApiKeyAuth
+// @Tags prizes
+// @Description open a loot box owned by the current staff
+// @Description a prize is drawn by the organization drop table and given to the staff
+// @ID open-loot-box
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} models.LootBoxDraw
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/prize/open/:id [post]
+func (h *Handler) OpenLootBox(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasPermission(models.PrizeStaffAll) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in opening loot box: %s", err).Error())
+		return
+	}
+
+	draw, err := h.Service.Prize.OpenLootBox(ctx, staff.ID, id)
+	if err != nil {
+		newServiceErrorResponse(c, "can not open loot box", err)
+		return
+	}
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"draw": draw,
+	})
+}
+
+// GetLootBoxDraws
+// @Summary Get loot box draws
+// @Security ApiKeyAuth
+// @Tags prizes
+// @Description get the audit log of all draws made from a loot box
+// @ID get-loot-box-draws
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []models.LootBoxDraw
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/prize/draws/:id [get]
+func (h *Handler) GetLootBoxDraws(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasPermission(models.PrizeGive) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in getting loot box draws: %s", err).Error())
+		return
+	}
+
+	draws, err := h.Service.Prize.GetLootBoxDraws(ctx, id)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get loot box draws", err)
+		return
+	}
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"draws": draws,
+	})
+}
+
+// GetDropTable
+// @Summary Get organization drop table
+// @Security ApiKeyAuth
+// @Tags prizes
+// @Description get loot box weights by prize status for organization by ID
+// @Description not configured statuses use default weights
+// @ID get-drop-table
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} dropTable
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/prize/drop/:id [get]
+func (h *Handler) GetDropTable(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasPermission(models.PrizeGetAll) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in getting drop table: %s", err).Error())
+		return
+	}
+
+	if staff.OrganizationID != id {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	table, err := h.Service.Prize.GetDropTable(ctx, id)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get drop table", err)
+		return
+	}
+	c.JSON(http.StatusOK, dropTable{
+		OrganizationID: id,
+		Weights:        table,
+	})
+}
+
+// UpdateDropTable
+// @Summary Update organization drop table
+// @Security ApiKeyAuth
+// @Tags prizes
+// @Description set loot box weights by prize status for organization by ID
+// @ID update-drop-table
+// @Accept  json
+// @Produce  json
+// @Param input body dropTable true "weights by prize status"
+// @Success 200 {object} boolean
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/prize/drop/:id [put]
+func (h *Handler) UpdateDropTable(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasPermission(models.PrizeUpdate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in updating drop table: %s", err).Error())
+		return
+	}
+
+	if staff.OrganizationID != id {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	var table dropTable
+
+	if err := c.Bind(&table); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get input model in updating drop table: %s", err).Error())
+		return
+	}
+
+	err = h.Service.Prize.UpdateDropTable(ctx, id, table.Weights)
+	if err != nil {
+		newServiceErrorResponse(c, "can not update drop table", err)
+		return
+	}
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"updated": true,
+	})
+}
+
+// GetOrganizationPrizes
+// @Summary Get all prizes in organization
+// @Security ApiKeyAuth
+// @Tags prizes
+// @Description get prizes created in organization by ID
+// @Description filters: type, status, step_id, in_stock (true/false), from and to of creation date
+// @Description archived=true returns only archived prizes, otherwise only active ones
+// @Description sort: -creation_date by default or name, - prefix for descending order
+// @ID get-organization-prizes
+// @Accept  json
+// @Produce  json
+// @Param limit query int false "page size, 50 by default, 200 at most"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param sort query string false "sort field"
+// @Success 200 {object} []models.Prize
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/prize/org/:id [get]
+func (h *Handler) GetOrganizationPrizes(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasPermission(models.PrizeGetAll) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in getting organization prizes: %s", err).Error())
+		return
+	}
+
+	if staff.OrganizationID != id {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	filter := models.PrizeFilter{OrganizationID: id}
+	if stepID := c.Query("step_id"); stepID != "" {
+		filter.StepID, err = uuid.Parse(stepID)
+		if err != nil {
+			newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse step id filter: %s", err).Error())
+			return
+		}
+	}
+	if inStock := c.Query("in_stock"); inStock != "" {
+		value, err := strconv.ParseBool(inStock)
+		if err != nil {
+			newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse in_stock filter: %s", err).Error())
+			return
+		}
+		filter.InStock = &value
+	}
+	if archived := c.Query("archived"); archived != "" {
+		filter.Archived, err = strconv.ParseBool(archived)
+		if err != nil {
+			newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse archived filter: %s", err).Error())
+			return
+		}
+	}
+
+	query, err := models.NewListQuery(c.Request.URL.Query(), models.PrizeList)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse list query: %s", err).Error())
+		return
+	}
+	prizes, next, err := h.Service.Prize.GetAllPrizes(ctx, filter, query)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get prizes", err)
+		return
+	}
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"prizes":      prizes,
+		"next_cursor": next,
+	})
+}
+
+// DeletePrize
+// @Summary Archive prize
+// @Security ApiKeyAuth
+// @Tags prizes
+// @Description archive prize by ID
+// @Description archived prize can not be given, staff who own it keep it
+// @Description only prizes of the organization of staff can be archived
+// @ID delete-prize
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} boolean
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/prize/:id [delete]
+func (h *Handler) DeletePrize(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasPermission(models.PrizeDelete) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in deleting prize: %s", err).Error())
+		return
+	}
+
+	err = h.Service.Prize.DeletePrize(ctx, id, staff.OrganizationID)
+	if err != nil {
+		newServiceErrorResponse(c, "can not delete prize", err)
+		return
+	}
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"deleted": true,
+	})
+}
+
+// RestorePrize
+// @Summary Restore archived prize
+// @Security ApiKeyAuth
+// @Tags prizes
+// @Description restore archived prize by ID
+// @Description only prizes of the organization of staff can be restored
+// @ID restore-prize
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} boolean
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/prize/restore/:id [put]
+func (h *Handler) RestorePrize(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasPermission(models.PrizeDelete) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in restoring prize: %s", err).Error())
+		return
+	}
+
+	err = h.Service.Prize.RestorePrize(ctx, id, staff.OrganizationID)
+	if err != nil {
+		newServiceErrorResponse(c, "can not restore prize", err)
+		return
+	}
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"restored": true,
+	})
+}
@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"net/http"
+)
+
+// StartQuiz
+// @Summary Start quiz
+// @Security ApiKeyAuth
+// @Tags steps
+// @Description start quiz attempt by step ID or continue the active one
+// @Description questions are returned in attempt order without answer key
+// @ID start-quiz
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} models.QuizSession
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/step/quiz/:id [get]
+func (h *Handler) StartQuiz(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.StepGetByID, models.EventGetByID) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in starting quiz: %s", err).Error())
+		return
+	}
+
+	session, err := h.Service.Step.StartQuiz(ctx, id, staff.ID)
+	if err != nil {
+		newServiceErrorResponse(c, "can not start quiz", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"quiz": session,
+	})
+}
+
+// SubmitQuiz
+// @Summary Submit quiz
+// @Security ApiKeyAuth
+// @Tags steps
+// @Description submit answers of the active quiz attempt by step ID
+// @Description answers are graded automatically into staff step score and status
+// @Description answers after time limit are not graded
+// @ID submit-quiz
+// @Accept  json
+// @Produce  json
+// @Param input body models.QuizAnswersRequest true "answers"
+// @Success 200 {object} models.QuizAttempt
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/step/quiz/:id [post]
+func (h *Handler) SubmitQuiz(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.StepGetByID, models.EventGetByID) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in submitting quiz: %s", err).Error())
+		return
+	}
+
+	var input models.QuizAnswersRequest
+
+	if err := c.Bind(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get input model in submitting quiz: %s", err).Error())
+		return
+	}
+
+	attempt, err := h.Service.Step.SubmitQuiz(ctx, id, staff.ID, input.Answers)
+	if err != nil {
+		newServiceErrorResponse(c, "can not submit quiz", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"attempt": attempt,
+	})
+}
+
+// GetQuizAttempts
+// @Summary Get quiz attempts
+// @Security ApiKeyAuth
+// @Tags steps
+// @Description get quiz attempts of current staff by step ID
+// @ID get-quiz-attempts
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []models.QuizAttempt
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/step/quiz/attempts/:id [get]
+func (h *Handler) GetQuizAttempts(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.StepGetByID, models.EventGetByID) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in getting quiz attempts: %s", err).Error())
+		return
+	}
+
+	attempts, err := h.Service.Step.GetQuizAttempts(ctx, id, staff.ID)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get quiz attempts", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"attempts": attempts,
+	})
+}
+
+// GetQuizKey
+// @Summary Get quiz key
+// @Security ApiKeyAuth
+// @Tags steps
+// @Description get quiz questions with answer key by step ID
+// @Description only creator or admin of the event may get it
+// @ID get-quiz-key
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []models.QuizQuestion
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/step/quiz/key/:id [get]
+func (h *Handler) GetQuizKey(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.StepUpdate, models.EventCreate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in getting quiz key: %s", err).Error())
+		return
+	}
+
+	questions, err := h.Service.Step.GetQuizKey(ctx, id, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get quiz key", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"questions": questions,
+	})
+}
+
+// UpdateQuiz
+// @Summary Update quiz
+// @Security ApiKeyAuth
+// @Tags steps
+// @Description replace quiz questions with answer key by step ID
+// @Description only creator or admin of the event may replace them
+// @Description questions can not be changed after staff started the quiz
+// @ID update-quiz
+// @Accept  json
+// @Produce  json
+// @Param input body []models.QuizQuestion true "questions"
+// @Success 200 {object} boolean
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/step/quiz/key/:id [put]
+func (h *Handler) UpdateQuiz(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.StepUpdate, models.EventCreate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in updating quiz: %s", err).Error())
+		return
+	}
+
+	var questions []*models.QuizQuestion
+
+	if err := c.Bind(&questions); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get input model in updating quiz: %s", err).Error())
+		return
+	}
+
+	err = h.Service.Step.UpdateQuiz(ctx, id, userID.(uuid.UUID), questions)
+	if err != nil {
+		newServiceErrorResponse(c, "can not update quiz", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"updated": true,
+	})
+}
@@ -1,11 +1,15 @@
 package handlers
 
 import (
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/miprokop/fication/internal/models"
+	"github.com/miprokop/fication/internal/services"
 	"github.com/sirupsen/logrus"
 	"github.com/uptrace/bun"
+	"net/http"
+	"time"
 )
 
 type stepShortResponse struct {
@@ -29,7 +33,7 @@ type eventAllData struct {
 	Description    string               `json:"description"`
 	ImagePath      string               `json:"image_path"`
 	CreatedByID    uuid.UUID            `json:"created_by_id" bun:"created_by"`
-	EventStatus    string               `json:"event_status"`
+	EventStatus    models.EventStatus   `json:"event_status"`
 	EventType      string               `json:"event_type"`
 	OrganizationID uuid.UUID            `json:"organization_id"`
 	StaffEvents    []*staffEvents       `json:"staff"`
@@ -103,16 +107,16 @@ type stepImageRequest struct {
 }
 
 type eventShortData struct {
-	ID             uuid.UUID `json:"id" bun:",pk"`
-	Name           string    `json:"name"`
-	CreationDate   string    `json:"creation_date"`
-	EndDate        string    `json:"end_date"`
-	Description    string    `json:"description"`
-	ImagePath      string    `json:"image_path"`
-	CreatedByID    uuid.UUID `json:"created_by_id" bun:"created_by"`
-	EventStatus    string    `json:"event_status"`
-	EventType      string    `json:"event_type"`
-	OrganizationID uuid.UUID `json:"organization_id"`
+	ID             uuid.UUID          `json:"id" bun:",pk"`
+	Name           string             `json:"name"`
+	CreationDate   string             `json:"creation_date"`
+	EndDate        string             `json:"end_date"`
+	Description    string             `json:"description"`
+	ImagePath      string             `json:"image_path"`
+	CreatedByID    uuid.UUID          `json:"created_by_id" bun:"created_by"`
+	EventStatus    models.EventStatus `json:"event_status"`
+	EventType      string             `json:"event_type"`
+	OrganizationID uuid.UUID          `json:"organization_id"`
 }
 
 type staffEvents struct {
@@ -169,42 +173,92 @@ type updatePosition struct {
 }
 
 type eventRequestUpdate struct {
-	ID             uuid.UUID `json:"id" bun:",pk"`
-	Name           string    `json:"name"`
-	CreationDate   string    `json:"creation_date"`
-	EndDate        string    `json:"end_date"`
-	Description    string    `json:"description"`
-	ImagePath      string    `json:"image_path"`
-	CreatedByID    uuid.UUID `json:"created_by_id" bun:"created_by"`
-	EventStatus    string    `json:"event_status"`
-	EventType      string    `json:"event_type"`
-	OrganizationID uuid.UUID `json:"organization_id"`
+	ID                   uuid.UUID          `json:"id" bun:",pk"`
+	Name                 string             `json:"name"`
+	CreationDate         string             `json:"creation_date"`
+	EndDate              string             `json:"end_date"`
+	Description          string             `json:"description"`
+	ImagePath            string             `json:"image_path"`
+	CreatedByID          uuid.UUID          `json:"created_by_id" bun:"created_by"`
+	EventStatus          models.EventStatus `json:"event_status"`
+	EventType            string             `json:"event_type"`
+	OrganizationID       uuid.UUID          `json:"organization_id"`
+	MaxParticipants      uint               `json:"max_participants"`
+	RegistrationDeadline *time.Time         `json:"registration_deadline"`
+	InvitationTTL        uint               `json:"invitation_ttl"`
 }
 
 type eventRequest struct {
-	ID             uuid.UUID      `json:"id" bun:",pk"`
-	Name           string         `json:"name"`
-	CreationDate   string         `json:"creation_date"`
-	EndDate        string         `json:"end_date"`
-	Description    string         `json:"description"`
-	ImagePath      string         `json:"image_path"`
-	CreatedByID    uuid.UUID      `json:"created_by_id" bun:"created_by"`
-	EventStatus    string         `json:"event_status"`
-	EventType      string         `json:"event_type"`
-	OrganizationID uuid.UUID      `json:"organization_id"`
-	StaffEvents    []*staffEvents `json:"staff" bun:"m2m:staff_events,join:Event=Staff"`
-	Steps          []*stepRequest `json:"steps" bun:"rel:has-many,join:id=event_id"`
+	ID                   uuid.UUID          `json:"id" bun:",pk"`
+	Name                 string             `json:"name"`
+	CreationDate         string             `json:"creation_date"`
+	EndDate              string             `json:"end_date"`
+	Description          string             `json:"description"`
+	ImagePath            string             `json:"image_path"`
+	CreatedByID          uuid.UUID          `json:"created_by_id" bun:"created_by"`
+	EventStatus          models.EventStatus `json:"event_status"`
+	EventType            string             `json:"event_type"`
+	OrganizationID       uuid.UUID          `json:"organization_id"`
+	StaffEvents          []*staffEvents     `json:"staff" bun:"m2m:staff_events,join:Event=Staff"`
+	Steps                []*stepRequest     `json:"steps" bun:"rel:has-many,join:id=event_id"`
+	MaxParticipants      uint               `json:"max_participants"`
+	RegistrationDeadline *time.Time         `json:"registration_deadline"`
+	InvitationTTL        uint               `json:"invitation_ttl"`
 }
 
+type dropTable struct {
+	OrganizationID uuid.UUID          `json:"organization_id"`
+	Weights        services.DropTable `json:"weights"`
+}
+
+// errorResponse is the body of every error. Code is one of errorCodes or services.ErrorCode,
+// Fields are set for validation errors of the input.
 type errorResponse struct {
-	Message string `json:"message"`
+	Code    string                `json:"code"`
+	Message string                `json:"message"`
+	Fields  []services.FieldError `json:"fields,omitempty"`
 }
 
 type statusResponse struct {
 	Status string `json:"status"`
 }
 
+var errorCodes = map[int]string{
+	http.StatusBadRequest:          "bad_request",
+	http.StatusUnauthorized:        "unauthorized",
+	http.StatusForbidden:           "forbidden",
+	http.StatusNotFound:            "not_found",
+	http.StatusConflict:            "conflict",
+	http.StatusInternalServerError: "internal",
+}
+
+var errorStatuses = map[services.ErrorCode]int{
+	services.CodeNotFound:   http.StatusNotFound,
+	services.CodeConflict:   http.StatusConflict,
+	services.CodeValidation: http.StatusBadRequest,
+	services.CodeForbidden:  http.StatusForbidden,
+}
+
+// newErrorResponse responds with an error found by the handler itself, e.g. an id that can not be parsed.
 func newErrorResponse(c *gin.Context, statusCode int, message string) {
 	logrus.Error(message)
-	c.AbortWithStatusJSON(statusCode, errorResponse{message})
+	c.AbortWithStatusJSON(statusCode, errorResponse{Code: errorCodes[statusCode], Message: message})
+}
+
+// newServiceErrorResponse responds with an error of a service, or of anything but the input. Domain errors
+// get their status and are shown after the message. Other errors are internal: they are logged and only
+// the message is shown, so database and file system details do not leak.
+func newServiceErrorResponse(c *gin.Context, message string, err error) {
+	logrus.Errorf("%s: %s", message, err)
+	domain := services.ErrorOf(err)
+	if domain == nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError,
+			errorResponse{Code: errorCodes[http.StatusInternalServerError], Message: message})
+		return
+	}
+	c.AbortWithStatusJSON(errorStatuses[domain.Code], errorResponse{
+		Code:    string(domain.Code),
+		Message: fmt.Sprintf("%s: %s", message, domain.Message),
+		Fields:  domain.Fields,
+	})
 }
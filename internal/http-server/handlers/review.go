@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"net/http"
+)
+
+// GetReviewAssignments
+// @Summary Get review assignments
+// @Security ApiKeyAuth
+// @Tags steps
+// @Description get pending reviews assigned to current staff
+// @ID get-review-assignments
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []models.ReviewAssignment
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/step/reviews [get]
+func (h *Handler) GetReviewAssignments(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	reviews, err := h.Service.Step.GetReviewerAssignments(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get review assignments", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"reviews": reviews,
+	})
+}
+
+// SubmitReview
+// @Summary Submit review
+// @Security ApiKeyAuth
+// @Tags steps
+// @Description score submission by review assignment ID
+// @Description when all reviewers are done the step score is aggregated by the step score strategy
+// @ID submit-review
+// @Accept  json
+// @Produce  json
+// @Param input body models.SubmissionReview true "score and comment"
+// @Success 200 {object} boolean
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/step/review/:id [put]
+func (h *Handler) SubmitReview(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in submitting review: %s", err).Error())
+		return
+	}
+
+	var input models.SubmissionReview
+
+	if err := c.Bind(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get input model in submitting review: %s", err).Error())
+		return
+	}
+
+	err = h.Service.Step.SubmitReview(ctx, id, userID.(uuid.UUID), input)
+	if err != nil {
+		newServiceErrorResponse(c, "can not submit review", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"reviewed": true,
+	})
+}
+
+// AssignReviewer
+// @Summary Assign reviewer
+// @Security ApiKeyAuth
+// @Tags steps
+// @Description assign one more reviewer to pending submission by ID
+// @Description reviewer must pass conflict of interest rules of the step
+// @ID assign-reviewer
+// @Accept  json
+// @Produce  json
+// @Param input body models.StaffID true "reviewer id"
+// @Success 200 {object} boolean
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/step/submission/reviewer/:id [put]
+func (h *Handler) AssignReviewer(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.StepUpdate, models.EventUpdate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in assigning reviewer: %s", err).Error())
+		return
+	}
+
+	var staffID *models.StaffID
+
+	if err := c.Bind(&staffID); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get input model in assigning reviewer: %s", err).Error())
+		return
+	}
+
+	err = h.Service.Step.AssignReviewer(ctx, id, staffID.StaffID)
+	if err != nil {
+		newServiceErrorResponse(c, "can not assign reviewer", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"assigned": true,
+	})
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"net/http"
+)
+
+// UpdateRubric
+// @Summary Update step rubric
+// @Security ApiKeyAuth
+// @Tags steps
+// @Description replace weighted scoring criteria of step by step ID
+// @Description rubric can not be changed after staff got criterion scores
+// @ID update-rubric
+// @Accept  json
+// @Produce  json
+// @Param input body []models.RubricCriterion true "rubric criteria"
+// @Success 200 {object} boolean
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/step/rubric/:id [put]
+func (h *Handler) UpdateRubric(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.StepUpdate, models.EventCreate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in updating rubric: %s", err).Error())
+		return
+	}
+
+	var rubric []*models.RubricCriterion
+
+	if err := c.Bind(&rubric); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get input model in updating rubric: %s", err).Error())
+		return
+	}
+
+	err = h.Service.Step.UpdateRubric(ctx, id, rubric)
+	if err != nil {
+		newServiceErrorResponse(c, "can not update rubric", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"updated": true,
+	})
+}
+
+// GetStepResult
+// @Summary Get step result
+// @Security ApiKeyAuth
+// @Tags steps
+// @Description get staff score in step by step ID with per-criterion breakdown
+// @Description current staff result is returned unless staff_id is passed by staff who can read all steps
+// @ID get-step-result
+// @Accept  json
+// @Produce  json
+// @Param staff_id query string false "staff ID"
+// @Success 200 {object} models.StepResult
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/step/result/:id [get]
+func (h *Handler) GetStepResult(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in getting result: %s", err).Error())
+		return
+	}
+
+	staffID := userID.(uuid.UUID)
+	if param := c.Query("staff_id"); param != "" {
+		staffID, err = uuid.Parse(param)
+		if err != nil {
+			newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse staff id: %s", err).Error())
+			return
+		}
+	}
+	if staffID != userID.(uuid.UUID) {
+		staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+		if err != nil {
+			newServiceErrorResponse(c, "can not get staff by id", err)
+			return
+		}
+		if !staff.HasOneOfPermissions(models.StepGetAll, models.EventGetByID, models.EventGetAll) {
+			newErrorResponse(c, http.StatusForbidden,
+				"no access to this action")
+			return
+		}
+	}
+
+	result, err := h.Service.Step.GetStepResult(ctx, id, staffID)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get step result", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"result": result,
+	})
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"net/http"
+)
+
+// Search
+// @Summary Search
+// @Security ApiKeyAuth
+// @Tags search
+// @Description full text search of event names and descriptions, step names, tasks and descriptions,
+// @Description prize names and descriptions and staff names; words match by prefix, "secur wee" finds "Security week"
+// @Description only results current staff may see are returned, best ranked first
+// @Description title and snippet are HTML escaped, matched words are wrapped in <mark>
+// @ID search
+// @Accept  json
+// @Produce  json
+// @Param q query string true "search text"
+// @Param type query string false "comma separated types: event, step, prize, staff"
+// @Param limit query int false "max number of results, 20 by default, at most 100"
+// @Success 200 {array} models.SearchResult
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/search [get]
+func (h *Handler) Search(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	query, err := models.NewSearchQuery(c.Request.URL.Query())
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	results, err := h.Service.Search.Search(ctx, userID.(uuid.UUID), query)
+	if err != nil {
+		newServiceErrorResponse(c, "can not search", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"results": results,
+	})
+}
@@ -0,0 +1,392 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"net/http"
+	"strconv"
+)
+
+// CreateSeries
+// @Summary Create recurring event series
+// @Security ApiKeyAuth
+// @Tags events
+// @Description create series which creates an event from template on every occurrence of its recurrence rule
+// @Description frequency is daily, weekly or monthly with interval 1 by default, series ends after count occurrences or at until
+// @Description events are created lead_time seconds before their start, staff of team is invited to every event
+// @Description template must be of the organization of staff, the series and its events are in it
+// @ID create-series
+// @Accept  json
+// @Produce  json
+// @Param input body models.EventSeries true "series info"
+// @Success 200 {string} uuid
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/series/ [post]
+func (h *Handler) CreateSeries(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.EventCreate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	var series models.EventSeries
+
+	if err := c.Bind(&series); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get input model in creating series: %s", err).Error())
+		return
+	}
+	series.ID = uuid.New()
+	series.CreatedByID = staff.ID
+
+	err = h.Service.Series.CreateSeries(ctx, &series, staff.OrganizationID)
+	if err != nil {
+		newServiceErrorResponse(c, "can not create series", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"created": series.ID,
+	})
+}
+
+// GetSeries
+// @Summary Get event series
+// @Security ApiKeyAuth
+// @Tags events
+// @Description get recurring event series by ID
+// @ID get-series
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} models.EventSeries
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/series/:id [get]
+func (h *Handler) GetSeries(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.EventCreate, models.EventGetAll) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in getting series: %s", err).Error())
+		return
+	}
+
+	series, err := h.Service.Series.GetSeries(ctx, id, staff.OrganizationID)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get series", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"series": series,
+	})
+}
+
+// GetOrganizationSeries
+// @Summary Get organization event series
+// @Security ApiKeyAuth
+// @Tags events
+// @Description get recurring event series of organization by organization ID
+// @ID get-organization-series
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []models.EventSeries
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/series/org/:id [get]
+func (h *Handler) GetOrganizationSeries(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.EventCreate, models.EventGetAll) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in getting organization series: %s", err).Error())
+		return
+	}
+
+	if staff.OrganizationID != id {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	series, err := h.Service.Series.GetOrganizationSeries(ctx, id)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get series", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"series": series,
+	})
+}
+
+// DeleteSeries
+// @Summary Delete event series
+// @Security ApiKeyAuth
+// @Tags events
+// @Description stop recurring event series by ID, events created by the series are kept
+// @ID delete-series
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} boolean
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/series/:id [delete]
+func (h *Handler) DeleteSeries(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.EventCreate, models.EventDelete) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in deleting series: %s", err).Error())
+		return
+	}
+
+	err = h.Service.Series.DeleteSeries(ctx, id, staff.OrganizationID)
+	if err != nil {
+		newServiceErrorResponse(c, "can not delete series", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"deleted": true,
+	})
+}
+
+// GetOccurrences
+// @Summary Get series occurrences
+// @Security ApiKeyAuth
+// @Tags events
+// @Description get occurrences of series by series ID: created, skipped and edited ones and upcoming ones by recurrence rule
+// @Description limit is the number of listed pending occurrences, 10 by default
+// @ID get-occurrences
+// @Accept  json
+// @Produce  json
+// @Param limit query int false "pending occurrences limit"
+// @Success 200 {object} []models.EventOccurrence
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/series/occurrences/:id [get]
+func (h *Handler) GetOccurrences(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.EventCreate, models.EventGetAll) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in getting occurrences: %s", err).Error())
+		return
+	}
+
+	var limit int
+	if param := c.Query("limit"); param != "" {
+		limit, err = strconv.Atoi(param)
+		if err != nil {
+			newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse limit: %s", err).Error())
+			return
+		}
+	}
+
+	occurrences, err := h.Service.Series.GetOccurrences(ctx, id, staff.OrganizationID, limit)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get occurrences", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"occurrences": occurrences,
+	})
+}
+
+// UpdateOccurrence
+// @Summary Skip or edit series occurrence
+// @Security ApiKeyAuth
+// @Tags events
+// @Description skip, restore or change start date and name of one occurrence of series by series ID
+// @Description other occurrences of the series are not changed, occurrences with created event can not be changed
+// @ID update-occurrence
+// @Accept  json
+// @Produce  json
+// @Param input body models.OccurrenceRequest true "occurrence changes"
+// @Success 200 {object} models.EventOccurrence
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/series/occurrence/:id [put]
+func (h *Handler) UpdateOccurrence(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.EventCreate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in updating occurrence: %s", err).Error())
+		return
+	}
+
+	var request models.OccurrenceRequest
+
+	if err := c.Bind(&request); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get input model in updating occurrence: %s", err).Error())
+		return
+	}
+
+	occurrence, err := h.Service.Series.UpdateOccurrence(ctx, id, staff.OrganizationID, request)
+	if err != nil {
+		newServiceErrorResponse(c, "can not update occurrence", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"occurrence": occurrence,
+	})
+}
@@ -28,7 +28,7 @@ func (h *Handler) CreateStep(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -91,7 +91,7 @@ func (h *Handler) CreateStep(c *gin.Context) {
 	}
 	err = h.Service.Step.CreateStep(ctx, step, creationTime, endTime)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create step: %s", err).Error())
+		newServiceErrorResponse(c, "can not create step", err)
 		return
 	}
 
@@ -118,7 +118,7 @@ func (h *Handler) UpdateStep(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -145,7 +145,7 @@ func (h *Handler) UpdateStep(c *gin.Context) {
 
 	err = h.Service.Step.UpdateStep(ctx, step)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model: %s", err).Error())
+		newServiceErrorResponse(c, "can not create model", err)
 		return
 	}
 
@@ -172,7 +172,7 @@ func (h *Handler) GetStep(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -190,7 +190,7 @@ func (h *Handler) GetStep(c *gin.Context) {
 
 	step, err := h.Service.Step.GetStep(ctx, id)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model: %s", err).Error())
+		newServiceErrorResponse(c, "can not create model", err)
 		return
 	}
 
@@ -217,7 +217,7 @@ func (h *Handler) DeleteStep(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -235,7 +235,7 @@ func (h *Handler) DeleteStep(c *gin.Context) {
 
 	err = h.Service.Step.DeleteStep(ctx, id)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model: %s", err).Error())
+		newServiceErrorResponse(c, "can not create model", err)
 		return
 	}
 
@@ -262,7 +262,7 @@ func (h *Handler) GetSteps(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -278,14 +278,20 @@ func (h *Handler) GetSteps(c *gin.Context) {
 		return
 	}
 
-	steps, err := h.Service.Step.GetSteps(ctx, id)
+	query, err := models.NewListQuery(c.Request.URL.Query(), models.StepList)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model: %s", err).Error())
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse list query: %s", err).Error())
+		return
+	}
+	steps, next, err := h.Service.Step.GetSteps(ctx, id, query)
+	if err != nil {
+		newServiceErrorResponse(c, "can not create model", err)
 		return
 	}
 
 	c.JSON(http.StatusOK, map[string]interface{}{
-		"steps": steps,
+		"steps":       steps,
+		"next_cursor": next,
 	})
 }
 
@@ -307,7 +313,7 @@ func (h *Handler) GetStepPrizes(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -325,7 +331,7 @@ func (h *Handler) GetStepPrizes(c *gin.Context) {
 
 	prizes, err := h.Service.Step.GetStepPrizes(ctx, id)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model: %s", err).Error())
+		newServiceErrorResponse(c, "can not create model", err)
 		return
 	}
 
@@ -352,7 +358,7 @@ func (h *Handler) PassStaff(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -375,33 +381,12 @@ func (h *Handler) PassStaff(c *gin.Context) {
 		return
 	}
 
-	err = h.Service.Step.PassStaff(ctx, id, stepStatus.StaffID, stepStatus.StepStatus, stepStatus.Score)
+	err = h.Service.Step.PassStaff(ctx, id, stepStatus.StaffID, stepStatus.StepStatus, stepStatus.Score,
+		stepStatus.Criteria)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model: %s", err).Error())
+		newServiceErrorResponse(c, "can not create model", err)
 		return
 	}
-	if stepStatus.StepStatus == models.Done {
-		step, err := h.Service.Step.GetStep(ctx, id)
-		if err != nil {
-			newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not get model: %s", err).Error())
-			return
-		}
-		steps, err := h.Service.Step.GetSteps(ctx, step.EventID)
-		if err != nil {
-			newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not get model: %s", err).Error())
-			return
-		}
-		for _, s := range steps {
-			if s.Level > step.Level {
-				err = h.Service.Step.AssignStaff(ctx, stepStatus.StaffID, s.ID)
-				if err != nil {
-					newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model: %s", err).Error())
-					return
-				}
-				break
-			}
-		}
-	}
 
 	c.JSON(http.StatusOK, map[string]interface{}{
 		"changed": true,
@@ -426,7 +411,7 @@ func (h *Handler) AssignStaff(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -451,7 +436,7 @@ func (h *Handler) AssignStaff(c *gin.Context) {
 	for _, staffID := range staffIDs {
 		err = h.Service.Step.AssignStaff(ctx, staffID.StaffID, id)
 		if err != nil {
-			newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model: %s", err).Error())
+			newServiceErrorResponse(c, "can not create model", err)
 			return
 		}
 	}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	server "github.com/miprokop/fication/internal/http-server"
+	"github.com/miprokop/fication/internal/models"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// streamHeartbeat is how often a comment is sent to idle streams, so proxies do not close them
+// and closed connections are noticed soon.
+const streamHeartbeat = 5 * time.Second
+
+// StreamEvent
+// @Summary Stream event updates
+// @Security ApiKeyAuth
+// @Tags stream
+// @Description stream real-time updates of the event by event ID
+// @Description messages are server-sent events with id, event type and json data
+// @Description types: score, step-status, event-status, participant, prize
+// @Description a comment is sent every 5 seconds as a heartbeat
+// @Description reconnect with Last-Event-ID header or last_event_id query to get missed messages
+// @ID stream-event
+// @Accept  json
+// @Produce  text/event-stream
+// @Success 200 {object} models.StreamMessage
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/stream/event/:id [get]
+func (h *Handler) StreamEvent(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.EventGetByID) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in streaming event: %s", err).Error())
+		return
+	}
+
+	h.stream(c, models.StreamScope{EventID: id})
+}
+
+// StreamOrganization
+// @Summary Stream organization updates
+// @Security ApiKeyAuth
+// @Tags stream
+// @Description stream real-time updates of every event of the organization by organization ID
+// @Description only staff of the organization can subscribe
+// @Description messages are server-sent events with id, event type and json data
+// @Description types: score, step-status, event-status, participant, prize
+// @Description a comment is sent every 5 seconds as a heartbeat
+// @Description reconnect with Last-Event-ID header or last_event_id query to get missed messages
+// @ID stream-organization
+// @Accept  json
+// @Produce  text/event-stream
+// @Success 200 {object} models.StreamMessage
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/stream/org/:id [get]
+func (h *Handler) StreamOrganization(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.OrganizationGetByID) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in streaming organization: %s", err).Error())
+		return
+	}
+
+	if staff.OrganizationID != id {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	h.stream(c, models.StreamScope{OrganizationID: id})
+}
+
+// stream sends messages of the scope until the client disconnects. Messages the client
+// has missed since Last-Event-ID are sent first, ids it has already got are skipped.
+func (h *Handler) stream(c *gin.Context, scope models.StreamScope) {
+	lastID, err := lastEventID(c)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse last event id: %s", err).Error())
+		return
+	}
+
+	subscription, missed, err := h.Service.Stream.Subscribe(context.Background(), scope, lastID)
+	if err != nil {
+		newServiceErrorResponse(c, "can not subscribe", err)
+		return
+	}
+	defer h.Service.Stream.Unsubscribe(subscription)
+
+	// Streams are open longer than the write timeout of the server.
+	if w, ok := server.ConnWriter(c.Request.Context()); ok {
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+			log.Warnf("can not clear write deadline of stream: %s", err)
+		}
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	// Replayed messages may also come through the subscription. Messages of the subscription
+	// are not compared with lastID: a message committed late can have a lower id.
+	replayed := make(map[int64]struct{}, len(missed))
+	for _, message := range missed {
+		if err := writeStreamMessage(c.Writer, message); err != nil {
+			return
+		}
+		replayed[message.ID] = struct{}{}
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case message, ok := <-subscription.Messages:
+			if !ok {
+				return
+			}
+			if _, ok := replayed[message.ID]; ok {
+				continue
+			}
+			if err := writeStreamMessage(c.Writer, message); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func lastEventID(c *gin.Context) (int64, error) {
+	param := c.GetHeader("Last-Event-ID")
+	if param == "" {
+		param = c.Query("last_event_id")
+	}
+	if param == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(param, 10, 64)
+}
+
+func writeStreamMessage(w gin.ResponseWriter, message *models.StreamMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", message.ID, message.MessageType, data); err != nil {
+		return err
+	}
+	w.Flush()
+	return nil
+}
@@ -0,0 +1,534 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+const submissionPath = "upload/files/submissions"
+
+// SubmitStep
+// @Summary Submit step work
+// @Security ApiKeyAuth
+// @Tags steps
+// @Description submit work for a step by step ID
+// @Description multipart form: json field with text and links, files field with attachments
+// @Description staff step moves to ready-check until a reviewer approves or rejects it
+// @ID submit-step
+// @Accept  mpfd
+// @Produce  json
+// @Param json formData string true "models.SubmissionRequest"
+// @Success 200 {string} uuid
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/step/submit/:id [post]
+func (h *Handler) SubmitStep(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.StepGetByID, models.EventGetByID) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in submitting step: %s", err).Error())
+		return
+	}
+
+	var input models.SubmissionRequest
+	if data := c.PostForm("json"); data != "" {
+		if err := json.Unmarshal([]byte(data), &input); err != nil {
+			newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get input model in submitting step: %s", err).Error())
+			return
+		}
+	}
+	for _, link := range input.Links {
+		if _, err := url.ParseRequestURI(link); err != nil {
+			newErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("can not url: %s", link))
+			return
+		}
+	}
+
+	submission := &models.Submission{
+		ID:      uuid.New(),
+		StepID:  id,
+		StaffID: staff.ID,
+		Text:    input.Text,
+		Links:   input.Links,
+	}
+	if submission.Links == nil {
+		submission.Links = []string{}
+	}
+
+	form, _ := c.MultipartForm()
+	if form != nil && len(form.File["files"]) != 0 {
+		dir := fmt.Sprintf("%s/%s", submissionPath, submission.ID)
+		if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+			err := os.MkdirAll(dir, os.ModePerm)
+			if err != nil {
+				log.Println(err)
+			}
+		}
+		// files are stored by attachment id, names of uploaded files may repeat
+		for _, file := range form.File["files"] {
+			attachment := &models.SubmissionAttachment{
+				ID:       uuid.New(),
+				FileName: filepath.Base(file.Filename),
+			}
+			attachment.FilePath = fmt.Sprintf("%s/%s", dir, attachment.ID)
+			if err := c.SaveUploadedFile(file, attachment.FilePath); err != nil {
+				_ = os.RemoveAll(dir)
+				newServiceErrorResponse(c, "can not save submission file", err)
+				return
+			}
+			submission.Attachments = append(submission.Attachments, attachment)
+		}
+	}
+
+	err = h.Service.Step.SubmitStep(ctx, submission)
+	if err != nil {
+		_ = os.RemoveAll(fmt.Sprintf("%s/%s", submissionPath, submission.ID))
+		newServiceErrorResponse(c, "can not submit step", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"created": submission.ID,
+	})
+}
+
+// GetSubmissionQueue
+// @Summary Get reviewer queue
+// @Security ApiKeyAuth
+// @Tags steps
+// @Description get pending submissions of all steps in event by event ID
+// @Description oldest submissions go first
+// @ID get-submission-queue
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []models.Submission
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/step/queue/:id [get]
+func (h *Handler) GetSubmissionQueue(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.StepUpdate, models.EventUpdate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in getting submission queue: %s", err).Error())
+		return
+	}
+
+	submissions, err := h.Service.Step.GetSubmissionQueue(ctx, id)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get submission queue", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"submissions": submissions,
+	})
+}
+
+// GetSubmission
+// @Summary Get submission
+// @Security ApiKeyAuth
+// @Tags steps
+// @Description get submission by ID with attachments and comments
+// @Description available to the author and to reviewers
+// @ID get-submission
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} models.Submission
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/step/submission/:id [get]
+func (h *Handler) GetSubmission(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in getting submission: %s", err).Error())
+		return
+	}
+
+	submission, err := h.Service.Step.GetSubmission(ctx, id)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get submission", err)
+		return
+	}
+
+	if submission.StaffID != staff.ID && !staff.HasOneOfPermissions(models.StepUpdate, models.EventUpdate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"submission": submission,
+	})
+}
+
+// GetStaffSubmissions
+// @Summary Get staff submissions for step
+// @Security ApiKeyAuth
+// @Tags steps
+// @Description get submission history of current staff for step by step ID
+// @Description reviewers can pass staff_id query to get history of another staff
+// @ID get-staff-submissions
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []models.Submission
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/step/submissions/:id [get]
+func (h *Handler) GetStaffSubmissions(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in getting submissions: %s", err).Error())
+		return
+	}
+
+	staffID := staff.ID
+	if query := c.Query("staff_id"); query != "" {
+		if !staff.HasOneOfPermissions(models.StepUpdate, models.EventUpdate) {
+			newErrorResponse(c, http.StatusForbidden,
+				"no access to this action")
+			return
+		}
+		staffID, err = uuid.Parse(query)
+		if err != nil {
+			newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse staff id in getting submissions: %s", err).Error())
+			return
+		}
+	}
+
+	submissions, err := h.Service.Step.GetStaffSubmissions(ctx, id, staffID)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get submissions", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"submissions": submissions,
+	})
+}
+
+// ApproveSubmission
+// @Summary Approve submission
+// @Security ApiKeyAuth
+// @Tags steps
+// @Description approve pending submission by ID
+// @Description staff step becomes done with the given score, staff is assigned to the next step
+// @ID approve-submission
+// @Accept  json
+// @Produce  json
+// @Param input body models.SubmissionReview true "score and comment"
+// @Success 200 {object} boolean
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/step/submission/approve/:id [put]
+func (h *Handler) ApproveSubmission(c *gin.Context) {
+	h.reviewSubmission(c, h.Service.Step.ApproveSubmission)
+}
+
+// RejectSubmission
+// @Summary Reject submission
+// @Security ApiKeyAuth
+// @Tags steps
+// @Description reject pending submission by ID, comment is required
+// @Description staff step returns to process and staff can submit again
+// @ID reject-submission
+// @Accept  json
+// @Produce  json
+// @Param input body models.SubmissionReview true "comment"
+// @Success 200 {object} boolean
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/step/submission/reject/:id [put]
+func (h *Handler) RejectSubmission(c *gin.Context) {
+	h.reviewSubmission(c, h.Service.Step.RejectSubmission)
+}
+
+func (h *Handler) reviewSubmission(c *gin.Context,
+	review func(ctx context.Context, id, reviewerID uuid.UUID, review models.SubmissionReview) error) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.StepUpdate, models.EventUpdate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in reviewing submission: %s", err).Error())
+		return
+	}
+
+	var input models.SubmissionReview
+
+	if err := c.Bind(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get input model in reviewing submission: %s", err).Error())
+		return
+	}
+
+	err = review(ctx, id, staff.ID, input)
+	if err != nil {
+		newServiceErrorResponse(c, "can not review submission", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"reviewed": true,
+	})
+}
+
+// CommentSubmission
+// @Summary Comment submission
+// @Security ApiKeyAuth
+// @Tags steps
+// @Description add comment to submission by ID
+// @Description available to the author and to reviewers
+// @ID comment-submission
+// @Accept  json
+// @Produce  json
+// @Param input body models.SubmissionReview true "comment"
+// @Success 200 {string} uuid
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/step/submission/comment/:id [post]
+func (h *Handler) CommentSubmission(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in commenting submission: %s", err).Error())
+		return
+	}
+
+	submission, err := h.Service.Step.GetSubmission(ctx, id)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get submission", err)
+		return
+	}
+	if submission.StaffID != staff.ID && !staff.HasOneOfPermissions(models.StepUpdate, models.EventUpdate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	var input models.SubmissionReview
+
+	if err := c.Bind(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get input model in commenting submission: %s", err).Error())
+		return
+	}
+
+	comment := &models.SubmissionComment{
+		ID:           uuid.New(),
+		SubmissionID: id,
+		AuthorID:     staff.ID,
+		Body:         input.Comment,
+	}
+	err = h.Service.Step.CommentSubmission(ctx, comment)
+	if err != nil {
+		newServiceErrorResponse(c, "can not comment submission", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"created": comment.ID,
+	})
+}
+
+func (h *Handler) GetSubmissionFile(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in getting submission file: %s", err).Error())
+		return
+	}
+
+	submission, err := h.Service.Step.GetSubmission(ctx, id)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get submission", err)
+		return
+	}
+	if submission.StaffID != staff.ID && !staff.HasOneOfPermissions(models.StepUpdate, models.EventUpdate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	// name is the attachment id, or the file name for files of older submissions
+	name := c.Param("name")
+	for _, attachment := range submission.Attachments {
+		if attachment.ID.String() == name || attachment.FileName == name {
+			c.FileAttachment(attachment.FilePath, attachment.FileName)
+			return
+		}
+	}
+	newErrorResponse(c, http.StatusNotFound, fmt.Sprintf("no such file in submission: %s", name))
+}
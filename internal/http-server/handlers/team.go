@@ -26,7 +26,7 @@ func (h *Handler) CreateTeam(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -46,7 +46,7 @@ func (h *Handler) CreateTeam(c *gin.Context) {
 
 	err = h.Service.Team.CreateTeam(ctx, team)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model: %s", err).Error())
+		newServiceErrorResponse(c, "can not create model", err)
 		return
 	}
 	c.JSON(http.StatusOK, map[string]interface{}{
@@ -71,7 +71,7 @@ func (h *Handler) GetTeamsByOrganizationID(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -88,7 +88,7 @@ func (h *Handler) GetTeamsByOrganizationID(c *gin.Context) {
 
 	teams, err := h.Service.Team.GetTeamsByOrganizationID(ctx, id)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model: %s", err).Error())
+		newServiceErrorResponse(c, "can not create model", err)
 		return
 	}
 	c.JSON(http.StatusOK, map[string]interface{}{
@@ -113,7 +113,7 @@ func (h *Handler) GetTeamsByEventID(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -130,7 +130,7 @@ func (h *Handler) GetTeamsByEventID(c *gin.Context) {
 
 	teams, err := h.Service.Team.GetTeamsByEvent(ctx, id)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model: %s", err).Error())
+		newServiceErrorResponse(c, "can not create model", err)
 		return
 	}
 	c.JSON(http.StatusOK, map[string]interface{}{
@@ -155,7 +155,7 @@ func (h *Handler) GetTeamByID(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -172,7 +172,7 @@ func (h *Handler) GetTeamByID(c *gin.Context) {
 
 	team, err := h.Service.Team.GetTeamByID(ctx, id)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model: %s", err).Error())
+		newServiceErrorResponse(c, "can not create model", err)
 		return
 	}
 	c.JSON(http.StatusOK, map[string]interface{}{
@@ -198,7 +198,7 @@ func (h *Handler) UpdateTeam(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -225,7 +225,7 @@ func (h *Handler) UpdateTeam(c *gin.Context) {
 
 	err = h.Service.Team.UpdateTeam(ctx, team)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not update model in updating team: %s", err).Error())
+		newServiceErrorResponse(c, "can not update model in updating team", err)
 		return
 	}
 	c.JSON(http.StatusOK, map[string]interface{}{
@@ -250,7 +250,7 @@ func (h *Handler) DeleteTeamByID(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -267,7 +267,7 @@ func (h *Handler) DeleteTeamByID(c *gin.Context) {
 
 	err = h.Service.Team.DeleteTeam(ctx, id)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not delete team: %s", err).Error())
+		newServiceErrorResponse(c, "can not delete team", err)
 		return
 	}
 	c.JSON(http.StatusOK, map[string]interface{}{
@@ -0,0 +1,395 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"net/http"
+)
+
+// SaveTemplate
+// @Summary Save event as template
+// @Security ApiKeyAuth
+// @Tags events
+// @Description save event by event ID with steps, relative step dates, prizes, rubrics, quizzes and code tests as a template
+// @Description template name by default is event name, only creator or admin of the event may save it
+// @ID save-template
+// @Accept  json
+// @Produce  json
+// @Param input body models.TemplateRequest true "template info"
+// @Success 200 {object} models.EventTemplate
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/template/:id [post]
+func (h *Handler) SaveTemplate(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.EventCreate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in saving template: %s", err).Error())
+		return
+	}
+
+	var request models.TemplateRequest
+
+	if err := c.Bind(&request); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get input model in saving template: %s", err).Error())
+		return
+	}
+
+	template, err := h.Service.Template.SaveTemplate(ctx, id, staff.ID, request)
+	if err != nil {
+		newServiceErrorResponse(c, "can not save template", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"template": template,
+	})
+}
+
+// GetTemplates
+// @Summary Get organization templates
+// @Security ApiKeyAuth
+// @Tags events
+// @Description get event templates of organization by organization ID
+// @ID get-templates
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} []models.EventTemplate
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/templates/:id [get]
+func (h *Handler) GetTemplates(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.EventCreate, models.EventGetAll) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in getting templates: %s", err).Error())
+		return
+	}
+
+	if staff.OrganizationID != id {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	templates, err := h.Service.Template.GetTemplates(ctx, id)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get templates", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"templates": templates,
+	})
+}
+
+// GetTemplate
+// @Summary Get template
+// @Security ApiKeyAuth
+// @Tags events
+// @Description get event template by ID
+// @ID get-template
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} models.EventTemplate
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/template/:id [get]
+func (h *Handler) GetTemplate(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.EventCreate, models.EventGetAll) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in getting template: %s", err).Error())
+		return
+	}
+
+	template, err := h.Service.Template.GetTemplate(ctx, id, staff.OrganizationID)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get template", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"template": template,
+	})
+}
+
+// DeleteTemplate
+// @Summary Delete template
+// @Security ApiKeyAuth
+// @Tags events
+// @Description delete event template by ID, events created from it are kept
+// @ID delete-template
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} boolean
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/template/:id [delete]
+func (h *Handler) DeleteTemplate(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.EventCreate, models.EventDelete) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in deleting template: %s", err).Error())
+		return
+	}
+
+	err = h.Service.Template.DeleteTemplate(ctx, id, staff.OrganizationID)
+	if err != nil {
+		newServiceErrorResponse(c, "can not delete template", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"deleted": true,
+	})
+}
+
+// InstantiateTemplate
+// @Summary Create event from template
+// @Security ApiKeyAuth
+// @Tags events
+// @Description create event from template by template ID starting at start date, now by default
+// @Description steps keep their offsets from event start, all IDs are new
+// @Description staff of team is invited to the event, the event is of template organization
+// @Description only staff of template organization may create it
+// @ID instantiate-template
+// @Accept  json
+// @Produce  json
+// @Param input body models.InstantiateRequest true "new event info"
+// @Success 200 {string} uuid
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/template/instantiate/:id [post]
+func (h *Handler) InstantiateTemplate(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.EventCreate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in creating event from template: %s", err).Error())
+		return
+	}
+
+	var request models.InstantiateRequest
+
+	if err := c.Bind(&request); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get input model in creating event from template: %s", err).Error())
+		return
+	}
+
+	eventID, err := h.Service.Template.InstantiateTemplate(ctx, id, staff.ID, request)
+	if err != nil {
+		newServiceErrorResponse(c, "can not create event from template", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"created": eventID,
+	})
+}
+
+// CloneEvent
+// @Summary Clone event
+// @Security ApiKeyAuth
+// @Tags events
+// @Description create a deep copy of event by event ID with steps, prizes, rubrics, quizzes and code tests
+// @Description all IDs are new, dates are moved to start date keeping their offsets, source event start by default
+// @Description staff of team is invited to the copy, the copy is of source event organization
+// @Description only creator or admin of the source event may copy it
+// @ID clone-event
+// @Accept  json
+// @Produce  json
+// @Param input body models.InstantiateRequest true "new event info"
+// @Success 200 {string} uuid
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/event/clone/:id [post]
+func (h *Handler) CloneEvent(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.EventCreate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in cloning event: %s", err).Error())
+		return
+	}
+
+	var request models.InstantiateRequest
+
+	if err := c.Bind(&request); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get input model in cloning event: %s", err).Error())
+		return
+	}
+
+	eventID, err := h.Service.Template.CloneEvent(ctx, id, staff.ID, request)
+	if err != nil {
+		newServiceErrorResponse(c, "can not clone event", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"created": eventID,
+	})
+}
@@ -31,7 +31,7 @@ func (h *Handler) GetStaffInvites(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 	if !staff.HasOneOfPermissions(models.StaffGetInvites, models.StaffGetSelfInvites) {
@@ -42,7 +42,7 @@ func (h *Handler) GetStaffInvites(c *gin.Context) {
 
 	invites, err := h.Service.Staff.GetInvites(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newServiceErrorResponse(c, "can not get invites", err)
 		return
 	}
 	c.JSON(http.StatusOK, map[string]interface{}{
@@ -71,7 +71,7 @@ func (h *Handler) GetStaffPrizes(c *gin.Context) {
 	}
 	staff, err := h.Service.Staff.GetStaff(ctx, id)
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 	if id == userID.(uuid.UUID) {
@@ -87,13 +87,19 @@ func (h *Handler) GetStaffPrizes(c *gin.Context) {
 			return
 		}
 	}
-	prizes, err := h.Service.Staff.GetStaffPrizes(ctx, id)
+	query, err := models.NewListQuery(c.Request.URL.Query(), models.PrizeList)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse list query: %s", err).Error())
+		return
+	}
+	prizes, next, err := h.Service.Staff.GetStaffPrizes(ctx, id, query)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff prizes", err)
 		return
 	}
 	c.JSON(http.StatusOK, map[string]interface{}{
-		"prizes": prizes,
+		"prizes":      prizes,
+		"next_cursor": next,
 	})
 }
 
@@ -107,8 +113,7 @@ func (h *Handler) GetAllUsersInEvent(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaffByEvent(ctx, id)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError,
-			fmt.Errorf("can not parse input id in users in events: %s", err).Error())
+		newServiceErrorResponse(c, "can not parse input id in users in events", err)
 		return
 	}
 
@@ -127,8 +132,7 @@ func (h *Handler) GetAllUsersInStep(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaffByStep(ctx, id)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError,
-			fmt.Errorf("can not parse input id in users in step: %s", err).Error())
+		newServiceErrorResponse(c, "can not parse input id in users in step", err)
 		return
 	}
 
@@ -159,8 +163,7 @@ func (h *Handler) GetStaffByID(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, id)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError,
-			fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -198,7 +201,7 @@ func (h *Handler) UpdateStaffByID(c *gin.Context) {
 	}
 	staff, err := h.Service.Staff.GetStaff(ctx, id)
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 	_, ok = userID.(uuid.UUID)
@@ -259,8 +262,7 @@ func (h *Handler) UpdateStaffByID(c *gin.Context) {
 
 	err = h.Service.Staff.UpdateStaff(ctx, staffUpdate)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError,
-			fmt.Errorf("can not update staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not update staff by id", err)
 		return
 	}
 
@@ -284,7 +286,7 @@ func (h *Handler) DeleteStaff(c *gin.Context) {
 	}
 	staff, err := h.Service.Staff.GetStaff(ctx, id)
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 	_, ok = userID.(uuid.UUID)
@@ -308,8 +310,7 @@ func (h *Handler) DeleteStaff(c *gin.Context) {
 	}
 	err = h.Service.Staff.DeleteStaff(ctx, id)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError,
-			fmt.Errorf("can not delete staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not delete staff by id", err)
 		return
 	}
 
@@ -346,7 +347,7 @@ func (h *Handler) CreateStaff(c *gin.Context) {
 
 	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
 	if err != nil {
-		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not get staff by id: %s", err).Error())
+		newServiceErrorResponse(c, "can not get staff by id", err)
 		return
 	}
 
@@ -358,7 +359,7 @@ func (h *Handler) CreateStaff(c *gin.Context) {
 	var input *models.StaffSignUp
 
 	if err := c.Bind(&input); err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, fmt.Errorf("can not create model: %s", err).Error())
+		newServiceErrorResponse(c, "can not create model", err)
 		return
 	}
 	if !staff.Sex.IsCorrect(string(staff.Sex)) {
@@ -384,7 +385,7 @@ func (h *Handler) CreateStaff(c *gin.Context) {
 	input.BackgroundColor = "#fffff"
 	err = h.Service.Staff.CreateStaffUser(c.Request.Context(), input)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newServiceErrorResponse(c, "can not create staff", err)
 		return
 	}
 
@@ -419,7 +420,7 @@ func (h *Handler) UploadImage(c *gin.Context) {
 	err = c.SaveUploadedFile(file, dst)
 	if err != nil {
 		_ = os.Remove(dst)
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newServiceErrorResponse(c, "can not save image", err)
 		return
 	}
 
@@ -430,7 +431,7 @@ func (h *Handler) UploadImage(c *gin.Context) {
 	}
 	err = h.Service.Staff.UploadImage(c.Request.Context(), staffImage)
 	if err != nil {
-		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		newServiceErrorResponse(c, "can not upload image", err)
 		return
 	}
 
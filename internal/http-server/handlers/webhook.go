@@ -0,0 +1,421 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"net/http"
+	"strconv"
+)
+
+// CreateWebhook
+// @Summary Create webhook
+// @Security ApiKeyAuth
+// @Tags webhooks
+// @Description create webhook of organization of current staff subscribed to domain events
+// @Description events: event.created, event.finished, step.created, step.finished, prize.awarded, staff.joined
+// @Description deliveries are signed with header X-Acheer-Signature: sha256=HMAC-SHA256(secret, X-Acheer-Timestamp + "." + body)
+// @Description secret is returned only once
+// @ID create-webhook
+// @Accept  json
+// @Produce  json
+// @Param input body models.WebhookRequest true "webhook info"
+// @Success 200 {object} models.Webhook
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/webhook/ [post]
+func (h *Handler) CreateWebhook(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.OrganizationUpdate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	var input models.WebhookRequest
+	if err := c.Bind(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not bind webhook: %s", err).Error())
+		return
+	}
+
+	webhook, secret, err := h.Service.Webhook.CreateWebhook(ctx, staff.OrganizationID, staff.ID, input)
+	if err != nil {
+		newServiceErrorResponse(c, "can not create webhook", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"webhook": webhook,
+		"secret":  secret,
+	})
+}
+
+// GetWebhooks
+// @Summary Get webhooks
+// @Security ApiKeyAuth
+// @Tags webhooks
+// @Description get webhooks of organization of current staff
+// @ID get-webhooks
+// @Accept  json
+// @Produce  json
+// @Success 200 {array} models.Webhook
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/webhook/ [get]
+func (h *Handler) GetWebhooks(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.OrganizationUpdate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	webhooks, err := h.Service.Webhook.GetWebhooks(ctx, staff.OrganizationID)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get webhooks", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// GetWebhook
+// @Summary Get webhook
+// @Security ApiKeyAuth
+// @Tags webhooks
+// @Description get webhook by id
+// @ID get-webhook
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} models.Webhook
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/webhook/{id} [get]
+func (h *Handler) GetWebhook(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.OrganizationUpdate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in webhook: %s", err).Error())
+		return
+	}
+
+	webhook, err := h.Service.Webhook.GetWebhook(ctx, id, staff.OrganizationID)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get webhook", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// UpdateWebhook
+// @Summary Update webhook
+// @Security ApiKeyAuth
+// @Tags webhooks
+// @Description update url, events or enabled of webhook
+// @Description enabling a disabled webhook resets its failures
+// @ID update-webhook
+// @Accept  json
+// @Produce  json
+// @Param input body models.WebhookRequest true "webhook info"
+// @Success 200 {object} models.Webhook
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/webhook/{id} [put]
+func (h *Handler) UpdateWebhook(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.OrganizationUpdate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in webhook: %s", err).Error())
+		return
+	}
+
+	var input models.WebhookRequest
+	if err := c.Bind(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not bind webhook: %s", err).Error())
+		return
+	}
+
+	webhook, err := h.Service.Webhook.UpdateWebhook(ctx, id, staff.OrganizationID, input)
+	if err != nil {
+		newServiceErrorResponse(c, "can not update webhook", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// DeleteWebhook
+// @Summary Delete webhook
+// @Security ApiKeyAuth
+// @Tags webhooks
+// @Description delete webhook with its delivery log
+// @ID delete-webhook
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} boolean
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/webhook/{id} [delete]
+func (h *Handler) DeleteWebhook(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.OrganizationUpdate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in webhook: %s", err).Error())
+		return
+	}
+
+	if err := h.Service.Webhook.DeleteWebhook(ctx, id, staff.OrganizationID); err != nil {
+		newServiceErrorResponse(c, "can not delete webhook", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, true)
+}
+
+// GetWebhookDeliveries
+// @Summary Get webhook deliveries
+// @Security ApiKeyAuth
+// @Tags webhooks
+// @Description get delivery log of webhook, newest first
+// @Description limit is 50 at most
+// @ID get-webhook-deliveries
+// @Accept  json
+// @Produce  json
+// @Success 200 {array} models.WebhookDelivery
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/webhook/deliveries/{id} [get]
+func (h *Handler) GetWebhookDeliveries(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.OrganizationUpdate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in webhook: %s", err).Error())
+		return
+	}
+
+	var limit int
+	if l := c.Query("limit"); l != "" {
+		limit, err = strconv.Atoi(l)
+		if err != nil {
+			newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse limit: %s", err).Error())
+			return
+		}
+	}
+
+	deliveries, err := h.Service.Webhook.GetDeliveries(ctx, id, staff.OrganizationID, limit)
+	if err != nil {
+		newServiceErrorResponse(c, "can not get webhook deliveries", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// RedeliverWebhook
+// @Summary Redeliver webhook delivery
+// @Security ApiKeyAuth
+// @Tags webhooks
+// @Description send payload of delivery again as a new delivery with the same event id
+// @ID redeliver-webhook
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} models.WebhookDelivery
+// @Failure 400,403 {object} errorResponse
+// @Failure 500 {object} errorResponse
+// @Failure default {object} errorResponse
+// @Router /api/webhook/redeliver/{id} [post]
+func (h *Handler) RedeliverWebhook(c *gin.Context) {
+	ctx := context.Background()
+
+	userID, ok := c.Get("userID")
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"there is no userID in context")
+		return
+	}
+	_, ok = userID.(uuid.UUID)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError,
+			"can not parse user id from context")
+		return
+	}
+
+	staff, err := h.Service.Staff.GetStaff(ctx, userID.(uuid.UUID))
+	if err != nil {
+		newServiceErrorResponse(c, "can not get staff by id", err)
+		return
+	}
+
+	if !staff.HasOneOfPermissions(models.OrganizationUpdate) {
+		newErrorResponse(c, http.StatusForbidden,
+			"no access to this action")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, fmt.Errorf("can not parse input id in delivery: %s", err).Error())
+		return
+	}
+
+	delivery, err := h.Service.Webhook.Redeliver(ctx, id, staff.OrganizationID)
+	if err != nil {
+		newServiceErrorResponse(c, "can not redeliver webhook delivery", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
@@ -10,10 +10,21 @@ type Server struct {
 	httpServer *http.Server
 }
 
+type connWriterKey struct{}
+
+// ConnWriter returns the writer of the connection of the request. Gin writers do not unwrap
+// to it, so handlers that stream take it from here to change the write deadline.
+func ConnWriter(ctx context.Context) (http.ResponseWriter, bool) {
+	w, ok := ctx.Value(connWriterKey{}).(http.ResponseWriter)
+	return w, ok
+}
+
 func (s *Server) Run(port string, handler http.Handler) error {
 	s.httpServer = &http.Server{
-		Addr:           ":" + port,
-		Handler:        handler,
+		Addr: ":" + port,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), connWriterKey{}, w)))
+		}),
 		MaxHeaderBytes: 1 << 20,
 		ReadTimeout:    10 * time.Second,
 		WriteTimeout:   10 * time.Second,
@@ -0,0 +1,28 @@
+// Package mail sends emails. Senders are pluggable, SMTP is used in production
+// and the log sender when no mail server is configured.
+package mail
+
+import (
+	"context"
+	log "github.com/sirupsen/logrus"
+)
+
+// Message is an email with a plain text and an HTML version of the same content.
+type Message struct {
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+type Sender interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// LogSender writes messages to the log instead of sending them.
+type LogSender struct{}
+
+func (LogSender) Send(ctx context.Context, message Message) error {
+	log.WithFields(log.Fields{"to": message.To, "subject": message.Subject}).Info(message.Text)
+	return nil
+}
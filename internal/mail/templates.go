@@ -0,0 +1,68 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var files embed.FS
+
+var funcs = map[string]interface{}{
+	// signed writes a change with its sign, e.g. +3 or -1.
+	"signed": func(n int) string {
+		if n > 0 {
+			return fmt.Sprintf("+%d", n)
+		}
+		return fmt.Sprint(n)
+	},
+	"fullName": func(first, last string) string {
+		return strings.TrimSpace(first + " " + last)
+	},
+}
+
+// Templates render messages from templates/<name>.txt and templates/<name>.html.
+// The subject is the "<name>.subject" template defined in the text one.
+type Templates struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+func (t *Templates) Render(name string, data interface{}) (Message, error) {
+	var subject, text, html bytes.Buffer
+	textTemplate := t.text.Lookup(name + ".txt")
+	htmlTemplate := t.html.Lookup(name + ".html")
+	if textTemplate == nil || htmlTemplate == nil {
+		return Message{}, fmt.Errorf("there is no mail template %s", name)
+	}
+	if err := textTemplate.Execute(&text, data); err != nil {
+		return Message{}, err
+	}
+	if err := textTemplate.ExecuteTemplate(&subject, name+".subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := htmlTemplate.Execute(&html, data); err != nil {
+		return Message{}, err
+	}
+	return Message{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+func NewTemplates() (*Templates, error) {
+	text, err := texttemplate.New("").Funcs(funcs).ParseFS(files, "templates/*.txt")
+	if err != nil {
+		return nil, err
+	}
+	html, err := htmltemplate.New("").Funcs(funcs).ParseFS(files, "templates/*.html")
+	if err != nil {
+		return nil, err
+	}
+	return &Templates{text: text, html: html}, nil
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+type ChatPlatform string
+
+const (
+	ChatSlack      ChatPlatform = "slack"
+	ChatMattermost ChatPlatform = "mattermost"
+)
+
+func NewChatPlatform(s string) (ChatPlatform, error) {
+	switch ChatPlatform(s) {
+	case ChatSlack, ChatMattermost:
+		return ChatPlatform(s), nil
+	}
+	return "", fmt.Errorf("incorrent chat platform: %s; want: %s or %s", s, ChatSlack, ChatMattermost)
+}
+
+// ChatIntegration connects a chat team to an organization. Slash commands of the team are verified
+// with SigningSecret, which is the signing secret of a Slack app or the token of a Mattermost command.
+// Prize awards and leaderboards are posted to WebhookURL. BotToken reads emails of chat users
+// from the chat API, ServerURL is the address of the Mattermost server.
+type ChatIntegration struct {
+	bun.BaseModel `bun:"table:chat_integration,alias:chat_integration"`
+
+	ID             uuid.UUID    `json:"id" bun:",pk"`
+	OrganizationID uuid.UUID    `json:"organization_id"`
+	Platform       ChatPlatform `json:"platform"`
+	TeamID         string       `json:"team_id"`
+	WebhookURL     string       `json:"webhook_url"`
+	ServerURL      string       `json:"server_url,omitempty"`
+	SigningSecret  string       `json:"-"`
+	BotToken       string       `json:"-"`
+	Enabled        bool         `json:"enabled"`
+	CreatedByID    uuid.UUID    `json:"created_by_id" bun:"created_by,nullzero"`
+	CreatedAt      time.Time    `json:"created_at" bun:",nullzero,default:current_timestamp"`
+}
+
+// ChatIntegrationRequest creates or updates a chat integration. Empty secrets are not changed on update.
+type ChatIntegrationRequest struct {
+	Platform      ChatPlatform `json:"platform"`
+	TeamID        string       `json:"team_id"`
+	WebhookURL    string       `json:"webhook_url"`
+	ServerURL     string       `json:"server_url"`
+	SigningSecret string       `json:"signing_secret"`
+	BotToken      string       `json:"bot_token"`
+	Enabled       *bool        `json:"enabled"`
+}
+
+// ChatUser is a user of a chat team. Mentions may have only one of ID and Name.
+type ChatUser struct {
+	ID   string
+	Name string
+}
+
+// ChatCommand is a slash command of a chat user, Text is everything after the command.
+type ChatCommand struct {
+	TeamID    string
+	ChannelID string
+	User      ChatUser
+	Command   string
+	Text      string
+}
+
+// ChatResponse answers a slash command. Ephemeral responses are shown only to the user of the command.
+type ChatResponse struct {
+	Text      string
+	Ephemeral bool
+}
+
+// LeaderboardEntry is the total score of staff in an event.
+type LeaderboardEntry struct {
+	StaffID   uuid.UUID `json:"staff_id"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Score     int       `json:"score"`
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+type CodeTestCase struct {
+	bun.BaseModel `bun:"table:code_test_case,alias:code_test_case"`
+
+	ID             uuid.UUID `json:"id" bun:",pk"`
+	StepID         uuid.UUID `json:"step_id"`
+	Input          string    `json:"input"`
+	ExpectedOutput string    `json:"expected_output"`
+	Score          uint      `json:"score"`
+	Hidden         bool      `json:"hidden"`
+	Position       uint      `json:"position"`
+}
+
+type CodeSubmissionStatus string
+
+const (
+	CodePending CodeSubmissionStatus = "pending"
+	CodeDone    CodeSubmissionStatus = "done"
+	CodeError   CodeSubmissionStatus = "error"
+)
+
+type CodeSubmission struct {
+	bun.BaseModel `bun:"table:code_submission,alias:code_submission"`
+
+	ID          uuid.UUID            `json:"id" bun:",pk"`
+	StepID      uuid.UUID            `json:"step_id"`
+	StaffID     uuid.UUID            `json:"staff_id"`
+	Language    string               `json:"language"`
+	Source      string               `json:"source"`
+	Status      CodeSubmissionStatus `json:"status"`
+	Score       uint                 `json:"score"`
+	Passed      uint                 `json:"passed"`
+	Total       uint                 `json:"total"`
+	Error       string               `json:"error,omitempty"`
+	SubmittedAt time.Time            `json:"submitted_at" bun:",nullzero,default:current_timestamp"`
+	FinishedAt  *time.Time           `json:"finished_at,omitempty"`
+	Results     []*CodeTestResult    `json:"results,omitempty" bun:"rel:has-many,join:id=submission_id"`
+}
+
+type CodeTestStatus string
+
+const (
+	TestPassed      CodeTestStatus = "passed"
+	TestWrongAnswer CodeTestStatus = "wrong-answer"
+)
+
+// CodeTestResult keeps the hidden flag of its test case, output of hidden tests is not shown to staff.
+type CodeTestResult struct {
+	bun.BaseModel `bun:"table:code_test_result,alias:code_test_result"`
+
+	ID           uuid.UUID      `json:"id" bun:",pk"`
+	SubmissionID uuid.UUID      `json:"submission_id"`
+	TestCaseID   uuid.UUID      `json:"test_case_id"`
+	Status       CodeTestStatus `json:"status"`
+	Hidden       bool           `json:"hidden"`
+	Output       string         `json:"output,omitempty"`
+	Duration     uint           `json:"duration"`
+}
+
+type CodeSubmissionRequest struct {
+	Language string `json:"language"`
+	Source   string `json:"source"`
+}
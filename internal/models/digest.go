@@ -0,0 +1,101 @@
+package models
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+type DigestKind string
+
+const (
+	// DigestStaff is the weekly digest of staff about its own invitations, deadlines, points and rank.
+	DigestStaff DigestKind = "staff"
+	// DigestTeam is the weekly digest of a team for staff allowed to manage it.
+	DigestTeam DigestKind = "team"
+)
+
+var DigestKinds = []DigestKind{DigestStaff, DigestTeam}
+
+func NewDigestKind(s string) (DigestKind, error) {
+	for _, kind := range DigestKinds {
+		if DigestKind(s) == kind {
+			return kind, nil
+		}
+	}
+	return "", fmt.Errorf("incorrent digest kind: %s; want one of: %v", s, DigestKinds)
+}
+
+// DigestPreference turns a weekly digest on or off for staff. Digests without a preference are on.
+type DigestPreference struct {
+	bun.BaseModel `bun:"table:digest_preference,alias:digest_preference"`
+
+	StaffID uuid.UUID  `json:"-" bun:",pk"`
+	Kind    DigestKind `json:"kind" bun:",pk"`
+	Enabled bool       `json:"enabled"`
+}
+
+// DigestSnapshot is the total score and the rank in the organization of staff at the start of a digest week.
+// Points earned and rank changes are the difference of two snapshots in a row.
+type DigestSnapshot struct {
+	bun.BaseModel `bun:"table:digest_snapshot,alias:digest_snapshot"`
+
+	StaffID uuid.UUID `bun:",pk"`
+	Week    time.Time `bun:",pk"`
+	Score   int
+	Rank    int
+}
+
+// DigestSent records a digest delivered to staff, so a retried digest job does not send it twice.
+type DigestSent struct {
+	bun.BaseModel `bun:"table:digest_sent,alias:digest_sent"`
+
+	StaffID uuid.UUID  `bun:",pk"`
+	Kind    DigestKind `bun:",pk"`
+	Week    time.Time  `bun:",pk"`
+	SentAt  time.Time  `bun:",nullzero,default:current_timestamp"`
+}
+
+// DigestDeadline is the end of a step staff has not finished yet.
+type DigestDeadline struct {
+	StaffID   uuid.UUID `bun:"staff_id"`
+	StepID    uuid.UUID `bun:"step_id"`
+	StepName  string    `bun:"step_name"`
+	EventName string    `bun:"event_name"`
+	EndDate   time.Time `bun:"end_date"`
+}
+
+// DigestStanding is the score of staff for the week and its rank in the organization.
+type DigestStanding struct {
+	Score      int
+	Points     int
+	Rank       int
+	RankChange int
+}
+
+// StaffDigest is the content of the weekly digest of one staff.
+type StaffDigest struct {
+	Staff          *Staff
+	Week           time.Time
+	Invitations    []*StaffEvents
+	Deadlines      []DigestDeadline
+	Standing       DigestStanding
+	UnsubscribeURL string
+}
+
+// TeamDigestMember is a member of the team with its standing, members are ordered by points earned.
+type TeamDigestMember struct {
+	Staff    *Staff
+	Standing DigestStanding
+}
+
+// TeamDigest is the content of the weekly digest of a team sent to a manager of it.
+type TeamDigest struct {
+	Manager        *Staff
+	Team           *Team
+	Week           time.Time
+	Members        []TeamDigestMember
+	Points         int
+	UnsubscribeURL string
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+type DomainEventType string
+
+const (
+	DomainEventCreated  DomainEventType = "event.created"
+	DomainEventFinished DomainEventType = "event.finished"
+	DomainStepCreated   DomainEventType = "step.created"
+	DomainStepFinished  DomainEventType = "step.finished"
+	DomainStepResult    DomainEventType = "step.result"
+	DomainPrizeAwarded  DomainEventType = "prize.awarded"
+	DomainStaffJoined   DomainEventType = "staff.joined"
+)
+
+// DomainEvent is a fact about a change, written to the outbox in the transaction of the change
+// and handed off to every subscriber by the dispatcher. EventID is the event the change belongs to,
+// when there is one. Handled lists subscribers the domain event is already handed off to.
+type DomainEvent struct {
+	bun.BaseModel `bun:"table:domain_event,alias:domain_event"`
+
+	ID             uuid.UUID       `json:"id" bun:",pk"`
+	EventType      DomainEventType `json:"event_type"`
+	OrganizationID uuid.UUID       `json:"organization_id"`
+	EventID        uuid.UUID       `json:"event_id" bun:",nullzero"`
+	Data           json.RawMessage `json:"data" bun:"type:jsonb"`
+	Handled        []string        `json:"handled" bun:",array"`
+	Attempts       uint            `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at" bun:",nullzero,default:current_timestamp"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at" bun:",nullzero,default:current_timestamp"`
+	DispatchedAt   *time.Time      `json:"dispatched_at,omitempty"`
+}
+
+// NewDomainEvent creates a domain event of the organization with data as its payload.
+func NewDomainEvent(eventType DomainEventType, orgID, eventID uuid.UUID, data interface{}) (*DomainEvent, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return &DomainEvent{
+		ID:             uuid.New(),
+		EventType:      eventType,
+		OrganizationID: orgID,
+		EventID:        eventID,
+		Data:           payload,
+	}, nil
+}
+
+func (d *DomainEvent) IsHandled(subscriber string) bool {
+	for _, name := range d.Handled {
+		if name == subscriber {
+			return true
+		}
+	}
+	return false
+}
+
+// EventData is the data of event.created and event.finished.
+type EventData struct {
+	EventID     uuid.UUID   `json:"event_id"`
+	Name        string      `json:"name"`
+	Status      EventStatus `json:"status"`
+	CreatedByID uuid.UUID   `json:"created_by_id"`
+}
+
+// StepData is the data of step.created and step.finished.
+type StepData struct {
+	StepID  uuid.UUID  `json:"step_id"`
+	EventID uuid.UUID  `json:"event_id"`
+	Name    string     `json:"name"`
+	Status  StepStatus `json:"status"`
+}
+
+// StepResultData is the data of step.result, a step of staff is done, failed or back in process.
+type StepResultData struct {
+	StepID         uuid.UUID      `json:"step_id"`
+	EventID        uuid.UUID      `json:"event_id"`
+	StaffID        uuid.UUID      `json:"staff_id"`
+	Accomplishment Accomplishment `json:"accomplishment"`
+	Score          uint           `json:"score"`
+}
+
+// PrizeData is the data of prize.awarded. LootBox is the name of the loot box the prize is drawn from.
+type PrizeData struct {
+	PrizeID uuid.UUID `json:"prize_id"`
+	StaffID uuid.UUID `json:"staff_id"`
+	StepID  uuid.UUID `json:"step_id,omitempty"`
+	Name    string    `json:"name"`
+	LootBox string    `json:"loot_box,omitempty"`
+}
+
+// StaffJoinedData is the data of staff.joined, waitlisted staff joins too.
+type StaffJoinedData struct {
+	EventID uuid.UUID    `json:"event_id"`
+	StaffID uuid.UUID    `json:"staff_id"`
+	Status  InviteStatus `json:"status"`
+}
@@ -1,10 +1,48 @@
 package models
 
 import (
+	"fmt"
 	"github.com/google/uuid"
 	"github.com/uptrace/bun"
+	"time"
 )
 
+type EventStatus string
+
+const (
+	EventDraft     EventStatus = "draft"
+	EventScheduled EventStatus = "scheduled"
+	EventRunning   EventStatus = "running"
+	EventFinished  EventStatus = "finished"
+	EventCanceled  EventStatus = "canceled"
+)
+
+var eventTransitions = map[EventStatus][]EventStatus{
+	EventDraft:     {EventScheduled, EventRunning, EventCanceled},
+	EventScheduled: {EventDraft, EventRunning, EventCanceled},
+	EventRunning:   {EventFinished, EventCanceled},
+}
+
+func NewEventStatus(s string) (EventStatus, error) {
+	switch EventStatus(s) {
+	case EventDraft, EventScheduled, EventRunning, EventFinished, EventCanceled:
+		return EventStatus(s), nil
+	}
+	return "", fmt.Errorf("incorrent event status: %s; want: %s, %s, %s, %s, %s",
+		s, EventDraft, EventScheduled, EventRunning, EventFinished, EventCanceled)
+}
+
+// CanTransition reports whether an event can move from s to status.
+// Finished and canceled events are final.
+func (s EventStatus) CanTransition(status EventStatus) bool {
+	for _, next := range eventTransitions[s] {
+		if next == status {
+			return true
+		}
+	}
+	return false
+}
+
 type Event struct {
 	bun.BaseModel `bun:"table:event,alias:event"`
 
@@ -16,11 +54,24 @@ type Event struct {
 	ImagePath      string         `json:"image_path"`
 	CreatedByID    uuid.UUID      `json:"created_by_id" bun:"created_by"`
 	CreatedBy      *Staff         `json:"created_by" bun:"rel:belongs-to,join:created_by=id"`
-	EventStatus    string         `json:"event_status"`
+	EventStatus    EventStatus    `json:"event_status"`
 	EventType      string         `json:"event_type"`
 	OrganizationID uuid.UUID      `json:"organization_id"`
 	StaffEvents    []*StaffEvents `json:"staff" bun:"m2m:staff_events,join:Event=Staff"`
 	Steps          []*Step        `json:"steps" bun:"rel:has-many,join:id=event_id"`
+
+	MaxParticipants      uint       `json:"max_participants"`
+	RegistrationDeadline *time.Time `json:"registration_deadline,omitempty"`
+	InvitationTTL        uint       `json:"invitation_ttl"`
+}
+
+// RegistrationClosed reports whether the registration deadline of the event has passed.
+func (e *Event) RegistrationClosed(now time.Time) bool {
+	return e.RegistrationDeadline != nil && now.After(*e.RegistrationDeadline)
+}
+
+type EventStatusRequest struct {
+	Status EventStatus `json:"status"`
 }
 
 type StaffScore struct {
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+type JobType string
+
+const (
+	JobStepCreate  JobType = "step-create"
+	JobStepFinish  JobType = "step-finish"
+	JobEventStart  JobType = "event-start"
+	JobEventFinish JobType = "event-finish"
+	JobOccurrence  JobType = "series-occurrence"
+	JobDigest      JobType = "digest"
+	JobReminder    JobType = "step-reminder"
+)
+
+type JobStatus string
+
+const (
+	JobPending  JobStatus = "pending"
+	JobRunning  JobStatus = "running"
+	JobDone     JobStatus = "done"
+	JobFailed   JobStatus = "failed"
+	JobCanceled JobStatus = "canceled"
+)
+
+// Job is a persisted scheduled action on a step, an event, an event series or of the weekly digest.
+// Payload is internal to the job handler and may contain data staff must not see, e.g. quiz answer keys.
+type Job struct {
+	bun.BaseModel `bun:"table:scheduled_job,alias:scheduled_job"`
+
+	ID         uuid.UUID       `json:"id" bun:",pk"`
+	JobType    JobType         `json:"job_type"`
+	StepID     uuid.UUID       `json:"step_id" bun:",nullzero"`
+	EventID    uuid.UUID       `json:"event_id" bun:",nullzero"`
+	SeriesID   uuid.UUID       `json:"series_id" bun:",nullzero"`
+	Status     JobStatus       `json:"status"`
+	RunAt      time.Time       `json:"run_at"`
+	Payload    json.RawMessage `json:"-" bun:"type:jsonb,nullzero"`
+	Attempts   uint            `json:"attempts"`
+	LastError  string          `json:"last_error,omitempty"`
+	CreatedAt  time.Time       `json:"created_at" bun:",nullzero,default:current_timestamp"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+// JoinCode is a shareable code staff can join an event with, mostly a private one.
+// A code without expiry time or with zero max uses is not limited by it.
+type JoinCode struct {
+	bun.BaseModel `bun:"table:event_join_code,alias:event_join_code"`
+
+	ID          uuid.UUID  `json:"id" bun:",pk"`
+	EventID     uuid.UUID  `json:"event_id"`
+	Code        string     `json:"code"`
+	CreatedByID uuid.UUID  `json:"created_by_id" bun:"created_by,nullzero"`
+	CreatedAt   time.Time  `json:"created_at" bun:",nullzero,default:current_timestamp"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	MaxUses     uint       `json:"max_uses"`
+	Uses        uint       `json:"uses"`
+}
+
+// Valid reports whether staff can still join with the code.
+func (j *JoinCode) Valid(now time.Time) bool {
+	if j.ExpiresAt != nil && now.After(*j.ExpiresAt) {
+		return false
+	}
+	return j.MaxUses == 0 || j.Uses < j.MaxUses
+}
+
+// JoinCodeRequest sets the lifetime of a new join code in seconds, zero means the code does not expire.
+type JoinCodeRequest struct {
+	TTL     uint `json:"ttl"`
+	MaxUses uint `json:"max_uses"`
+}
+
+type JoinByCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type JoinRequestAnswer struct {
+	Status InviteStatus `json:"status"`
+}
@@ -0,0 +1,205 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ListLimit    = 50
+	ListMaxLimit = 200
+)
+
+// ListSpec is what a list route can be sorted and filtered by. The first sort is the default one,
+// a sort starting with - is descending. Filters with no allowed values are not supported by the route.
+type ListSpec struct {
+	Sorts    []string
+	Statuses []string
+	Types    []string
+	Team     bool
+	Dates    bool
+}
+
+var (
+	OrganizationList = ListSpec{Sorts: []string{"name"}}
+	StaffList        = ListSpec{Sorts: []string{"last_name", "first_name"}, Team: true}
+	EventList        = ListSpec{
+		Sorts: []string{"end_date", "name", "creation_date"},
+		Statuses: []string{string(EventDraft), string(EventScheduled), string(EventRunning),
+			string(EventFinished), string(EventCanceled)},
+		Types: []string{"public", "private", "team-only"},
+		Dates: true,
+	}
+	StepList = ListSpec{
+		Sorts:    []string{"level", "name", "end_date"},
+		Statuses: []string{string(Process), string(Changed), string(Finished), string(Canceled)},
+		Types:    []string{string(TaskStep), string(QuizStep), string(CodeStep)},
+		Dates:    true,
+	}
+	PrizeList = ListSpec{
+		Sorts:    []string{"-creation_date", "name"},
+		Statuses: []string{string(Common), string(Rare), string(Mith), string(Legendary)},
+		Types:    []string{string(Image), string(Medal), string(Background), string(Text), string(LootBox)},
+		Dates:    true,
+	}
+	InvitationList = ListSpec{Sorts: []string{"-invited_at", "expires_at"}}
+)
+
+// ListQuery is a page of a list route. Pages are keyset ones: After is the last row of the previous
+// page, so rows created or deleted meanwhile do not shift the next page.
+type ListQuery struct {
+	Limit  int
+	Sort   string
+	Desc   bool
+	After  uuid.UUID
+	Status string
+	Type   string
+	TeamID uuid.UUID
+	// From and To limit the date of the route, To is exclusive.
+	From *time.Time
+	To   *time.Time
+}
+
+// listCursor is the encoded next_cursor. It keeps the sort, a page can not be sorted differently.
+type listCursor struct {
+	After uuid.UUID `json:"after"`
+	Sort  string    `json:"sort"`
+	Desc  bool      `json:"desc"`
+}
+
+// NewListQuery reads limit, cursor, sort, status, type, team_id, from and to query parameters
+// and checks them against the spec of the route.
+func NewListQuery(values url.Values, spec ListSpec) (ListQuery, error) {
+	query := ListQuery{Limit: ListLimit}
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return query, fmt.Errorf("incorrent limit: %s", limit)
+		}
+		if n > ListMaxLimit {
+			n = ListMaxLimit
+		}
+		query.Limit = n
+	}
+
+	sort := values.Get("sort")
+	if sort == "" {
+		sort = spec.Sorts[0]
+	}
+	query.Desc = strings.HasPrefix(sort, "-")
+	query.Sort = strings.TrimPrefix(sort, "-")
+	if !spec.sortable(query.Sort) {
+		return query, fmt.Errorf("incorrent sort: %s; want one of: %s", sort, strings.Join(spec.sortNames(), ", "))
+	}
+	if encoded := values.Get("cursor"); encoded != "" {
+		cursor, err := decodeListCursor(encoded)
+		if err != nil {
+			return query, err
+		}
+		if !spec.sortable(cursor.Sort) {
+			return query, fmt.Errorf("incorrent cursor: %s", encoded)
+		}
+		if values.Get("sort") != "" && (cursor.Sort != query.Sort || cursor.Desc != query.Desc) {
+			return query, fmt.Errorf("cursor is sorted by another field than %s", sort)
+		}
+		query.After, query.Sort, query.Desc = cursor.After, cursor.Sort, cursor.Desc
+	}
+
+	var err error
+	if query.Status, err = listFilter(values, "status", spec.Statuses); err != nil {
+		return query, err
+	}
+	if query.Type, err = listFilter(values, "type", spec.Types); err != nil {
+		return query, err
+	}
+	if team := values.Get("team_id"); team != "" {
+		if !spec.Team {
+			return query, fmt.Errorf("can not filter by team_id here")
+		}
+		if query.TeamID, err = uuid.Parse(team); err != nil {
+			return query, fmt.Errorf("incorrent team_id: %s", err)
+		}
+	}
+	if query.From, err = listDate(values, "from", spec.Dates); err != nil {
+		return query, err
+	}
+	if query.To, err = listDate(values, "to", spec.Dates); err != nil {
+		return query, err
+	}
+	return query, nil
+}
+
+// NextCursor returns the cursor of the page after the row with lastID.
+func (q ListQuery) NextCursor(lastID uuid.UUID) string {
+	data, _ := json.Marshal(listCursor{After: lastID, Sort: q.Sort, Desc: q.Desc})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeListCursor(encoded string) (listCursor, error) {
+	var cursor listCursor
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err == nil {
+		err = json.Unmarshal(data, &cursor)
+	}
+	if err != nil || cursor.After == uuid.Nil {
+		return cursor, fmt.Errorf("incorrent cursor: %s", encoded)
+	}
+	return cursor, nil
+}
+
+func (s ListSpec) sortNames() []string {
+	names := make([]string, len(s.Sorts))
+	for i, sort := range s.Sorts {
+		names[i] = strings.TrimPrefix(sort, "-")
+	}
+	return names
+}
+
+func (s ListSpec) sortable(name string) bool {
+	for _, sort := range s.sortNames() {
+		if sort == name {
+			return true
+		}
+	}
+	return false
+}
+
+func listFilter(values url.Values, name string, allowed []string) (string, error) {
+	value := values.Get(name)
+	if value == "" {
+		return "", nil
+	}
+	if len(allowed) == 0 {
+		return "", fmt.Errorf("can not filter by %s here", name)
+	}
+	for _, a := range allowed {
+		if value == a {
+			return value, nil
+		}
+	}
+	return "", fmt.Errorf("incorrent %s: %s; want one of: %s", name, value, strings.Join(allowed, ", "))
+}
+
+// listDate reads a date filter as RFC 3339 time or as a date.
+func listDate(values url.Values, name string, allowed bool) (*time.Time, error) {
+	value := values.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	if !allowed {
+		return nil, fmt.Errorf("can not filter by %s here", name)
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		if t, err = time.Parse("2006-01-02", value); err != nil {
+			return nil, fmt.Errorf("incorrent %s: %s; want RFC 3339 time or date", name, value)
+		}
+	}
+	return &t, nil
+}
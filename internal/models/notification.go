@@ -0,0 +1,71 @@
+package models
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+type NotificationType string
+
+const (
+	NotifyInvitation  NotificationType = "invitation"
+	NotifyPrize       NotificationType = "prize"
+	NotifyStepResult  NotificationType = "step-result"
+	NotifyLevelUp     NotificationType = "level-up"
+	NotifyEventStart  NotificationType = "event-start"
+	NotifyEventFinish NotificationType = "event-finish"
+	NotifyKudos       NotificationType = "kudos"
+	NotifyDeadline    NotificationType = "step-deadline"
+)
+
+var NotificationTypes = []NotificationType{
+	NotifyInvitation, NotifyPrize, NotifyStepResult, NotifyLevelUp, NotifyEventStart, NotifyEventFinish, NotifyKudos,
+	NotifyDeadline,
+}
+
+func NewNotificationType(s string) (NotificationType, error) {
+	for _, t := range NotificationTypes {
+		if NotificationType(s) == t {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("incorrent notification type: %s; want one of: %v", s, NotificationTypes)
+}
+
+// Notification is a message for one staff. It refers to the event, step or prize it is about.
+type Notification struct {
+	bun.BaseModel `bun:"table:notification,alias:notification"`
+
+	ID               uuid.UUID        `json:"id" bun:",pk"`
+	StaffID          uuid.UUID        `json:"staff_id"`
+	NotificationType NotificationType `json:"type"`
+	Message          string           `json:"message"`
+	EventID          uuid.UUID        `json:"event_id" bun:",nullzero"`
+	StepID           uuid.UUID        `json:"step_id" bun:",nullzero"`
+	PrizeID          uuid.UUID        `json:"prize_id" bun:",nullzero"`
+	SourceID         uuid.UUID        `json:"-" bun:",nullzero"`
+	ReadAt           *time.Time       `json:"read_at,omitempty"`
+	CreatedAt        time.Time        `json:"created_at" bun:",nullzero,default:current_timestamp"`
+}
+
+// NotificationPreference turns notifications of one type on or off for staff.
+// Types without a preference are on.
+type NotificationPreference struct {
+	bun.BaseModel `bun:"table:notification_preference,alias:notification_preference"`
+
+	StaffID          uuid.UUID        `json:"-" bun:",pk"`
+	NotificationType NotificationType `json:"type" bun:",pk"`
+	Enabled          bool             `json:"enabled"`
+}
+
+type NotificationFilter struct {
+	Unread bool
+	Limit  int
+}
+
+// MarkReadRequest marks the listed notifications as read, all of them when the list is empty.
+type MarkReadRequest struct {
+	IDs []uuid.UUID `json:"ids"`
+}
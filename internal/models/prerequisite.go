@@ -0,0 +1,84 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+// StepPrerequisite locks a step until staff has done the required step with at least min score.
+// With failed outcome the step is an alternate one, it unlocks when staff failed the required step.
+type StepPrerequisite struct {
+	bun.BaseModel `bun:"table:step_prerequisite,alias:step_prerequisite"`
+
+	ID             uuid.UUID      `json:"id" bun:",pk"`
+	StepID         uuid.UUID      `json:"step_id"`
+	RequiredStepID uuid.UUID      `json:"required_step_id"`
+	MinScore       uint           `json:"min_score"`
+	Outcome        Accomplishment `json:"outcome"`
+}
+
+type StepState string
+
+const (
+	StepLocked     StepState = "locked"
+	StepAvailable  StepState = "available"
+	StepInProgress StepState = "in-progress"
+	StepDone       StepState = "done"
+	StepSkipped    StepState = "skipped"
+)
+
+// StepProgress is a step of an event as seen by one staff.
+type StepProgress struct {
+	Step           *Step               `json:"step"`
+	State          StepState           `json:"state"`
+	Accomplishment Accomplishment      `json:"accomplishment,omitempty"`
+	Score          uint                `json:"score"`
+	Missing        []*StepPrerequisite `json:"missing,omitempty"`
+}
+
+// BranchChoice is a branch of a fork picked by staff. Steps of other branches
+// of the fork are not on the staff path.
+type BranchChoice struct {
+	bun.BaseModel `bun:"table:branch_choice,alias:branch_choice"`
+
+	ID       uuid.UUID `json:"id" bun:",pk"`
+	EventID  uuid.UUID `json:"event_id"`
+	StaffID  uuid.UUID `json:"staff_id"`
+	Fork     string    `json:"fork"`
+	Branch   string    `json:"branch"`
+	ChosenAt time.Time `json:"chosen_at" bun:",nullzero,default:current_timestamp"`
+}
+
+type BranchChoiceRequest struct {
+	Fork   string `json:"fork"`
+	Branch string `json:"branch"`
+}
+
+type Fork struct {
+	Name     string   `json:"name"`
+	Branches []string `json:"branches"`
+}
+
+// StepGraph is the steps of an event with prerequisites between them.
+type StepGraph struct {
+	Steps         []*Step             `json:"steps"`
+	Prerequisites []*StepPrerequisite `json:"prerequisites"`
+	Forks         []*Fork             `json:"forks"`
+}
+
+// StaffPath is the steps taken by staff in an event ordered by level.
+type StaffPath struct {
+	StaffID uuid.UUID       `json:"staff_id"`
+	Choices []*BranchChoice `json:"choices"`
+	Steps   []*StepProgress `json:"steps"`
+}
+
+// OnPath reports whether the step is on the path of staff with the branch choices.
+func (s *Step) OnPath(choices map[string]string) bool {
+	if s.Fork == "" {
+		return true
+	}
+	branch, ok := choices[s.Fork]
+	return !ok || branch == s.Branch
+}
@@ -14,13 +14,14 @@ const (
 	Medal      PrizeType = "medal"
 	Background PrizeType = "background"
 	Text       PrizeType = "text"
+	LootBox    PrizeType = "loot-box"
 )
 
 func NewPrizeType(p string) (PrizeType, error) {
 	if p != string(Image) && p != string(Medal) &&
-		p != string(Background) && p != string(Text) {
-		return "", fmt.Errorf("can not create type, incorrent prize type name: %s; want: %s, %s, %s, %s",
-			p, Image, Medal, Background, Text)
+		p != string(Background) && p != string(Text) && p != string(LootBox) {
+		return "", fmt.Errorf("can not create type, incorrent prize type name: %s; want: %s, %s, %s, %s, %s",
+			p, Image, Medal, Background, Text, LootBox)
 	}
 	return PrizeType(p), nil
 }
@@ -39,6 +40,18 @@ const (
 	Legendary PrizeStatus = "legendary"
 )
 
+// PrizeStatuses lists rarities from the most to the least common one,
+// the order is used to walk drop tables deterministically.
+var PrizeStatuses = []PrizeStatus{Common, Rare, Mith, Legendary}
+
+// DefaultDropWeights are used for organizations that have not configured their own drop table.
+var DefaultDropWeights = map[PrizeStatus]uint{
+	Common:    60,
+	Rare:      25,
+	Mith:      10,
+	Legendary: 5,
+}
+
 type Prize struct {
 	bun.BaseModel `bun:"table:prize,alias:prize"`
 
@@ -56,6 +69,16 @@ type Prize struct {
 	Data         string        `json:"data"`
 	Description  string        `json:"description"`
 	Prizes       []*StaffPrize `json:"prizes" bun:"m2m:staff_prizes,join:Staff=Prize"`
+	Items        []uuid.UUID   `json:"items,omitempty" bun:"-"`
+	ArchivedAt   *time.Time    `json:"archived_at,omitempty"`
+}
+
+// PrizeFilter narrows the organization prize listing, zero fields are not applied.
+type PrizeFilter struct {
+	OrganizationID uuid.UUID
+	StepID         uuid.UUID
+	InStock        *bool
+	Archived       bool
 }
 
 type PrizeRepo struct {
@@ -85,3 +108,36 @@ type StaffPrize struct {
 	PrizeID uuid.UUID `json:"prize_id"`
 	Prize   *Prize    `bun:"rel:belongs-to,join:prize_id=id"`
 }
+
+type LootBoxItem struct {
+	bun.BaseModel `bun:"table:loot_box_item,alias:loot_box_item"`
+
+	ID        uuid.UUID `json:"id" bun:",pk"`
+	LootBoxID uuid.UUID `json:"loot_box_id"`
+	PrizeID   uuid.UUID `json:"prize_id"`
+	Prize     *Prize    `json:"prize" bun:"rel:belongs-to,join:prize_id=id"`
+}
+
+type DropWeight struct {
+	bun.BaseModel `bun:"table:drop_weight,alias:drop_weight"`
+
+	ID             uuid.UUID   `json:"id" bun:",pk"`
+	OrganizationID uuid.UUID   `json:"organization_id"`
+	PrizeStatus    PrizeStatus `json:"status"`
+	Weight         uint        `json:"weight"`
+}
+
+type LootBoxDraw struct {
+	bun.BaseModel `bun:"table:loot_box_draw,alias:loot_box_draw"`
+
+	ID           uuid.UUID   `json:"id" bun:",pk"`
+	StaffPrizeID uuid.UUID   `json:"staff_prize_id"`
+	LootBoxID    uuid.UUID   `json:"loot_box_id"`
+	StaffID      uuid.UUID   `json:"staff_id"`
+	PrizeID      uuid.UUID   `json:"prize_id"`
+	Prize        *Prize      `json:"prize,omitempty" bun:"rel:belongs-to,join:prize_id=id"`
+	PrizeStatus  PrizeStatus `json:"status"`
+	Roll         uint        `json:"roll"`
+	TotalWeight  uint        `json:"total_weight"`
+	CreatedAt    time.Time   `json:"created_at" bun:",nullzero,default:current_timestamp"`
+}
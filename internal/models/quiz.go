@@ -0,0 +1,124 @@
+package models
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+type StepType string
+
+const (
+	TaskStep StepType = "task"
+	QuizStep StepType = "quiz"
+	CodeStep StepType = "code"
+)
+
+func NewStepType(s string) (StepType, error) {
+	if s == "" {
+		return TaskStep, nil
+	}
+	if s != string(TaskStep) && s != string(QuizStep) && s != string(CodeStep) {
+		return "", fmt.Errorf("incorrent step type: %s; want: %s, %s, %s", s, TaskStep, QuizStep, CodeStep)
+	}
+	return StepType(s), nil
+}
+
+type QuestionType string
+
+const (
+	SingleChoice   QuestionType = "single-choice"
+	MultipleChoice QuestionType = "multiple-choice"
+	Numeric        QuestionType = "numeric"
+	ShortAnswer    QuestionType = "short-answer"
+)
+
+func NewQuestionType(s string) (QuestionType, error) {
+	switch QuestionType(s) {
+	case SingleChoice, MultipleChoice, Numeric, ShortAnswer:
+		return QuestionType(s), nil
+	}
+	return "", fmt.Errorf("incorrent question type: %s; want: %s, %s, %s, %s",
+		s, SingleChoice, MultipleChoice, Numeric, ShortAnswer)
+}
+
+// QuizQuestion keeps the answer key, it is shown only to staff who can update the step.
+// Participants get QuizQuestionView.
+type QuizQuestion struct {
+	bun.BaseModel `bun:"table:quiz_question,alias:quiz_question"`
+
+	ID             uuid.UUID    `json:"id" bun:",pk"`
+	StepID         uuid.UUID    `json:"step_id"`
+	QuestionType   QuestionType `json:"question_type"`
+	Text           string       `json:"text"`
+	Options        []string     `json:"options" bun:",array"`
+	CorrectOptions []int        `json:"correct_options" bun:",array"`
+	NumericAnswer  float64      `json:"numeric_answer"`
+	Tolerance      float64      `json:"tolerance"`
+	TextAnswers    []string     `json:"text_answers" bun:",array"`
+	Score          uint         `json:"score"`
+	Position       uint         `json:"position"`
+}
+
+type QuizQuestionView struct {
+	ID           uuid.UUID    `json:"id"`
+	QuestionType QuestionType `json:"question_type"`
+	Text         string       `json:"text"`
+	Options      []string     `json:"options"`
+	Score        uint         `json:"score"`
+}
+
+func (q *QuizQuestion) View() *QuizQuestionView {
+	return &QuizQuestionView{
+		ID:           q.ID,
+		QuestionType: q.QuestionType,
+		Text:         q.Text,
+		Options:      q.Options,
+		Score:        q.Score,
+	}
+}
+
+type QuizAttempt struct {
+	bun.BaseModel `bun:"table:quiz_attempt,alias:quiz_attempt"`
+
+	ID            uuid.UUID     `json:"id" bun:",pk"`
+	StepID        uuid.UUID     `json:"step_id"`
+	StaffID       uuid.UUID     `json:"staff_id"`
+	QuestionOrder []uuid.UUID   `json:"-" bun:",array"`
+	StartedAt     time.Time     `json:"started_at" bun:",nullzero,default:current_timestamp"`
+	Deadline      *time.Time    `json:"deadline,omitempty"`
+	SubmittedAt   *time.Time    `json:"submitted_at,omitempty"`
+	Expired       bool          `json:"expired"`
+	Score         uint          `json:"score"`
+	Answers       []*QuizAnswer `json:"answers,omitempty" bun:"rel:has-many,join:id=attempt_id"`
+}
+
+// Active reports whether answers can still be submitted for the attempt.
+func (a *QuizAttempt) Active(now time.Time) bool {
+	return a.SubmittedAt == nil && (a.Deadline == nil || now.Before(*a.Deadline))
+}
+
+type QuizAnswer struct {
+	bun.BaseModel `bun:"table:quiz_answer,alias:quiz_answer"`
+
+	ID         uuid.UUID `json:"id" bun:",pk"`
+	AttemptID  uuid.UUID `json:"attempt_id"`
+	QuestionID uuid.UUID `json:"question_id"`
+	Options    []int     `json:"options" bun:",array"`
+	Number     *float64  `json:"number,omitempty"`
+	Text       string    `json:"text"`
+	Correct    bool      `json:"correct"`
+	Score      uint      `json:"score"`
+}
+
+type QuizAnswersRequest struct {
+	Answers []*QuizAnswer `json:"answers"`
+}
+
+// QuizSession is an attempt with its questions in the order they are shown to staff.
+type QuizSession struct {
+	Attempt      *QuizAttempt        `json:"attempt"`
+	Questions    []*QuizQuestionView `json:"questions"`
+	AttemptsLeft int                 `json:"attempts_left"`
+}
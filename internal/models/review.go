@@ -0,0 +1,50 @@
+package models
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+type ScoreStrategy string
+
+const (
+	Mean        ScoreStrategy = "mean"
+	Median      ScoreStrategy = "median"
+	TrimmedMean ScoreStrategy = "trimmed-mean"
+)
+
+func NewScoreStrategy(s string) (ScoreStrategy, error) {
+	if s == "" {
+		return Mean, nil
+	}
+	if s != string(Mean) && s != string(Median) && s != string(TrimmedMean) {
+		return "", fmt.Errorf("incorrent score strategy: %s; want: %s, %s, %s",
+			s, Mean, Median, TrimmedMean)
+	}
+	return ScoreStrategy(s), nil
+}
+
+type ReviewStatus string
+
+const (
+	ReviewAssigned ReviewStatus = "assigned"
+	ReviewDone     ReviewStatus = "done"
+)
+
+type ReviewAssignment struct {
+	bun.BaseModel `bun:"table:review_assignment,alias:review_assignment"`
+
+	ID           uuid.UUID    `json:"id" bun:",pk"`
+	SubmissionID uuid.UUID    `json:"submission_id"`
+	Submission   *Submission  `json:"submission,omitempty" bun:"rel:belongs-to,join:submission_id=id"`
+	ReviewerID   uuid.UUID    `json:"reviewer_id"`
+	Status       ReviewStatus `json:"status"`
+	Score        uint         `json:"score"`
+	Comment      string       `json:"comment"`
+	AssignedAt   time.Time    `json:"assigned_at" bun:",nullzero,default:current_timestamp"`
+	ReviewedAt   *time.Time   `json:"reviewed_at,omitempty"`
+
+	Criteria []*CriterionScore `json:"criteria,omitempty" bun:"-"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type RubricCriterion struct {
+	bun.BaseModel `bun:"table:rubric_criterion,alias:rubric_criterion"`
+
+	ID          uuid.UUID `json:"id" bun:",pk"`
+	StepID      uuid.UUID `json:"step_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	MaxScore    uint      `json:"max_score"`
+	Weight      float64   `json:"weight"`
+	Position    uint      `json:"position"`
+}
+
+// CriterionScore is a score for one rubric criterion. Reviewer scores keep
+// the review assignment, the final result of a staff step has none.
+type CriterionScore struct {
+	bun.BaseModel `bun:"table:criterion_score,alias:criterion_score"`
+
+	ID                 uuid.UUID        `json:"id" bun:",pk"`
+	StepID             uuid.UUID        `json:"step_id"`
+	StaffID            uuid.UUID        `json:"staff_id"`
+	CriterionID        uuid.UUID        `json:"criterion_id"`
+	Criterion          *RubricCriterion `json:"criterion,omitempty" bun:"rel:belongs-to,join:criterion_id=id"`
+	ReviewAssignmentID uuid.UUID        `json:"review_assignment_id" bun:",nullzero"`
+	Score              uint             `json:"score"`
+}
+
+type StepResult struct {
+	StepID         uuid.UUID         `json:"step_id"`
+	StaffID        uuid.UUID         `json:"staff_id"`
+	Accomplishment Accomplishment    `json:"accomplishment"`
+	Score          uint              `json:"score"`
+	MaxScore       uint              `json:"max_score"`
+	Criteria       []*CriterionScore `json:"criteria"`
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	SearchLimit    = 20
+	SearchMaxLimit = 100
+)
+
+// SearchStartSel and SearchStopSel wrap matched words in texts found by the database. They are
+// private use characters, so texts can be escaped before the words are marked up.
+const (
+	SearchStartSel = "\ue000"
+	SearchStopSel  = "\ue001"
+)
+
+type SearchType string
+
+const (
+	SearchEvent SearchType = "event"
+	SearchStep  SearchType = "step"
+	SearchPrize SearchType = "prize"
+	SearchStaff SearchType = "staff"
+)
+
+var SearchTypes = []SearchType{SearchEvent, SearchStep, SearchPrize, SearchStaff}
+
+// SearchResult is a found event, step, prize or staff. Title and Snippet are HTML escaped
+// with matched words wrapped in <mark>. EventID and StepID are set for steps and prizes.
+type SearchResult struct {
+	Type    SearchType `json:"type"`
+	ID      uuid.UUID  `json:"id"`
+	EventID *uuid.UUID `json:"event_id,omitempty"`
+	StepID  *uuid.UUID `json:"step_id,omitempty"`
+	Title   string     `json:"title"`
+	Snippet string     `json:"snippet"`
+	Rank    float64    `json:"rank"`
+}
+
+// SearchQuery is a full text search. Every word of the text must match the prefix of a word
+// of the result, so "secur wee" finds "Security week".
+type SearchQuery struct {
+	Text  string
+	Words []string
+	Types []SearchType
+	Limit int
+}
+
+var searchWord = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// NewSearchQuery reads q, type and limit query parameters. Type is a comma separated list,
+// all types are searched without it.
+func NewSearchQuery(values url.Values) (SearchQuery, error) {
+	query := SearchQuery{Text: values.Get("q"), Limit: SearchLimit}
+	query.Words = searchWord.FindAllString(strings.ToLower(query.Text), -1)
+	if len(query.Words) == 0 {
+		return query, fmt.Errorf("incorrent q: %q; want at least one word", query.Text)
+	}
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return query, fmt.Errorf("incorrent limit: %s", limit)
+		}
+		if n > SearchMaxLimit {
+			n = SearchMaxLimit
+		}
+		query.Limit = n
+	}
+	for _, kind := range strings.Split(values.Get("type"), ",") {
+		kind = strings.TrimSpace(kind)
+		if kind == "" {
+			continue
+		}
+		searchType, ok := newSearchType(kind)
+		if !ok {
+			return query, fmt.Errorf("incorrent type: %s; want one of: event, step, prize, staff", kind)
+		}
+		query.Types = append(query.Types, searchType)
+	}
+	if len(query.Types) == 0 {
+		query.Types = SearchTypes
+	}
+	return query, nil
+}
+
+// TSQuery returns the words as a prefix tsquery. Words have only letters and digits,
+// so they need no quoting.
+func (q SearchQuery) TSQuery() string {
+	terms := make([]string, len(q.Words))
+	for i, word := range q.Words {
+		terms[i] = word + ":*"
+	}
+	return strings.Join(terms, " & ")
+}
+
+// Searches reports whether results of the type are wanted.
+func (q SearchQuery) Searches(searchType SearchType) bool {
+	for _, t := range q.Types {
+		if t == searchType {
+			return true
+		}
+	}
+	return false
+}
+
+func newSearchType(input string) (SearchType, bool) {
+	for _, t := range SearchTypes {
+		if string(t) == input {
+			return t, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "daily"
+	Weekly  Frequency = "weekly"
+	Monthly Frequency = "monthly"
+)
+
+func NewFrequency(f string) (Frequency, error) {
+	switch Frequency(f) {
+	case Daily, Weekly, Monthly:
+		return Frequency(f), nil
+	}
+	return "", fmt.Errorf("incorrent recurrence frequency: %s; want: %s, %s, %s", f, Daily, Weekly, Monthly)
+}
+
+// EventSeries creates an event from the template on every occurrence of its recurrence rule.
+// The series ends after count occurrences or at until, it never ends when both are empty.
+// Events are created lead time seconds before their start.
+type EventSeries struct {
+	bun.BaseModel `bun:"table:event_series,alias:event_series"`
+
+	ID             uuid.UUID  `json:"id" bun:",pk"`
+	Name           string     `json:"name"`
+	TemplateID     uuid.UUID  `json:"template_id"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	TeamID         uuid.UUID  `json:"team_id" bun:",nullzero"`
+	CreatedByID    uuid.UUID  `json:"created_by_id" bun:"created_by"`
+	Frequency      Frequency  `json:"frequency"`
+	Interval       uint       `json:"interval"`
+	Count          uint       `json:"count"`
+	Until          *time.Time `json:"until,omitempty"`
+	StartDate      time.Time  `json:"start_date"`
+	LeadTime       uint       `json:"lead_time"`
+	CreatedAt      time.Time  `json:"created_at" bun:",nullzero,default:current_timestamp"`
+}
+
+// OccurrenceDate returns the start of the n-th occurrence by the recurrence rule.
+// Monthly occurrences on days missing in a month fall on the last day of the month.
+func (s *EventSeries) OccurrenceDate(n uint) time.Time {
+	step := int(n * s.Interval)
+	switch s.Frequency {
+	case Daily:
+		return s.StartDate.AddDate(0, 0, step)
+	case Weekly:
+		return s.StartDate.AddDate(0, 0, 7*step)
+	}
+	year, month, day := s.StartDate.Date()
+	first := time.Date(year, month+time.Month(step), 1, s.StartDate.Hour(), s.StartDate.Minute(),
+		s.StartDate.Second(), s.StartDate.Nanosecond(), s.StartDate.Location())
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// HasOccurrence reports whether the series has the n-th occurrence.
+func (s *EventSeries) HasOccurrence(n uint) bool {
+	if s.Count != 0 && n >= s.Count {
+		return false
+	}
+	return s.Until == nil || !s.OccurrenceDate(n).After(*s.Until)
+}
+
+type OccurrenceStatus string
+
+const (
+	OccurrencePending OccurrenceStatus = "pending"
+	OccurrenceCreated OccurrenceStatus = "created"
+	OccurrenceSkipped OccurrenceStatus = "skipped"
+)
+
+// EventOccurrence is a stored occurrence of a series. Occurrences are stored when their event
+// is created or when they are skipped or edited, the others follow the recurrence rule.
+type EventOccurrence struct {
+	bun.BaseModel `bun:"table:event_occurrence,alias:event_occurrence"`
+
+	ID        uuid.UUID        `json:"id" bun:",pk"`
+	SeriesID  uuid.UUID        `json:"series_id"`
+	Index     uint             `json:"index" bun:"occurrence_index"`
+	StartDate time.Time        `json:"start_date"`
+	Name      string           `json:"name"`
+	Status    OccurrenceStatus `json:"status"`
+	EventID   uuid.UUID        `json:"event_id" bun:",nullzero"`
+}
+
+// OccurrenceRequest skips or edits a single occurrence. Zero fields are not changed.
+type OccurrenceRequest struct {
+	Index     uint   `json:"index"`
+	Skip      *bool  `json:"skip"`
+	StartDate string `json:"start_date"`
+	Name      string `json:"name"`
+}
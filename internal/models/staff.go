@@ -4,6 +4,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/uptrace/bun"
 	"strings"
+	"time"
 )
 
 type StaffRole string
@@ -37,6 +38,15 @@ type StaffEvents struct {
 	Event     *Event         `json:"event" bun:"rel:belongs-to,join:event_id=id"`
 	Status    InviteStatus   `json:"status"`
 	StaffRole EventStaffRole `json:"staff_role" bun:"user_role"`
+
+	InvitedAt    time.Time  `json:"invited_at" bun:",nullzero,default:current_timestamp"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	WaitlistedAt *time.Time `json:"waitlisted_at,omitempty"`
+}
+
+// Expired reports whether a pending invite has expired.
+func (s *StaffEvents) Expired(now time.Time) bool {
+	return s.Status == InProgress && s.ExpiresAt != nil && now.After(*s.ExpiresAt)
 }
 
 type StaffLogin struct {
@@ -211,6 +221,9 @@ const (
 	Accepted   InviteStatus = "accepted"
 	InProgress InviteStatus = "none"
 	Declared   InviteStatus = "declared"
+	Waitlisted InviteStatus = "waitlisted"
+	Expired    InviteStatus = "expired"
+	Requested  InviteStatus = "requested"
 )
 
 type EventStaffRole string
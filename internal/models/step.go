@@ -25,9 +25,10 @@ const (
 )
 
 type StepStatusRequest struct {
-	StaffID    uuid.UUID      `json:"staff_id"`
-	StepStatus Accomplishment `json:"step_status"`
-	Score      uint           `json:"score"`
+	StaffID    uuid.UUID         `json:"staff_id"`
+	StepStatus Accomplishment    `json:"step_status"`
+	Score      uint              `json:"score"`
+	Criteria   []*CriterionScore `json:"criteria"`
 }
 
 type Step struct {
@@ -46,6 +47,34 @@ type Step struct {
 	Images       []*StepImage `json:"images" bun:"rel:has-many,join:id=step_id"`
 	ActiveStaff  []*Staff     `json:"active_staff" bun:"m2m:staff_step,join:Step=Staff"`
 	Description  string       `json:"description"`
+
+	ReviewersCount uint          `json:"reviewers_count"`
+	ScoreStrategy  ScoreStrategy `json:"score_strategy"`
+	PeerReview     bool          `json:"peer_review"`
+	AllowSameTeam  bool          `json:"allow_same_team"`
+
+	Rubric []*RubricCriterion `json:"rubric" bun:"rel:has-many,join:id=step_id"`
+
+	StepType         StepType        `json:"step_type"`
+	AttemptsLimit    uint            `json:"attempts_limit"`
+	TimeLimit        uint            `json:"time_limit"`
+	ShuffleQuestions bool            `json:"shuffle_questions"`
+	PassScore        uint            `json:"pass_score"`
+	Questions        []*QuizQuestion `json:"questions,omitempty" bun:"rel:has-many,join:id=step_id"`
+
+	CodeLanguages []string        `json:"code_languages" bun:",array"`
+	RunTimeLimit  uint            `json:"run_time_limit"`
+	MemoryLimit   uint            `json:"memory_limit"`
+	TestCases     []*CodeTestCase `json:"test_cases,omitempty" bun:"rel:has-many,join:id=step_id"`
+
+	Prerequisites []*StepPrerequisite `json:"prerequisites" bun:"rel:has-many,join:id=step_id"`
+	Fork          string              `json:"fork"`
+	Branch        string              `json:"branch"`
+}
+
+// MultiReview reports whether the step result is aggregated from several reviews.
+func (s *Step) MultiReview() bool {
+	return s.PeerReview || s.ReviewersCount > 1
 }
 
 type StepImage struct {
@@ -68,4 +97,6 @@ type StepStaff struct {
 	Accomplishment Accomplishment `json:"accomplishment"`
 	Score          uint           `json:"score"`
 	StartDate      string         `json:"start_date"`
+
+	Criteria []*CriterionScore `json:"criteria,omitempty" bun:"-"`
 }
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+type StreamMessageType string
+
+const (
+	StreamScore       StreamMessageType = "score"
+	StreamStepStatus  StreamMessageType = "step-status"
+	StreamEventStatus StreamMessageType = "event-status"
+	StreamParticipant StreamMessageType = "participant"
+	StreamPrize       StreamMessageType = "prize"
+)
+
+// StreamMessage is a real-time update of an event or an organization.
+// IDs grow, so a client that reconnects gets every message after the last one it has seen.
+type StreamMessage struct {
+	bun.BaseModel `bun:"table:stream_message,alias:stream_message"`
+
+	ID             int64             `json:"id" bun:",pk,autoincrement"`
+	MessageType    StreamMessageType `json:"type"`
+	EventID        uuid.UUID         `json:"event_id" bun:",nullzero"`
+	OrganizationID uuid.UUID         `json:"organization_id" bun:",nullzero"`
+	Data           json.RawMessage   `json:"data" bun:"type:jsonb"`
+	CreatedAt      time.Time         `json:"created_at" bun:",nullzero,default:current_timestamp"`
+}
+
+// StreamScope selects messages of one event or of every event of one organization.
+type StreamScope struct {
+	EventID        uuid.UUID
+	OrganizationID uuid.UUID
+}
+
+func (s StreamScope) Match(message *StreamMessage) bool {
+	if s.EventID != uuid.Nil {
+		return message.EventID == s.EventID
+	}
+	return message.OrganizationID == s.OrganizationID
+}
+
+type ScoreMessage struct {
+	StaffID        uuid.UUID      `json:"staff_id"`
+	StepID         uuid.UUID      `json:"step_id"`
+	Accomplishment Accomplishment `json:"accomplishment"`
+	Score          uint           `json:"score"`
+}
+
+type StepStatusMessage struct {
+	StepID uuid.UUID  `json:"step_id,omitempty"`
+	Status StepStatus `json:"status"`
+}
+
+type EventStatusMessage struct {
+	Status EventStatus `json:"status"`
+}
+
+type ParticipantMessage struct {
+	StaffID uuid.UUID    `json:"staff_id"`
+	Status  InviteStatus `json:"status"`
+}
+
+type PrizeMessage struct {
+	StaffID uuid.UUID `json:"staff_id"`
+	PrizeID uuid.UUID `json:"prize_id"`
+	Name    string    `json:"name"`
+}
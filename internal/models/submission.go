@@ -0,0 +1,67 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+type SubmissionStatus string
+
+const (
+	SubmissionPending  SubmissionStatus = "pending"
+	SubmissionApproved SubmissionStatus = "approved"
+	SubmissionRejected SubmissionStatus = "rejected"
+)
+
+type Submission struct {
+	bun.BaseModel `bun:"table:step_submission,alias:step_submission"`
+
+	ID          uuid.UUID               `json:"id" bun:",pk"`
+	StepID      uuid.UUID               `json:"step_id"`
+	Step        *Step                   `json:"step,omitempty" bun:"rel:belongs-to,join:step_id=id"`
+	StaffID     uuid.UUID               `json:"staff_id"`
+	Staff       *Staff                  `json:"staff,omitempty" bun:"rel:belongs-to,join:staff_id=id"`
+	Text        string                  `json:"text"`
+	Links       []string                `json:"links" bun:",array"`
+	Status      SubmissionStatus        `json:"status"`
+	Score       uint                    `json:"score"`
+	ReviewerID  uuid.UUID               `json:"reviewer_id" bun:",nullzero"`
+	SubmittedAt time.Time               `json:"submitted_at" bun:",nullzero,default:current_timestamp"`
+	ReviewedAt  *time.Time              `json:"reviewed_at,omitempty"`
+	Attachments []*SubmissionAttachment `json:"attachments" bun:"rel:has-many,join:id=submission_id"`
+	Comments    []*SubmissionComment    `json:"comments" bun:"rel:has-many,join:id=submission_id"`
+	Reviews     []*ReviewAssignment     `json:"reviews" bun:"rel:has-many,join:id=submission_id"`
+
+	Criteria []*CriterionScore `json:"criteria,omitempty" bun:"-"`
+}
+
+type SubmissionAttachment struct {
+	bun.BaseModel `bun:"table:submission_attachment,alias:submission_attachment"`
+
+	ID           uuid.UUID `json:"id" bun:",pk"`
+	SubmissionID uuid.UUID `json:"submission_id"`
+	FileName     string    `json:"file_name"`
+	FilePath     string    `json:"file_path"`
+}
+
+type SubmissionComment struct {
+	bun.BaseModel `bun:"table:submission_comment,alias:submission_comment"`
+
+	ID           uuid.UUID `json:"id" bun:",pk"`
+	SubmissionID uuid.UUID `json:"submission_id"`
+	AuthorID     uuid.UUID `json:"author_id" bun:",nullzero"`
+	Body         string    `json:"body"`
+	CreatedAt    time.Time `json:"created_at" bun:",nullzero,default:current_timestamp"`
+}
+
+type SubmissionRequest struct {
+	Text  string   `json:"text"`
+	Links []string `json:"links"`
+}
+
+type SubmissionReview struct {
+	Score    uint              `json:"score"`
+	Comment  string            `json:"comment"`
+	Criteria []*CriterionScore `json:"criteria"`
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+// EventTemplate is a reusable copy of an event. Steps keep their time relative
+// to the event start, so the template can be instantiated at any date.
+type EventTemplate struct {
+	bun.BaseModel `bun:"table:event_template,alias:event_template"`
+
+	ID             uuid.UUID      `json:"id" bun:",pk"`
+	Name           string         `json:"name"`
+	Description    string         `json:"description"`
+	OrganizationID uuid.UUID      `json:"organization_id"`
+	CreatedByID    uuid.UUID      `json:"created_by_id" bun:"created_by,nullzero"`
+	CreatedAt      time.Time      `json:"created_at" bun:",nullzero,default:current_timestamp"`
+	Event          *TemplateEvent `json:"event" bun:"type:jsonb"`
+}
+
+type TemplateEvent struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	ImagePath   string          `json:"image_path"`
+	EventType   string          `json:"event_type"`
+	Duration    int64           `json:"duration"`
+	Steps       []*TemplateStep `json:"steps"`
+}
+
+// TemplateStep is a step with its start offset and duration in seconds.
+type TemplateStep struct {
+	Step        *Step `json:"step"`
+	StartOffset int64 `json:"start_offset"`
+	Duration    int64 `json:"duration"`
+}
+
+type TemplateRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// InstantiateRequest sets up an event created from a template or cloned from another event.
+// Staff of the team is invited to the new event.
+type InstantiateRequest struct {
+	Name           string    `json:"name"`
+	StartDate      string    `json:"start_date"`
+	OrganizationID uuid.UUID `json:"organization_id"`
+	TeamID         uuid.UUID `json:"team_id"`
+	EventStatus    string    `json:"event_status"`
+	// EventID is the ID of the new event, a new one is made when it is not set.
+	EventID uuid.UUID `json:"-"`
+}
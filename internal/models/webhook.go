@@ -0,0 +1,104 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+type WebhookEventType string
+
+const (
+	WebhookEventCreated  WebhookEventType = "event.created"
+	WebhookEventFinished WebhookEventType = "event.finished"
+	WebhookStepCreated   WebhookEventType = "step.created"
+	WebhookStepFinished  WebhookEventType = "step.finished"
+	WebhookPrizeAwarded  WebhookEventType = "prize.awarded"
+	WebhookStaffJoined   WebhookEventType = "staff.joined"
+)
+
+var WebhookEventTypes = []WebhookEventType{
+	WebhookEventCreated, WebhookEventFinished, WebhookStepCreated, WebhookStepFinished,
+	WebhookPrizeAwarded, WebhookStaffJoined,
+}
+
+func NewWebhookEventType(s string) (WebhookEventType, error) {
+	for _, t := range WebhookEventTypes {
+		if WebhookEventType(s) == t {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("incorrent webhook event type: %s; want one of: %v", s, WebhookEventTypes)
+}
+
+// Webhook is an endpoint of an organization integration subscribed to domain events.
+// It is disabled after too many failed deliveries in a row.
+type Webhook struct {
+	bun.BaseModel `bun:"table:webhook,alias:webhook"`
+
+	ID             uuid.UUID          `json:"id" bun:",pk"`
+	OrganizationID uuid.UUID          `json:"organization_id"`
+	URL            string             `json:"url"`
+	Secret         string             `json:"-"`
+	Events         []WebhookEventType `json:"events" bun:",array"`
+	Enabled        bool               `json:"enabled"`
+	FailureCount   uint               `json:"failure_count"`
+	DisabledAt     *time.Time         `json:"disabled_at,omitempty"`
+	CreatedByID    uuid.UUID          `json:"created_by_id" bun:"created_by,nullzero"`
+	CreatedAt      time.Time          `json:"created_at" bun:",nullzero,default:current_timestamp"`
+}
+
+func (w *Webhook) Subscribed(eventType WebhookEventType) bool {
+	for _, t := range w.Events {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookRequest creates or updates a webhook. Enabling a webhook resets its failures.
+type WebhookRequest struct {
+	URL     string             `json:"url"`
+	Events  []WebhookEventType `json:"events"`
+	Enabled *bool              `json:"enabled"`
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// WebhookDelivery is one domain event to send to one webhook. Pending deliveries are the outbox,
+// the rest is the delivery log. EventID is the same for deliveries of one domain event.
+type WebhookDelivery struct {
+	bun.BaseModel `bun:"table:webhook_delivery,alias:webhook_delivery"`
+
+	ID            uuid.UUID        `json:"id" bun:",pk"`
+	WebhookID     uuid.UUID        `json:"webhook_id"`
+	EventID       uuid.UUID        `json:"event_id"`
+	EventType     WebhookEventType `json:"event_type"`
+	Payload       json.RawMessage  `json:"payload" bun:"type:jsonb"`
+	Status        DeliveryStatus   `json:"status"`
+	Attempts      uint             `json:"attempts"`
+	NextAttemptAt time.Time        `json:"next_attempt_at"`
+	ResponseCode  int              `json:"response_code"`
+	LastError     string           `json:"last_error,omitempty"`
+	Redelivery    bool             `json:"redelivery"`
+	CreatedAt     time.Time        `json:"created_at" bun:",nullzero,default:current_timestamp"`
+	DeliveredAt   *time.Time       `json:"delivered_at,omitempty"`
+}
+
+// WebhookPayload is the body of a delivery.
+type WebhookPayload struct {
+	ID             uuid.UUID        `json:"id"`
+	Type           WebhookEventType `json:"type"`
+	OrganizationID uuid.UUID        `json:"organization_id"`
+	CreatedAt      time.Time        `json:"created_at"`
+	Data           json.RawMessage  `json:"data"`
+}
@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"github.com/uptrace/bun"
+)
+
+type ChatRepo struct {
+	DB  *bun.DB
+	ctx context.Context
+}
+
+func (c *ChatRepo) CreateChatIntegration(ctx context.Context, integration *models.ChatIntegration) error {
+	_, err := c.DB.NewInsert().Model(integration).Exec(ctx)
+	return err
+}
+
+func (c *ChatRepo) GetChatIntegration(ctx context.Context, id uuid.UUID) (*models.ChatIntegration, error) {
+	integration := new(models.ChatIntegration)
+	err := c.DB.NewSelect().Model(integration).Where("id = ?", id).Scan(ctx)
+	return integration, err
+}
+
+func (c *ChatRepo) GetChatIntegrations(ctx context.Context, orgID uuid.UUID) ([]*models.ChatIntegration, error) {
+	integrations := new([]*models.ChatIntegration)
+	err := c.DB.NewSelect().Model(integrations).
+		Where("organization_id = ?", orgID).
+		Order("created_at").
+		Scan(ctx)
+	return *integrations, err
+}
+
+// GetTeamIntegration returns the integration of the chat team a slash command is sent from.
+func (c *ChatRepo) GetTeamIntegration(ctx context.Context, platform models.ChatPlatform,
+	teamID string) (*models.ChatIntegration, error) {
+	integration := new(models.ChatIntegration)
+	err := c.DB.NewSelect().Model(integration).
+		Where("platform = ?", platform).
+		Where("team_id = ?", teamID).
+		Scan(ctx)
+	return integration, err
+}
+
+func (c *ChatRepo) UpdateChatIntegration(ctx context.Context, integration *models.ChatIntegration) error {
+	_, err := c.DB.NewUpdate().Model(integration).
+		Column("team_id", "webhook_url", "server_url", "signing_secret", "bot_token", "enabled").
+		Where("id = ?", integration.ID).
+		Exec(ctx)
+	return err
+}
+
+func (c *ChatRepo) DeleteChatIntegration(ctx context.Context, id uuid.UUID) error {
+	_, err := c.DB.NewDelete().Model((*models.ChatIntegration)(nil)).Where("id = ?", id).Exec(ctx)
+	return err
+}
+
+// ClaimChatPost records that the domain event is posted to the integration. It returns false
+// if the domain event is already posted there, so a domain event handed off again is not posted twice.
+func (c *ChatRepo) ClaimChatPost(ctx context.Context, integrationID, domainEventID uuid.UUID) (bool, error) {
+	res, err := c.DB.ExecContext(ctx, `
+		INSERT INTO chat_post (integration_id, domain_event_id) VALUES (?, ?)
+		ON CONFLICT DO NOTHING`, integrationID, domainEventID)
+	if err != nil {
+		return false, err
+	}
+	rows, err := res.RowsAffected()
+	return rows > 0, err
+}
+
+// ReleaseChatPost forgets a claimed post that could not be sent, so it is posted on the next attempt.
+func (c *ChatRepo) ReleaseChatPost(ctx context.Context, integrationID, domainEventID uuid.UUID) error {
+	_, err := c.DB.ExecContext(ctx, "DELETE FROM chat_post WHERE integration_id = ? AND domain_event_id = ?",
+		integrationID, domainEventID)
+	return err
+}
+
+// GetStaffByEmail finds staff of the organization by email, ignoring case.
+func (c *ChatRepo) GetStaffByEmail(ctx context.Context, orgID uuid.UUID, email string) (*models.Staff, error) {
+	staff := new(models.Staff)
+	err := c.DB.NewSelect().Model(staff).
+		Where("company_id = ?", orgID).
+		Where("lower(email) = lower(?)", email).
+		Scan(ctx)
+	return staff, err
+}
+
+// GetRunningEvents returns running events staff participates in.
+func (c *ChatRepo) GetRunningEvents(ctx context.Context, staffID uuid.UUID) ([]*models.Event, error) {
+	events := new([]*models.Event)
+	err := c.DB.NewSelect().Model(events).
+		Join("JOIN staff_events ON staff_events.event_id = event.id").
+		Where("staff_events.user_id = ?", staffID).
+		Where("staff_events.status = ?", models.Accepted).
+		Where("event.event_status = ?", models.EventRunning).
+		Order("event.end_date").
+		Scan(ctx)
+	return *events, err
+}
+
+// GetEventLeaderboard returns staff of the event with the highest total score of its steps first.
+func (c *ChatRepo) GetEventLeaderboard(ctx context.Context, eventID uuid.UUID, limit int) ([]models.LeaderboardEntry, error) {
+	var entries []models.LeaderboardEntry
+	err := c.DB.NewSelect().Model((*models.StepStaff)(nil)).
+		ColumnExpr("staff.id AS staff_id, staff.first_name, staff.last_name").
+		ColumnExpr("sum(staff_step.score) AS score").
+		Join("JOIN step ON step.id = staff_step.step_id").
+		Join("JOIN staff ON staff.id = staff_step.staff_id").
+		Where("step.event_id = ?", eventID).
+		Group("staff.id").
+		Order("score DESC").
+		Limit(limit).
+		Scan(ctx, &entries)
+	return entries, err
+}
+
+func NewChatRepo(ctx context.Context, DB *bun.DB) *ChatRepo {
+	return &ChatRepo{DB: DB, ctx: ctx}
+}
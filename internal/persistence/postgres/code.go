@@ -0,0 +1,130 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"time"
+)
+
+func (s *StepRepo) GetCodeTestCases(ctx context.Context, stepID uuid.UUID) ([]*models.CodeTestCase, error) {
+	tests := new([]*models.CodeTestCase)
+	err := s.DB.NewSelect().Model(tests).
+		Where("step_id = ?", stepID).
+		Order("position").
+		Scan(ctx)
+	return *tests, err
+}
+
+// ReplaceCodeTestCases replaces step test cases and sets step max score when it is given.
+func (s *StepRepo) ReplaceCodeTestCases(ctx context.Context, stepID uuid.UUID, tests []*models.CodeTestCase,
+	maxScore uint) error {
+	tx, err := s.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	_, err = tx.NewDelete().Model((*models.CodeTestCase)(nil)).Where("step_id = ?", stepID).Exec(ctx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if len(tests) != 0 {
+		_, err = tx.NewInsert().Model(&tests).Exec(ctx)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if maxScore != 0 {
+		_, err = tx.NewUpdate().Model((*models.Step)(nil)).
+			Set("max_score = ?", maxScore).
+			Where("id = ?", stepID).
+			Exec(ctx)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *StepRepo) HasCodeSubmissions(ctx context.Context, stepID uuid.UUID) (bool, error) {
+	return s.DB.NewSelect().Model((*models.CodeSubmission)(nil)).
+		Where("step_id = ?", stepID).
+		Exists(ctx)
+}
+
+func (s *StepRepo) GetCodeSubmissions(ctx context.Context, stepID, staffID uuid.UUID) ([]*models.CodeSubmission, error) {
+	submissions := new([]*models.CodeSubmission)
+	err := s.DB.NewSelect().Model(submissions).
+		Relation("Results").
+		Where("code_submission.step_id = ?", stepID).
+		Where("code_submission.staff_id = ?", staffID).
+		Order("code_submission.submitted_at").
+		Scan(ctx)
+	return *submissions, err
+}
+
+func (s *StepRepo) CreateCodeSubmission(ctx context.Context, submission *models.CodeSubmission) error {
+	_, err := s.DB.NewInsert().Model(submission).ExcludeColumn("finished_at").Exec(ctx)
+	return err
+}
+
+// ExpireCodeSubmissions marks submissions pending since before as failed by the runner.
+func (s *StepRepo) ExpireCodeSubmissions(ctx context.Context, before time.Time, message string) (int64, error) {
+	res, err := s.DB.NewUpdate().Model((*models.CodeSubmission)(nil)).
+		Set("status = ?", models.CodeError).
+		Set("error = ?", message).
+		Set("finished_at = ?", time.Now()).
+		Where("status = ?", models.CodePending).
+		Where("submitted_at < ?", before).
+		Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// FinishCodeSubmission stores test results of a submission.
+// The staff step is updated only for submissions the runner could check.
+func (s *StepRepo) FinishCodeSubmission(ctx context.Context, submission *models.CodeSubmission,
+	staff models.StepStaff) error {
+	tx, err := s.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	res, err := tx.NewUpdate().Model(submission).
+		Column("status", "score", "passed", "total", "error", "finished_at").
+		Where("id = ?", submission.ID).
+		Where("status = ?", models.CodePending).
+		Exec(ctx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		tx.Rollback()
+		return conflict("can not finish code submission %s; it is already finished", submission.ID)
+	}
+	if len(submission.Results) != 0 {
+		_, err = tx.NewInsert().Model(&submission.Results).Exec(ctx)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if submission.Status == models.CodeDone {
+		_, err = tx.NewUpdate().Model((*models.StepStaff)(nil)).
+			Set("accomplishment = ?", staff.Accomplishment).
+			Set("score = ?", staff.Score).
+			Where("step_id = ?", staff.StepID).
+			Where("staff_id = ?", staff.StaffID).
+			Exec(ctx)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
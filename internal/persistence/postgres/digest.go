@@ -0,0 +1,152 @@
+package postgres
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+type DigestRepo struct {
+	DB  *bun.DB
+	ctx context.Context
+}
+
+// CreateDigestJob schedules the digest job unless a job with its id exists.
+// Ids of digest jobs are derived from the week, so every instance can schedule the same week.
+func (d *DigestRepo) CreateDigestJob(ctx context.Context, job *models.Job) error {
+	_, err := d.DB.NewInsert().Model(job).
+		ExcludeColumn("finished_at").
+		On("CONFLICT (id) DO NOTHING").
+		Exec(ctx)
+	return err
+}
+
+func (d *DigestRepo) GetDigestPreferences(ctx context.Context, staffID uuid.UUID) ([]*models.DigestPreference, error) {
+	preferences := new([]*models.DigestPreference)
+	err := d.DB.NewSelect().Model(preferences).Where("staff_id = ?", staffID).Scan(ctx)
+	return *preferences, err
+}
+
+// GetDisabledDigests returns preferences of all staff turning a digest off.
+func (d *DigestRepo) GetDisabledDigests(ctx context.Context) ([]*models.DigestPreference, error) {
+	preferences := new([]*models.DigestPreference)
+	err := d.DB.NewSelect().Model(preferences).Where("enabled = false").Scan(ctx)
+	return *preferences, err
+}
+
+func (d *DigestRepo) SaveDigestPreferences(ctx context.Context, preferences []*models.DigestPreference) error {
+	_, err := d.DB.NewInsert().Model(&preferences).
+		On("CONFLICT (staff_id, kind) DO UPDATE").
+		Set("enabled = EXCLUDED.enabled").
+		Exec(ctx)
+	return err
+}
+
+// SaveDigestSnapshots saves the total score and the rank in the organization of every staff for the week.
+// Snapshots already saved for the week are kept, so a retried digest job compares the same numbers.
+func (d *DigestRepo) SaveDigestSnapshots(ctx context.Context, week time.Time) error {
+	_, err := d.DB.ExecContext(ctx, `
+		INSERT INTO digest_snapshot (staff_id, week, score, rank)
+		SELECT staff.id, ?, coalesce(sum(staff_step.score), 0),
+			rank() OVER (PARTITION BY staff.company_id ORDER BY coalesce(sum(staff_step.score), 0) DESC)
+		FROM staff
+		LEFT JOIN staff_step ON staff_step.staff_id = staff.id
+		GROUP BY staff.id
+		ON CONFLICT DO NOTHING`, week)
+	return err
+}
+
+func (d *DigestRepo) GetDigestSnapshots(ctx context.Context, week time.Time) ([]*models.DigestSnapshot, error) {
+	snapshots := new([]*models.DigestSnapshot)
+	err := d.DB.NewSelect().Model(snapshots).Where("week = ?", week).Scan(ctx)
+	return *snapshots, err
+}
+
+// GetPreviousDigestSnapshots returns the latest snapshot of every staff taken before the week.
+func (d *DigestRepo) GetPreviousDigestSnapshots(ctx context.Context, week time.Time) ([]*models.DigestSnapshot, error) {
+	snapshots := new([]*models.DigestSnapshot)
+	err := d.DB.NewSelect().Model(snapshots).
+		DistinctOn("staff_id").
+		Where("week < ?", week).
+		Order("staff_id", "week DESC").
+		Scan(ctx)
+	return *snapshots, err
+}
+
+func (d *DigestRepo) DeleteDigestSnapshotsBefore(ctx context.Context, week time.Time) error {
+	_, err := d.DB.NewDelete().Model((*models.DigestSnapshot)(nil)).Where("week < ?", week).Exec(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = d.DB.NewDelete().Model((*models.DigestSent)(nil)).Where("week < ?", week).Exec(ctx)
+	return err
+}
+
+func (d *DigestRepo) GetSentDigests(ctx context.Context, week time.Time) ([]*models.DigestSent, error) {
+	sent := new([]*models.DigestSent)
+	err := d.DB.NewSelect().Model(sent).Where("week = ?", week).Scan(ctx)
+	return *sent, err
+}
+
+func (d *DigestRepo) MarkDigestSent(ctx context.Context, sent *models.DigestSent) error {
+	_, err := d.DB.NewInsert().Model(sent).On("CONFLICT DO NOTHING").Exec(ctx)
+	return err
+}
+
+// GetDigestStaff returns all staff with an email.
+func (d *DigestRepo) GetDigestStaff(ctx context.Context) ([]*models.Staff, error) {
+	staff := new([]*models.Staff)
+	err := d.DB.NewSelect().Model(staff).
+		Column("id", "first_name", "last_name", "email", "team_id", "position_id", "company_id").
+		Where("email <> ''").
+		Order("last_name", "first_name").
+		Scan(ctx)
+	return *staff, err
+}
+
+// GetTeamManagers returns staff whose position is allowed to update teams, with the team they are in.
+func (d *DigestRepo) GetTeamManagers(ctx context.Context) ([]*models.Staff, error) {
+	staff := new([]*models.Staff)
+	err := d.DB.NewSelect().Model(staff).
+		Column("staff.id", "staff.first_name", "staff.last_name", "staff.email", "staff.team_id", "staff.company_id").
+		Relation("Team").
+		Join("JOIN permissions ON permissions.position_id = staff.position_id").
+		Where("permissions.permission = ?", models.TeamUpdate).
+		Where("staff.email <> ''").
+		Scan(ctx)
+	return *staff, err
+}
+
+// GetNewInvitations returns invitations sent since the time and not answered yet, with their events.
+func (d *DigestRepo) GetNewInvitations(ctx context.Context, since time.Time) ([]*models.StaffEvents, error) {
+	invitations := new([]*models.StaffEvents)
+	err := d.DB.NewSelect().Model(invitations).
+		Relation("Event").
+		Where("staff_events.status = ?", models.InProgress).
+		Where("staff_events.invited_at >= ?", since).
+		Where("staff_events.expires_at IS NULL OR staff_events.expires_at > now()").
+		Order("staff_events.invited_at").
+		Scan(ctx)
+	return *invitations, err
+}
+
+// GetUpcomingDeadlines returns ends of steps between from and until for staff still doing them.
+func (d *DigestRepo) GetUpcomingDeadlines(ctx context.Context, from, until time.Time) ([]models.DigestDeadline, error) {
+	var deadlines []models.DigestDeadline
+	err := d.DB.NewSelect().Model((*models.StepStaff)(nil)).
+		ColumnExpr("staff_step.staff_id, step.id AS step_id, step.name AS step_name").
+		ColumnExpr("event.name AS event_name, step.end_date").
+		Join("JOIN step ON step.id = staff_step.step_id").
+		Join("JOIN event ON event.id = step.event_id").
+		Where("staff_step.accomplishment = ?", models.InProcess).
+		Where("step.end_date BETWEEN ? AND ?", from, until).
+		Order("step.end_date").
+		Scan(ctx, &deadlines)
+	return deadlines, err
+}
+
+func NewDigestRepo(ctx context.Context, DB *bun.DB) *DigestRepo {
+	return &DigestRepo{DB: DB, ctx: ctx}
+}
@@ -0,0 +1,30 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"github.com/uptrace/bun/driver/pgdriver"
+)
+
+// ConflictError is returned when a row can not be changed in its current state,
+// e.g. an invitation that already exists or a prize that is out of stock.
+type ConflictError struct {
+	Message string
+}
+
+func (e *ConflictError) Error() string {
+	return e.Message
+}
+
+func conflict(format string, args ...interface{}) error {
+	return &ConflictError{Message: fmt.Sprintf(format, args...)}
+}
+
+// ErrorState returns the SQLSTATE code of an error of the database, empty for other errors.
+func ErrorState(err error) string {
+	var pgErr pgdriver.Error
+	if errors.As(err, &pgErr) {
+		return pgErr.Field('C')
+	}
+	return ""
+}
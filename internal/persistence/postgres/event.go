@@ -3,10 +3,10 @@ package postgres
 import (
 	"context"
 	"database/sql"
-	"fmt"
 	"github.com/google/uuid"
 	"github.com/miprokop/fication/internal/models"
 	"github.com/uptrace/bun"
+	"time"
 )
 
 type EventRepo struct {
@@ -14,10 +14,17 @@ type EventRepo struct {
 	ctx context.Context
 }
 
-func (e *EventRepo) GetStaffsEvents(ctx context.Context, id uuid.UUID) ([]*models.Event, error) {
+// GetStaffsEvents returns events staff takes part in with any role.
+func (e *EventRepo) GetStaffsEvents(ctx context.Context, id uuid.UUID, query models.ListQuery) ([]*models.Event, string, error) {
 	events := new([]*models.Event)
-	err := e.DB.NewSelect().Model(events).Scan(ctx)
-	return *events, err
+	err := eventListTable.apply(e.DB.NewSelect().Model(events).
+		Where("event.id IN (SELECT event_id FROM staff_events WHERE user_id = ?)", id), query).
+		Scan(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	n, next := listPage(query, len(*events), func(i int) uuid.UUID { return (*events)[i].ID })
+	return (*events)[:n], next, nil
 }
 
 func (e *EventRepo) DeleteInvitation(ctx context.Context, events models.StaffEvents) error {
@@ -80,12 +87,19 @@ func (e *EventRepo) IsStaffInTeam(ctx context.Context, staffID, teamID uuid.UUID
 	return exists, err
 }
 
-func (e *EventRepo) GetInvites(ctx context.Context, staffID uuid.UUID) ([]*models.StaffEvents, error) {
+func (e *EventRepo) GetInvites(ctx context.Context, staffID uuid.UUID,
+	query models.ListQuery) ([]*models.StaffEvents, string, error) {
 	invites := new([]*models.StaffEvents)
-	err := e.DB.NewSelect().Model(invites).Relation("Event").Where("user_id = ?", staffID).
-		Where("status NOT IN (?)", bun.In([]string{string(models.Accepted), string(models.Declared)})).
-		Distinct().Scan(ctx)
-	return *invites, err
+	err := invitationListTable.apply(e.DB.NewSelect().Model(invites).Relation("Event").
+		Where("staff_events.user_id = ?", staffID).
+		Where("staff_events.status NOT IN (?)", bun.In([]string{string(models.Accepted), string(models.Declared),
+			string(models.Expired), string(models.Requested)})), query).
+		Scan(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	n, next := listPage(query, len(*invites), func(i int) uuid.UUID { return (*invites)[i].ID })
+	return (*invites)[:n], next, nil
 }
 
 func (e *EventRepo) GetStaffScore(ctx context.Context, eventID, staffID uuid.UUID) (models.StaffScore, error) {
@@ -95,9 +109,24 @@ func (e *EventRepo) GetStaffScore(ctx context.Context, eventID, staffID uuid.UUI
 	if err != nil {
 		return models.StaffScore{}, err
 	}
-	stepsIDs := make([]uuid.UUID, len(*steps))
-	for i := range *steps {
-		stepsIDs[i] = (*steps)[i].ID
+	choices := new([]*models.BranchChoice)
+	err = e.DB.NewSelect().Model(choices).
+		Where("event_id = ?", eventID).Where("staff_id = ?", staffID).Scan(ctx)
+	if err != nil {
+		return models.StaffScore{}, err
+	}
+	branches := make(map[string]string, len(*choices))
+	for _, choice := range *choices {
+		branches[choice.Fork] = choice.Branch
+	}
+	stepsIDs := make([]uuid.UUID, 0, len(*steps))
+	for _, step := range *steps {
+		if step.OnPath(branches) {
+			stepsIDs = append(stepsIDs, step.ID)
+		}
+	}
+	if len(stepsIDs) == 0 {
+		return score, nil
 	}
 	stepsStaff := new([]*models.StepStaff)
 	err = e.DB.NewSelect().Model(stepsStaff).
@@ -132,7 +161,7 @@ func (e *EventRepo) AssignStaff(ctx context.Context, events models.StaffEvents)
 			return err
 		}
 	} else {
-		return fmt.Errorf("invitation exists")
+		return conflict("invitation exists")
 	}
 	return nil
 }
@@ -141,7 +170,7 @@ func NewEventRepo(ctx context.Context, DB *bun.DB) *EventRepo {
 	return &EventRepo{DB: DB, ctx: ctx}
 }
 
-func (e *EventRepo) CreateEvent(ctx context.Context, event *models.Event) error {
+func (e *EventRepo) CreateEvent(ctx context.Context, event *models.Event, events ...*models.DomainEvent) error {
 	tx, err := e.DB.BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return err
@@ -159,6 +188,10 @@ func (e *EventRepo) CreateEvent(ctx context.Context, event *models.Event) error
 			return err
 		}
 	}
+	if err := insertDomainEvents(ctx, tx, events); err != nil {
+		tx.Rollback()
+		return err
+	}
 	return tx.Commit()
 }
 
@@ -181,29 +214,19 @@ func (e *EventRepo) GetEvent(ctx context.Context, id uuid.UUID) (*models.Event,
 	return event, err
 }
 
-func (e *EventRepo) GetEventsByTeamID(ctx context.Context, teamID uuid.UUID) ([]*models.Event, error) {
+// GetEventsByTeamID returns events staff of the team takes part in.
+func (e *EventRepo) GetEventsByTeamID(ctx context.Context, teamID uuid.UUID,
+	query models.ListQuery) ([]*models.Event, string, error) {
 	events := new([]*models.Event)
-	staff := new([]*models.Staff)
-	staffEvents := new([]*models.StaffEvents)
-	err := e.DB.NewSelect().Model(staff).Where("team_id = ?", teamID).Scan(ctx)
-	if err != nil {
-		return nil, err
-	}
-	ids := make([]uuid.UUID, len(*staff))
-	for i := range *staff {
-		ids[i] = (*staff)[i].ID
-	}
-	err = e.DB.NewSelect().Model(staffEvents).Where("user_id IN (?)", bun.In(ids)).Scan(ctx)
+	err := eventListTable.apply(e.DB.NewSelect().Model(events).
+		Where(`event.id IN (SELECT staff_events.event_id FROM staff_events
+			JOIN staff ON staff.id = staff_events.user_id WHERE staff.team_id = ?)`, teamID), query).
+		Scan(ctx)
 	if err != nil {
-		return nil, err
-	}
-	ids = nil
-	ids = make([]uuid.UUID, len(*staffEvents))
-	for i := range *staffEvents {
-		ids[i] = (*staffEvents)[i].EventID
+		return nil, "", err
 	}
-	err = e.DB.NewSelect().Model(events).Where("id IN (?)", bun.In(ids)).Scan(ctx)
-	return *events, err
+	n, next := listPage(query, len(*events), func(i int) uuid.UUID { return (*events)[i].ID })
+	return (*events)[:n], next, nil
 }
 
 func (e *EventRepo) GetEventsByCommandID(ctx context.Context, commandID uuid.UUID) ([]*models.Event, error) {
@@ -245,26 +268,215 @@ func (e *EventRepo) UpdateEvent(ctx context.Context, event *models.Event) error
 			return err
 		}
 	}
-	return nil
+	return tx.Commit()
+}
+
+// UpdateMaxParticipants sets the participants cap, unlike UpdateEvent it can store 0.
+func (e *EventRepo) UpdateMaxParticipants(ctx context.Context, id uuid.UUID, maxParticipants uint) error {
+	_, err := e.DB.NewUpdate().Model((*models.Event)(nil)).
+		Set("max_participants = ?", maxParticipants).
+		Where("id = ?", id).
+		Exec(ctx)
+	return err
+}
+
+// UpdateEventStatus moves event from one status to another.
+// It fails when the event status was changed concurrently.
+func (e *EventRepo) UpdateEventStatus(ctx context.Context, id uuid.UUID, from, to models.EventStatus,
+	events ...*models.DomainEvent) error {
+	tx, err := e.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	res, err := tx.NewUpdate().Model((*models.Event)(nil)).
+		Set("event_status = ?", to).
+		Where("id = ?", id).
+		Where("event_status = ?", from).
+		Exec(ctx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		tx.Rollback()
+		return conflict("can not change event %s status; it is not %s", id, from)
+	}
+	if err := insertDomainEvents(ctx, tx, events); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
 }
 
-func (e *EventRepo) GetStaffsEventsByRole(ctx context.Context, id uuid.UUID, role string) ([]*models.Event, error) {
-	var staffEvents = new([]*models.StaffEvents)
+// CloseEventSteps sets status of event steps that are still open.
+func (e *EventRepo) CloseEventSteps(ctx context.Context, eventID uuid.UUID, status models.StepStatus) error {
+	_, err := e.DB.NewUpdate().Model((*models.Step)(nil)).
+		Set("step_status = ?", status).
+		Where("event_id = ?", eventID).
+		Where("step_status NOT IN (?)", bun.In([]models.StepStatus{models.Finished, models.Canceled})).
+		Exec(ctx)
+	return err
+}
 
-	err := e.DB.NewSelect().Model(staffEvents).
-		Where("user_id = ?", id).
-		Where("user_role = ?", role).
+func (e *EventRepo) GetStaffsEventsByRole(ctx context.Context, id uuid.UUID, role string,
+	query models.ListQuery) ([]*models.Event, string, error) {
+	events := new([]*models.Event)
+	err := eventListTable.apply(e.DB.NewSelect().Model(events).
+		Where("event.id IN (SELECT event_id FROM staff_events WHERE user_id = ? AND user_role = ?)", id, role), query).
 		Scan(ctx)
 	if err != nil {
+		return nil, "", err
+	}
+	n, next := listPage(query, len(*events), func(i int) uuid.UUID { return (*events)[i].ID })
+	return (*events)[:n], next, nil
+}
+
+func (e *EventRepo) GetInvitation(ctx context.Context, id, staffID uuid.UUID) (*models.StaffEvents, error) {
+	invite := new(models.StaffEvents)
+	err := e.DB.NewSelect().Model(invite).
+		Where("id = ?", id).
+		Where("user_id = ?", staffID).
+		Scan(ctx)
+	return invite, err
+}
+
+// ExpireInvitations marks pending invites after their expiry time as expired.
+func (e *EventRepo) ExpireInvitations(ctx context.Context) error {
+	_, err := e.DB.NewUpdate().Model((*models.StaffEvents)(nil)).
+		Set("status = ?", models.Expired).
+		Where("status = ?", models.InProgress).
+		Where("expires_at < ?", time.Now()).
+		Exec(ctx)
+	return err
+}
+
+// lockParticipants locks the event row, so participants of the event are counted
+// and changed by one transaction at a time, and returns the number of free places.
+// Free places are -1 for events without participant limit.
+func lockParticipants(ctx context.Context, tx bun.Tx, eventID uuid.UUID) (int, error) {
+	var limit int
+	err := tx.NewSelect().Model((*models.Event)(nil)).
+		Column("max_participants").
+		Where("id = ?", eventID).
+		For("UPDATE").
+		Scan(ctx, &limit)
+	if err != nil || limit == 0 {
+		return -1, err
+	}
+	accepted, err := tx.NewSelect().Model((*models.StaffEvents)(nil)).
+		Where("event_id = ?", eventID).
+		Where("status = ?", models.Accepted).
+		Where("user_role = ?", models.Default).
+		Count(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if accepted >= limit {
+		return 0, nil
+	}
+	return limit - accepted, nil
+}
+
+// AcceptInvitation accepts the invite when the event has a free place,
+// otherwise puts staff on the waitlist. It returns the new invite status.
+func (e *EventRepo) AcceptInvitation(ctx context.Context, invite *models.StaffEvents) (models.InviteStatus, error) {
+	tx, err := e.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return "", err
+	}
+	status, err := acceptInvitation(ctx, tx, invite)
+	if err != nil {
+		tx.Rollback()
+		return "", err
+	}
+	return status, tx.Commit()
+}
+
+func acceptInvitation(ctx context.Context, tx bun.Tx, invite *models.StaffEvents) (models.InviteStatus, error) {
+	free, err := lockParticipants(ctx, tx, invite.EventID)
+	if err != nil {
+		return "", err
+	}
+	q := tx.NewUpdate().Model((*models.StaffEvents)(nil)).
+		Where("id = ?", invite.ID).
+		Where("user_id = ?", invite.StaffID)
+	status := models.Accepted
+	if free == 0 && invite.StaffRole == models.Default {
+		status = models.Waitlisted
+		q = q.Set("waitlisted_at = ?", time.Now())
+	}
+	if _, err = q.Set("status = ?", status).Exec(ctx); err != nil {
+		return "", err
+	}
+	joined, err := newStaffJoined(invite.EventID, invite.StaffID, status)
+	if err != nil {
+		return "", err
+	}
+	return status, insertDomainEvents(ctx, tx, []*models.DomainEvent{joined})
+}
+
+func newStaffJoined(eventID, staffID uuid.UUID, status models.InviteStatus) (*models.DomainEvent, error) {
+	return models.NewDomainEvent(models.DomainStaffJoined, uuid.Nil, eventID, models.StaffJoinedData{
+		EventID: eventID,
+		StaffID: staffID,
+		Status:  status,
+	})
+}
+
+// PromoteWaitlist accepts waitlisted staff in order of joining the waitlist while the event
+// has free places. It returns the promoted invites.
+func (e *EventRepo) PromoteWaitlist(ctx context.Context, eventID uuid.UUID) ([]*models.StaffEvents, error) {
+	tx, err := e.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	free, err := lockParticipants(ctx, tx, eventID)
+	if err != nil {
+		tx.Rollback()
 		return nil, err
 	}
-	ids := make([]uuid.UUID, len(*staffEvents))
-	for i := range *staffEvents {
-		ids[i] = (*staffEvents)[i].EventID
+	if free == 0 {
+		return nil, tx.Rollback()
 	}
-	events := new([]*models.Event)
-	err = e.DB.NewSelect().Model(events).
+	waitlist := new([]*models.StaffEvents)
+	q := tx.NewSelect().Model(waitlist).
+		Where("event_id = ?", eventID).
+		Where("status = ?", models.Waitlisted).
+		Order("waitlisted_at")
+	if free > 0 {
+		q = q.Limit(free)
+	}
+	if err := q.Scan(ctx); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if len(*waitlist) == 0 {
+		return nil, tx.Rollback()
+	}
+	ids := make([]uuid.UUID, len(*waitlist))
+	joined := make([]*models.DomainEvent, len(*waitlist))
+	for i, invite := range *waitlist {
+		ids[i] = invite.ID
+		invite.Status = models.Accepted
+		invite.WaitlistedAt = nil
+		joined[i], err = newStaffJoined(eventID, invite.StaffID, models.Accepted)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	_, err = tx.NewUpdate().Model((*models.StaffEvents)(nil)).
+		Set("status = ?", models.Accepted).
+		Set("waitlisted_at = NULL").
 		Where("id IN (?)", bun.In(ids)).
-		Scan(ctx)
-	return *events, err
+		Exec(ctx)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := insertDomainEvents(ctx, tx, joined); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return *waitlist, tx.Commit()
 }
@@ -0,0 +1,151 @@
+package postgres
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+type JobRepo struct {
+	DB  *bun.DB
+	ctx context.Context
+}
+
+func (j *JobRepo) CreateJob(ctx context.Context, job *models.Job) error {
+	_, err := j.DB.NewInsert().Model(job).ExcludeColumn("finished_at").Exec(ctx)
+	return err
+}
+
+func (j *JobRepo) GetJob(ctx context.Context, id uuid.UUID) (*models.Job, error) {
+	job := new(models.Job)
+	err := j.DB.NewSelect().Model(job).Where("id = ?", id).Scan(ctx)
+	return job, err
+}
+
+func (j *JobRepo) GetStepJobs(ctx context.Context, stepID uuid.UUID) ([]*models.Job, error) {
+	jobs := new([]*models.Job)
+	err := j.DB.NewSelect().Model(jobs).
+		Where("step_id = ?", stepID).
+		Order("run_at").
+		Scan(ctx)
+	return *jobs, err
+}
+
+// CancelStepJobs cancels pending jobs of a step, of all types when jobTypes is empty.
+func (j *JobRepo) CancelStepJobs(ctx context.Context, stepID uuid.UUID, jobTypes ...models.JobType) error {
+	q := j.DB.NewUpdate().Model((*models.Job)(nil)).
+		Set("status = ?", models.JobCanceled).
+		Set("finished_at = ?", time.Now()).
+		Where("step_id = ?", stepID).
+		Where("status = ?", models.JobPending)
+	if len(jobTypes) != 0 {
+		q = q.Where("job_type IN (?)", bun.In(jobTypes))
+	}
+	_, err := q.Exec(ctx)
+	return err
+}
+
+// CancelEventJobs cancels pending jobs of an event and of its steps, of all types when jobTypes is empty.
+func (j *JobRepo) CancelEventJobs(ctx context.Context, eventID uuid.UUID, jobTypes ...models.JobType) error {
+	q := j.DB.NewUpdate().Model((*models.Job)(nil)).
+		Set("status = ?", models.JobCanceled).
+		Set("finished_at = ?", time.Now()).
+		Where("event_id = ?", eventID).
+		Where("status = ?", models.JobPending)
+	if len(jobTypes) != 0 {
+		q = q.Where("job_type IN (?)", bun.In(jobTypes))
+	}
+	_, err := q.Exec(ctx)
+	return err
+}
+
+// CancelSeriesJobs cancels pending jobs of an event series.
+func (j *JobRepo) CancelSeriesJobs(ctx context.Context, seriesID uuid.UUID) error {
+	_, err := j.DB.NewUpdate().Model((*models.Job)(nil)).
+		Set("status = ?", models.JobCanceled).
+		Set("finished_at = ?", time.Now()).
+		Where("series_id = ?", seriesID).
+		Where("status = ?", models.JobPending).
+		Exec(ctx)
+	return err
+}
+
+// ClaimDueJobs marks due pending jobs as running and returns them.
+// Rows locked by another transaction are skipped, so a job is never claimed twice.
+func (j *JobRepo) ClaimDueJobs(ctx context.Context, limit int) ([]*models.Job, error) {
+	jobs := new([]*models.Job)
+	due := j.DB.NewSelect().Model((*models.Job)(nil)).
+		Column("id").
+		Where("status = ?", models.JobPending).
+		Where("run_at <= ?", time.Now()).
+		Order("run_at").
+		Limit(limit).
+		For("UPDATE SKIP LOCKED")
+	_, err := j.DB.NewUpdate().Model((*models.Job)(nil)).
+		Set("status = ?", models.JobRunning).
+		Set("attempts = attempts + 1").
+		Where("id IN (?)", due).
+		Returning("*").
+		Exec(ctx, jobs)
+	return *jobs, err
+}
+
+func (j *JobRepo) FinishJob(ctx context.Context, job *models.Job) error {
+	_, err := j.DB.NewUpdate().Model(job).
+		Column("status", "run_at", "last_error", "finished_at").
+		Where("id = ?", job.ID).
+		Exec(ctx)
+	return err
+}
+
+// ResetRunningJobs returns jobs left running by a stopped scheduler to the queue.
+func (j *JobRepo) ResetRunningJobs(ctx context.Context) error {
+	_, err := j.DB.NewUpdate().Model((*models.Job)(nil)).
+		Set("status = ?", models.JobPending).
+		Where("status = ?", models.JobRunning).
+		Exec(ctx)
+	return err
+}
+
+// AdvisoryLock is a session level Postgres advisory lock held on its own connection.
+type AdvisoryLock struct {
+	conn bun.Conn
+	key  int64
+}
+
+// TryAdvisoryLock takes the lock without waiting. It returns nil when another session holds it.
+func (j *JobRepo) TryAdvisoryLock(ctx context.Context, key int64) (*AdvisoryLock, error) {
+	conn, err := j.DB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock(?)", key).Scan(&locked); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !locked {
+		conn.Close()
+		return nil, nil
+	}
+	return &AdvisoryLock{conn: conn, key: key}, nil
+}
+
+// Held reports whether the connection holding the lock is still alive.
+func (l *AdvisoryLock) Held(ctx context.Context) bool {
+	return l.conn.PingContext(ctx) == nil
+}
+
+func (l *AdvisoryLock) Release() error {
+	_, err := l.conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock(?)", l.key)
+	if closeErr := l.conn.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func NewJobRepo(ctx context.Context, DB *bun.DB) *JobRepo {
+	return &JobRepo{DB: DB, ctx: ctx}
+}
@@ -0,0 +1,135 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+type JoinRepo struct {
+	DB  *bun.DB
+	ctx context.Context
+}
+
+func (j *JoinRepo) CreateJoinCode(ctx context.Context, code *models.JoinCode) error {
+	_, err := j.DB.NewInsert().Model(code).Exec(ctx)
+	return err
+}
+
+func (j *JoinRepo) GetJoinCode(ctx context.Context, code string) (*models.JoinCode, error) {
+	joinCode := new(models.JoinCode)
+	err := j.DB.NewSelect().Model(joinCode).Where("code = ?", code).Scan(ctx)
+	return joinCode, err
+}
+
+func (j *JoinRepo) GetJoinCodeByID(ctx context.Context, id uuid.UUID) (*models.JoinCode, error) {
+	joinCode := new(models.JoinCode)
+	err := j.DB.NewSelect().Model(joinCode).Where("id = ?", id).Scan(ctx)
+	return joinCode, err
+}
+
+func (j *JoinRepo) GetJoinCodes(ctx context.Context, eventID uuid.UUID) ([]*models.JoinCode, error) {
+	codes := new([]*models.JoinCode)
+	err := j.DB.NewSelect().Model(codes).
+		Where("event_id = ?", eventID).
+		Order("created_at DESC").
+		Scan(ctx)
+	return *codes, err
+}
+
+func (j *JoinRepo) DeleteJoinCode(ctx context.Context, id uuid.UUID) error {
+	_, err := j.DB.NewDelete().Model((*models.JoinCode)(nil)).Where("id = ?", id).Exec(ctx)
+	return err
+}
+
+// JoinEvent adds staff to the event and accepts it at once, or puts it on the waitlist
+// when the event is full. A join code is used up in the same transaction, so it is not
+// used more times than allowed. It returns the status of the new invite.
+func (j *JoinRepo) JoinEvent(ctx context.Context, invite *models.StaffEvents, codeID uuid.UUID) (models.InviteStatus, error) {
+	tx, err := j.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return "", err
+	}
+	if codeID != uuid.Nil {
+		res, err := tx.NewUpdate().Model((*models.JoinCode)(nil)).
+			Set("uses = uses + 1").
+			Where("id = ?", codeID).
+			Where("max_uses = 0 OR uses < max_uses").
+			Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+			Exec(ctx)
+		if err != nil {
+			tx.Rollback()
+			return "", err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			tx.Rollback()
+			return "", conflict("join code %s is no longer valid", codeID)
+		}
+	}
+	if err := insertInvite(ctx, tx, invite); err != nil {
+		tx.Rollback()
+		return "", err
+	}
+	status, err := acceptInvitation(ctx, tx, invite)
+	if err != nil {
+		tx.Rollback()
+		return "", err
+	}
+	return status, tx.Commit()
+}
+
+// CreateJoinRequest saves a request of staff to join the event.
+func (j *JoinRepo) CreateJoinRequest(ctx context.Context, invite *models.StaffEvents) error {
+	tx, err := j.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	if err := insertInvite(ctx, tx, invite); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (j *JoinRepo) GetJoinRequest(ctx context.Context, id uuid.UUID) (*models.StaffEvents, error) {
+	request := new(models.StaffEvents)
+	err := j.DB.NewSelect().Model(request).
+		Where("id = ?", id).
+		Where("status = ?", models.Requested).
+		Scan(ctx)
+	return request, err
+}
+
+func (j *JoinRepo) GetJoinRequests(ctx context.Context, eventID uuid.UUID) ([]*models.StaffEvents, error) {
+	requests := new([]*models.StaffEvents)
+	err := j.DB.NewSelect().Model(requests).
+		Relation("Staff").
+		Where("event_id = ?", eventID).
+		Where("staff_events.status = ?", models.Requested).
+		Order("invited_at").
+		Scan(ctx)
+	return *requests, err
+}
+
+// insertInvite inserts the invite unless staff is already invited to the event.
+func insertInvite(ctx context.Context, tx bun.Tx, invite *models.StaffEvents) error {
+	exists, err := tx.NewSelect().Model((*models.StaffEvents)(nil)).
+		Where("user_id = ?", invite.StaffID).
+		Where("event_id = ?", invite.EventID).
+		Exists(ctx)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return conflict("invitation exists")
+	}
+	_, err = tx.NewInsert().Model(invite).Exec(ctx)
+	return err
+}
+
+func NewJoinRepo(ctx context.Context, DB *bun.DB) *JoinRepo {
+	return &JoinRepo{DB: DB, ctx: ctx}
+}
@@ -0,0 +1,115 @@
+package postgres
+
+import (
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"github.com/uptrace/bun"
+)
+
+// listTable maps sorts and filters of a models.ListSpec to columns of the listed table.
+// Sort expressions must not be null, rows are compared with the row of the cursor.
+type listTable struct {
+	table  string
+	id     string
+	sorts  map[string]string
+	status string
+	kind   string
+	team   string
+	date   string
+}
+
+var (
+	organizationListTable = listTable{
+		table: "organizations AS organizations",
+		id:    "organizations.id",
+		sorts: map[string]string{"name": "coalesce(organizations.name, '')"},
+	}
+	staffListTable = listTable{
+		table: "staff AS staff",
+		id:    "staff.id",
+		sorts: map[string]string{"last_name": "staff.last_name", "first_name": "staff.first_name"},
+		team:  "staff.team_id",
+	}
+	eventListTable = listTable{
+		table: "event AS event",
+		id:    "event.id",
+		sorts: map[string]string{
+			"end_date":      "event.end_date",
+			"name":          "coalesce(event.name, '')",
+			"creation_date": "event.creation_date",
+		},
+		status: "event.event_status",
+		kind:   "event.event_type",
+		date:   "event.end_date",
+	}
+	stepListTable = listTable{
+		table: "step AS step",
+		id:    "step.id",
+		sorts: map[string]string{
+			"level":    "coalesce(step.level, 0)",
+			"name":     "step.name",
+			"end_date": "coalesce(step.end_date, 'infinity')",
+		},
+		status: "step.step_status",
+		kind:   "step.step_type",
+		date:   "step.end_date",
+	}
+	prizeListTable = listTable{
+		table:  "prize AS prize",
+		id:     "prize.id",
+		sorts:  map[string]string{"creation_date": "prize.creation_date", "name": "prize.name"},
+		status: "prize.prize_status",
+		kind:   "prize.prize_type",
+		date:   "prize.creation_date",
+	}
+	invitationListTable = listTable{
+		table: "staff_events AS staff_events",
+		id:    "staff_events.id",
+		sorts: map[string]string{
+			"invited_at": "staff_events.invited_at",
+			"expires_at": "coalesce(staff_events.expires_at, 'infinity')",
+		},
+	}
+)
+
+// apply filters, sorts and limits the query to the page. One row more than the limit is selected
+// to know whether there is a next page, see listPage.
+func (t listTable) apply(q *bun.SelectQuery, query models.ListQuery) *bun.SelectQuery {
+	if query.Status != "" {
+		q = q.Where("? = ?", bun.Safe(t.status), query.Status)
+	}
+	if query.Type != "" {
+		q = q.Where("? = ?", bun.Safe(t.kind), query.Type)
+	}
+	if query.TeamID != uuid.Nil {
+		q = q.Where("? = ?", bun.Safe(t.team), query.TeamID)
+	}
+	if query.From != nil {
+		q = q.Where("? >= ?", bun.Safe(t.date), *query.From)
+	}
+	if query.To != nil {
+		q = q.Where("? < ?", bun.Safe(t.date), *query.To)
+	}
+
+	sort := bun.Safe(t.sorts[query.Sort])
+	direction, compare := bun.Safe("ASC"), bun.Safe(">")
+	if query.Desc {
+		direction, compare = bun.Safe("DESC"), bun.Safe("<")
+	}
+	if query.After != uuid.Nil {
+		q = q.Where("(?, ?) ? ((SELECT ? FROM ? WHERE ? = ?), ?)",
+			sort, bun.Safe(t.id), compare, sort, bun.Safe(t.table), bun.Safe(t.id), query.After, query.After)
+	}
+	return q.OrderExpr("? ?", sort, direction).
+		OrderExpr("? ?", bun.Safe(t.id), direction).
+		Limit(query.Limit + 1)
+}
+
+// listPage returns how many of n selected rows belong to the page and the cursor of the next page,
+// empty on the last page. id returns the id of the i-th selected row.
+func listPage(query models.ListQuery, n int, id func(i int) uuid.UUID) (int, string) {
+	if n <= query.Limit {
+		return n, ""
+	}
+	return query.Limit, query.NextCursor(id(query.Limit - 1))
+}
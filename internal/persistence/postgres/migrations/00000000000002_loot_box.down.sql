@@ -0,0 +1,9 @@
+BEGIN;
+
+DROP TABLE IF EXISTS loot_box_draw;
+DROP TABLE IF EXISTS drop_weight;
+DROP TABLE IF EXISTS loot_box_item;
+
+DELETE FROM prize WHERE prize_type = 'loot-box';
+
+END;
@@ -0,0 +1,46 @@
+ALTER TYPE prize_type ADD VALUE IF NOT EXISTS 'loot-box';
+
+BEGIN;
+
+CREATE TABLE loot_box_item (
+    id uuid NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    loot_box_id uuid NOT NULL,
+    prize_id uuid NOT NULL,
+    UNIQUE(loot_box_id, prize_id),
+    CONSTRAINT fk_loot_box FOREIGN KEY(loot_box_id) REFERENCES prize(id)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_prize FOREIGN KEY(prize_id) REFERENCES prize(id)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE drop_weight (
+    id uuid NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    organization_id uuid NOT NULL,
+    prize_status prize_status NOT NULL,
+    weight INTEGER NOT NULL DEFAULT 0 CHECK (weight >= 0),
+    UNIQUE(organization_id, prize_status),
+    CONSTRAINT fk_organization FOREIGN KEY(organization_id) REFERENCES organizations(id)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE loot_box_draw (
+    id uuid NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    staff_prize_id uuid NOT NULL UNIQUE,
+    loot_box_id uuid NOT NULL,
+    staff_id uuid NOT NULL,
+    prize_id uuid NOT NULL,
+    prize_status prize_status NOT NULL,
+    roll INTEGER NOT NULL,
+    total_weight INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT fk_staff_prize FOREIGN KEY(staff_prize_id) REFERENCES staff_prizes(id)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_loot_box FOREIGN KEY(loot_box_id) REFERENCES prize(id)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_staff FOREIGN KEY(staff_id) REFERENCES staff(id)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_prize FOREIGN KEY(prize_id) REFERENCES prize(id)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+END;
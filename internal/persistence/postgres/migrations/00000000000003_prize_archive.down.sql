@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP INDEX IF EXISTS prize_archived_at_idx;
+
+ALTER TABLE prize DROP COLUMN IF EXISTS archived_at;
+
+END;
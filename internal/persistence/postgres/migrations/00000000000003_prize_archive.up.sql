@@ -0,0 +1,7 @@
+BEGIN;
+
+ALTER TABLE prize ADD COLUMN archived_at TIMESTAMP;
+
+CREATE INDEX prize_archived_at_idx ON prize (archived_at);
+
+END;
@@ -0,0 +1,8 @@
+BEGIN;
+
+DROP TABLE IF EXISTS submission_comment;
+DROP TABLE IF EXISTS submission_attachment;
+DROP TABLE IF EXISTS step_submission;
+DROP TYPE IF EXISTS submission_status;
+
+END;
@@ -0,0 +1,47 @@
+BEGIN;
+
+CREATE TYPE submission_status AS ENUM ('pending', 'approved', 'rejected');
+
+CREATE TABLE step_submission (
+    id uuid NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    step_id uuid NOT NULL,
+    staff_id uuid NOT NULL,
+    text VARCHAR NOT NULL DEFAULT '',
+    links VARCHAR[] NOT NULL DEFAULT '{}',
+    status submission_status DEFAULT 'pending' NOT NULL,
+    score INTEGER NOT NULL DEFAULT 0,
+    reviewer_id uuid,
+    submitted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    reviewed_at TIMESTAMP,
+    CONSTRAINT fk_step FOREIGN KEY(step_id) REFERENCES step(id)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_staff FOREIGN KEY(staff_id) REFERENCES staff(id)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_reviewer FOREIGN KEY(reviewer_id) REFERENCES staff(id)
+        ON DELETE SET NULL ON UPDATE CASCADE
+);
+
+CREATE INDEX step_submission_step_status_idx ON step_submission (step_id, status);
+
+CREATE TABLE submission_attachment (
+    id uuid NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    submission_id uuid NOT NULL,
+    file_name VARCHAR NOT NULL,
+    file_path VARCHAR NOT NULL,
+    CONSTRAINT fk_submission FOREIGN KEY(submission_id) REFERENCES step_submission(id)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE submission_comment (
+    id uuid NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    submission_id uuid NOT NULL,
+    author_id uuid,
+    body VARCHAR NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    CONSTRAINT fk_submission FOREIGN KEY(submission_id) REFERENCES step_submission(id)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_author FOREIGN KEY(author_id) REFERENCES staff(id)
+        ON DELETE SET NULL ON UPDATE CASCADE
+);
+
+END;
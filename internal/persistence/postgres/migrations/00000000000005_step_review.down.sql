@@ -0,0 +1,12 @@
+BEGIN;
+
+DROP TABLE IF EXISTS review_assignment;
+DROP TYPE IF EXISTS review_status;
+
+ALTER TABLE step
+    DROP COLUMN IF EXISTS reviewers_count,
+    DROP COLUMN IF EXISTS score_strategy,
+    DROP COLUMN IF EXISTS peer_review,
+    DROP COLUMN IF EXISTS allow_same_team;
+
+END;
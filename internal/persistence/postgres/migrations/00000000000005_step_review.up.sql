@@ -0,0 +1,29 @@
+BEGIN;
+
+ALTER TABLE step
+    ADD COLUMN reviewers_count INTEGER NOT NULL DEFAULT 1,
+    ADD COLUMN score_strategy VARCHAR NOT NULL DEFAULT 'mean',
+    ADD COLUMN peer_review BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN allow_same_team BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TYPE review_status AS ENUM ('assigned', 'done');
+
+CREATE TABLE review_assignment (
+    id uuid NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    submission_id uuid NOT NULL,
+    reviewer_id uuid NOT NULL,
+    status review_status DEFAULT 'assigned' NOT NULL,
+    score INTEGER NOT NULL DEFAULT 0,
+    comment VARCHAR NOT NULL DEFAULT '',
+    assigned_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    reviewed_at TIMESTAMP,
+    UNIQUE(submission_id, reviewer_id),
+    CONSTRAINT fk_submission FOREIGN KEY(submission_id) REFERENCES step_submission(id)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_reviewer FOREIGN KEY(reviewer_id) REFERENCES staff(id)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX review_assignment_reviewer_idx ON review_assignment (reviewer_id, status);
+
+END;
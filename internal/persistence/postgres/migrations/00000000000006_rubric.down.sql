@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS criterion_score;
+DROP TABLE IF EXISTS rubric_criterion;
+
+END;
@@ -0,0 +1,34 @@
+BEGIN;
+
+CREATE TABLE rubric_criterion (
+    id uuid NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    step_id uuid NOT NULL,
+    name VARCHAR NOT NULL,
+    description VARCHAR NOT NULL DEFAULT '',
+    max_score INTEGER NOT NULL CHECK (max_score > 0),
+    weight REAL NOT NULL DEFAULT 1 CHECK (weight > 0),
+    position INTEGER NOT NULL DEFAULT 0,
+    CONSTRAINT fk_step FOREIGN KEY(step_id) REFERENCES step(id)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE criterion_score (
+    id uuid NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    step_id uuid NOT NULL,
+    staff_id uuid NOT NULL,
+    criterion_id uuid NOT NULL,
+    review_assignment_id uuid,
+    score INTEGER NOT NULL DEFAULT 0,
+    CONSTRAINT fk_step FOREIGN KEY(step_id) REFERENCES step(id)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_staff FOREIGN KEY(staff_id) REFERENCES staff(id)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_criterion FOREIGN KEY(criterion_id) REFERENCES rubric_criterion(id)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_review_assignment FOREIGN KEY(review_assignment_id) REFERENCES review_assignment(id)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX criterion_score_step_staff_idx ON criterion_score (step_id, staff_id);
+
+END;
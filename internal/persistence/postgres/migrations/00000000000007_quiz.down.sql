@@ -0,0 +1,17 @@
+BEGIN;
+
+DROP TABLE IF EXISTS quiz_answer;
+DROP TABLE IF EXISTS quiz_attempt;
+DROP TABLE IF EXISTS quiz_question;
+
+ALTER TABLE step
+    DROP COLUMN IF EXISTS pass_score,
+    DROP COLUMN IF EXISTS shuffle_questions,
+    DROP COLUMN IF EXISTS time_limit,
+    DROP COLUMN IF EXISTS attempts_limit,
+    DROP COLUMN IF EXISTS step_type;
+
+DROP TYPE IF EXISTS question_type;
+DROP TYPE IF EXISTS step_type;
+
+END;
@@ -0,0 +1,63 @@
+BEGIN;
+
+CREATE TYPE step_type AS ENUM ('task', 'quiz');
+CREATE TYPE question_type AS ENUM ('single-choice', 'multiple-choice', 'numeric', 'short-answer');
+
+ALTER TABLE step
+    ADD COLUMN step_type step_type NOT NULL DEFAULT 'task',
+    ADD COLUMN attempts_limit INTEGER NOT NULL DEFAULT 0 CHECK (attempts_limit >= 0),
+    ADD COLUMN time_limit INTEGER NOT NULL DEFAULT 0 CHECK (time_limit >= 0),
+    ADD COLUMN shuffle_questions BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN pass_score INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE quiz_question (
+    id uuid NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    step_id uuid NOT NULL,
+    question_type question_type NOT NULL,
+    text VARCHAR NOT NULL,
+    options VARCHAR[] NOT NULL DEFAULT '{}',
+    correct_options INTEGER[] NOT NULL DEFAULT '{}',
+    numeric_answer DOUBLE PRECISION NOT NULL DEFAULT 0,
+    tolerance DOUBLE PRECISION NOT NULL DEFAULT 0,
+    text_answers VARCHAR[] NOT NULL DEFAULT '{}',
+    score INTEGER NOT NULL DEFAULT 1 CHECK (score >= 0),
+    position INTEGER NOT NULL DEFAULT 0,
+    CONSTRAINT fk_step FOREIGN KEY(step_id) REFERENCES step(id)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE quiz_attempt (
+    id uuid NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    step_id uuid NOT NULL,
+    staff_id uuid NOT NULL,
+    question_order uuid[] NOT NULL DEFAULT '{}',
+    started_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+    deadline TIMESTAMP,
+    submitted_at TIMESTAMP,
+    expired BOOLEAN NOT NULL DEFAULT false,
+    score INTEGER NOT NULL DEFAULT 0,
+    CONSTRAINT fk_step FOREIGN KEY(step_id) REFERENCES step(id)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_staff FOREIGN KEY(staff_id) REFERENCES staff(id)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX quiz_attempt_step_staff_idx ON quiz_attempt (step_id, staff_id);
+
+CREATE TABLE quiz_answer (
+    id uuid NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    attempt_id uuid NOT NULL,
+    question_id uuid NOT NULL,
+    options INTEGER[] NOT NULL DEFAULT '{}',
+    number DOUBLE PRECISION,
+    text VARCHAR NOT NULL DEFAULT '',
+    correct BOOLEAN NOT NULL DEFAULT false,
+    score INTEGER NOT NULL DEFAULT 0,
+    UNIQUE(attempt_id, question_id),
+    CONSTRAINT fk_attempt FOREIGN KEY(attempt_id) REFERENCES quiz_attempt(id)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_question FOREIGN KEY(question_id) REFERENCES quiz_question(id)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+END;
@@ -0,0 +1,16 @@
+BEGIN;
+
+DROP TABLE IF EXISTS code_test_result;
+DROP TABLE IF EXISTS code_submission;
+DROP TABLE IF EXISTS code_test_case;
+
+ALTER TABLE step
+    DROP COLUMN IF EXISTS memory_limit,
+    DROP COLUMN IF EXISTS run_time_limit,
+    DROP COLUMN IF EXISTS code_languages;
+
+DROP TYPE IF EXISTS code_submission_status;
+
+UPDATE step SET step_type = 'task' WHERE step_type = 'code';
+
+END;
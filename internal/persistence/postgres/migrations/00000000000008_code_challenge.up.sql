@@ -0,0 +1,59 @@
+ALTER TYPE step_type ADD VALUE IF NOT EXISTS 'code';
+
+BEGIN;
+
+CREATE TYPE code_submission_status AS ENUM ('pending', 'done', 'error');
+
+ALTER TABLE step
+    ADD COLUMN code_languages VARCHAR[] NOT NULL DEFAULT '{}',
+    ADD COLUMN run_time_limit INTEGER NOT NULL DEFAULT 0 CHECK (run_time_limit >= 0),
+    ADD COLUMN memory_limit INTEGER NOT NULL DEFAULT 0 CHECK (memory_limit >= 0);
+
+CREATE TABLE code_test_case (
+    id uuid NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    step_id uuid NOT NULL,
+    input TEXT NOT NULL DEFAULT '',
+    expected_output TEXT NOT NULL DEFAULT '',
+    score INTEGER NOT NULL DEFAULT 1 CHECK (score > 0),
+    hidden BOOLEAN NOT NULL DEFAULT true,
+    position INTEGER NOT NULL DEFAULT 0,
+    CONSTRAINT fk_step FOREIGN KEY(step_id) REFERENCES step(id)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE code_submission (
+    id uuid NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    step_id uuid NOT NULL,
+    staff_id uuid NOT NULL,
+    language VARCHAR NOT NULL,
+    source TEXT NOT NULL,
+    status code_submission_status NOT NULL DEFAULT 'pending',
+    score INTEGER NOT NULL DEFAULT 0,
+    passed INTEGER NOT NULL DEFAULT 0,
+    total INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    submitted_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+    finished_at TIMESTAMP,
+    CONSTRAINT fk_step FOREIGN KEY(step_id) REFERENCES step(id)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_staff FOREIGN KEY(staff_id) REFERENCES staff(id)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX code_submission_step_staff_idx ON code_submission (step_id, staff_id);
+
+CREATE TABLE code_test_result (
+    id uuid NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    submission_id uuid NOT NULL,
+    test_case_id uuid NOT NULL,
+    status VARCHAR NOT NULL,
+    hidden BOOLEAN NOT NULL DEFAULT true,
+    output TEXT NOT NULL DEFAULT '',
+    duration INTEGER NOT NULL DEFAULT 0,
+    CONSTRAINT fk_submission FOREIGN KEY(submission_id) REFERENCES code_submission(id)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_test_case FOREIGN KEY(test_case_id) REFERENCES code_test_case(id)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+END;
@@ -0,0 +1,8 @@
+BEGIN;
+
+DROP TABLE IF EXISTS scheduled_job;
+
+DROP TYPE IF EXISTS job_status;
+DROP TYPE IF EXISTS job_type;
+
+END;
@@ -0,0 +1,22 @@
+BEGIN;
+
+CREATE TYPE job_type AS ENUM ('step-create', 'step-finish');
+CREATE TYPE job_status AS ENUM ('pending', 'running', 'done', 'failed', 'canceled');
+
+CREATE TABLE scheduled_job (
+    id uuid NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    job_type job_type NOT NULL,
+    step_id uuid NOT NULL,
+    status job_status NOT NULL DEFAULT 'pending',
+    run_at TIMESTAMP NOT NULL,
+    payload JSONB,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX scheduled_job_due_idx ON scheduled_job (run_at) WHERE status = 'pending';
+CREATE INDEX scheduled_job_step_idx ON scheduled_job (step_id);
+
+END;
@@ -0,0 +1,25 @@
+BEGIN;
+
+DROP INDEX IF EXISTS scheduled_job_event_idx;
+DELETE FROM scheduled_job WHERE job_type IN ('event-start', 'event-finish');
+ALTER TABLE scheduled_job DROP COLUMN IF EXISTS event_id;
+ALTER TABLE scheduled_job ALTER COLUMN step_id SET NOT NULL;
+
+ALTER TABLE event ALTER COLUMN end_date TYPE DATE;
+ALTER TABLE event ALTER COLUMN creation_date TYPE DATE;
+ALTER TABLE event ALTER COLUMN creation_date SET DEFAULT CURRENT_DATE;
+
+ALTER TABLE event ALTER COLUMN event_status DROP NOT NULL;
+ALTER TABLE event ALTER COLUMN event_status DROP DEFAULT;
+ALTER TABLE event ALTER COLUMN event_status TYPE time_status USING (
+    CASE event_status
+        WHEN 'finished' THEN 'finished'
+        WHEN 'canceled' THEN 'canceled'
+        ELSE 'process'
+    END
+)::time_status;
+ALTER TABLE event ALTER COLUMN event_status SET DEFAULT 'process';
+
+DROP TYPE IF EXISTS event_status;
+
+END;
@@ -0,0 +1,28 @@
+ALTER TYPE job_type ADD VALUE IF NOT EXISTS 'event-start';
+ALTER TYPE job_type ADD VALUE IF NOT EXISTS 'event-finish';
+
+BEGIN;
+
+CREATE TYPE event_status AS ENUM ('draft', 'scheduled', 'running', 'finished', 'canceled');
+
+ALTER TABLE event ALTER COLUMN event_status DROP DEFAULT;
+ALTER TABLE event ALTER COLUMN event_status TYPE event_status USING (
+    CASE event_status
+        WHEN 'finished' THEN 'finished'
+        WHEN 'canceled' THEN 'canceled'
+        ELSE 'running'
+    END
+)::event_status;
+ALTER TABLE event ALTER COLUMN event_status SET DEFAULT 'draft';
+ALTER TABLE event ALTER COLUMN event_status SET NOT NULL;
+
+ALTER TABLE event ALTER COLUMN creation_date TYPE TIMESTAMP;
+ALTER TABLE event ALTER COLUMN creation_date SET DEFAULT current_timestamp;
+ALTER TABLE event ALTER COLUMN end_date TYPE TIMESTAMP;
+
+ALTER TABLE scheduled_job ALTER COLUMN step_id DROP NOT NULL;
+ALTER TABLE scheduled_job ADD COLUMN event_id uuid;
+
+CREATE INDEX scheduled_job_event_idx ON scheduled_job (event_id);
+
+END;
@@ -0,0 +1,18 @@
+BEGIN;
+
+CREATE TABLE step_prerequisite (
+    id uuid NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    step_id uuid NOT NULL,
+    required_step_id uuid NOT NULL,
+    min_score INTEGER NOT NULL DEFAULT 0,
+    CONSTRAINT fk_step FOREIGN KEY(step_id) REFERENCES step(id)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_required_step FOREIGN KEY(required_step_id) REFERENCES step(id)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT step_prerequisite_unique UNIQUE (step_id, required_step_id),
+    CONSTRAINT step_prerequisite_self CHECK (step_id <> required_step_id)
+);
+
+CREATE INDEX step_prerequisite_required_idx ON step_prerequisite (required_step_id);
+
+END;
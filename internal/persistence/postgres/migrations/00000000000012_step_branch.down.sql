@@ -0,0 +1,10 @@
+BEGIN;
+
+DROP TABLE IF EXISTS branch_choice;
+
+ALTER TABLE step_prerequisite DROP COLUMN IF EXISTS outcome;
+
+ALTER TABLE step DROP COLUMN IF EXISTS branch;
+ALTER TABLE step DROP COLUMN IF EXISTS fork;
+
+END;
@@ -0,0 +1,23 @@
+BEGIN;
+
+ALTER TABLE step ADD COLUMN fork VARCHAR NOT NULL DEFAULT '';
+ALTER TABLE step ADD COLUMN branch VARCHAR NOT NULL DEFAULT '';
+
+ALTER TABLE step_prerequisite ADD COLUMN outcome accomplishment NOT NULL DEFAULT 'done'
+    CHECK (outcome IN ('done', 'failed'));
+
+CREATE TABLE branch_choice (
+    id uuid NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    event_id uuid NOT NULL,
+    staff_id uuid NOT NULL,
+    fork VARCHAR NOT NULL,
+    branch VARCHAR NOT NULL,
+    chosen_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+    CONSTRAINT fk_event FOREIGN KEY(event_id) REFERENCES event(id)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_staff FOREIGN KEY(staff_id) REFERENCES staff(id)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT branch_choice_unique UNIQUE (event_id, staff_id, fork)
+);
+
+END;
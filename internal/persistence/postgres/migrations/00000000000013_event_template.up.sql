@@ -0,0 +1,19 @@
+BEGIN;
+
+CREATE TABLE event_template (
+    id uuid NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    name VARCHAR NOT NULL,
+    description VARCHAR NOT NULL DEFAULT '',
+    organization_id uuid NOT NULL,
+    created_by uuid,
+    created_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+    event JSONB NOT NULL,
+    CONSTRAINT fk_organization FOREIGN KEY(organization_id) REFERENCES organizations(id)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_created_by FOREIGN KEY(created_by) REFERENCES staff(id)
+        ON DELETE SET NULL ON UPDATE CASCADE
+);
+
+CREATE INDEX event_template_organization_idx ON event_template (organization_id);
+
+END;
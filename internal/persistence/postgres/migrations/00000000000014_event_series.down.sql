@@ -0,0 +1,13 @@
+BEGIN;
+
+DELETE FROM scheduled_job WHERE job_type = 'series-occurrence';
+DROP INDEX IF EXISTS scheduled_job_series_idx;
+ALTER TABLE scheduled_job DROP COLUMN IF EXISTS series_id;
+
+DROP TABLE IF EXISTS event_occurrence;
+DROP TABLE IF EXISTS event_series;
+
+DROP TYPE IF EXISTS occurrence_status;
+DROP TYPE IF EXISTS recurrence_frequency;
+
+END;
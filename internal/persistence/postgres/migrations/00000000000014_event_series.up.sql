@@ -0,0 +1,51 @@
+ALTER TYPE job_type ADD VALUE IF NOT EXISTS 'series-occurrence';
+
+BEGIN;
+
+CREATE TYPE recurrence_frequency AS ENUM ('daily', 'weekly', 'monthly');
+CREATE TYPE occurrence_status AS ENUM ('pending', 'created', 'skipped');
+
+CREATE TABLE event_series (
+    id uuid NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    name VARCHAR NOT NULL,
+    template_id uuid NOT NULL,
+    organization_id uuid NOT NULL,
+    team_id uuid,
+    created_by uuid NOT NULL,
+    frequency recurrence_frequency NOT NULL,
+    interval INTEGER NOT NULL DEFAULT 1 CHECK (interval > 0),
+    count INTEGER NOT NULL DEFAULT 0,
+    until TIMESTAMP,
+    start_date TIMESTAMP NOT NULL,
+    lead_time INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+    CONSTRAINT fk_template FOREIGN KEY(template_id) REFERENCES event_template(id)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_organization FOREIGN KEY(organization_id) REFERENCES organizations(id)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_team FOREIGN KEY(team_id) REFERENCES team(id)
+        ON DELETE SET NULL ON UPDATE CASCADE,
+    CONSTRAINT fk_created_by FOREIGN KEY(created_by) REFERENCES staff(id)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE event_occurrence (
+    id uuid NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    series_id uuid NOT NULL,
+    occurrence_index INTEGER NOT NULL,
+    start_date TIMESTAMP NOT NULL,
+    name VARCHAR NOT NULL DEFAULT '',
+    status occurrence_status NOT NULL DEFAULT 'pending',
+    event_id uuid,
+    CONSTRAINT fk_series FOREIGN KEY(series_id) REFERENCES event_series(id)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_event FOREIGN KEY(event_id) REFERENCES event(id)
+        ON DELETE SET NULL ON UPDATE CASCADE,
+    CONSTRAINT event_occurrence_unique UNIQUE (series_id, occurrence_index)
+);
+
+ALTER TABLE scheduled_job ADD COLUMN series_id uuid;
+
+CREATE INDEX scheduled_job_series_idx ON scheduled_job (series_id);
+
+END;
@@ -0,0 +1,16 @@
+BEGIN;
+
+UPDATE staff_events SET status = 'none' WHERE status = 'waitlisted';
+UPDATE staff_events SET status = 'declared' WHERE status = 'expired';
+
+DROP INDEX IF EXISTS staff_events_event_status_idx;
+
+ALTER TABLE staff_events DROP COLUMN IF EXISTS waitlisted_at;
+ALTER TABLE staff_events DROP COLUMN IF EXISTS expires_at;
+ALTER TABLE staff_events DROP COLUMN IF EXISTS invited_at;
+
+ALTER TABLE event DROP COLUMN IF EXISTS invitation_ttl;
+ALTER TABLE event DROP COLUMN IF EXISTS registration_deadline;
+ALTER TABLE event DROP COLUMN IF EXISTS max_participants;
+
+END;
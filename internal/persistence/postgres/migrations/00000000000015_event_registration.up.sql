@@ -0,0 +1,16 @@
+ALTER TYPE status ADD VALUE IF NOT EXISTS 'waitlisted';
+ALTER TYPE status ADD VALUE IF NOT EXISTS 'expired';
+
+BEGIN;
+
+ALTER TABLE event ADD COLUMN max_participants INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE event ADD COLUMN registration_deadline TIMESTAMP;
+ALTER TABLE event ADD COLUMN invitation_ttl INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE staff_events ADD COLUMN invited_at TIMESTAMP NOT NULL DEFAULT current_timestamp;
+ALTER TABLE staff_events ADD COLUMN expires_at TIMESTAMP;
+ALTER TABLE staff_events ADD COLUMN waitlisted_at TIMESTAMP;
+
+CREATE INDEX staff_events_event_status_idx ON staff_events (event_id, status);
+
+END;
@@ -0,0 +1,7 @@
+BEGIN;
+
+DELETE FROM staff_events WHERE status = 'requested';
+
+DROP TABLE IF EXISTS event_join_code;
+
+END;
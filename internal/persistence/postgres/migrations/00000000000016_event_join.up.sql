@@ -0,0 +1,22 @@
+ALTER TYPE status ADD VALUE IF NOT EXISTS 'requested';
+
+BEGIN;
+
+CREATE TABLE event_join_code (
+    id uuid NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    event_id uuid NOT NULL,
+    code VARCHAR NOT NULL UNIQUE,
+    created_by uuid,
+    created_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+    expires_at TIMESTAMP,
+    max_uses INTEGER NOT NULL DEFAULT 0,
+    uses INTEGER NOT NULL DEFAULT 0,
+    CONSTRAINT fk_event FOREIGN KEY(event_id) REFERENCES event(id)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_created_by FOREIGN KEY(created_by) REFERENCES staff(id)
+        ON DELETE SET NULL ON UPDATE CASCADE
+);
+
+CREATE INDEX event_join_code_event_idx ON event_join_code (event_id);
+
+END;
@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE IF EXISTS notification_preference;
+DROP TABLE IF EXISTS notification;
+DROP TYPE IF EXISTS notification_type;
+
+END;
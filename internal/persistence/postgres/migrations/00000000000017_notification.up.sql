@@ -0,0 +1,38 @@
+BEGIN;
+
+CREATE TYPE notification_type AS ENUM ('invitation', 'prize', 'step-result', 'level-up', 'event-start', 'event-finish');
+
+CREATE TABLE notification (
+    id uuid NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    staff_id uuid NOT NULL,
+    notification_type notification_type NOT NULL,
+    message VARCHAR NOT NULL DEFAULT '',
+    event_id uuid,
+    step_id uuid,
+    prize_id uuid,
+    read_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+    CONSTRAINT fk_staff FOREIGN KEY(staff_id) REFERENCES staff(id)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_event FOREIGN KEY(event_id) REFERENCES event(id)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_step FOREIGN KEY(step_id) REFERENCES step(id)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_prize FOREIGN KEY(prize_id) REFERENCES prize(id)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX notification_staff_created_idx ON notification (staff_id, created_at DESC);
+CREATE INDEX notification_unread_idx ON notification (staff_id) WHERE read_at IS NULL;
+CREATE INDEX notification_created_idx ON notification (created_at);
+
+CREATE TABLE notification_preference (
+    staff_id uuid NOT NULL,
+    notification_type notification_type NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    PRIMARY KEY (staff_id, notification_type),
+    CONSTRAINT fk_staff FOREIGN KEY(staff_id) REFERENCES staff(id)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+END;
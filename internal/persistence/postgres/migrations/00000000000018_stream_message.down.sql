@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS stream_message;
+DROP TYPE IF EXISTS stream_message_type;
+
+END;
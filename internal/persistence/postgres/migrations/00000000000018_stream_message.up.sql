@@ -0,0 +1,18 @@
+BEGIN;
+
+CREATE TYPE stream_message_type AS ENUM ('score', 'step-status', 'event-status', 'participant', 'prize');
+
+CREATE TABLE stream_message (
+    id BIGSERIAL PRIMARY KEY,
+    message_type stream_message_type NOT NULL,
+    event_id uuid,
+    organization_id uuid,
+    data JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL DEFAULT current_timestamp
+);
+
+CREATE INDEX stream_message_event_idx ON stream_message (event_id, id);
+CREATE INDEX stream_message_organization_idx ON stream_message (organization_id, id);
+CREATE INDEX stream_message_created_idx ON stream_message (created_at);
+
+END;
@@ -0,0 +1,44 @@
+BEGIN;
+
+CREATE TYPE delivery_status AS ENUM ('pending', 'delivered', 'failed');
+
+CREATE TABLE webhook (
+    id uuid NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    organization_id uuid NOT NULL,
+    url VARCHAR NOT NULL,
+    secret VARCHAR NOT NULL,
+    events VARCHAR[] NOT NULL DEFAULT '{}',
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    failure_count INTEGER NOT NULL DEFAULT 0,
+    disabled_at TIMESTAMP,
+    created_by uuid,
+    created_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+    CONSTRAINT fk_organization FOREIGN KEY(organization_id) REFERENCES organizations(id)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_created_by FOREIGN KEY(created_by) REFERENCES staff(id)
+        ON DELETE SET NULL ON UPDATE CASCADE
+);
+
+CREATE INDEX webhook_organization_idx ON webhook (organization_id);
+
+CREATE TABLE webhook_delivery (
+    id uuid NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    webhook_id uuid NOT NULL,
+    event_id uuid NOT NULL,
+    event_type VARCHAR NOT NULL,
+    payload JSONB NOT NULL,
+    status delivery_status NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+    response_code INTEGER NOT NULL DEFAULT 0,
+    last_error VARCHAR NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+    delivered_at TIMESTAMP,
+    CONSTRAINT fk_webhook FOREIGN KEY(webhook_id) REFERENCES webhook(id)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE INDEX webhook_delivery_webhook_idx ON webhook_delivery (webhook_id, created_at DESC);
+CREATE INDEX webhook_delivery_due_idx ON webhook_delivery (next_attempt_at) WHERE status = 'pending';
+
+END;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS domain_event;
+DROP TYPE IF EXISTS domain_event_type;
+
+END;
@@ -0,0 +1,23 @@
+BEGIN;
+
+CREATE TYPE domain_event_type AS ENUM ('event.created', 'event.finished', 'step.created', 'step.finished',
+    'step.result', 'prize.awarded', 'staff.joined');
+
+CREATE TABLE domain_event (
+    id uuid PRIMARY KEY,
+    event_type domain_event_type NOT NULL,
+    organization_id uuid NOT NULL,
+    event_id uuid,
+    data JSONB NOT NULL DEFAULT '{}',
+    handled TEXT[] NOT NULL DEFAULT '{}',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+    dispatched_at TIMESTAMP
+);
+
+CREATE INDEX domain_event_due_idx ON domain_event (next_attempt_at) WHERE dispatched_at IS NULL;
+CREATE INDEX domain_event_dispatched_idx ON domain_event (dispatched_at) WHERE dispatched_at IS NOT NULL;
+
+END;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS chat_integration;
+DROP TYPE IF EXISTS chat_platform;
+
+END;
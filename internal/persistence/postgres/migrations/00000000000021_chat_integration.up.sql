@@ -0,0 +1,28 @@
+ALTER TYPE notification_type ADD VALUE IF NOT EXISTS 'kudos';
+
+BEGIN;
+
+CREATE TYPE chat_platform AS ENUM ('slack', 'mattermost');
+
+CREATE TABLE chat_integration (
+    id uuid NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    organization_id uuid NOT NULL,
+    platform chat_platform NOT NULL,
+    team_id VARCHAR NOT NULL,
+    webhook_url VARCHAR NOT NULL DEFAULT '',
+    server_url VARCHAR NOT NULL DEFAULT '',
+    signing_secret VARCHAR NOT NULL,
+    bot_token VARCHAR NOT NULL DEFAULT '',
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by uuid,
+    created_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+    CONSTRAINT fk_organization FOREIGN KEY(organization_id) REFERENCES organizations(id)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_created_by FOREIGN KEY(created_by) REFERENCES staff(id)
+        ON DELETE SET NULL ON UPDATE CASCADE,
+    CONSTRAINT chat_integration_team_key UNIQUE (platform, team_id)
+);
+
+CREATE INDEX chat_integration_organization_idx ON chat_integration (organization_id);
+
+END;
@@ -0,0 +1,10 @@
+BEGIN;
+
+DELETE FROM scheduled_job WHERE job_type = 'digest';
+
+DROP TABLE IF EXISTS digest_sent;
+DROP TABLE IF EXISTS digest_snapshot;
+DROP TABLE IF EXISTS digest_preference;
+DROP TYPE IF EXISTS digest_kind;
+
+END;
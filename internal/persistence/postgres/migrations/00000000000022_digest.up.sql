@@ -0,0 +1,36 @@
+ALTER TYPE job_type ADD VALUE IF NOT EXISTS 'digest';
+
+BEGIN;
+
+CREATE TYPE digest_kind AS ENUM ('staff', 'team');
+
+CREATE TABLE digest_preference (
+    staff_id uuid NOT NULL,
+    kind digest_kind NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    PRIMARY KEY (staff_id, kind),
+    CONSTRAINT fk_staff FOREIGN KEY(staff_id) REFERENCES staff(id)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE digest_snapshot (
+    staff_id uuid NOT NULL,
+    week DATE NOT NULL,
+    score INTEGER NOT NULL DEFAULT 0,
+    rank INTEGER NOT NULL,
+    PRIMARY KEY (staff_id, week),
+    CONSTRAINT fk_staff FOREIGN KEY(staff_id) REFERENCES staff(id)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+CREATE TABLE digest_sent (
+    staff_id uuid NOT NULL,
+    kind digest_kind NOT NULL,
+    week DATE NOT NULL,
+    sent_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+    PRIMARY KEY (staff_id, kind, week),
+    CONSTRAINT fk_staff FOREIGN KEY(staff_id) REFERENCES staff(id)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+END;
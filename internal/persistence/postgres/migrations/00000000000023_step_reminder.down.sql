@@ -0,0 +1,6 @@
+BEGIN;
+
+DELETE FROM scheduled_job WHERE job_type = 'step-reminder';
+DROP TABLE IF EXISTS step_reminder;
+
+END;
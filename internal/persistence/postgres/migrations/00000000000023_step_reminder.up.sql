@@ -0,0 +1,19 @@
+ALTER TYPE job_type ADD VALUE IF NOT EXISTS 'step-reminder';
+ALTER TYPE notification_type ADD VALUE IF NOT EXISTS 'step-deadline';
+
+BEGIN;
+
+CREATE TABLE step_reminder (
+    step_id uuid NOT NULL,
+    staff_id uuid NOT NULL,
+    end_date TIMESTAMP NOT NULL,
+    remind_before INTEGER NOT NULL,
+    sent_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+    PRIMARY KEY (step_id, staff_id, end_date, remind_before),
+    CONSTRAINT fk_step FOREIGN KEY(step_id) REFERENCES step(id)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_staff FOREIGN KEY(staff_id) REFERENCES staff(id)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+END;
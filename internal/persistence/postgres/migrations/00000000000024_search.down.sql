@@ -0,0 +1,12 @@
+BEGIN;
+
+DROP INDEX IF EXISTS event_search_idx;
+DROP INDEX IF EXISTS step_search_idx;
+DROP INDEX IF EXISTS prize_search_idx;
+DROP INDEX IF EXISTS staff_search_idx;
+ALTER TABLE event DROP COLUMN IF EXISTS search;
+ALTER TABLE step DROP COLUMN IF EXISTS search;
+ALTER TABLE prize DROP COLUMN IF EXISTS search;
+ALTER TABLE staff DROP COLUMN IF EXISTS search;
+
+END;
@@ -0,0 +1,29 @@
+BEGIN;
+
+-- The simple configuration does not stem words, so names and texts in any language
+-- are matched by the prefixes of their words.
+ALTER TABLE event ADD COLUMN search tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(description, '')), 'B')
+) STORED;
+CREATE INDEX event_search_idx ON event USING GIN (search);
+
+ALTER TABLE step ADD COLUMN search tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(task, '')), 'B') ||
+    setweight(to_tsvector('simple', coalesce(description, '')), 'C')
+) STORED;
+CREATE INDEX step_search_idx ON step USING GIN (search);
+
+ALTER TABLE prize ADD COLUMN search tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(description, '')), 'B')
+) STORED;
+CREATE INDEX prize_search_idx ON prize USING GIN (search);
+
+ALTER TABLE staff ADD COLUMN search tsvector GENERATED ALWAYS AS (
+    to_tsvector('simple', first_name || ' ' || last_name)
+) STORED;
+CREATE INDEX staff_search_idx ON staff USING GIN (search);
+
+END;
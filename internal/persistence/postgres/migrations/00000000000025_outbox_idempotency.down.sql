@@ -0,0 +1,9 @@
+BEGIN;
+
+DROP TABLE IF EXISTS chat_post;
+DROP INDEX IF EXISTS notification_source_idx;
+ALTER TABLE notification DROP COLUMN IF EXISTS source_id;
+DROP INDEX IF EXISTS webhook_delivery_event_idx;
+ALTER TABLE webhook_delivery DROP COLUMN IF EXISTS redelivery;
+
+END;
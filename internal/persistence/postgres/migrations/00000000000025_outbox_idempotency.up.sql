@@ -0,0 +1,29 @@
+BEGIN;
+
+ALTER TABLE webhook_delivery ADD COLUMN redelivery BOOLEAN NOT NULL DEFAULT false;
+
+UPDATE webhook_delivery d SET redelivery = true
+WHERE EXISTS (
+    SELECT 1 FROM webhook_delivery e
+    WHERE e.webhook_id = d.webhook_id AND e.event_id = d.event_id
+        AND (e.created_at, e.id) < (d.created_at, d.id)
+);
+
+CREATE UNIQUE INDEX webhook_delivery_event_idx ON webhook_delivery (webhook_id, event_id) WHERE NOT redelivery;
+
+ALTER TABLE notification ADD COLUMN source_id uuid;
+
+CREATE UNIQUE INDEX notification_source_idx ON notification (staff_id, source_id) WHERE source_id IS NOT NULL;
+
+CREATE TABLE chat_post (
+    integration_id uuid NOT NULL,
+    domain_event_id uuid NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+    PRIMARY KEY (integration_id, domain_event_id),
+    CONSTRAINT fk_integration FOREIGN KEY(integration_id) REFERENCES chat_integration(id)
+        ON DELETE CASCADE ON UPDATE CASCADE,
+    CONSTRAINT fk_domain_event FOREIGN KEY(domain_event_id) REFERENCES domain_event(id)
+        ON DELETE CASCADE ON UPDATE CASCADE
+);
+
+END;
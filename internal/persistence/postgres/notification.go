@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+type NotificationRepo struct {
+	DB  *bun.DB
+	ctx context.Context
+}
+
+// CreateNotifications skips notifications staff already has from the same source.
+func (n *NotificationRepo) CreateNotifications(ctx context.Context, notifications []*models.Notification) error {
+	_, err := n.DB.NewInsert().Model(&notifications).ExcludeColumn("read_at").
+		On("CONFLICT (staff_id, source_id) WHERE source_id IS NOT NULL DO NOTHING").
+		Exec(ctx)
+	return err
+}
+
+// GetNotifications returns the newest notifications of staff first.
+func (n *NotificationRepo) GetNotifications(ctx context.Context, staffID uuid.UUID,
+	filter models.NotificationFilter) ([]*models.Notification, error) {
+	notifications := new([]*models.Notification)
+	q := n.DB.NewSelect().Model(notifications).
+		Where("staff_id = ?", staffID).
+		Order("created_at DESC").
+		Limit(filter.Limit)
+	if filter.Unread {
+		q = q.Where("read_at IS NULL")
+	}
+	err := q.Scan(ctx)
+	return *notifications, err
+}
+
+func (n *NotificationRepo) CountUnread(ctx context.Context, staffID uuid.UUID) (int, error) {
+	return n.DB.NewSelect().Model((*models.Notification)(nil)).
+		Where("staff_id = ?", staffID).
+		Where("read_at IS NULL").
+		Count(ctx)
+}
+
+// MarkRead marks unread notifications of staff as read, all of them when ids are empty.
+// It returns the number of marked notifications.
+func (n *NotificationRepo) MarkRead(ctx context.Context, staffID uuid.UUID, ids []uuid.UUID) (int64, error) {
+	q := n.DB.NewUpdate().Model((*models.Notification)(nil)).
+		Set("read_at = ?", time.Now()).
+		Where("staff_id = ?", staffID).
+		Where("read_at IS NULL")
+	if len(ids) != 0 {
+		q = q.Where("id IN (?)", bun.In(ids))
+	}
+	res, err := q.Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// DeleteNotificationsBefore deletes notifications created before t and returns their number.
+func (n *NotificationRepo) DeleteNotificationsBefore(ctx context.Context, t time.Time) (int64, error) {
+	res, err := n.DB.NewDelete().Model((*models.Notification)(nil)).
+		Where("created_at < ?", t).
+		Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (n *NotificationRepo) GetPreferences(ctx context.Context, staffID uuid.UUID) ([]*models.NotificationPreference, error) {
+	preferences := new([]*models.NotificationPreference)
+	err := n.DB.NewSelect().Model(preferences).Where("staff_id = ?", staffID).Scan(ctx)
+	return *preferences, err
+}
+
+func (n *NotificationRepo) SavePreferences(ctx context.Context, preferences []*models.NotificationPreference) error {
+	_, err := n.DB.NewInsert().Model(&preferences).
+		On("CONFLICT (staff_id, notification_type) DO UPDATE").
+		Set("enabled = EXCLUDED.enabled").
+		Exec(ctx)
+	return err
+}
+
+// GetMutedStaff returns staff of staffIDs who turned notifications of the type off.
+func (n *NotificationRepo) GetMutedStaff(ctx context.Context, notificationType models.NotificationType,
+	staffIDs []uuid.UUID) ([]uuid.UUID, error) {
+	var muted []uuid.UUID
+	err := n.DB.NewSelect().Model((*models.NotificationPreference)(nil)).
+		Column("staff_id").
+		Where("notification_type = ?", notificationType).
+		Where("enabled = false").
+		Where("staff_id IN (?)", bun.In(staffIDs)).
+		Scan(ctx, &muted)
+	return muted, err
+}
+
+func NewNotificationRepo(ctx context.Context, DB *bun.DB) *NotificationRepo {
+	return &NotificationRepo{DB: DB, ctx: ctx}
+}
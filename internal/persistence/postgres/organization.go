@@ -13,11 +13,15 @@ type OrganizationRepo struct {
 	ctx context.Context
 }
 
-func (o *OrganizationRepo) GetOrganizations(ctx context.Context) ([]*models.Organization, error) {
+func (o *OrganizationRepo) GetOrganizations(ctx context.Context, query models.ListQuery) ([]*models.Organization, string, error) {
 	var organizations = new([]*models.Organization)
-	err := o.DB.NewSelect().Model(organizations).
-		Relation("Types").Relation("Positions").Relation("Teams").Scan(ctx)
-	return *organizations, err
+	err := organizationListTable.apply(o.DB.NewSelect().Model(organizations).
+		Relation("Types").Relation("Positions").Relation("Teams"), query).Scan(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	n, next := listPage(query, len(*organizations), func(i int) uuid.UUID { return (*organizations)[i].ID })
+	return (*organizations)[:n], next, nil
 }
 
 func (o *OrganizationRepo) GetOrganization(ctx context.Context, id uuid.UUID) (*models.Organization, error) {
@@ -95,26 +99,39 @@ func (o *OrganizationRepo) DeleteOrganization(ctx context.Context, id uuid.UUID)
 	return err
 }
 
-func (o *OrganizationRepo) GetOrganizationEvents(ctx context.Context, orgID, staffID uuid.UUID) ([]*models.Event, error) {
-	var events []*models.Event
-	var staff = new(models.Staff)
-	err := o.DB.NewSelect().Model(staff).Where("id = ?", staff.ID).Scan(ctx)
+// GetOrganizationEvents returns events of the organization staff can see: all but team-only ones
+// for its own organization and only public ones for others.
+func (o *OrganizationRepo) GetOrganizationEvents(ctx context.Context, orgID, staffID uuid.UUID,
+	query models.ListQuery) ([]*models.Event, string, error) {
+	events := new([]*models.Event)
+	staff := new(models.Staff)
+	err := o.DB.NewSelect().Model(staff).Where("id = ?", staffID).Scan(ctx)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
+	q := o.DB.NewSelect().Model(events).Where("event.organization_id = ?", orgID)
 	if staff.OrganizationID == orgID {
-		err = o.DB.NewSelect().Model(&events).Where("organization_id = ?", orgID).Where("event_type != 'team-only'").Scan(ctx)
+		q = q.Where("event.event_type != 'team-only'")
 	} else {
-		err = o.DB.NewSelect().Model(&events).Where("organization_id = ?", orgID).Where("event_type = 'public'").Scan(ctx)
+		q = q.Where("event.event_type = 'public'")
+	}
+	if err := eventListTable.apply(q, query).Scan(ctx); err != nil {
+		return nil, "", err
 	}
-	return events, err
+	n, next := listPage(query, len(*events), func(i int) uuid.UUID { return (*events)[i].ID })
+	return (*events)[:n], next, nil
 }
 
-func (o *OrganizationRepo) GetOrganizationStaff(ctx context.Context, orgID uuid.UUID) ([]models.Staff, error) {
+func (o *OrganizationRepo) GetOrganizationStaff(ctx context.Context, orgID uuid.UUID,
+	query models.ListQuery) ([]models.Staff, string, error) {
 	staff := new([]models.Staff)
-	err := o.DB.NewSelect().Model(staff).Where("staff.company_id = ?", orgID).
-		Relation("Position").Relation("Organization").Scan(ctx)
-	return *staff, err
+	err := staffListTable.apply(o.DB.NewSelect().Model(staff).Where("staff.company_id = ?", orgID).
+		Relation("Position").Relation("Organization"), query).Scan(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	n, next := listPage(query, len(*staff), func(i int) uuid.UUID { return (*staff)[i].ID })
+	return (*staff)[:n], next, nil
 }
 
 func (o *OrganizationRepo) CreateOrganizationType(ctx context.Context, orgType *models.OrganizationType) error {
@@ -0,0 +1,87 @@
+package postgres
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+type OutboxRepo struct {
+	DB  *bun.DB
+	ctx context.Context
+}
+
+// insertDomainEvents writes domain events to the outbox in the transaction of the change they describe.
+// Domain events of an event without the organization get the organization of the event.
+func insertDomainEvents(ctx context.Context, db bun.IDB, events []*models.DomainEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	for _, event := range events {
+		if event.OrganizationID != uuid.Nil || event.EventID == uuid.Nil {
+			continue
+		}
+		orgID, err := selectEventOrganization(ctx, db, event.EventID)
+		if err != nil {
+			return err
+		}
+		event.OrganizationID = orgID
+	}
+	_, err := db.NewInsert().Model(&events).ExcludeColumn("dispatched_at").Exec(ctx)
+	return err
+}
+
+// ClaimDomainEvents leases due domain events that are not dispatched yet and returns them, oldest first.
+// A claimed domain event is not due again until the lease ends, so two instances never hand it off at once.
+func (o *OutboxRepo) ClaimDomainEvents(ctx context.Context, limit int, lease time.Duration) ([]*models.DomainEvent, error) {
+	events := new([]*models.DomainEvent)
+	now := time.Now()
+	due := o.DB.NewSelect().Model((*models.DomainEvent)(nil)).
+		Column("id").
+		Where("dispatched_at IS NULL").
+		Where("next_attempt_at <= ?", now).
+		Order("created_at").
+		Limit(limit).
+		For("UPDATE SKIP LOCKED")
+	_, err := o.DB.NewUpdate().Model((*models.DomainEvent)(nil)).
+		Set("next_attempt_at = ?", now.Add(lease)).
+		Set("attempts = attempts + 1").
+		Where("id IN (?)", due).
+		Returning("*").
+		Exec(ctx, events)
+	return *events, err
+}
+
+// MarkHandled records that the domain event is handed off to the subscriber.
+func (o *OutboxRepo) MarkHandled(ctx context.Context, id uuid.UUID, subscriber string) error {
+	_, err := o.DB.NewUpdate().Model((*models.DomainEvent)(nil)).
+		Set("handled = array_append(handled, ?)", subscriber).
+		Where("id = ?", id).
+		Where("NOT ? = ANY(handled)", subscriber).
+		Exec(ctx)
+	return err
+}
+
+func (o *OutboxRepo) FinishDomainEvent(ctx context.Context, event *models.DomainEvent) error {
+	_, err := o.DB.NewUpdate().Model(event).
+		Column("next_attempt_at", "last_error", "dispatched_at").
+		Where("id = ?", event.ID).
+		Exec(ctx)
+	return err
+}
+
+func (o *OutboxRepo) DeleteDispatchedBefore(ctx context.Context, before time.Time) (int64, error) {
+	res, err := o.DB.NewDelete().Model((*models.DomainEvent)(nil)).
+		Where("dispatched_at < ?", before).
+		Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func NewOutboxRepo(ctx context.Context, DB *bun.DB) *OutboxRepo {
+	return &OutboxRepo{DB: DB, ctx: ctx}
+}
@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+)
+
+// GetEventPrerequisites returns prerequisites of all steps of an event.
+func (s *StepRepo) GetEventPrerequisites(ctx context.Context, eventID uuid.UUID) ([]*models.StepPrerequisite, error) {
+	prerequisites := new([]*models.StepPrerequisite)
+	err := s.DB.NewSelect().Model(prerequisites).
+		Join("JOIN step ON step.id = step_prerequisite.step_id").
+		Where("step.event_id = ?", eventID).
+		Scan(ctx)
+	return *prerequisites, err
+}
+
+func (s *StepRepo) ReplacePrerequisites(ctx context.Context, stepID uuid.UUID, prerequisites []*models.StepPrerequisite) error {
+	tx, err := s.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	_, err = tx.NewDelete().Model((*models.StepPrerequisite)(nil)).Where("step_id = ?", stepID).Exec(ctx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if len(prerequisites) != 0 {
+		_, err = tx.NewInsert().Model(&prerequisites).Exec(ctx)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetEventStaffSteps returns staff steps of one staff in all steps of an event.
+func (s *StepRepo) GetEventStaffSteps(ctx context.Context, eventID, staffID uuid.UUID) ([]*models.StepStaff, error) {
+	staffSteps := new([]*models.StepStaff)
+	err := s.DB.NewSelect().Model(staffSteps).
+		Relation("Step").
+		Where("step.event_id = ?", eventID).
+		Where("staff_step.staff_id = ?", staffID).
+		Scan(ctx)
+	return *staffSteps, err
+}
+
+func (s *StepRepo) GetBranchChoices(ctx context.Context, eventID, staffID uuid.UUID) ([]*models.BranchChoice, error) {
+	choices := new([]*models.BranchChoice)
+	err := s.DB.NewSelect().Model(choices).
+		Where("event_id = ?", eventID).
+		Where("staff_id = ?", staffID).
+		Order("chosen_at").
+		Scan(ctx)
+	return *choices, err
+}
+
+func (s *StepRepo) CreateBranchChoice(ctx context.Context, choice *models.BranchChoice) error {
+	_, err := s.DB.NewInsert().Model(choice).Exec(ctx)
+	return err
+}
@@ -16,7 +16,10 @@ type PrizeRepo struct {
 
 func (p *PrizeRepo) GetPrizesByType(ctx context.Context, prizeType models.PrizeType) ([]*models.Prize, error) {
 	var prizes = new([]*models.Prize)
-	err := p.DB.NewSelect().Model(prizes).Where("prize_type = ?", prizeType).Scan(ctx)
+	err := p.DB.NewSelect().Model(prizes).
+		Where("prize_type = ?", prizeType).
+		Where("archived_at IS NULL").
+		Scan(ctx)
 
 	return *prizes, err
 }
@@ -32,6 +35,21 @@ func (p *PrizeRepo) CreatePrize(ctx context.Context, prize *models.Prize) error
 		tx.Rollback()
 		return err
 	}
+	if prize.PrizeType == models.LootBox && len(prize.Items) != 0 {
+		items := make([]models.LootBoxItem, len(prize.Items))
+		for i := range prize.Items {
+			items[i] = models.LootBoxItem{
+				ID:        uuid.New(),
+				LootBoxID: prize.ID,
+				PrizeID:   prize.Items[i],
+			}
+		}
+		_, err = tx.NewInsert().Model(&items).Exec(ctx)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
 	creationTime, err := time.Parse(time.RFC3339, prize.CreationDate)
 	if err != nil {
 		tx.Rollback()
@@ -52,25 +70,107 @@ func (p *PrizeRepo) GetPrize(ctx context.Context, id uuid.UUID) (*models.Prize,
 	return prize, err
 }
 
-func (p *PrizeRepo) GetPrizes(ctx context.Context, userID uuid.UUID) ([]*models.Prize, error) {
+func (p *PrizeRepo) GetPrizes(ctx context.Context, userID uuid.UUID, query models.ListQuery) ([]*models.Prize, string, error) {
 	var prizes = new([]*models.Prize)
-	err := p.DB.NewSelect().Model(prizes).Where("created_by = ?", userID).Scan(ctx)
-	return *prizes, err
+	err := prizeListTable.apply(p.DB.NewSelect().Model(prizes).
+		Where("prize.created_by = ?", userID).
+		Where("prize.archived_at IS NULL"), query).
+		Scan(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	n, next := listPage(query, len(*prizes), func(i int) uuid.UUID { return (*prizes)[i].ID })
+	return (*prizes)[:n], next, nil
 }
 
-func (p *PrizeRepo) GetAllPrizes(ctx context.Context) ([]*models.Prize, error) {
-	//TODO implement me
-	panic("implement me")
+func (p *PrizeRepo) GetAllPrizes(ctx context.Context, filter models.PrizeFilter,
+	query models.ListQuery) ([]*models.Prize, string, error) {
+	var prizes = new([]*models.Prize)
+	q := p.DB.NewSelect().Model(prizes).
+		Join("JOIN staff ON staff.id = prize.created_by").
+		Where("staff.company_id = ?", filter.OrganizationID)
+	if filter.StepID != (uuid.UUID{}) {
+		q = q.Where("prize.step_id = ?", filter.StepID)
+	}
+	if filter.InStock != nil {
+		if *filter.InStock {
+			q = q.Where("prize.current_count > 0")
+		} else {
+			q = q.Where("prize.current_count = 0")
+		}
+	}
+	if filter.Archived {
+		q = q.Where("prize.archived_at IS NOT NULL")
+	} else {
+		q = q.Where("prize.archived_at IS NULL")
+	}
+	err := prizeListTable.apply(q, query).Scan(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	n, next := listPage(query, len(*prizes), func(i int) uuid.UUID { return (*prizes)[i].ID })
+	return (*prizes)[:n], next, nil
 }
 
 func (p *PrizeRepo) DeletePrize(ctx context.Context, id uuid.UUID) error {
-	//TODO implement me
-	panic("implement me")
+	res, err := p.DB.NewUpdate().Model((*models.Prize)(nil)).
+		Set("archived_at = ?", time.Now()).
+		Where("id = ?", id).
+		Where("archived_at IS NULL").
+		Exec(ctx)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return conflict("can not archive prize %s; no such active prize", id)
+	}
+	return nil
 }
 
-func (p *PrizeRepo) GivePrize(ctx context.Context, staffPrize *models.StaffPrize) error {
-	_, err := p.DB.NewInsert().Model(staffPrize).Exec(ctx)
-	return err
+func (p *PrizeRepo) RestorePrize(ctx context.Context, id uuid.UUID) error {
+	res, err := p.DB.NewUpdate().Model((*models.Prize)(nil)).
+		Set("archived_at = NULL").
+		Where("id = ?", id).
+		Where("archived_at IS NOT NULL").
+		Exec(ctx)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return conflict("can not restore prize %s; no such archived prize", id)
+	}
+	return nil
+}
+
+// GivePrize takes one prize out of stock and gives it to staff. It returns a conflict
+// when the prize is out of stock, so concurrent awards never give more than the stock.
+func (p *PrizeRepo) GivePrize(ctx context.Context, staffPrize *models.StaffPrize, events ...*models.DomainEvent) error {
+	tx, err := p.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	res, err := tx.NewUpdate().Model((*models.Prize)(nil)).
+		Set("current_count = current_count - 1").
+		Where("id = ?", staffPrize.PrizeID).
+		Where("current_count > 0").
+		Exec(ctx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		tx.Rollback()
+		return conflict("can not give prize to user; prize %s is out of stock", staffPrize.PrizeID)
+	}
+	if _, err := tx.NewInsert().Model(staffPrize).Exec(ctx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := insertDomainEvents(ctx, tx, events); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
 }
 
 func (p *PrizeRepo) UpdatePrize(ctx context.Context, prize *models.Prize) error {
@@ -78,6 +178,112 @@ func (p *PrizeRepo) UpdatePrize(ctx context.Context, prize *models.Prize) error
 	return err
 }
 
+func (p *PrizeRepo) GetLootBoxItems(ctx context.Context, lootBoxID uuid.UUID) ([]*models.Prize, error) {
+	var prizes = new([]*models.Prize)
+	err := p.DB.NewSelect().Model(prizes).
+		Join("JOIN loot_box_item ON loot_box_item.prize_id = prize.id").
+		Where("loot_box_item.loot_box_id = ?", lootBoxID).
+		Where("prize.archived_at IS NULL").
+		Scan(ctx)
+	return *prizes, err
+}
+
+func (p *PrizeRepo) GetPrizeOrganization(ctx context.Context, prizeID uuid.UUID) (uuid.UUID, error) {
+	var orgID uuid.UUID
+	err := p.DB.NewSelect().Model((*models.Prize)(nil)).
+		ColumnExpr("staff.company_id").
+		Join("JOIN staff ON staff.id = prize.created_by").
+		Where("prize.id = ?", prizeID).
+		Scan(ctx, &orgID)
+	return orgID, err
+}
+
+func (p *PrizeRepo) GetStaffOrganization(ctx context.Context, staffID uuid.UUID) (uuid.UUID, error) {
+	var orgID uuid.UUID
+	err := p.DB.NewSelect().Model((*models.Staff)(nil)).
+		Column("company_id").
+		Where("id = ?", staffID).
+		Scan(ctx, &orgID)
+	return orgID, err
+}
+
+func (p *PrizeRepo) GetDropWeights(ctx context.Context, orgID uuid.UUID) ([]models.DropWeight, error) {
+	var weights = new([]models.DropWeight)
+	err := p.DB.NewSelect().Model(weights).Where("organization_id = ?", orgID).Scan(ctx)
+	return *weights, err
+}
+
+func (p *PrizeRepo) SetDropWeights(ctx context.Context, weights []models.DropWeight) error {
+	if len(weights) == 0 {
+		return nil
+	}
+	_, err := p.DB.NewInsert().Model(&weights).
+		On("CONFLICT (organization_id, prize_status) DO UPDATE").
+		Set("weight = EXCLUDED.weight").
+		Exec(ctx)
+	return err
+}
+
+func (p *PrizeRepo) GetUnopenedLootBox(ctx context.Context, staffID, lootBoxID uuid.UUID) (*models.StaffPrize, error) {
+	var staffPrize = new(models.StaffPrize)
+	err := p.DB.NewSelect().Model(staffPrize).
+		Where("staff_prizes.staff_id = ?", staffID).
+		Where("staff_prizes.prize_id = ?", lootBoxID).
+		Where("NOT EXISTS (SELECT 1 FROM loot_box_draw WHERE loot_box_draw.staff_prize_id = staff_prizes.id)").
+		Limit(1).
+		Scan(ctx)
+	return staffPrize, err
+}
+
+func (p *PrizeRepo) OpenLootBox(ctx context.Context, draw *models.LootBoxDraw, events ...*models.DomainEvent) error {
+	tx, err := p.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	res, err := tx.NewUpdate().Model((*models.Prize)(nil)).
+		Set("current_count = current_count - 1").
+		Where("id = ?", draw.PrizeID).
+		Where("current_count > 0").
+		Exec(ctx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		tx.Rollback()
+		return conflict("can not open loot box; prize %s is out of stock", draw.PrizeID)
+	}
+	_, err = tx.NewInsert().Model(&models.StaffPrize{
+		ID:      uuid.New(),
+		StaffID: draw.StaffID,
+		PrizeID: draw.PrizeID,
+	}).Exec(ctx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	_, err = tx.NewInsert().Model(draw).Exec(ctx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := insertDomainEvents(ctx, tx, events); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (p *PrizeRepo) GetLootBoxDraws(ctx context.Context, lootBoxID uuid.UUID) ([]*models.LootBoxDraw, error) {
+	var draws = new([]*models.LootBoxDraw)
+	err := p.DB.NewSelect().Model(draws).
+		Relation("Prize").
+		Where("loot_box_draw.loot_box_id = ?", lootBoxID).
+		Order("loot_box_draw.created_at DESC").
+		Scan(ctx)
+	return *draws, err
+}
+
 func NewPrizeRepo(ctx context.Context, DB *bun.DB) *PrizeRepo {
 	return &PrizeRepo{DB: DB, ctx: ctx}
 }
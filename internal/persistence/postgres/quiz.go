@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+)
+
+func (s *StepRepo) GetQuizQuestions(ctx context.Context, stepID uuid.UUID) ([]*models.QuizQuestion, error) {
+	questions := new([]*models.QuizQuestion)
+	err := s.DB.NewSelect().Model(questions).
+		Where("step_id = ?", stepID).
+		Order("position").
+		Scan(ctx)
+	return *questions, err
+}
+
+// ReplaceQuizQuestions replaces step questions and sets step max score to their total.
+func (s *StepRepo) ReplaceQuizQuestions(ctx context.Context, stepID uuid.UUID, questions []*models.QuizQuestion,
+	maxScore uint) error {
+	tx, err := s.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	_, err = tx.NewDelete().Model((*models.QuizQuestion)(nil)).Where("step_id = ?", stepID).Exec(ctx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if len(questions) != 0 {
+		_, err = tx.NewInsert().Model(&questions).Exec(ctx)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	_, err = tx.NewUpdate().Model((*models.Step)(nil)).
+		Set("max_score = ?", maxScore).
+		Where("id = ?", stepID).
+		Exec(ctx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *StepRepo) HasQuizAttempts(ctx context.Context, stepID uuid.UUID) (bool, error) {
+	return s.DB.NewSelect().Model((*models.QuizAttempt)(nil)).
+		Where("step_id = ?", stepID).
+		Exists(ctx)
+}
+
+func (s *StepRepo) GetQuizAttempts(ctx context.Context, stepID, staffID uuid.UUID) ([]*models.QuizAttempt, error) {
+	attempts := new([]*models.QuizAttempt)
+	err := s.DB.NewSelect().Model(attempts).
+		Relation("Answers").
+		Where("quiz_attempt.step_id = ?", stepID).
+		Where("quiz_attempt.staff_id = ?", staffID).
+		Order("quiz_attempt.started_at").
+		Scan(ctx)
+	return *attempts, err
+}
+
+func (s *StepRepo) CreateQuizAttempt(ctx context.Context, attempt *models.QuizAttempt) error {
+	_, err := s.DB.NewInsert().Model(attempt).ExcludeColumn("submitted_at").Exec(ctx)
+	return err
+}
+
+// FinishQuizAttempt stores graded answers and the staff step result.
+// An attempt can be finished once.
+func (s *StepRepo) FinishQuizAttempt(ctx context.Context, attempt *models.QuizAttempt, staff models.StepStaff) error {
+	tx, err := s.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	res, err := tx.NewUpdate().Model(attempt).
+		Column("submitted_at", "expired", "score").
+		Where("id = ?", attempt.ID).
+		Where("submitted_at IS NULL").
+		Exec(ctx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		tx.Rollback()
+		return conflict("can not finish quiz attempt %s; it is already submitted", attempt.ID)
+	}
+	if len(attempt.Answers) != 0 {
+		_, err = tx.NewInsert().Model(&attempt.Answers).Exec(ctx)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	_, err = tx.NewUpdate().Model((*models.StepStaff)(nil)).
+		Set("accomplishment = ?", staff.Accomplishment).
+		Set("score = ?", staff.Score).
+		Where("step_id = ?", staff.StepID).
+		Where("staff_id = ?", staff.StaffID).
+		Exec(ctx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"github.com/uptrace/bun/dialect/pgdialect"
+	"time"
+)
+
+// GetUnremindedStaff returns staff still doing the step who are not reminded yet about its end date.
+func (s *StepRepo) GetUnremindedStaff(ctx context.Context, stepID uuid.UUID, endDate time.Time,
+	before time.Duration) ([]uuid.UUID, error) {
+	var staffIDs []uuid.UUID
+	rows, err := s.DB.QueryContext(ctx, `
+		SELECT staff_id FROM staff_step
+		WHERE step_id = ? AND accomplishment = ?
+			AND NOT EXISTS (SELECT 1 FROM step_reminder
+				WHERE step_reminder.step_id = staff_step.step_id AND step_reminder.staff_id = staff_step.staff_id
+					AND step_reminder.end_date = ? AND step_reminder.remind_before = ?)`,
+		stepID, models.InProcess, endDate, int(before/time.Second))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	err = s.DB.ScanRows(ctx, rows, &staffIDs)
+	return staffIDs, err
+}
+
+// SaveStepReminders records that staff are reminded about the end date of the step.
+func (s *StepRepo) SaveStepReminders(ctx context.Context, stepID uuid.UUID, endDate time.Time,
+	before time.Duration, staffIDs []uuid.UUID) error {
+	if len(staffIDs) == 0 {
+		return nil
+	}
+	_, err := s.DB.ExecContext(ctx, `
+		INSERT INTO step_reminder (step_id, staff_id, end_date, remind_before)
+		SELECT ?, staff_id, ?, ? FROM unnest(?::uuid[]) AS staff_id
+		ON CONFLICT DO NOTHING`, stepID, endDate, int(before/time.Second), pgdialect.Array(staffIDs))
+	return err
+}
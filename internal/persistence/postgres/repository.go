@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/google/uuid"
 	"github.com/miprokop/fication/internal/models"
+	"time"
 )
 
 type Repository struct {
@@ -13,6 +14,17 @@ type Repository struct {
 	Prize        Prize
 	Step         Step
 	Event        Event
+	Job          Job
+	Template     Template
+	Series       Series
+	Join         Join
+	Notification Notification
+	Stream       Stream
+	Webhook      Webhook
+	Outbox       Outbox
+	Chat         Chat
+	Digest       Digest
+	Search       Search
 }
 
 func NewRepository(db *Postgres) (*Repository, error) {
@@ -147,6 +159,17 @@ func NewRepository(db *Postgres) (*Repository, error) {
 		Prize:        NewPrizeRepo(ctx, db.DB),
 		Step:         NewStepRepo(ctx, db.DB),
 		Event:        NewEventRepo(ctx, db.DB),
+		Job:          NewJobRepo(ctx, db.DB),
+		Template:     NewTemplateRepo(ctx, db.DB),
+		Series:       NewSeriesRepo(ctx, db.DB),
+		Join:         NewJoinRepo(ctx, db.DB),
+		Notification: NewNotificationRepo(ctx, db.DB),
+		Stream:       NewStreamRepo(ctx, db.DB),
+		Webhook:      NewWebhookRepo(ctx, db.DB),
+		Outbox:       NewOutboxRepo(ctx, db.DB),
+		Chat:         NewChatRepo(ctx, db.DB),
+		Digest:       NewDigestRepo(ctx, db.DB),
+		Search:       NewSearchRepo(ctx, db.DB),
 	}, nil
 }
 
@@ -166,7 +189,7 @@ type Staff interface {
 	UpdateStaff(ctx context.Context, staff *models.Staff) error
 	SetStaffRole(ctx context.Context, role models.StaffRole) error
 	GetInvites(ctx context.Context, id uuid.UUID) ([]models.StaffEvents, error)
-	GetStaffPrizes(ctx context.Context, id uuid.UUID) ([]models.Prize, error)
+	GetStaffPrizes(ctx context.Context, id uuid.UUID, query models.ListQuery) ([]models.Prize, string, error)
 	SaveFile(ctx context.Context, image models.StaffImage) error
 	GetDefaultPosition(ctx context.Context, orgID uuid.UUID) (models.Position, error)
 	GetRole(ctx context.Context, id uuid.UUID) (*models.Position, error)
@@ -178,14 +201,15 @@ type Staff interface {
 }
 
 type Organization interface {
-	GetOrganizations(ctx context.Context) ([]*models.Organization, error)
+	GetOrganizations(ctx context.Context, query models.ListQuery) ([]*models.Organization, string, error)
 	GetOrganization(ctx context.Context, id uuid.UUID) (*models.Organization, error)
 	CreateOrganization(ctx context.Context, org *models.Organization, userID uuid.UUID) error
 	UpdateOrganization(ctx context.Context, org *models.Organization) error
 	AddUsersToOrg(ctx context.Context, staff *models.Staff) error
 	DeleteOrganization(ctx context.Context, id uuid.UUID) error
-	GetOrganizationEvents(ctx context.Context, orgID, staffID uuid.UUID) ([]*models.Event, error)
-	GetOrganizationStaff(ctx context.Context, orgID uuid.UUID) ([]models.Staff, error)
+	GetOrganizationEvents(ctx context.Context, orgID, staffID uuid.UUID,
+		query models.ListQuery) ([]*models.Event, string, error)
+	GetOrganizationStaff(ctx context.Context, orgID uuid.UUID, query models.ListQuery) ([]models.Staff, string, error)
 
 	CreateOrganizationType(ctx context.Context, orgType *models.OrganizationType) error
 	GetOrganizationTypeByID(ctx context.Context, id uuid.UUID) (*models.OrganizationType, error)
@@ -208,40 +232,220 @@ type Prize interface {
 	CreatePrize(ctx context.Context, prize *models.Prize) error
 	GetPrize(ctx context.Context, id uuid.UUID) (*models.Prize, error)
 	GetPrizesByType(ctx context.Context, prizeType models.PrizeType) ([]*models.Prize, error)
-	GetPrizes(ctx context.Context, userID uuid.UUID) ([]*models.Prize, error)
-	GetAllPrizes(ctx context.Context) ([]*models.Prize, error)
+	GetPrizes(ctx context.Context, userID uuid.UUID, query models.ListQuery) ([]*models.Prize, string, error)
+	GetAllPrizes(ctx context.Context, filter models.PrizeFilter, query models.ListQuery) ([]*models.Prize, string, error)
 	DeletePrize(ctx context.Context, id uuid.UUID) error
-	GivePrize(ctx context.Context, staffPrize *models.StaffPrize) error
+	RestorePrize(ctx context.Context, id uuid.UUID) error
+	GivePrize(ctx context.Context, staffPrize *models.StaffPrize, events ...*models.DomainEvent) error
 	UpdatePrize(ctx context.Context, prize *models.Prize) error
+	GetLootBoxItems(ctx context.Context, lootBoxID uuid.UUID) ([]*models.Prize, error)
+	GetPrizeOrganization(ctx context.Context, prizeID uuid.UUID) (uuid.UUID, error)
+	GetStaffOrganization(ctx context.Context, staffID uuid.UUID) (uuid.UUID, error)
+	GetDropWeights(ctx context.Context, orgID uuid.UUID) ([]models.DropWeight, error)
+	SetDropWeights(ctx context.Context, weights []models.DropWeight) error
+	GetUnopenedLootBox(ctx context.Context, staffID, lootBoxID uuid.UUID) (*models.StaffPrize, error)
+	OpenLootBox(ctx context.Context, draw *models.LootBoxDraw, events ...*models.DomainEvent) error
+	GetLootBoxDraws(ctx context.Context, lootBoxID uuid.UUID) ([]*models.LootBoxDraw, error)
 }
 
 type Step interface {
-	CreateStep(ctx context.Context, step *models.Step) error
+	CreateStep(ctx context.Context, step *models.Step, events ...*models.DomainEvent) error
 	GetStep(ctx context.Context, id uuid.UUID) (*models.Step, error)
 	GetStepPrizes(ctx context.Context, id uuid.UUID) ([]*models.Prize, error)
 	GetSteps(ctx context.Context, eventID uuid.UUID) ([]*models.Step, error)
+	ListSteps(ctx context.Context, eventID uuid.UUID, query models.ListQuery) ([]*models.Step, string, error)
 	DeleteStep(ctx context.Context, id uuid.UUID) error
 	AssignStaff(ctx context.Context, staff models.StepStaff) error
-	PassStaff(ctx context.Context, staff models.StepStaff) error
-	UpdateStep(ctx context.Context, step *models.Step) error
+	PassStaff(ctx context.Context, staff models.StepStaff, events ...*models.DomainEvent) error
+	UpdateStep(ctx context.Context, step *models.Step, events ...*models.DomainEvent) error
+	GetStepStaff(ctx context.Context, stepID, staffID uuid.UUID) (*models.StepStaff, error)
+	CreateSubmission(ctx context.Context, submission *models.Submission) error
+	GetSubmission(ctx context.Context, id uuid.UUID) (*models.Submission, error)
+	GetSubmissionQueue(ctx context.Context, eventID uuid.UUID) ([]*models.Submission, error)
+	GetStaffSubmissions(ctx context.Context, stepID, staffID uuid.UUID) ([]*models.Submission, error)
+	ReviewSubmission(ctx context.Context, submission *models.Submission,
+		accomplishment models.Accomplishment, comment *models.SubmissionComment) error
+	AddSubmissionComment(ctx context.Context, comment *models.SubmissionComment) error
+	GetStepParticipants(ctx context.Context, stepID uuid.UUID) ([]*models.Staff, error)
+	GetEventReviewers(ctx context.Context, eventID uuid.UUID) ([]*models.Staff, error)
+	GetReviewerLoad(ctx context.Context, reviewerIDs []uuid.UUID) (map[uuid.UUID]int, error)
+	CreateReviewAssignments(ctx context.Context, assignments []*models.ReviewAssignment) error
+	GetReviewAssignment(ctx context.Context, id uuid.UUID) (*models.ReviewAssignment, error)
+	GetReviewAssignments(ctx context.Context, submissionID uuid.UUID) ([]*models.ReviewAssignment, error)
+	GetReviewerAssignments(ctx context.Context, reviewerID uuid.UUID) ([]*models.ReviewAssignment, error)
+	CompleteReview(ctx context.Context, assignment *models.ReviewAssignment) error
+	GetRubric(ctx context.Context, stepID uuid.UUID) ([]*models.RubricCriterion, error)
+	ReplaceRubric(ctx context.Context, stepID uuid.UUID, rubric []*models.RubricCriterion) error
+	GetEventPrerequisites(ctx context.Context, eventID uuid.UUID) ([]*models.StepPrerequisite, error)
+	ReplacePrerequisites(ctx context.Context, stepID uuid.UUID, prerequisites []*models.StepPrerequisite) error
+	GetEventStaffSteps(ctx context.Context, eventID, staffID uuid.UUID) ([]*models.StepStaff, error)
+	GetBranchChoices(ctx context.Context, eventID, staffID uuid.UUID) ([]*models.BranchChoice, error)
+	CreateBranchChoice(ctx context.Context, choice *models.BranchChoice) error
+	HasCriterionScores(ctx context.Context, stepID uuid.UUID) (bool, error)
+	GetCriterionScores(ctx context.Context, stepID, staffID uuid.UUID) ([]*models.CriterionScore, error)
+	GetReviewCriterionScores(ctx context.Context, submissionID uuid.UUID) ([]*models.CriterionScore, error)
+	GetQuizQuestions(ctx context.Context, stepID uuid.UUID) ([]*models.QuizQuestion, error)
+	ReplaceQuizQuestions(ctx context.Context, stepID uuid.UUID, questions []*models.QuizQuestion, maxScore uint) error
+	HasQuizAttempts(ctx context.Context, stepID uuid.UUID) (bool, error)
+	GetQuizAttempts(ctx context.Context, stepID, staffID uuid.UUID) ([]*models.QuizAttempt, error)
+	CreateQuizAttempt(ctx context.Context, attempt *models.QuizAttempt) error
+	FinishQuizAttempt(ctx context.Context, attempt *models.QuizAttempt, staff models.StepStaff) error
+	GetCodeTestCases(ctx context.Context, stepID uuid.UUID) ([]*models.CodeTestCase, error)
+	ReplaceCodeTestCases(ctx context.Context, stepID uuid.UUID, tests []*models.CodeTestCase, maxScore uint) error
+	HasCodeSubmissions(ctx context.Context, stepID uuid.UUID) (bool, error)
+	GetCodeSubmissions(ctx context.Context, stepID, staffID uuid.UUID) ([]*models.CodeSubmission, error)
+	CreateCodeSubmission(ctx context.Context, submission *models.CodeSubmission) error
+	FinishCodeSubmission(ctx context.Context, submission *models.CodeSubmission, staff models.StepStaff) error
+	ExpireCodeSubmissions(ctx context.Context, before time.Time, message string) (int64, error)
+	GetEventStatus(ctx context.Context, eventID uuid.UUID) (models.EventStatus, error)
+	GetUnremindedStaff(ctx context.Context, stepID uuid.UUID, endDate time.Time, before time.Duration) ([]uuid.UUID, error)
+	SaveStepReminders(ctx context.Context, stepID uuid.UUID, endDate time.Time, before time.Duration, staffIDs []uuid.UUID) error
+}
+
+type Job interface {
+	CreateJob(ctx context.Context, job *models.Job) error
+	GetJob(ctx context.Context, id uuid.UUID) (*models.Job, error)
+	GetStepJobs(ctx context.Context, stepID uuid.UUID) ([]*models.Job, error)
+	CancelStepJobs(ctx context.Context, stepID uuid.UUID, jobTypes ...models.JobType) error
+	CancelEventJobs(ctx context.Context, eventID uuid.UUID, jobTypes ...models.JobType) error
+	CancelSeriesJobs(ctx context.Context, seriesID uuid.UUID) error
+	ClaimDueJobs(ctx context.Context, limit int) ([]*models.Job, error)
+	FinishJob(ctx context.Context, job *models.Job) error
+	ResetRunningJobs(ctx context.Context) error
+	TryAdvisoryLock(ctx context.Context, key int64) (*AdvisoryLock, error)
 }
 
 type Event interface {
 	RemoveStaffFromEvent(ctx context.Context, events models.StaffEvents) error
 	DeleteInvitation(ctx context.Context, events models.StaffEvents) error
-	GetInvites(ctx context.Context, staffID uuid.UUID) ([]*models.StaffEvents, error)
+	GetInvites(ctx context.Context, staffID uuid.UUID, query models.ListQuery) ([]*models.StaffEvents, string, error)
 	GetStaff(ctx context.Context, id uuid.UUID) (*models.Staff, error)
-	CreateEvent(ctx context.Context, event *models.Event) error
+	CreateEvent(ctx context.Context, event *models.Event, events ...*models.DomainEvent) error
 	AnswerInvitation(ctx context.Context, events models.StaffEvents) error
 	IsStaffInTeam(ctx context.Context, staffID, teamID uuid.UUID) (bool, error)
 	IsStaffInOrg(ctx context.Context, staffID, teamID uuid.UUID) (bool, error)
 	GetStaffScore(ctx context.Context, eventID, staffID uuid.UUID) (models.StaffScore, error)
 	GetEvent(ctx context.Context, id uuid.UUID) (*models.Event, error)
-	GetEventsByTeamID(ctx context.Context, orgID uuid.UUID) ([]*models.Event, error)
+	GetEventsByTeamID(ctx context.Context, teamID uuid.UUID, query models.ListQuery) ([]*models.Event, string, error)
 	AssignStaff(ctx context.Context, events models.StaffEvents) error
 	GetEventsByCommandID(ctx context.Context, commandID uuid.UUID) ([]*models.Event, error)
 	DeleteEvent(ctx context.Context, id uuid.UUID) error
 	UpdateEvent(ctx context.Context, step *models.Event) error
-	GetStaffsEventsByRole(ctx context.Context, id uuid.UUID, role string) ([]*models.Event, error)
-	GetStaffsEvents(ctx context.Context, id uuid.UUID) ([]*models.Event, error)
+	UpdateMaxParticipants(ctx context.Context, id uuid.UUID, maxParticipants uint) error
+	GetStaffsEventsByRole(ctx context.Context, id uuid.UUID, role string,
+		query models.ListQuery) ([]*models.Event, string, error)
+	GetStaffsEvents(ctx context.Context, id uuid.UUID, query models.ListQuery) ([]*models.Event, string, error)
+	UpdateEventStatus(ctx context.Context, id uuid.UUID, from, to models.EventStatus,
+		events ...*models.DomainEvent) error
+	CloseEventSteps(ctx context.Context, eventID uuid.UUID, status models.StepStatus) error
+	GetInvitation(ctx context.Context, id, staffID uuid.UUID) (*models.StaffEvents, error)
+	ExpireInvitations(ctx context.Context) error
+	AcceptInvitation(ctx context.Context, invite *models.StaffEvents) (models.InviteStatus, error)
+	PromoteWaitlist(ctx context.Context, eventID uuid.UUID) ([]*models.StaffEvents, error)
+}
+
+type Template interface {
+	CreateTemplate(ctx context.Context, template *models.EventTemplate) error
+	GetTemplate(ctx context.Context, id uuid.UUID) (*models.EventTemplate, error)
+	GetTemplates(ctx context.Context, orgID uuid.UUID) ([]*models.EventTemplate, error)
+	DeleteTemplate(ctx context.Context, id uuid.UUID) error
+}
+
+type Series interface {
+	CreateSeries(ctx context.Context, series *models.EventSeries) error
+	GetSeries(ctx context.Context, id uuid.UUID) (*models.EventSeries, error)
+	GetOrganizationSeries(ctx context.Context, orgID uuid.UUID) ([]*models.EventSeries, error)
+	DeleteSeries(ctx context.Context, id uuid.UUID) error
+	GetOccurrences(ctx context.Context, seriesID uuid.UUID) ([]*models.EventOccurrence, error)
+	SaveOccurrence(ctx context.Context, occurrence *models.EventOccurrence) error
+}
+
+type Join interface {
+	CreateJoinCode(ctx context.Context, code *models.JoinCode) error
+	GetJoinCode(ctx context.Context, code string) (*models.JoinCode, error)
+	GetJoinCodeByID(ctx context.Context, id uuid.UUID) (*models.JoinCode, error)
+	GetJoinCodes(ctx context.Context, eventID uuid.UUID) ([]*models.JoinCode, error)
+	DeleteJoinCode(ctx context.Context, id uuid.UUID) error
+	JoinEvent(ctx context.Context, invite *models.StaffEvents, codeID uuid.UUID) (models.InviteStatus, error)
+	CreateJoinRequest(ctx context.Context, invite *models.StaffEvents) error
+	GetJoinRequest(ctx context.Context, id uuid.UUID) (*models.StaffEvents, error)
+	GetJoinRequests(ctx context.Context, eventID uuid.UUID) ([]*models.StaffEvents, error)
+}
+
+type Notification interface {
+	CreateNotifications(ctx context.Context, notifications []*models.Notification) error
+	GetNotifications(ctx context.Context, staffID uuid.UUID, filter models.NotificationFilter) ([]*models.Notification, error)
+	CountUnread(ctx context.Context, staffID uuid.UUID) (int, error)
+	MarkRead(ctx context.Context, staffID uuid.UUID, ids []uuid.UUID) (int64, error)
+	DeleteNotificationsBefore(ctx context.Context, t time.Time) (int64, error)
+	GetPreferences(ctx context.Context, staffID uuid.UUID) ([]*models.NotificationPreference, error)
+	SavePreferences(ctx context.Context, preferences []*models.NotificationPreference) error
+	GetMutedStaff(ctx context.Context, notificationType models.NotificationType, staffIDs []uuid.UUID) ([]uuid.UUID, error)
+}
+
+type Stream interface {
+	CreateStreamMessage(ctx context.Context, message *models.StreamMessage) error
+	GetStreamMessages(ctx context.Context, afterID int64, limit int) ([]*models.StreamMessage, error)
+	GetScopeMessages(ctx context.Context, scope models.StreamScope, afterID int64, limit int) ([]*models.StreamMessage, error)
+	GetLastStreamMessageID(ctx context.Context) (int64, error)
+	DeleteStreamMessagesBefore(ctx context.Context, t time.Time) (int64, error)
+	GetEventOrganization(ctx context.Context, eventID uuid.UUID) (uuid.UUID, error)
+	GetStepEvent(ctx context.Context, stepID uuid.UUID) (uuid.UUID, error)
+}
+
+type Webhook interface {
+	CreateWebhook(ctx context.Context, webhook *models.Webhook) error
+	GetWebhook(ctx context.Context, id uuid.UUID) (*models.Webhook, error)
+	GetWebhooks(ctx context.Context, orgID uuid.UUID) ([]*models.Webhook, error)
+	GetSubscribedWebhooks(ctx context.Context, orgID uuid.UUID, eventType models.WebhookEventType) ([]*models.Webhook, error)
+	UpdateWebhook(ctx context.Context, webhook *models.Webhook) error
+	DeleteWebhook(ctx context.Context, id uuid.UUID) error
+	RecordWebhookResult(ctx context.Context, id uuid.UUID, delivered bool, disableAfter uint) (bool, error)
+	CreateDeliveries(ctx context.Context, deliveries []*models.WebhookDelivery) error
+	GetDelivery(ctx context.Context, id uuid.UUID) (*models.WebhookDelivery, error)
+	GetDeliveries(ctx context.Context, webhookID uuid.UUID, limit int) ([]*models.WebhookDelivery, error)
+	ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*models.WebhookDelivery, error)
+	FinishDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+}
+
+type Outbox interface {
+	ClaimDomainEvents(ctx context.Context, limit int, lease time.Duration) ([]*models.DomainEvent, error)
+	MarkHandled(ctx context.Context, id uuid.UUID, subscriber string) error
+	FinishDomainEvent(ctx context.Context, event *models.DomainEvent) error
+	DeleteDispatchedBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type Chat interface {
+	CreateChatIntegration(ctx context.Context, integration *models.ChatIntegration) error
+	GetChatIntegration(ctx context.Context, id uuid.UUID) (*models.ChatIntegration, error)
+	GetChatIntegrations(ctx context.Context, orgID uuid.UUID) ([]*models.ChatIntegration, error)
+	GetTeamIntegration(ctx context.Context, platform models.ChatPlatform, teamID string) (*models.ChatIntegration, error)
+	UpdateChatIntegration(ctx context.Context, integration *models.ChatIntegration) error
+	DeleteChatIntegration(ctx context.Context, id uuid.UUID) error
+	ClaimChatPost(ctx context.Context, integrationID, domainEventID uuid.UUID) (bool, error)
+	ReleaseChatPost(ctx context.Context, integrationID, domainEventID uuid.UUID) error
+	GetStaffByEmail(ctx context.Context, orgID uuid.UUID, email string) (*models.Staff, error)
+	GetRunningEvents(ctx context.Context, staffID uuid.UUID) ([]*models.Event, error)
+	GetEventLeaderboard(ctx context.Context, eventID uuid.UUID, limit int) ([]models.LeaderboardEntry, error)
+}
+
+type Digest interface {
+	CreateDigestJob(ctx context.Context, job *models.Job) error
+	GetDigestPreferences(ctx context.Context, staffID uuid.UUID) ([]*models.DigestPreference, error)
+	GetDisabledDigests(ctx context.Context) ([]*models.DigestPreference, error)
+	SaveDigestPreferences(ctx context.Context, preferences []*models.DigestPreference) error
+	SaveDigestSnapshots(ctx context.Context, week time.Time) error
+	GetDigestSnapshots(ctx context.Context, week time.Time) ([]*models.DigestSnapshot, error)
+	GetPreviousDigestSnapshots(ctx context.Context, week time.Time) ([]*models.DigestSnapshot, error)
+	DeleteDigestSnapshotsBefore(ctx context.Context, week time.Time) error
+	GetSentDigests(ctx context.Context, week time.Time) ([]*models.DigestSent, error)
+	MarkDigestSent(ctx context.Context, sent *models.DigestSent) error
+	GetDigestStaff(ctx context.Context) ([]*models.Staff, error)
+	GetTeamManagers(ctx context.Context) ([]*models.Staff, error)
+	GetNewInvitations(ctx context.Context, since time.Time) ([]*models.StaffEvents, error)
+	GetUpcomingDeadlines(ctx context.Context, from, until time.Time) ([]models.DigestDeadline, error)
+}
+
+type Search interface {
+	Search(ctx context.Context, staffID uuid.UUID, query models.SearchQuery) ([]models.SearchResult, error)
 }
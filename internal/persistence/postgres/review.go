@@ -0,0 +1,117 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+func (s *StepRepo) GetStepParticipants(ctx context.Context, stepID uuid.UUID) ([]*models.Staff, error) {
+	var staff = make([]*models.Staff, 0)
+	err := s.DB.NewSelect().Model(&staff).
+		Join("JOIN staff_step ON staff.id = staff_step.staff_id").
+		Where("staff_step.step_id = ?", stepID).
+		Scan(ctx)
+	return staff, err
+}
+
+func (s *StepRepo) GetEventReviewers(ctx context.Context, eventID uuid.UUID) ([]*models.Staff, error) {
+	var staff = make([]*models.Staff, 0)
+	err := s.DB.NewSelect().Model(&staff).
+		Join("JOIN staff_events ON staff.id = staff_events.user_id").
+		Where("staff_events.event_id = ?", eventID).
+		Where("staff_events.user_role IN (?)", bun.In([]models.EventStaffRole{models.Creator, models.Admin})).
+		Scan(ctx)
+	return staff, err
+}
+
+func (s *StepRepo) GetReviewerLoad(ctx context.Context, reviewerIDs []uuid.UUID) (map[uuid.UUID]int, error) {
+	var rows []struct {
+		ReviewerID uuid.UUID
+		Count      int
+	}
+	load := make(map[uuid.UUID]int, len(reviewerIDs))
+	if len(reviewerIDs) == 0 {
+		return load, nil
+	}
+	err := s.DB.NewSelect().Model((*models.ReviewAssignment)(nil)).
+		ColumnExpr("reviewer_id, count(*) AS count").
+		Where("reviewer_id IN (?)", bun.In(reviewerIDs)).
+		Where("status = ?", models.ReviewAssigned).
+		Group("reviewer_id").
+		Scan(ctx, &rows)
+	for _, row := range rows {
+		load[row.ReviewerID] = row.Count
+	}
+	return load, err
+}
+
+func (s *StepRepo) CreateReviewAssignments(ctx context.Context, assignments []*models.ReviewAssignment) error {
+	if len(assignments) == 0 {
+		return nil
+	}
+	_, err := s.DB.NewInsert().Model(&assignments).ExcludeColumn("reviewed_at").Exec(ctx)
+	return err
+}
+
+func (s *StepRepo) GetReviewAssignment(ctx context.Context, id uuid.UUID) (*models.ReviewAssignment, error) {
+	assignment := new(models.ReviewAssignment)
+	err := s.DB.NewSelect().Model(assignment).
+		Relation("Submission").
+		Where("review_assignment.id = ?", id).
+		Scan(ctx)
+	return assignment, err
+}
+
+func (s *StepRepo) GetReviewAssignments(ctx context.Context, submissionID uuid.UUID) ([]*models.ReviewAssignment, error) {
+	assignments := new([]*models.ReviewAssignment)
+	err := s.DB.NewSelect().Model(assignments).
+		Where("submission_id = ?", submissionID).
+		Order("assigned_at").
+		Scan(ctx)
+	return *assignments, err
+}
+
+func (s *StepRepo) GetReviewerAssignments(ctx context.Context, reviewerID uuid.UUID) ([]*models.ReviewAssignment, error) {
+	assignments := new([]*models.ReviewAssignment)
+	err := s.DB.NewSelect().Model(assignments).
+		Relation("Submission").
+		Where("review_assignment.reviewer_id = ?", reviewerID).
+		Where("review_assignment.status = ?", models.ReviewAssigned).
+		Order("review_assignment.assigned_at").
+		Scan(ctx)
+	return *assignments, err
+}
+
+func (s *StepRepo) CompleteReview(ctx context.Context, assignment *models.ReviewAssignment) error {
+	tx, err := s.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	res, err := tx.NewUpdate().Model(assignment).
+		Column("status", "score", "comment").
+		Set("reviewed_at = ?", time.Now()).
+		Where("id = ?", assignment.ID).
+		Where("status = ?", models.ReviewAssigned).
+		Exec(ctx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		tx.Rollback()
+		return conflict("can not complete review %s; it is already done", assignment.ID)
+	}
+	if len(assignment.Criteria) != 0 {
+		err = saveCriterionScores(ctx, tx, assignment.Submission.StepID, assignment.Submission.StaffID,
+			assignment.ID, assignment.Criteria)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
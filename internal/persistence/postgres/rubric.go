@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"github.com/uptrace/bun"
+)
+
+func (s *StepRepo) GetRubric(ctx context.Context, stepID uuid.UUID) ([]*models.RubricCriterion, error) {
+	rubric := new([]*models.RubricCriterion)
+	err := s.DB.NewSelect().Model(rubric).
+		Where("step_id = ?", stepID).
+		Order("position").
+		Scan(ctx)
+	return *rubric, err
+}
+
+func (s *StepRepo) ReplaceRubric(ctx context.Context, stepID uuid.UUID, rubric []*models.RubricCriterion) error {
+	tx, err := s.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	_, err = tx.NewDelete().Model((*models.RubricCriterion)(nil)).Where("step_id = ?", stepID).Exec(ctx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if len(rubric) != 0 {
+		_, err = tx.NewInsert().Model(&rubric).Exec(ctx)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *StepRepo) HasCriterionScores(ctx context.Context, stepID uuid.UUID) (bool, error) {
+	return s.DB.NewSelect().Model((*models.CriterionScore)(nil)).
+		Where("step_id = ?", stepID).
+		Exists(ctx)
+}
+
+// GetCriterionScores returns the final per-criterion result of staff in a step.
+func (s *StepRepo) GetCriterionScores(ctx context.Context, stepID, staffID uuid.UUID) ([]*models.CriterionScore, error) {
+	scores := new([]*models.CriterionScore)
+	err := s.DB.NewSelect().Model(scores).
+		Relation("Criterion").
+		Where("criterion_score.step_id = ?", stepID).
+		Where("criterion_score.staff_id = ?", staffID).
+		Where("criterion_score.review_assignment_id IS NULL").
+		Order("criterion.position").
+		Scan(ctx)
+	return *scores, err
+}
+
+// GetReviewCriterionScores returns per-criterion scores given by the reviewers of a submission.
+func (s *StepRepo) GetReviewCriterionScores(ctx context.Context, submissionID uuid.UUID) ([]*models.CriterionScore, error) {
+	scores := new([]*models.CriterionScore)
+	err := s.DB.NewSelect().Model(scores).
+		Join("JOIN review_assignment ON review_assignment.id = criterion_score.review_assignment_id").
+		Where("review_assignment.submission_id = ?", submissionID).
+		Scan(ctx)
+	return *scores, err
+}
+
+// saveCriterionScores replaces scores of staff in a step given by the review,
+// or the final scores when reviewID is empty.
+func saveCriterionScores(ctx context.Context, db bun.IDB, stepID, staffID, reviewID uuid.UUID,
+	scores []*models.CriterionScore) error {
+	q := db.NewDelete().Model((*models.CriterionScore)(nil)).
+		Where("step_id = ?", stepID).
+		Where("staff_id = ?", staffID)
+	if reviewID == (uuid.UUID{}) {
+		q = q.Where("review_assignment_id IS NULL")
+	} else {
+		q = q.Where("review_assignment_id = ?", reviewID)
+	}
+	if _, err := q.Exec(ctx); err != nil {
+		return err
+	}
+	if len(scores) == 0 {
+		return nil
+	}
+	for _, score := range scores {
+		if score.ID == (uuid.UUID{}) {
+			score.ID = uuid.New()
+		}
+		score.StepID = stepID
+		score.StaffID = staffID
+		score.ReviewAssignmentID = reviewID
+	}
+	_, err := db.NewInsert().Model(&scores).Exec(ctx)
+	return err
+}
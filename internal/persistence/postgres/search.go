@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"github.com/uptrace/bun"
+	"strings"
+)
+
+// searchVisible is the condition of events the caller may see: events they take part in,
+// public events, private events of their organization and team-only events of their team.
+// Drafts are seen by participants only.
+const searchVisible = `(event.id IN (SELECT event_id FROM staff_events WHERE user_id = caller.id)
+	OR (event.event_status != 'draft' AND (event.event_type = 'public'
+		OR (event.event_type = 'private' AND event.organization_id = caller.company_id)
+		OR (event.event_type = 'team-only' AND EXISTS (
+			SELECT 1 FROM staff AS owner WHERE owner.id = event.created_by AND owner.team_id = caller.team_id)))))`
+
+// searchBranches select title and snippet texts of every type, ts_headline is applied
+// to the page only.
+var searchBranches = map[models.SearchType]string{
+	models.SearchEvent: `SELECT 'event' AS type, event.id, NULL::uuid AS event_id, NULL::uuid AS step_id,
+		coalesce(event.name, '') AS title, event.description AS snippet,
+		ts_rank(event.search, search.query) AS rank
+	FROM event, search, caller
+	WHERE event.search @@ search.query AND ` + searchVisible,
+	models.SearchStep: `SELECT 'step', step.id, step.event_id, NULL::uuid,
+		step.name, concat_ws(' ', step.task, step.description),
+		ts_rank(step.search, search.query)
+	FROM step JOIN event ON event.id = step.event_id, search, caller
+	WHERE step.search @@ search.query AND ` + searchVisible,
+	models.SearchPrize: `SELECT 'prize', prize.id, event.id, step.id,
+		prize.name, prize.description,
+		ts_rank(prize.search, search.query)
+	FROM prize
+		LEFT JOIN step ON step.id = prize.step_id
+		LEFT JOIN event ON event.id = step.event_id
+		JOIN staff AS creator ON creator.id = prize.created_by, search, caller
+	WHERE prize.search @@ search.query AND prize.archived_at IS NULL
+		AND ((event.id IS NULL AND creator.company_id = caller.company_id) OR ` + searchVisible + `)`,
+	models.SearchStaff: `SELECT 'staff', staff.id, NULL::uuid, NULL::uuid,
+		staff.first_name || ' ' || staff.last_name, coalesce(team.name, ''),
+		ts_rank(staff.search, search.query)
+	FROM staff LEFT JOIN team ON team.id = staff.team_id, search, caller
+	WHERE staff.search @@ search.query AND staff.company_id = caller.company_id`,
+}
+
+var searchSelectors = fmt.Sprintf(`StartSel="%s", StopSel="%s"`, models.SearchStartSel, models.SearchStopSel)
+
+type SearchRepo struct {
+	DB  *bun.DB
+	ctx context.Context
+}
+
+// Search returns the best ranked results the staff may see. Matched words of titles and snippets
+// are wrapped in models.SearchStartSel and models.SearchStopSel.
+func (s *SearchRepo) Search(ctx context.Context, staffID uuid.UUID, query models.SearchQuery) ([]models.SearchResult, error) {
+	branches := make([]string, 0, len(models.SearchTypes))
+	for _, searchType := range models.SearchTypes {
+		if query.Searches(searchType) {
+			branches = append(branches, searchBranches[searchType])
+		}
+	}
+	sql := fmt.Sprintf(`
+		WITH search AS (SELECT to_tsquery('simple', ?) AS query),
+		caller AS (SELECT id, company_id, team_id FROM staff WHERE id = ?),
+		result AS (%s ORDER BY rank DESC, id LIMIT ?)
+		SELECT result.type, result.id, result.event_id, result.step_id,
+			ts_headline('simple', result.title, search.query, ?) AS title,
+			ts_headline('simple', result.snippet, search.query, ?) AS snippet,
+			result.rank
+		FROM result, search
+		ORDER BY result.rank DESC, result.id`, strings.Join(branches, " UNION ALL "))
+
+	rows, err := s.DB.QueryContext(ctx, sql, query.TSQuery(), staffID, query.Limit,
+		"HighlightAll=true, "+searchSelectors, "MaxFragments=2, MaxWords=20, MinWords=5, "+searchSelectors)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []models.SearchResult
+	err = s.DB.ScanRows(ctx, rows, &results)
+	return results, err
+}
+
+func NewSearchRepo(ctx context.Context, DB *bun.DB) *SearchRepo {
+	return &SearchRepo{DB: DB, ctx: ctx}
+}
@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"github.com/uptrace/bun"
+)
+
+type SeriesRepo struct {
+	DB  *bun.DB
+	ctx context.Context
+}
+
+func (s *SeriesRepo) CreateSeries(ctx context.Context, series *models.EventSeries) error {
+	_, err := s.DB.NewInsert().Model(series).Exec(ctx)
+	return err
+}
+
+func (s *SeriesRepo) GetSeries(ctx context.Context, id uuid.UUID) (*models.EventSeries, error) {
+	series := new(models.EventSeries)
+	err := s.DB.NewSelect().Model(series).Where("id = ?", id).Scan(ctx)
+	return series, err
+}
+
+func (s *SeriesRepo) GetOrganizationSeries(ctx context.Context, orgID uuid.UUID) ([]*models.EventSeries, error) {
+	series := new([]*models.EventSeries)
+	err := s.DB.NewSelect().Model(series).
+		Where("organization_id = ?", orgID).
+		Order("start_date").
+		Scan(ctx)
+	return *series, err
+}
+
+func (s *SeriesRepo) DeleteSeries(ctx context.Context, id uuid.UUID) error {
+	_, err := s.DB.NewDelete().Model((*models.EventSeries)(nil)).Where("id = ?", id).Exec(ctx)
+	return err
+}
+
+func (s *SeriesRepo) GetOccurrences(ctx context.Context, seriesID uuid.UUID) ([]*models.EventOccurrence, error) {
+	occurrences := new([]*models.EventOccurrence)
+	err := s.DB.NewSelect().Model(occurrences).
+		Where("series_id = ?", seriesID).
+		Order("occurrence_index").
+		Scan(ctx)
+	return *occurrences, err
+}
+
+// SaveOccurrence inserts the occurrence or updates the stored one with the same index.
+func (s *SeriesRepo) SaveOccurrence(ctx context.Context, occurrence *models.EventOccurrence) error {
+	_, err := s.DB.NewInsert().Model(occurrence).
+		On("CONFLICT (series_id, occurrence_index) DO UPDATE").
+		Set("start_date = EXCLUDED.start_date").
+		Set("name = EXCLUDED.name").
+		Set("status = EXCLUDED.status").
+		Set("event_id = EXCLUDED.event_id").
+		Exec(ctx)
+	return err
+}
+
+func NewSeriesRepo(ctx context.Context, DB *bun.DB) *SeriesRepo {
+	return &SeriesRepo{DB: DB, ctx: ctx}
+}
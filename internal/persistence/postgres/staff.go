@@ -56,7 +56,7 @@ func (s *StaffRepo) CreateStaffUser(ctx context.Context, staff *models.StaffSign
 	_, err = tx.NewInsert().Model(staff).Exec(ctx)
 	if err != nil {
 		tx.Rollback()
-		return uuid.UUID{}, fmt.Errorf("can not create staff; err: %s", err)
+		return uuid.UUID{}, fmt.Errorf("can not create staff; err: %w", err)
 	}
 	if staff.CurrentImage != "" {
 		var image = &models.StaffImage{
@@ -66,7 +66,7 @@ func (s *StaffRepo) CreateStaffUser(ctx context.Context, staff *models.StaffSign
 		_, err = tx.NewInsert().Model(image).Exec(ctx)
 		if err != nil {
 			tx.Rollback()
-			return uuid.UUID{}, fmt.Errorf(`can not insert image in staff creation; err: %s`, err)
+			return uuid.UUID{}, fmt.Errorf(`can not insert image in staff creation; err: %w`, err)
 		}
 	}
 	return staff.ID, tx.Commit()
@@ -143,19 +143,17 @@ func (s *StaffRepo) GetInvites(ctx context.Context, id uuid.UUID) ([]models.Staf
 	return *invites, err
 }
 
-func (s *StaffRepo) GetStaffPrizes(ctx context.Context, id uuid.UUID) ([]models.Prize, error) {
-	var prizes = new([]models.Prize)
-	var staffPrizes = new([]models.StaffPrize)
-	err := s.DB.NewSelect().Model(staffPrizes).Where("staff_id = ?", id).Scan(ctx)
+func (s *StaffRepo) GetStaffPrizes(ctx context.Context, id uuid.UUID,
+	query models.ListQuery) ([]models.Prize, string, error) {
+	prizes := new([]models.Prize)
+	err := prizeListTable.apply(s.DB.NewSelect().Model(prizes).
+		Where("prize.id IN (SELECT prize_id FROM staff_prizes WHERE staff_id = ?)", id), query).
+		Scan(ctx)
 	if err != nil {
-		return nil, err
-	}
-	ids := make([]uuid.UUID, len(*staffPrizes))
-	for i := range *staffPrizes {
-		ids[i] = (*staffPrizes)[i].PrizeID
+		return nil, "", err
 	}
-	err = s.DB.NewSelect().Model(prizes).Where("id IN (?)", bun.In(ids)).Scan(ctx)
-	return *prizes, err
+	n, next := listPage(query, len(*prizes), func(i int) uuid.UUID { return (*prizes)[i].ID })
+	return (*prizes)[:n], next, nil
 }
 
 func (s *StaffRepo) GetRole(ctx context.Context, id uuid.UUID) (*models.Position, error) {
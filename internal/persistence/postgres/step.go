@@ -16,7 +16,10 @@ type StepRepo struct {
 
 func (s *StepRepo) GetStepPrizes(ctx context.Context, id uuid.UUID) ([]*models.Prize, error) {
 	prizes := new([]*models.Prize)
-	err := s.DB.NewSelect().Model(prizes).Relation("Step").Where("step.id = ?", id).Scan(ctx)
+	err := s.DB.NewSelect().Model(prizes).Relation("Step").
+		Where("step.id = ?", id).
+		Where("prize.archived_at IS NULL").
+		Scan(ctx)
 	return *prizes, err
 }
 
@@ -25,16 +28,35 @@ func (s *StepRepo) AssignStaff(ctx context.Context, staff models.StepStaff) erro
 	return err
 }
 
-func (s *StepRepo) PassStaff(ctx context.Context, staff models.StepStaff) error {
-	_, err := s.DB.NewUpdate().OmitZero().
+func (s *StepRepo) PassStaff(ctx context.Context, staff models.StepStaff, events ...*models.DomainEvent) error {
+	tx, err := s.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	_, err = tx.NewUpdate().OmitZero().
 		Model(&staff).
 		Where("staff_id = ?", staff.StaffID).
 		Where("step_id = ?", staff.StepID).
 		Exec(ctx)
-	return err
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if len(staff.Criteria) != 0 {
+		err = saveCriterionScores(ctx, tx, staff.StepID, staff.StaffID, uuid.UUID{}, staff.Criteria)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := insertDomainEvents(ctx, tx, events); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
 }
 
-func (s *StepRepo) CreateStep(ctx context.Context, step *models.Step) error {
+func (s *StepRepo) CreateStep(ctx context.Context, step *models.Step, events ...*models.DomainEvent) error {
 	tx, err := s.DB.BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return err
@@ -74,6 +96,38 @@ func (s *StepRepo) CreateStep(ctx context.Context, step *models.Step) error {
 			return err
 		}
 	}
+	if len(step.Rubric) != 0 {
+		_, err = tx.NewInsert().Model(&step.Rubric).Exec(ctx)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if len(step.Questions) != 0 {
+		_, err = tx.NewInsert().Model(&step.Questions).Exec(ctx)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if len(step.TestCases) != 0 {
+		_, err = tx.NewInsert().Model(&step.TestCases).Exec(ctx)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if len(step.Prerequisites) != 0 {
+		_, err = tx.NewInsert().Model(&step.Prerequisites).Exec(ctx)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := insertDomainEvents(ctx, tx, events); err != nil {
+		tx.Rollback()
+		return err
+	}
 	return tx.Commit()
 }
 
@@ -81,9 +135,15 @@ func (s *StepRepo) GetStep(ctx context.Context, id uuid.UUID) (*models.Step, err
 	step := new(models.Step)
 	err := s.DB.NewSelect().
 		Model(step).
-		Relation("Prizes").
+		Relation("Prizes", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("prize.archived_at IS NULL")
+		}).
 		Relation("Images").
 		Relation("ActiveStaff").
+		Relation("Rubric", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Order("rubric_criterion.position")
+		}).
+		Relation("Prerequisites").
 		Where("id = ?", id).
 		Scan(ctx)
 	return step, err
@@ -95,12 +155,24 @@ func (s *StepRepo) GetSteps(ctx context.Context, eventID uuid.UUID) ([]*models.S
 	return *steps, err
 }
 
+// ListSteps returns a page of steps of the event, GetSteps returns all of them.
+func (s *StepRepo) ListSteps(ctx context.Context, eventID uuid.UUID, query models.ListQuery) ([]*models.Step, string, error) {
+	steps := new([]*models.Step)
+	err := stepListTable.apply(s.DB.NewSelect().Model(steps).Relation("Images").
+		Where("step.event_id = ?", eventID), query).Scan(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	n, next := listPage(query, len(*steps), func(i int) uuid.UUID { return (*steps)[i].ID })
+	return (*steps)[:n], next, nil
+}
+
 func (s *StepRepo) DeleteStep(ctx context.Context, id uuid.UUID) error {
 	_, err := s.DB.NewDelete().Model(&models.Step{}).Where("id = ?", id).Exec(ctx)
 	return err
 }
 
-func (s *StepRepo) UpdateStep(ctx context.Context, step *models.Step) error {
+func (s *StepRepo) UpdateStep(ctx context.Context, step *models.Step, events ...*models.DomainEvent) error {
 	tx, err := s.DB.BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
 		return err
@@ -126,10 +198,22 @@ func (s *StepRepo) UpdateStep(ctx context.Context, step *models.Step) error {
 			return err
 		}
 	}
-
+	if err := insertDomainEvents(ctx, tx, events); err != nil {
+		tx.Rollback()
+		return err
+	}
 	return tx.Commit()
 }
 
+func (s *StepRepo) GetEventStatus(ctx context.Context, eventID uuid.UUID) (models.EventStatus, error) {
+	var status models.EventStatus
+	err := s.DB.NewSelect().Model((*models.Event)(nil)).
+		Column("event_status").
+		Where("id = ?", eventID).
+		Scan(ctx, &status)
+	return status, err
+}
+
 func NewStepRepo(ctx context.Context, DB *bun.DB) *StepRepo {
 	return &StepRepo{DB: DB, ctx: ctx}
 }
@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+type StreamRepo struct {
+	DB  *bun.DB
+	ctx context.Context
+}
+
+func (s *StreamRepo) CreateStreamMessage(ctx context.Context, message *models.StreamMessage) error {
+	_, err := s.DB.NewInsert().Model(message).Returning("id, created_at").Exec(ctx)
+	return err
+}
+
+// GetStreamMessages returns messages of every scope after the message with afterID, oldest first.
+func (s *StreamRepo) GetStreamMessages(ctx context.Context, afterID int64, limit int) ([]*models.StreamMessage, error) {
+	messages := new([]*models.StreamMessage)
+	err := s.DB.NewSelect().Model(messages).
+		Where("id > ?", afterID).
+		Order("id").
+		Limit(limit).
+		Scan(ctx)
+	return *messages, err
+}
+
+// GetScopeMessages returns messages of the scope after the message with afterID, oldest first.
+func (s *StreamRepo) GetScopeMessages(ctx context.Context, scope models.StreamScope, afterID int64,
+	limit int) ([]*models.StreamMessage, error) {
+	messages := new([]*models.StreamMessage)
+	q := s.DB.NewSelect().Model(messages).
+		Where("id > ?", afterID).
+		Order("id").
+		Limit(limit)
+	if scope.EventID != uuid.Nil {
+		q = q.Where("event_id = ?", scope.EventID)
+	} else {
+		q = q.Where("organization_id = ?", scope.OrganizationID)
+	}
+	err := q.Scan(ctx)
+	return *messages, err
+}
+
+func (s *StreamRepo) GetLastStreamMessageID(ctx context.Context) (int64, error) {
+	var id int64
+	err := s.DB.NewSelect().Model((*models.StreamMessage)(nil)).
+		ColumnExpr("COALESCE(MAX(id), 0)").
+		Scan(ctx, &id)
+	return id, err
+}
+
+func (s *StreamRepo) DeleteStreamMessagesBefore(ctx context.Context, t time.Time) (int64, error) {
+	res, err := s.DB.NewDelete().Model((*models.StreamMessage)(nil)).
+		Where("created_at < ?", t).
+		Exec(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func (s *StreamRepo) GetEventOrganization(ctx context.Context, eventID uuid.UUID) (uuid.UUID, error) {
+	return selectEventOrganization(ctx, s.DB, eventID)
+}
+
+func (s *StreamRepo) GetStepEvent(ctx context.Context, stepID uuid.UUID) (uuid.UUID, error) {
+	return selectStepEvent(ctx, s.DB, stepID)
+}
+
+func selectEventOrganization(ctx context.Context, db bun.IDB, eventID uuid.UUID) (uuid.UUID, error) {
+	var orgID uuid.UUID
+	err := db.NewSelect().Model((*models.Event)(nil)).
+		Column("organization_id").
+		Where("id = ?", eventID).
+		Scan(ctx, &orgID)
+	return orgID, err
+}
+
+func selectStepEvent(ctx context.Context, db bun.IDB, stepID uuid.UUID) (uuid.UUID, error) {
+	var eventID uuid.UUID
+	err := db.NewSelect().Model((*models.Step)(nil)).
+		Column("event_id").
+		Where("id = ?", stepID).
+		Scan(ctx, &eventID)
+	return eventID, err
+}
+
+func NewStreamRepo(ctx context.Context, DB *bun.DB) *StreamRepo {
+	return &StreamRepo{DB: DB, ctx: ctx}
+}
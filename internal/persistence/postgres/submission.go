@@ -0,0 +1,152 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+func (s *StepRepo) GetStepStaff(ctx context.Context, stepID, staffID uuid.UUID) (*models.StepStaff, error) {
+	staffStep := new(models.StepStaff)
+	err := s.DB.NewSelect().Model(staffStep).
+		Relation("Staff").
+		Where("staff_step.step_id = ?", stepID).
+		Where("staff_step.staff_id = ?", staffID).
+		Scan(ctx)
+	return staffStep, err
+}
+
+func (s *StepRepo) CreateSubmission(ctx context.Context, submission *models.Submission) error {
+	tx, err := s.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	_, err = tx.NewInsert().Model(submission).ExcludeColumn("reviewed_at").Exec(ctx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if len(submission.Attachments) != 0 {
+		_, err = tx.NewInsert().Model(&submission.Attachments).Exec(ctx)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if len(submission.Reviews) != 0 {
+		_, err = tx.NewInsert().Model(&submission.Reviews).ExcludeColumn("reviewed_at").Exec(ctx)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	_, err = tx.NewUpdate().Model((*models.StepStaff)(nil)).
+		Set("accomplishment = ?", models.ReadyToCheck).
+		Where("step_id = ?", submission.StepID).
+		Where("staff_id = ?", submission.StaffID).
+		Exec(ctx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *StepRepo) GetSubmission(ctx context.Context, id uuid.UUID) (*models.Submission, error) {
+	submission := new(models.Submission)
+	err := s.DB.NewSelect().Model(submission).
+		Relation("Step").
+		Relation("Staff").
+		Relation("Attachments").
+		Relation("Comments", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Order("submission_comment.created_at")
+		}).
+		Relation("Reviews").
+		Where("step_submission.id = ?", id).
+		Scan(ctx)
+	return submission, err
+}
+
+func (s *StepRepo) GetSubmissionQueue(ctx context.Context, eventID uuid.UUID) ([]*models.Submission, error) {
+	submissions := new([]*models.Submission)
+	err := s.DB.NewSelect().Model(submissions).
+		Relation("Step").
+		Relation("Staff").
+		Relation("Attachments").
+		Where("step.event_id = ?", eventID).
+		Where("step_submission.status = ?", models.SubmissionPending).
+		Order("step_submission.submitted_at").
+		Scan(ctx)
+	return *submissions, err
+}
+
+func (s *StepRepo) GetStaffSubmissions(ctx context.Context, stepID, staffID uuid.UUID) ([]*models.Submission, error) {
+	submissions := new([]*models.Submission)
+	err := s.DB.NewSelect().Model(submissions).
+		Relation("Attachments").
+		Relation("Comments", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Order("submission_comment.created_at")
+		}).
+		Where("step_submission.step_id = ?", stepID).
+		Where("step_submission.staff_id = ?", staffID).
+		Order("step_submission.submitted_at DESC").
+		Scan(ctx)
+	return *submissions, err
+}
+
+func (s *StepRepo) ReviewSubmission(ctx context.Context, submission *models.Submission,
+	accomplishment models.Accomplishment, comment *models.SubmissionComment) error {
+	tx, err := s.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+	res, err := tx.NewUpdate().Model(submission).
+		Column("status", "score", "reviewer_id").
+		Set("reviewed_at = ?", time.Now()).
+		Where("id = ?", submission.ID).
+		Where("status = ?", models.SubmissionPending).
+		Exec(ctx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		tx.Rollback()
+		return conflict("can not review submission %s; it is already reviewed", submission.ID)
+	}
+	q := tx.NewUpdate().Model((*models.StepStaff)(nil)).
+		Set("accomplishment = ?", accomplishment).
+		Where("step_id = ?", submission.StepID).
+		Where("staff_id = ?", submission.StaffID)
+	if submission.Status == models.SubmissionApproved {
+		q = q.Set("score = ?", submission.Score)
+	}
+	_, err = q.Exec(ctx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if len(submission.Criteria) != 0 {
+		err = saveCriterionScores(ctx, tx, submission.StepID, submission.StaffID, uuid.UUID{}, submission.Criteria)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if comment != nil {
+		_, err = tx.NewInsert().Model(comment).Exec(ctx)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *StepRepo) AddSubmissionComment(ctx context.Context, comment *models.SubmissionComment) error {
+	_, err := s.DB.NewInsert().Model(comment).Exec(ctx)
+	return err
+}
@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"github.com/uptrace/bun"
+)
+
+type TemplateRepo struct {
+	DB  *bun.DB
+	ctx context.Context
+}
+
+func (t *TemplateRepo) CreateTemplate(ctx context.Context, template *models.EventTemplate) error {
+	_, err := t.DB.NewInsert().Model(template).Exec(ctx)
+	return err
+}
+
+func (t *TemplateRepo) GetTemplate(ctx context.Context, id uuid.UUID) (*models.EventTemplate, error) {
+	template := new(models.EventTemplate)
+	err := t.DB.NewSelect().Model(template).Where("id = ?", id).Scan(ctx)
+	return template, err
+}
+
+func (t *TemplateRepo) GetTemplates(ctx context.Context, orgID uuid.UUID) ([]*models.EventTemplate, error) {
+	templates := new([]*models.EventTemplate)
+	err := t.DB.NewSelect().Model(templates).
+		Where("organization_id = ?", orgID).
+		Order("created_at DESC").
+		Scan(ctx)
+	return *templates, err
+}
+
+func (t *TemplateRepo) DeleteTemplate(ctx context.Context, id uuid.UUID) error {
+	_, err := t.DB.NewDelete().Model((*models.EventTemplate)(nil)).Where("id = ?", id).Exec(ctx)
+	return err
+}
+
+func NewTemplateRepo(ctx context.Context, DB *bun.DB) *TemplateRepo {
+	return &TemplateRepo{DB: DB, ctx: ctx}
+}
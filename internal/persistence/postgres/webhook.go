@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"github.com/uptrace/bun"
+	"time"
+)
+
+type WebhookRepo struct {
+	DB  *bun.DB
+	ctx context.Context
+}
+
+func (w *WebhookRepo) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	_, err := w.DB.NewInsert().Model(webhook).ExcludeColumn("disabled_at").Exec(ctx)
+	return err
+}
+
+func (w *WebhookRepo) GetWebhook(ctx context.Context, id uuid.UUID) (*models.Webhook, error) {
+	webhook := new(models.Webhook)
+	err := w.DB.NewSelect().Model(webhook).Where("id = ?", id).Scan(ctx)
+	return webhook, err
+}
+
+func (w *WebhookRepo) GetWebhooks(ctx context.Context, orgID uuid.UUID) ([]*models.Webhook, error) {
+	webhooks := new([]*models.Webhook)
+	err := w.DB.NewSelect().Model(webhooks).
+		Where("organization_id = ?", orgID).
+		Order("created_at").
+		Scan(ctx)
+	return *webhooks, err
+}
+
+// GetSubscribedWebhooks returns enabled webhooks of the organization subscribed to the event type.
+func (w *WebhookRepo) GetSubscribedWebhooks(ctx context.Context, orgID uuid.UUID,
+	eventType models.WebhookEventType) ([]*models.Webhook, error) {
+	webhooks := new([]*models.Webhook)
+	err := w.DB.NewSelect().Model(webhooks).
+		Where("organization_id = ?", orgID).
+		Where("enabled").
+		Where("? = ANY(events)", eventType).
+		Scan(ctx)
+	return *webhooks, err
+}
+
+func (w *WebhookRepo) UpdateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	_, err := w.DB.NewUpdate().Model(webhook).
+		Column("url", "events", "enabled", "failure_count", "disabled_at").
+		Where("id = ?", webhook.ID).
+		Exec(ctx)
+	return err
+}
+
+func (w *WebhookRepo) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	_, err := w.DB.NewDelete().Model((*models.Webhook)(nil)).Where("id = ?", id).Exec(ctx)
+	return err
+}
+
+// RecordWebhookResult resets failures of the webhook after a delivered attempt, otherwise
+// counts the failure and disables the webhook when it has failed disableAfter times in a row.
+// It only ever disables the webhook, a webhook disabled meanwhile stays disabled.
+// It returns whether the webhook is still enabled.
+func (w *WebhookRepo) RecordWebhookResult(ctx context.Context, id uuid.UUID, delivered bool,
+	disableAfter uint) (bool, error) {
+	q := w.DB.NewUpdate().Model((*models.Webhook)(nil)).Where("id = ?", id)
+	if delivered {
+		q = q.Set("failure_count = 0")
+	} else {
+		q = q.Set("failure_count = failure_count + 1").
+			Set("enabled = enabled AND failure_count + 1 < ?", disableAfter).
+			Set("disabled_at = CASE WHEN enabled AND failure_count + 1 >= ? THEN ? ELSE disabled_at END",
+				disableAfter, time.Now())
+	}
+	var enabled bool
+	_, err := q.Returning("enabled").Exec(ctx, &enabled)
+	return enabled, err
+}
+
+// CreateDeliveries skips first deliveries of an event the webhook already has,
+// so a domain event handed off again is not delivered twice. Redeliveries are always created.
+func (w *WebhookRepo) CreateDeliveries(ctx context.Context, deliveries []*models.WebhookDelivery) error {
+	_, err := w.DB.NewInsert().Model(&deliveries).ExcludeColumn("delivered_at").
+		On("CONFLICT (webhook_id, event_id) WHERE NOT redelivery DO NOTHING").
+		Exec(ctx)
+	return err
+}
+
+func (w *WebhookRepo) GetDelivery(ctx context.Context, id uuid.UUID) (*models.WebhookDelivery, error) {
+	delivery := new(models.WebhookDelivery)
+	err := w.DB.NewSelect().Model(delivery).Where("id = ?", id).Scan(ctx)
+	return delivery, err
+}
+
+// GetDeliveries returns the newest deliveries of the webhook first.
+func (w *WebhookRepo) GetDeliveries(ctx context.Context, webhookID uuid.UUID, limit int) ([]*models.WebhookDelivery, error) {
+	deliveries := new([]*models.WebhookDelivery)
+	err := w.DB.NewSelect().Model(deliveries).
+		Where("webhook_id = ?", webhookID).
+		Order("created_at DESC").
+		Limit(limit).
+		Scan(ctx)
+	return *deliveries, err
+}
+
+// ClaimDueDeliveries leases due pending deliveries of enabled webhooks and returns them.
+// A claimed delivery is not due again until the lease ends, so an instance that stops
+// while sending it leaves it to be sent again, and two instances never send it at once.
+func (w *WebhookRepo) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+	deliveries := new([]*models.WebhookDelivery)
+	now := time.Now()
+	due := w.DB.NewSelect().Model((*models.WebhookDelivery)(nil)).
+		Column("webhook_delivery.id").
+		Join("JOIN webhook ON webhook.id = webhook_delivery.webhook_id").
+		Where("webhook_delivery.status = ?", models.DeliveryPending).
+		Where("webhook_delivery.next_attempt_at <= ?", now).
+		Where("webhook.enabled").
+		Order("webhook_delivery.next_attempt_at").
+		Limit(limit).
+		For("UPDATE OF webhook_delivery SKIP LOCKED")
+	_, err := w.DB.NewUpdate().Model((*models.WebhookDelivery)(nil)).
+		Set("next_attempt_at = ?", now.Add(lease)).
+		Set("attempts = attempts + 1").
+		Where("id IN (?)", due).
+		Returning("*").
+		Exec(ctx, deliveries)
+	return *deliveries, err
+}
+
+func (w *WebhookRepo) FinishDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	_, err := w.DB.NewUpdate().Model(delivery).
+		Column("status", "next_attempt_at", "response_code", "last_error", "delivered_at").
+		Where("id = ?", delivery.ID).
+		Exec(ctx)
+	return err
+}
+
+func NewWebhookRepo(ctx context.Context, DB *bun.DB) *WebhookRepo {
+	return &WebhookRepo{DB: DB, ctx: ctx}
+}
@@ -0,0 +1,68 @@
+//go:build linux
+
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+)
+
+// limitedCommand starts the program through sh with ulimit so the CPU time, the data segment,
+// the address space, written files and, for a sandbox user, processes are limited by the kernel.
+// The process gets its own group, so everything it spawned is killed with it.
+func limitedCommand(ctx context.Context, limits Limits, sandbox Sandbox, name string, args ...string) *exec.Cmd {
+	script := ""
+	if limits.Time != 0 {
+		script += fmt.Sprintf("ulimit -t %d; ", int(math.Ceil(limits.Time.Seconds())))
+	}
+	if limits.Memory != 0 {
+		script += fmt.Sprintf("ulimit -d %d; ulimit -v %d; ", limits.Memory>>10, limits.Memory>>10)
+	}
+	if sandbox.FileSize != 0 {
+		script += fmt.Sprintf("ulimit -f %d; ", sandbox.FileSize>>10)
+	}
+	if sandbox.UID != 0 && sandbox.Processes != 0 {
+		script += fmt.Sprintf("ulimit -u %d; ", sandbox.Processes)
+	}
+	script += `exec "$@"`
+	cmd := exec.CommandContext(ctx, "/bin/sh", append([]string{"-c", script, "sh", name}, args...)...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if sandbox.UID != 0 {
+		cmd.SysProcAttr.Credential = &syscall.Credential{Uid: sandbox.UID, Gid: sandbox.GID}
+	}
+	return cmd
+}
+
+// sandboxDir gives the work directory to the sandbox user.
+func sandboxDir(dir string, sandbox Sandbox) error {
+	if sandbox.UID == 0 {
+		return nil
+	}
+	return filepath.Walk(dir, func(path string, _ os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(path, int(sandbox.UID), int(sandbox.GID))
+	})
+}
+
+func killGroup(cmd *exec.Cmd) {
+	if cmd.Process != nil {
+		_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+func cpuLimitExceeded(err error) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled() && status.Signal() == syscall.SIGXCPU
+}
@@ -0,0 +1,28 @@
+//go:build !linux
+
+package runner
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+)
+
+// limitedCommand applies only the wall time limit through the context, memory is not limited.
+func limitedCommand(ctx context.Context, _ Limits, _ Sandbox, name string, args ...string) *exec.Cmd {
+	return exec.CommandContext(ctx, name, args...)
+}
+
+// sandboxDir refuses a sandbox user, programs can be run as another user only on linux.
+func sandboxDir(_ string, sandbox Sandbox) error {
+	if sandbox.UID != 0 {
+		return errors.New("can not run programs as another user on this OS")
+	}
+	return nil
+}
+
+func killGroup(*exec.Cmd) {}
+
+func cpuLimitExceeded(error) bool {
+	return false
+}
@@ -0,0 +1,180 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	compileTimeout = 30 * time.Second
+	maxOutput      = 64 << 10
+)
+
+// Language describes how to build and run a source file. Commands run inside the work directory.
+// Env is added to the environment of commands, $WORK in it is the work directory.
+// Reserve is address space the runtime reserves up front, it is added to the memory limit
+// of the address space so programs are not killed before they start.
+type Language struct {
+	File    string
+	Compile []string
+	Run     []string
+	Env     []string
+	Reserve uint64
+}
+
+var DefaultLanguages = map[string]Language{
+	"python": {File: "main.py", Run: []string{"python3", "main.py"}, Reserve: 64 << 20},
+	"go": {File: "main.go", Compile: []string{"go", "build", "-o", "main", "main.go"},
+		Run: []string{"./main"}, Env: []string{"GOCACHE=$WORK/.cache", "GOPATH=$WORK/.go", "GOTOOLCHAIN=local"},
+		Reserve: 1 << 30},
+	"c":          {File: "main.c", Compile: []string{"gcc", "-O2", "-o", "main", "main.c"}, Run: []string{"./main"}},
+	"cpp":        {File: "main.cpp", Compile: []string{"g++", "-O2", "-o", "main", "main.cpp"}, Run: []string{"./main"}},
+	"javascript": {File: "main.js", Run: []string{"node", "main.js"}, Reserve: 16 << 30},
+}
+
+// LocalRunner runs programs as local processes in a temporary directory.
+// Limits are enforced by the OS where it is supported, see limitedCommand.
+type LocalRunner struct {
+	workDir   string
+	languages map[string]Language
+	sandbox   Sandbox
+}
+
+// NewLocalRunner creates a runner with DefaultLanguages when languages is nil.
+// Empty workDir means the OS temporary directory.
+func NewLocalRunner(workDir string, languages map[string]Language, sandbox Sandbox) *LocalRunner {
+	if languages == nil {
+		languages = DefaultLanguages
+	}
+	return &LocalRunner{workDir: workDir, languages: languages, sandbox: sandbox}
+}
+
+func (r *LocalRunner) Languages() []string {
+	names := make([]string, 0, len(r.languages))
+	for name := range r.languages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (r *LocalRunner) Run(ctx context.Context, program Program, inputs []string, limits Limits) ([]Result, error) {
+	lang, ok := r.languages[program.Language]
+	if !ok {
+		return nil, fmt.Errorf("unsupported language: %s", program.Language)
+	}
+	dir, err := os.MkdirTemp(r.workDir, "run-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	if err := os.WriteFile(filepath.Join(dir, lang.File), []byte(program.Source), 0o600); err != nil {
+		return nil, err
+	}
+	if err := sandboxDir(dir, r.sandbox); err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, len(inputs))
+	if len(lang.Compile) != 0 {
+		res := r.exec(ctx, dir, lang, lang.Compile, "", Limits{Time: compileTimeout})
+		if res.Status != OK {
+			for i := range results {
+				results[i] = Result{Status: CompileError, Error: res.Error}
+			}
+			return results, nil
+		}
+	}
+	for i, input := range inputs {
+		results[i] = r.exec(ctx, dir, lang, lang.Run, input, limits)
+	}
+	return results, nil
+}
+
+func (r *LocalRunner) exec(ctx context.Context, dir string, lang Language, command []string, input string,
+	limits Limits) Result {
+	if limits.Time != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, limits.Time)
+		defer cancel()
+	}
+	if limits.Memory != 0 {
+		limits.Memory += lang.Reserve
+	}
+	cmd := limitedCommand(ctx, limits, r.sandbox, command[0], command[1:]...)
+	cmd.Dir = dir
+	cmd.Env = r.env(dir, lang)
+	cmd.Stdin = strings.NewReader(input)
+	stdout, stderr := &limitedBuffer{max: maxOutput}, &limitedBuffer{max: maxOutput}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+
+	start := time.Now()
+	err := cmd.Run()
+	killGroup(cmd)
+	res := Result{Output: stdout.String(), Error: stderr.String(), Duration: time.Since(start)}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		res.Status = OK
+	case ctx.Err() == context.DeadlineExceeded || cpuLimitExceeded(err):
+		res.Status = TimeLimit
+	case errors.As(err, &exitErr) && outOfMemory(res.Error):
+		res.Status = MemoryLimit
+	default:
+		res.Status = RuntimeError
+		if res.Error == "" {
+			res.Error = err.Error()
+		}
+	}
+	return res
+}
+
+// env is the whole environment of a command, nothing of the server environment is passed.
+func (r *LocalRunner) env(dir string, lang Language) []string {
+	env := []string{"PATH=" + r.sandbox.Path, "HOME=" + dir, "TMPDIR=" + dir, "LANG=C.UTF-8"}
+	for _, v := range lang.Env {
+		env = append(env, os.Expand(v, func(name string) string {
+			if name == "WORK" {
+				return dir
+			}
+			return ""
+		}))
+	}
+	return env
+}
+
+func outOfMemory(stderr string) bool {
+	stderr = strings.ToLower(stderr)
+	return strings.Contains(stderr, "out of memory") || strings.Contains(stderr, "memoryerror") ||
+		strings.Contains(stderr, "cannot allocate memory") || strings.Contains(stderr, "bad_alloc")
+}
+
+// limitedBuffer drops output after max bytes so a program can not exhaust server memory.
+type limitedBuffer struct {
+	buf bytes.Buffer
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if left := b.max - b.buf.Len(); left > 0 {
+		if len(p) > left {
+			b.buf.Write(p[:left])
+		} else {
+			b.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
@@ -0,0 +1,60 @@
+// Package runner runs untrusted programs of code challenge steps against test inputs.
+package runner
+
+import (
+	"context"
+	"time"
+)
+
+type Status string
+
+const (
+	OK           Status = "ok"
+	TimeLimit    Status = "time-limit"
+	MemoryLimit  Status = "memory-limit"
+	RuntimeError Status = "runtime-error"
+	CompileError Status = "compile-error"
+)
+
+type Program struct {
+	Language string
+	Source   string
+}
+
+// Limits are applied to every run of a program. Zero value means no limit.
+type Limits struct {
+	Time   time.Duration
+	Memory uint64
+}
+
+// Sandbox is how the local runner isolates programs from the server. Programs get only the
+// environment below, Path and the variables of their language. Processes and FileSize are
+// limited with ulimit. With UID set, programs run as that user; the server must run as root then.
+// The kernel counts all processes of a user, so Processes is applied only with UID.
+type Sandbox struct {
+	Path      string
+	UID       uint32
+	GID       uint32
+	Processes uint64
+	FileSize  uint64
+}
+
+var DefaultSandbox = Sandbox{
+	Path:      "/usr/local/go/bin:/usr/local/bin:/usr/bin:/bin",
+	Processes: 64,
+	FileSize:  64 << 20,
+}
+
+type Result struct {
+	Status   Status
+	Output   string
+	Error    string
+	Duration time.Duration
+}
+
+// Runner compiles a program once and runs it for every input.
+// The returned results are in the order of inputs; an error means the runner itself failed.
+type Runner interface {
+	Languages() []string
+	Run(ctx context.Context, program Program, inputs []string, limits Limits) ([]Result, error)
+}
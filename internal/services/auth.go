@@ -37,7 +37,7 @@ func (s *AuthService) GenerateToken(email, password string) (string, uuid.UUID,
 		return "", uuid.UUID{}, uuid.UUID{}, err
 	}
 	if staff == nil {
-		return "", uuid.UUID{}, uuid.UUID{}, fmt.Errorf("no such user")
+		return "", uuid.UUID{}, uuid.UUID{}, NotFound("no such user")
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, TokenClaims{
 		jwt.StandardClaims{
@@ -0,0 +1,392 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/chatops"
+	"github.com/miprokop/fication/internal/models"
+	"github.com/miprokop/fication/internal/persistence/postgres"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// chatLeaderboardSize is the number of staff in leaderboards posted when an event is finished.
+const chatLeaderboardSize = 5
+
+const chatHelp = `Commands:
+score - your score in running events
+kudos @user message - thank a colleague
+events - running events you participate in`
+
+// ChatService runs slash commands of chat teams and posts prize awards and leaderboards
+// to their incoming webhooks. Chat users are staff of the organization with the same email.
+type ChatService struct {
+	repo     postgres.Chat
+	events   postgres.Event
+	notify   *NotificationService
+	adapters map[models.ChatPlatform]chatops.Adapter
+	client   *http.Client
+	ctx      context.Context
+}
+
+func (c *ChatService) CreateChatIntegration(ctx context.Context, orgID, staffID uuid.UUID,
+	request models.ChatIntegrationRequest) (*models.ChatIntegration, error) {
+	platform, err := models.NewChatPlatform(string(request.Platform))
+	if err != nil {
+		return nil, InvalidField("platform", "%s", err)
+	}
+	integration := &models.ChatIntegration{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		Platform:       platform,
+		Enabled:        true,
+		CreatedByID:    staffID,
+	}
+	applyChatRequest(integration, request)
+	if err := checkChatIntegration(integration); err != nil {
+		return nil, err
+	}
+	if err := c.repo.CreateChatIntegration(ctx, integration); err != nil {
+		return nil, err
+	}
+	return integration, nil
+}
+
+// GetChatIntegration returns the integration when it belongs to the organization.
+func (c *ChatService) GetChatIntegration(ctx context.Context, id, orgID uuid.UUID) (*models.ChatIntegration, error) {
+	integration, err := c.repo.GetChatIntegration(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if integration.OrganizationID != orgID {
+		return nil, NotFound("chat integration %s is not in organization %s", id, orgID)
+	}
+	return integration, nil
+}
+
+func (c *ChatService) GetChatIntegrations(ctx context.Context, orgID uuid.UUID) ([]*models.ChatIntegration, error) {
+	return c.repo.GetChatIntegrations(ctx, orgID)
+}
+
+func (c *ChatService) UpdateChatIntegration(ctx context.Context, id, orgID uuid.UUID,
+	request models.ChatIntegrationRequest) (*models.ChatIntegration, error) {
+	integration, err := c.GetChatIntegration(ctx, id, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if request.Platform != "" && request.Platform != integration.Platform {
+		return nil, InvalidField("platform", "can not change chat platform from %s to %s", integration.Platform, request.Platform)
+	}
+	applyChatRequest(integration, request)
+	if err := checkChatIntegration(integration); err != nil {
+		return nil, err
+	}
+	if err := c.repo.UpdateChatIntegration(ctx, integration); err != nil {
+		return nil, err
+	}
+	return integration, nil
+}
+
+func (c *ChatService) DeleteChatIntegration(ctx context.Context, id, orgID uuid.UUID) error {
+	if _, err := c.GetChatIntegration(ctx, id, orgID); err != nil {
+		return err
+	}
+	return c.repo.DeleteChatIntegration(ctx, id)
+}
+
+// VerifyCommand reads a slash command and checks that it is sent by a team with an enabled integration.
+func (c *ChatService) VerifyCommand(ctx context.Context, platform models.ChatPlatform, header http.Header,
+	body []byte) (*models.ChatIntegration, *models.ChatCommand, error) {
+	adapter, err := c.adapter(platform)
+	if err != nil {
+		return nil, nil, err
+	}
+	command, err := adapter.ParseCommand(body)
+	if err != nil {
+		return nil, nil, Validation("%s", err)
+	}
+	integration, err := c.repo.GetTeamIntegration(ctx, platform, command.TeamID)
+	if err != nil {
+		return nil, nil, notFound(err, "no integration of %s team %s", platform, command.TeamID)
+	}
+	if !integration.Enabled {
+		return nil, nil, Forbidden("integration of %s team %s is disabled", platform, command.TeamID)
+	}
+	if err := adapter.Verify(header, body, integration.SigningSecret, time.Now()); err != nil {
+		return nil, nil, Forbidden("%s", err)
+	}
+	return integration, command, nil
+}
+
+// RunCommand runs a verified slash command. Failures are answered to the chat user, not returned.
+func (c *ChatService) RunCommand(ctx context.Context, integration *models.ChatIntegration,
+	command *models.ChatCommand) models.ChatResponse {
+	adapter, err := c.adapter(integration.Platform)
+	if err != nil {
+		return chatError(err)
+	}
+	staff, err := c.chatStaff(ctx, adapter, integration, command.User)
+	if err != nil {
+		return chatError(err)
+	}
+	args := strings.Fields(command.Text)
+	if len(args) == 0 {
+		return models.ChatResponse{Text: chatHelp, Ephemeral: true}
+	}
+	switch strings.ToLower(args[0]) {
+	case "score":
+		return c.score(ctx, staff)
+	case "kudos":
+		return c.kudos(ctx, adapter, integration, staff, args[1:])
+	case "events":
+		return c.runningEvents(ctx, staff)
+	}
+	return models.ChatResponse{Text: fmt.Sprintf("unknown command %q\n%s", args[0], chatHelp), Ephemeral: true}
+}
+
+func (c *ChatService) EncodeResponse(platform models.ChatPlatform, response models.ChatResponse) ([]byte, error) {
+	adapter, err := c.adapter(platform)
+	if err != nil {
+		return nil, err
+	}
+	return adapter.EncodeResponse(response)
+}
+
+func (c *ChatService) score(ctx context.Context, staff *models.Staff) models.ChatResponse {
+	events, err := c.repo.GetRunningEvents(ctx, staff.ID)
+	if err != nil {
+		return chatError(err)
+	}
+	if len(events) == 0 {
+		return models.ChatResponse{Text: "you do not participate in running events", Ephemeral: true}
+	}
+	lines := make([]string, len(events))
+	for i, event := range events {
+		score, err := c.events.GetStaffScore(ctx, event.ID, staff.ID)
+		if err != nil {
+			return chatError(err)
+		}
+		lines[i] = fmt.Sprintf("%s: %d", event.Name, score.Score)
+	}
+	return models.ChatResponse{Text: "Your score:\n" + strings.Join(lines, "\n"), Ephemeral: true}
+}
+
+func (c *ChatService) runningEvents(ctx context.Context, staff *models.Staff) models.ChatResponse {
+	events, err := c.repo.GetRunningEvents(ctx, staff.ID)
+	if err != nil {
+		return chatError(err)
+	}
+	if len(events) == 0 {
+		return models.ChatResponse{Text: "you do not participate in running events", Ephemeral: true}
+	}
+	lines := make([]string, len(events))
+	for i, event := range events {
+		lines[i] = fmt.Sprintf("%s, till %s", event.Name, event.EndDate)
+	}
+	return models.ChatResponse{Text: "Running events:\n" + strings.Join(lines, "\n"), Ephemeral: true}
+}
+
+// kudos notifies the mentioned staff and thanks it in the channel.
+func (c *ChatService) kudos(ctx context.Context, adapter chatops.Adapter, integration *models.ChatIntegration,
+	staff *models.Staff, args []string) models.ChatResponse {
+	if len(args) == 0 {
+		return models.ChatResponse{Text: "usage: kudos @user message", Ephemeral: true}
+	}
+	mention, ok := adapter.ParseMention(args[0])
+	if !ok {
+		return models.ChatResponse{Text: fmt.Sprintf("%s is not a mention of a user", args[0]), Ephemeral: true}
+	}
+	receiver, err := c.chatStaff(ctx, adapter, integration, mention)
+	if err != nil {
+		return chatError(err)
+	}
+	if receiver.ID == staff.ID {
+		return models.ChatResponse{Text: "you can not give kudos to yourself", Ephemeral: true}
+	}
+	message := fmt.Sprintf("%s %s gave kudos to %s %s", staff.FirstName, staff.LastName,
+		receiver.FirstName, receiver.LastName)
+	if reason := strings.Join(args[1:], " "); reason != "" {
+		message += ": " + reason
+	}
+	c.notify.Notify(ctx, models.Notification{
+		NotificationType: models.NotifyKudos,
+		Message:          message,
+	}, receiver.ID)
+	return models.ChatResponse{Text: message}
+}
+
+// chatStaff finds staff of the organization of the integration with the email of the chat user.
+func (c *ChatService) chatStaff(ctx context.Context, adapter chatops.Adapter, integration *models.ChatIntegration,
+	user models.ChatUser) (*models.Staff, error) {
+	email, err := adapter.UserEmail(ctx, integration, user)
+	if err != nil {
+		return nil, err
+	}
+	staff, err := c.repo.GetStaffByEmail(ctx, integration.OrganizationID, email)
+	if err != nil {
+		return nil, NotFound("can not find staff with email %s", email)
+	}
+	return staff, nil
+}
+
+// HandleDomainEvent posts prize awards and leaderboards of finished events to chat teams of the organization.
+// A domain event is posted to every team once: teams already posted to stay claimed, so when a post
+// fails the domain event is handed off again and posted only to teams that did not get it.
+func (c *ChatService) HandleDomainEvent(ctx context.Context, event *models.DomainEvent) error {
+	var text string
+	switch event.EventType {
+	case models.DomainPrizeAwarded:
+		var prize models.PrizeData
+		if err := json.Unmarshal(event.Data, &prize); err != nil {
+			return err
+		}
+		staff, err := c.events.GetStaff(ctx, prize.StaffID)
+		if err != nil {
+			return err
+		}
+		text = fmt.Sprintf("%s %s is awarded prize %s", staff.FirstName, staff.LastName, prize.Name)
+	case models.DomainEventFinished:
+		var finished models.EventData
+		if err := json.Unmarshal(event.Data, &finished); err != nil {
+			return err
+		}
+		leaderboard, err := c.repo.GetEventLeaderboard(ctx, finished.EventID, chatLeaderboardSize)
+		if err != nil {
+			return err
+		}
+		if len(leaderboard) == 0 {
+			return nil
+		}
+		lines := make([]string, len(leaderboard))
+		for i, entry := range leaderboard {
+			lines[i] = fmt.Sprintf("%d. %s %s - %d", i+1, entry.FirstName, entry.LastName, entry.Score)
+		}
+		text = fmt.Sprintf("Event %s is finished. Leaderboard:\n%s", finished.Name, strings.Join(lines, "\n"))
+	default:
+		return nil
+	}
+
+	integrations, err := c.repo.GetChatIntegrations(ctx, event.OrganizationID)
+	if err != nil {
+		return err
+	}
+	var failed error
+	for _, integration := range integrations {
+		if !integration.Enabled || integration.WebhookURL == "" {
+			continue
+		}
+		claimed, err := c.repo.ClaimChatPost(ctx, integration.ID, event.ID)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+		if err := c.post(ctx, integration, text); err != nil {
+			log.WithFields(log.Fields{"integration": integration.ID, "platform": integration.Platform}).
+				Errorf("can not post chat message: %s", err)
+			if err := c.repo.ReleaseChatPost(ctx, integration.ID, event.ID); err != nil {
+				log.Error(err)
+			}
+			failed = err
+		}
+	}
+	return failed
+}
+
+// post sends a message to the incoming webhook of the integration.
+func (c *ChatService) post(ctx context.Context, integration *models.ChatIntegration, text string) error {
+	adapter, err := c.adapter(integration.Platform)
+	if err != nil {
+		return err
+	}
+	body, err := adapter.EncodeMessage(text)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, integration.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("chat webhook responded %d: %s", resp.StatusCode, body)
+	}
+	return nil
+}
+
+func (c *ChatService) adapter(platform models.ChatPlatform) (chatops.Adapter, error) {
+	adapter, ok := c.adapters[platform]
+	if !ok {
+		return nil, InvalidField("platform", "incorrent chat platform: %s", platform)
+	}
+	return adapter, nil
+}
+
+func chatError(err error) models.ChatResponse {
+	return models.ChatResponse{Text: err.Error(), Ephemeral: true}
+}
+
+func applyChatRequest(integration *models.ChatIntegration, request models.ChatIntegrationRequest) {
+	if request.TeamID != "" {
+		integration.TeamID = request.TeamID
+	}
+	if request.WebhookURL != "" {
+		integration.WebhookURL = request.WebhookURL
+	}
+	if request.ServerURL != "" {
+		integration.ServerURL = request.ServerURL
+	}
+	if request.SigningSecret != "" {
+		integration.SigningSecret = request.SigningSecret
+	}
+	if request.BotToken != "" {
+		integration.BotToken = request.BotToken
+	}
+	if request.Enabled != nil {
+		integration.Enabled = *request.Enabled
+	}
+}
+
+func checkChatIntegration(integration *models.ChatIntegration) error {
+	if integration.TeamID == "" || integration.SigningSecret == "" {
+		return Validation("can not save chat integration without team_id and signing_secret")
+	}
+	if integration.Platform == models.ChatMattermost && integration.ServerURL == "" {
+		return InvalidField("server_url", "can not save mattermost integration without server_url")
+	}
+	for _, address := range []string{integration.WebhookURL, integration.ServerURL} {
+		if address == "" {
+			continue
+		}
+		u, err := url.Parse(address)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return Validation("incorrent url: %s; want an http or https url", address)
+		}
+	}
+	return nil
+}
+
+// NewChatService creates a chat service with adapters of every supported platform,
+// client posts messages to incoming webhooks.
+func NewChatService(ctx context.Context, repo postgres.Chat, events postgres.Event, notify *NotificationService,
+	client *http.Client, adapters ...chatops.Adapter) *ChatService {
+	byPlatform := make(map[models.ChatPlatform]chatops.Adapter, len(adapters))
+	for _, adapter := range adapters {
+		byPlatform[adapter.Platform()] = adapter
+	}
+	return &ChatService{repo: repo, events: events, notify: notify, adapters: byPlatform, client: client, ctx: ctx}
+}
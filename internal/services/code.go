@@ -0,0 +1,382 @@
+package services
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"github.com/miprokop/fication/internal/runner"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRunTimeLimit = 2 * time.Second
+	defaultMemoryLimit  = 256
+	maxSourceSize       = 64 << 10
+	// codeQueueSize is how many submissions wait for a free worker, per worker.
+	codeQueueSize = 16
+)
+
+// codeRun is a submission waiting in the queue of code workers.
+type codeRun struct {
+	step       *models.Step
+	submission *models.CodeSubmission
+	tests      []*models.CodeTestCase
+	previous   []*models.CodeSubmission
+}
+
+// prepareCode checks test cases of a code step.
+// A step without max score gets the total score of its test cases.
+func (s *StepService) prepareCode(step *models.Step) error {
+	if step.StepType != models.CodeStep {
+		if len(step.TestCases) != 0 {
+			return Validation("can not add test cases to %s step", step.StepType)
+		}
+		return nil
+	}
+	if len(step.Rubric) != 0 {
+		return Validation("can not add rubric to code step; code is graded by test cases")
+	}
+	for _, language := range step.CodeLanguages {
+		if !s.supportsLanguage(language) {
+			return InvalidField("code_languages", "unsupported language: %s; want one of: %s", language,
+				strings.Join(s.runner.Languages(), ", "))
+		}
+	}
+	var total uint
+	for i, test := range step.TestCases {
+		if test.Score == 0 {
+			test.Score = 1
+		}
+		if test.ID == (uuid.UUID{}) {
+			test.ID = uuid.New()
+		}
+		test.StepID = step.ID
+		test.Position = uint(i)
+		total += test.Score
+	}
+	if step.MaxScore == 0 {
+		step.MaxScore = total
+	}
+	if step.PassScore > step.MaxScore {
+		return InvalidField("pass_score", "can not create code step; pass score %d is more than max score %d", step.PassScore, step.MaxScore)
+	}
+	return nil
+}
+
+func (s *StepService) supportsLanguage(language string) bool {
+	for _, supported := range s.runner.Languages() {
+		if supported == language {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *StepService) GetCodeLanguages() []string {
+	return s.runner.Languages()
+}
+
+// SubmitCode stores the source and runs it against step test cases in background.
+// The result is available in code submissions of staff when the run is finished.
+func (s *StepService) SubmitCode(ctx context.Context, stepID, staffID uuid.UUID,
+	input models.CodeSubmissionRequest) (*models.CodeSubmission, error) {
+	step, err := s.repo.GetStep(ctx, stepID)
+	if err != nil {
+		return nil, err
+	}
+	if step.StepType != models.CodeStep {
+		return nil, Validation("step %s is not a code challenge", stepID)
+	}
+	if step.Status == models.Finished || step.Status == models.Canceled {
+		return nil, Conflict("can not submit code; step status is %s", step.Status)
+	}
+	if err := s.checkEventRunning(ctx, step.EventID); err != nil {
+		return nil, err
+	}
+	staffStep, err := s.repo.GetStepStaff(ctx, stepID, staffID)
+	if err != nil {
+		return nil, Forbidden("staff %s is not assigned to step %s", staffID, stepID)
+	}
+	if staffStep.Accomplishment != models.InProcess {
+		return nil, Conflict("can not submit code; step is already %s", staffStep.Accomplishment)
+	}
+	if strings.TrimSpace(input.Source) == "" {
+		return nil, InvalidField("source", "can not submit empty source")
+	}
+	if len(input.Source) > maxSourceSize {
+		return nil, InvalidField("source", "source is too large: %d bytes; max: %d", len(input.Source), maxSourceSize)
+	}
+	if !s.supportsLanguage(input.Language) ||
+		len(step.CodeLanguages) != 0 && !containsString(step.CodeLanguages, input.Language) {
+		return nil, InvalidField("language", "language %s is not allowed in step %s", input.Language, stepID)
+	}
+	tests, err := s.repo.GetCodeTestCases(ctx, stepID)
+	if err != nil {
+		return nil, err
+	}
+	if len(tests) == 0 {
+		return nil, Conflict("code step %s has no test cases", stepID)
+	}
+
+	submissions, err := s.repo.GetCodeSubmissions(ctx, stepID, staffID)
+	if err != nil {
+		return nil, err
+	}
+	var used uint
+	for _, previous := range submissions {
+		switch previous.Status {
+		case models.CodePending:
+			return nil, Conflict("can not submit code; previous submission is still running")
+		case models.CodeDone:
+			used++
+		}
+	}
+	if step.AttemptsLimit != 0 && used >= step.AttemptsLimit {
+		return nil, Conflict("can not submit code; all %d attempts are used", step.AttemptsLimit)
+	}
+
+	submission := &models.CodeSubmission{
+		ID:          uuid.New(),
+		StepID:      stepID,
+		StaffID:     staffID,
+		Language:    input.Language,
+		Source:      input.Source,
+		Status:      models.CodePending,
+		Total:       uint(len(tests)),
+		SubmittedAt: time.Now(),
+	}
+	if err := s.repo.CreateCodeSubmission(ctx, submission); err != nil {
+		return nil, err
+	}
+	select {
+	case s.codeRuns <- codeRun{step: step, submission: submission, tests: tests, previous: submissions}:
+	default:
+		s.failCode(ctx, step, submission, "code runner is busy")
+		return nil, Conflict("can not submit code; code runner is busy, try again later")
+	}
+	return submission, nil
+}
+
+// RunCode runs queued submissions with a fixed number of workers. Submissions still pending
+// after the lease, e.g. lost on restart, are marked as failed by the runner.
+func (s *StepService) RunCode(ctx context.Context) {
+	for i := 0; i < s.codeWorkers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case run := <-s.codeRuns:
+					s.runCode(ctx, run.step, run.submission, run.tests, run.previous)
+				}
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(s.codeLease / 2)
+	defer ticker.Stop()
+
+	for {
+		expired, err := s.repo.ExpireCodeSubmissions(ctx, time.Now().Add(-s.codeLease), "code run was lost")
+		if err != nil {
+			log.Errorf("can not expire code submissions: %s", err)
+		} else if expired != 0 {
+			log.WithFields(log.Fields{"expired": expired}).Warn("stale code submissions marked as failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// failCode marks the submission as failed by the runner, attempts of staff are not used.
+func (s *StepService) failCode(ctx context.Context, step *models.Step, submission *models.CodeSubmission,
+	message string) {
+	log.WithFields(log.Fields{"submission": submission.ID, "step": step.ID}).Errorf("can not run code: %s", message)
+	now := time.Now()
+	submission.FinishedAt = &now
+	submission.Status = models.CodeError
+	submission.Error = message
+	if err := s.repo.FinishCodeSubmission(ctx, submission, models.StepStaff{}); err != nil {
+		log.Error(err)
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// runCode runs the submission and maps passed test scores to the step max score.
+// Staff passes with pass score or with all tests passed when the step has no pass score.
+// The run is stopped when the lease of the submission is over.
+func (s *StepService) runCode(ctx context.Context, step *models.Step, submission *models.CodeSubmission,
+	tests []*models.CodeTestCase, previous []*models.CodeSubmission) {
+	ctx, cancel := context.WithDeadline(ctx, submission.SubmittedAt.Add(s.codeLease))
+	defer cancel()
+	limits := runner.Limits{Time: defaultRunTimeLimit, Memory: defaultMemoryLimit << 20}
+	if step.RunTimeLimit != 0 {
+		limits.Time = time.Duration(step.RunTimeLimit) * time.Millisecond
+	}
+	if step.MemoryLimit != 0 {
+		limits.Memory = uint64(step.MemoryLimit) << 20
+	}
+	inputs := make([]string, len(tests))
+	for i, test := range tests {
+		inputs[i] = test.Input
+	}
+
+	results, err := s.runner.Run(ctx, runner.Program{Language: submission.Language, Source: submission.Source},
+		inputs, limits)
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		s.failCode(s.ctx, step, submission, err.Error())
+		return
+	}
+	now := time.Now()
+	submission.FinishedAt = &now
+
+	var passedScore, totalScore uint
+	for i, test := range tests {
+		status := models.CodeTestStatus(results[i].Status)
+		if results[i].Status == runner.OK {
+			status = models.TestWrongAnswer
+			if sameOutput(results[i].Output, test.ExpectedOutput) {
+				status = models.TestPassed
+			}
+		}
+		if status == models.TestPassed {
+			submission.Passed++
+			passedScore += test.Score
+		}
+		totalScore += test.Score
+		output := results[i].Output
+		if results[i].Status == runner.CompileError || results[i].Status == runner.RuntimeError {
+			output = results[i].Error
+		}
+		submission.Results = append(submission.Results, &models.CodeTestResult{
+			ID:           uuid.New(),
+			SubmissionID: submission.ID,
+			TestCaseID:   test.ID,
+			Status:       status,
+			Hidden:       test.Hidden,
+			Output:       output,
+			Duration:     uint(results[i].Duration.Milliseconds()),
+		})
+	}
+	submission.Status = models.CodeDone
+	submission.Score = roundDiv(step.MaxScore*passedScore, totalScore)
+
+	best, used := submission.Score, uint(1)
+	for _, p := range previous {
+		if p.Status == models.CodeDone {
+			used++
+			if p.Score > best {
+				best = p.Score
+			}
+		}
+	}
+	passScore := step.PassScore
+	if passScore == 0 {
+		passScore = step.MaxScore
+	}
+	accomplishment := models.InProcess
+	switch {
+	case best >= passScore:
+		accomplishment = models.Done
+	case step.AttemptsLimit != 0 && used >= step.AttemptsLimit:
+		accomplishment = models.Failed
+	}
+
+	err = s.repo.FinishCodeSubmission(ctx, submission, models.StepStaff{
+		StepID:         step.ID,
+		StaffID:        submission.StaffID,
+		Accomplishment: accomplishment,
+		Score:          best,
+	})
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	if accomplishment != models.InProcess {
+		s.reportResult(ctx, step, submission.StaffID, accomplishment, best)
+	}
+	if accomplishment == models.Done {
+		if err := s.assignNextStep(ctx, step, submission.StaffID); err != nil {
+			log.Error(err)
+		}
+	}
+}
+
+// sameOutput compares outputs ignoring trailing spaces of lines and trailing empty lines.
+func sameOutput(got, want string) bool {
+	return normalizeOutput(got) == normalizeOutput(want)
+}
+
+func normalizeOutput(output string) string {
+	lines := strings.Split(strings.ReplaceAll(output, "\r\n", "\n"), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], " \t")
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+// GetCodeSubmissions returns code submissions of staff without output of hidden tests.
+func (s *StepService) GetCodeSubmissions(ctx context.Context, stepID, staffID uuid.UUID) ([]*models.CodeSubmission, error) {
+	submissions, err := s.repo.GetCodeSubmissions(ctx, stepID, staffID)
+	if err != nil {
+		return nil, err
+	}
+	for _, submission := range submissions {
+		for _, result := range submission.Results {
+			if result.Hidden {
+				result.Output = ""
+			}
+		}
+	}
+	return submissions, nil
+}
+
+// GetCodeTestCases returns step test cases with hidden ones to creator or admin of the event.
+func (s *StepService) GetCodeTestCases(ctx context.Context, stepID, staffID uuid.UUID) ([]*models.CodeTestCase, error) {
+	if _, err := s.managedStep(ctx, stepID, staffID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetCodeTestCases(ctx, stepID)
+}
+
+// UpdateCodeTestCases replaces step test cases, only creator or admin of the event may do it.
+// Tests can not be changed after staff submitted code.
+func (s *StepService) UpdateCodeTestCases(ctx context.Context, stepID, staffID uuid.UUID,
+	tests []*models.CodeTestCase) error {
+	step, err := s.managedStep(ctx, stepID, staffID)
+	if err != nil {
+		return err
+	}
+	if step.StepType != models.CodeStep {
+		return Validation("step %s is not a code challenge", stepID)
+	}
+	submitted, err := s.repo.HasCodeSubmissions(ctx, stepID)
+	if err != nil {
+		return err
+	}
+	if submitted {
+		return Conflict("can not update test cases; step %s already has submissions", stepID)
+	}
+	step.TestCases = tests
+	if err := s.prepareCode(step); err != nil {
+		return err
+	}
+	return s.repo.ReplaceCodeTestCases(ctx, stepID, step.TestCases, step.MaxScore)
+}
@@ -0,0 +1,363 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/mail"
+	"github.com/miprokop/fication/internal/models"
+	"github.com/miprokop/fication/internal/persistence/postgres"
+	log "github.com/sirupsen/logrus"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	digestWeek = 7 * 24 * time.Hour
+	// digestHistory is how long snapshots and sent records are kept.
+	digestHistory = 8 * digestWeek
+)
+
+// digestNamespace derives ids of digest jobs from their week.
+var digestNamespace = uuid.MustParse("7b0b5f4e-3f2c-4a59-9a43-2d7c1e0f6a11")
+
+type digestPayload struct {
+	Week time.Time `json:"week"`
+}
+
+// DigestService sends weekly digests: one for every staff about its own week and one for every
+// team to staff allowed to update it. Points and rank changes are differences of weekly snapshots.
+type DigestService struct {
+	repo      postgres.Digest
+	sender    mail.Sender
+	templates *mail.Templates
+	weekday   time.Weekday
+	hour      int
+	baseURL   string
+	secret    []byte
+	ctx       context.Context
+}
+
+// Schedule makes sure the next digest job exists. Every instance calls it on start.
+func (d *DigestService) Schedule(ctx context.Context) error {
+	return d.scheduleWeek(ctx, d.nextRun(time.Now()))
+}
+
+// nextRun returns the first digest time after t.
+func (d *DigestService) nextRun(t time.Time) time.Time {
+	t = t.Local()
+	next := time.Date(t.Year(), t.Month(), t.Day(), d.hour, 0, 0, 0, t.Location())
+	next = next.AddDate(0, 0, (int(d.weekday)-int(next.Weekday())+7)%7)
+	if !next.After(t) {
+		next = next.AddDate(0, 0, 7)
+	}
+	return next
+}
+
+func (d *DigestService) scheduleWeek(ctx context.Context, runAt time.Time) error {
+	payload, err := json.Marshal(digestPayload{Week: runAt})
+	if err != nil {
+		return err
+	}
+	return d.repo.CreateDigestJob(ctx, &models.Job{
+		ID:      uuid.NewSHA1(digestNamespace, []byte(runAt.UTC().Format(time.RFC3339))),
+		JobType: models.JobDigest,
+		Status:  models.JobPending,
+		RunAt:   runAt,
+		Payload: payload,
+	})
+}
+
+// RunDigestJob sends digests of the week of the job and schedules the next week.
+// Digests already sent for the week are skipped, so a retried job only sends the failed ones.
+func (d *DigestService) RunDigestJob(ctx context.Context, job *models.Job) error {
+	var payload digestPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return fmt.Errorf("can not parse digest job payload: %s", err)
+	}
+	if err := d.scheduleWeek(ctx, d.nextRun(payload.Week)); err != nil {
+		return fmt.Errorf("can not schedule next digest: %s", err)
+	}
+	week := time.Date(payload.Week.Year(), payload.Week.Month(), payload.Week.Day(), 0, 0, 0, 0, time.UTC)
+
+	if err := d.repo.SaveDigestSnapshots(ctx, week); err != nil {
+		return fmt.Errorf("can not save digest snapshots: %s", err)
+	}
+	standings, err := d.getStandings(ctx, week)
+	if err != nil {
+		return err
+	}
+	skip, err := d.getSkipped(ctx, week)
+	if err != nil {
+		return err
+	}
+	staff, err := d.repo.GetDigestStaff(ctx)
+	if err != nil {
+		return fmt.Errorf("can not get digest staff: %s", err)
+	}
+
+	failed, err := d.sendStaffDigests(ctx, week, staff, standings, skip[models.DigestStaff])
+	if err != nil {
+		return err
+	}
+	teamFailed, err := d.sendTeamDigests(ctx, week, staff, standings, skip[models.DigestTeam])
+	if err != nil {
+		return err
+	}
+	failed += teamFailed
+
+	if err := d.repo.DeleteDigestSnapshotsBefore(ctx, week.Add(-digestHistory)); err != nil {
+		log.Errorf("can not delete old digest snapshots: %s", err)
+	}
+	if failed != 0 {
+		return fmt.Errorf("can not send %d digests", failed)
+	}
+	return nil
+}
+
+// getStandings compares snapshots of the week with the previous ones. Staff without a previous
+// snapshot joined after it, so all its score is earned this week.
+func (d *DigestService) getStandings(ctx context.Context, week time.Time) (map[uuid.UUID]models.DigestStanding, error) {
+	current, err := d.repo.GetDigestSnapshots(ctx, week)
+	if err != nil {
+		return nil, fmt.Errorf("can not get digest snapshots: %s", err)
+	}
+	previous, err := d.repo.GetPreviousDigestSnapshots(ctx, week)
+	if err != nil {
+		return nil, fmt.Errorf("can not get previous digest snapshots: %s", err)
+	}
+	before := make(map[uuid.UUID]*models.DigestSnapshot, len(previous))
+	for _, snapshot := range previous {
+		before[snapshot.StaffID] = snapshot
+	}
+	standings := make(map[uuid.UUID]models.DigestStanding, len(current))
+	for _, snapshot := range current {
+		standing := models.DigestStanding{Score: snapshot.Score, Points: snapshot.Score, Rank: snapshot.Rank}
+		if prev, ok := before[snapshot.StaffID]; ok {
+			standing.Points = snapshot.Score - prev.Score
+			standing.RankChange = prev.Rank - snapshot.Rank
+		}
+		standings[snapshot.StaffID] = standing
+	}
+	return standings, nil
+}
+
+// getSkipped returns staff not to send digests to by kind: turned off or already sent this week.
+func (d *DigestService) getSkipped(ctx context.Context, week time.Time) (map[models.DigestKind]map[uuid.UUID]bool, error) {
+	skip := make(map[models.DigestKind]map[uuid.UUID]bool, len(models.DigestKinds))
+	for _, kind := range models.DigestKinds {
+		skip[kind] = make(map[uuid.UUID]bool)
+	}
+	disabled, err := d.repo.GetDisabledDigests(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can not get digest preferences: %s", err)
+	}
+	for _, preference := range disabled {
+		skip[preference.Kind][preference.StaffID] = true
+	}
+	sent, err := d.repo.GetSentDigests(ctx, week)
+	if err != nil {
+		return nil, fmt.Errorf("can not get sent digests: %s", err)
+	}
+	for _, s := range sent {
+		skip[s.Kind][s.StaffID] = true
+	}
+	return skip, nil
+}
+
+// sendStaffDigests sends digests of staff with something to tell and returns the number of failed ones.
+func (d *DigestService) sendStaffDigests(ctx context.Context, week time.Time, staff []*models.Staff,
+	standings map[uuid.UUID]models.DigestStanding, skip map[uuid.UUID]bool) (int, error) {
+	invitations, err := d.repo.GetNewInvitations(ctx, week.Add(-digestWeek))
+	if err != nil {
+		return 0, fmt.Errorf("can not get invitations for digests: %s", err)
+	}
+	now := time.Now()
+	deadlines, err := d.repo.GetUpcomingDeadlines(ctx, now, now.Add(digestWeek))
+	if err != nil {
+		return 0, fmt.Errorf("can not get deadlines for digests: %s", err)
+	}
+	staffInvitations := make(map[uuid.UUID][]*models.StaffEvents)
+	for _, invitation := range invitations {
+		staffInvitations[invitation.StaffID] = append(staffInvitations[invitation.StaffID], invitation)
+	}
+	staffDeadlines := make(map[uuid.UUID][]models.DigestDeadline)
+	for _, deadline := range deadlines {
+		staffDeadlines[deadline.StaffID] = append(staffDeadlines[deadline.StaffID], deadline)
+	}
+
+	failed := 0
+	for _, s := range staff {
+		if skip[s.ID] {
+			continue
+		}
+		digest := models.StaffDigest{
+			Staff:          s,
+			Week:           week,
+			Invitations:    staffInvitations[s.ID],
+			Deadlines:      staffDeadlines[s.ID],
+			Standing:       standings[s.ID],
+			UnsubscribeURL: d.UnsubscribeURL(s.ID, models.DigestStaff),
+		}
+		if len(digest.Invitations) == 0 && len(digest.Deadlines) == 0 && digest.Standing.Points == 0 &&
+			digest.Standing.RankChange == 0 {
+			continue
+		}
+		if err := d.send(ctx, s, models.DigestStaff, week, "staff_digest", digest); err != nil {
+			log.WithFields(log.Fields{"staff": s.ID}).Errorf("can not send staff digest: %s", err)
+			failed++
+		}
+	}
+	return failed, nil
+}
+
+// sendTeamDigests sends digests of teams to their managers and returns the number of failed ones.
+func (d *DigestService) sendTeamDigests(ctx context.Context, week time.Time, staff []*models.Staff,
+	standings map[uuid.UUID]models.DigestStanding, skip map[uuid.UUID]bool) (int, error) {
+	managers, err := d.repo.GetTeamManagers(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("can not get team managers: %s", err)
+	}
+	members := make(map[uuid.UUID][]models.TeamDigestMember)
+	for _, s := range staff {
+		members[s.TeamID] = append(members[s.TeamID], models.TeamDigestMember{Staff: s, Standing: standings[s.ID]})
+	}
+
+	failed := 0
+	for _, manager := range managers {
+		if skip[manager.ID] || manager.Team == nil || len(members[manager.TeamID]) == 0 {
+			continue
+		}
+		digest := models.TeamDigest{
+			Manager:        manager,
+			Team:           manager.Team,
+			Week:           week,
+			Members:        append([]models.TeamDigestMember(nil), members[manager.TeamID]...),
+			UnsubscribeURL: d.UnsubscribeURL(manager.ID, models.DigestTeam),
+		}
+		sort.SliceStable(digest.Members, func(i, j int) bool {
+			return digest.Members[i].Standing.Points > digest.Members[j].Standing.Points
+		})
+		for _, member := range digest.Members {
+			digest.Points += member.Standing.Points
+		}
+		if err := d.send(ctx, manager, models.DigestTeam, week, "team_digest", digest); err != nil {
+			log.WithFields(log.Fields{"staff": manager.ID, "team": manager.TeamID}).
+				Errorf("can not send team digest: %s", err)
+			failed++
+		}
+	}
+	return failed, nil
+}
+
+func (d *DigestService) send(ctx context.Context, staff *models.Staff, kind models.DigestKind, week time.Time,
+	template string, data interface{}) error {
+	message, err := d.templates.Render(template, data)
+	if err != nil {
+		return err
+	}
+	message.To = []string{staff.Email}
+	if err := d.sender.Send(ctx, message); err != nil {
+		return err
+	}
+	return d.repo.MarkDigestSent(ctx, &models.DigestSent{StaffID: staff.ID, Kind: kind, Week: week})
+}
+
+// UnsubscribeURL returns the link turning the digest off for staff without signing in.
+func (d *DigestService) UnsubscribeURL(staffID uuid.UUID, kind models.DigestKind) string {
+	query := url.Values{
+		"staff": {staffID.String()},
+		"kind":  {string(kind)},
+		"token": {d.unsubscribeToken(staffID, kind)},
+	}
+	return strings.TrimRight(d.baseURL, "/") + "/digest/unsubscribe?" + query.Encode()
+}
+
+// unsubscribeToken is the hex HMAC-SHA256 of "staffID:kind".
+func (d *DigestService) unsubscribeToken(staffID uuid.UUID, kind models.DigestKind) string {
+	mac := hmac.New(sha256.New, d.secret)
+	fmt.Fprintf(mac, "%s:%s", staffID, kind)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// digestSecret derives the key of unsubscribe tokens from the signing key of access tokens
+// when digest.secret is not set, so the signing key itself never signs anything mailed to staff.
+func digestSecret(signingKey string) string {
+	mac := hmac.New(sha256.New, []byte(signingKey))
+	mac.Write([]byte("digest-unsubscribe"))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Unsubscribe turns the digest off for staff by the token of its unsubscribe link.
+func (d *DigestService) Unsubscribe(ctx context.Context, staffID uuid.UUID, kind models.DigestKind, token string) error {
+	if !hmac.Equal([]byte(token), []byte(d.unsubscribeToken(staffID, kind))) {
+		return Forbidden("incorrent unsubscribe token")
+	}
+	return d.repo.SaveDigestPreferences(ctx, []*models.DigestPreference{{StaffID: staffID, Kind: kind, Enabled: false}})
+}
+
+// GetPreferences returns preferences of staff for every digest kind.
+func (d *DigestService) GetPreferences(ctx context.Context, staffID uuid.UUID) ([]*models.DigestPreference, error) {
+	saved, err := d.repo.GetDigestPreferences(ctx, staffID)
+	if err != nil {
+		return nil, err
+	}
+	enabled := make(map[models.DigestKind]bool, len(saved))
+	for _, preference := range saved {
+		enabled[preference.Kind] = preference.Enabled
+	}
+	preferences := make([]*models.DigestPreference, len(models.DigestKinds))
+	for i, kind := range models.DigestKinds {
+		on, ok := enabled[kind]
+		preferences[i] = &models.DigestPreference{StaffID: staffID, Kind: kind, Enabled: on || !ok}
+	}
+	return preferences, nil
+}
+
+func (d *DigestService) UpdatePreferences(ctx context.Context, staffID uuid.UUID,
+	preferences []*models.DigestPreference) ([]*models.DigestPreference, error) {
+	for _, preference := range preferences {
+		kind, err := models.NewDigestKind(string(preference.Kind))
+		if err != nil {
+			return nil, InvalidField("kind", "%s", err)
+		}
+		preference.Kind = kind
+		preference.StaffID = staffID
+	}
+	if len(preferences) != 0 {
+		if err := d.repo.SaveDigestPreferences(ctx, preferences); err != nil {
+			return nil, err
+		}
+	}
+	return d.GetPreferences(ctx, staffID)
+}
+
+// parseWeekday reads an English weekday name, e.g. monday.
+func parseWeekday(s string) (time.Weekday, bool) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(day.String(), s) {
+			return day, true
+		}
+	}
+	return 0, false
+}
+
+func NewDigestService(ctx context.Context, repo postgres.Digest, sender mail.Sender, templates *mail.Templates,
+	weekday time.Weekday, hour int, baseURL, secret string) *DigestService {
+	return &DigestService{
+		repo:      repo,
+		sender:    sender,
+		templates: templates,
+		weekday:   weekday,
+		hour:      hour,
+		baseURL:   baseURL,
+		secret:    []byte(secret),
+		ctx:       ctx,
+	}
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/miprokop/fication/internal/persistence/postgres"
+)
+
+type ErrorCode string
+
+const (
+	CodeNotFound   ErrorCode = "not_found"
+	CodeConflict   ErrorCode = "conflict"
+	CodeValidation ErrorCode = "validation"
+	CodeForbidden  ErrorCode = "forbidden"
+)
+
+// FieldError is why one field of the input is not valid.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is an error of the domain that can be shown to the caller as it is, unlike errors
+// of the database. Err is the cause, it is logged but not shown.
+type Error struct {
+	Code    ErrorCode
+	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func NotFound(format string, args ...interface{}) *Error {
+	return &Error{Code: CodeNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+func Conflict(format string, args ...interface{}) *Error {
+	return &Error{Code: CodeConflict, Message: fmt.Sprintf(format, args...)}
+}
+
+func Validation(format string, args ...interface{}) *Error {
+	return &Error{Code: CodeValidation, Message: fmt.Sprintf(format, args...)}
+}
+
+func Forbidden(format string, args ...interface{}) *Error {
+	return &Error{Code: CodeForbidden, Message: fmt.Sprintf(format, args...)}
+}
+
+// InvalidField is a validation error of one field, its message is also the message of the error.
+func InvalidField(field, format string, args ...interface{}) *Error {
+	message := fmt.Sprintf(format, args...)
+	return &Error{Code: CodeValidation, Message: message, Fields: []FieldError{{Field: field, Message: message}}}
+}
+
+// notFound returns a NotFound error when there is no row and err as it is otherwise.
+func notFound(err error, format string, args ...interface{}) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		e := NotFound(format, args...)
+		e.Err = err
+		return e
+	}
+	return err
+}
+
+// ErrorOf returns the domain error of err, nil for internal errors. Missing rows, conflicts of
+// repositories and constraint violations of the database are domain errors, but with messages
+// of their own: database messages name tables and constraints.
+func ErrorOf(err error) *Error {
+	var domain *Error
+	if errors.As(err, &domain) {
+		return domain
+	}
+	var conflict *postgres.ConflictError
+	if errors.As(err, &conflict) {
+		return &Error{Code: CodeConflict, Message: conflict.Message, Err: err}
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return &Error{Code: CodeNotFound, Message: "not found", Err: err}
+	}
+	switch postgres.ErrorState(err) {
+	case "23505":
+		return &Error{Code: CodeConflict, Message: "already exists", Err: err}
+	case "23503":
+		return &Error{Code: CodeValidation, Message: "refers to a missing item or is still in use", Err: err}
+	case "23502", "23514", "22P02", "22001", "22003", "22007", "22008":
+		return &Error{Code: CodeValidation, Message: "incorrent value", Err: err}
+	}
+	return nil
+}
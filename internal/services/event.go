@@ -6,101 +6,252 @@ import (
 	"github.com/google/uuid"
 	"github.com/miprokop/fication/internal/models"
 	"github.com/miprokop/fication/internal/persistence/postgres"
+	"time"
 )
 
 type EventService struct {
-	repo postgres.Event
-	ctx  context.Context
+	repo   postgres.Event
+	jobs   postgres.Job
+	notify *NotificationService
+	stream *StreamService
+	ctx    context.Context
 }
 
+// RemoveStaffFromEvent removes staff and its steps from the event.
+// The freed place goes to the first staff on the waitlist.
 func (e *EventService) RemoveStaffFromEvent(ctx context.Context, events models.StaffEvents) error {
-	return e.repo.RemoveStaffFromEvent(ctx, events)
+	if err := e.repo.RemoveStaffFromEvent(ctx, events); err != nil {
+		return err
+	}
+	return e.promoteWaitlist(ctx, events.EventID)
 }
 
-func (e *EventService) GetInvites(ctx context.Context, staffID uuid.UUID) ([]*models.StaffEvents, error) {
-	return e.repo.GetInvites(ctx, staffID)
+// promoteWaitlist fills free places of the event from the waitlist and notifies promoted staff.
+func (e *EventService) promoteWaitlist(ctx context.Context, eventID uuid.UUID) error {
+	promoted, err := e.repo.PromoteWaitlist(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	staffIDs := make([]uuid.UUID, len(promoted))
+	for i, invite := range promoted {
+		staffIDs[i] = invite.StaffID
+		e.participantJoined(ctx, eventID, invite.StaffID, models.Accepted)
+	}
+	e.notify.Notify(ctx, models.Notification{
+		NotificationType: models.NotifyInvitation,
+		Message:          "you are moved from the waitlist to participants of the event",
+		EventID:          eventID,
+	}, staffIDs...)
+	return nil
+}
+
+func (e *EventService) GetInvites(ctx context.Context, staffID uuid.UUID,
+	query models.ListQuery) ([]*models.StaffEvents, string, error) {
+	if err := e.repo.ExpireInvitations(ctx); err != nil {
+		return nil, "", err
+	}
+	return e.repo.GetInvites(ctx, staffID, query)
 }
 
 func (e *EventService) GetStaffScore(ctx context.Context, eventID, staffID uuid.UUID) (models.StaffScore, error) {
 	return e.repo.GetStaffScore(ctx, eventID, staffID)
 }
 
-func (e *EventService) AnswerInvitation(ctx context.Context, events models.StaffEvents) error {
-	return e.repo.AnswerInvitation(ctx, events)
+// AnswerInvitation accepts or declines an invite and returns its new status.
+// Invites can be accepted only in running events before the registration deadline,
+// staff is put on the waitlist when the event is full. Declining an accepted invite
+// gives the place to the first staff on the waitlist.
+func (e *EventService) AnswerInvitation(ctx context.Context, events models.StaffEvents) (models.InviteStatus, error) {
+	invite, err := e.repo.GetInvitation(ctx, events.ID, events.StaffID)
+	if err != nil {
+		return "", notFound(err, "no such invite %s", events.ID)
+	}
+	switch events.Status {
+	case models.Accepted:
+		if invite.Expired(time.Now()) {
+			if err := e.repo.ExpireInvitations(ctx); err != nil {
+				return "", err
+			}
+			return "", Conflict("invite %s has expired at %s", invite.ID, invite.ExpiresAt)
+		}
+		if invite.Status != models.InProgress {
+			return "", Conflict("can not accept invite; it is already %s", invite.Status)
+		}
+		event, err := e.repo.GetEvent(ctx, invite.EventID)
+		if err != nil {
+			return "", err
+		}
+		if err := checkRegistration(event); err != nil {
+			return "", err
+		}
+		status, err := e.repo.AcceptInvitation(ctx, invite)
+		if err != nil {
+			return "", err
+		}
+		e.participantJoined(ctx, invite.EventID, invite.StaffID, status)
+		return status, nil
+	case models.Declared:
+		if invite.Status == models.Declared || invite.Status == models.Expired {
+			return "", Conflict("can not decline invite; it is already %s", invite.Status)
+		}
+		if err := e.repo.AnswerInvitation(ctx, events); err != nil {
+			return "", err
+		}
+		if invite.Status == models.Accepted {
+			if err := e.promoteWaitlist(ctx, invite.EventID); err != nil {
+				return "", err
+			}
+		}
+		return models.Declared, nil
+	}
+	return "", InvalidField("status", "incorrent invite answer: %s; want: %s or %s", events.Status, models.Accepted, models.Declared)
+}
+
+// participantJoined publishes a new participant of the event, waitlisted staff included.
+func (e *EventService) participantJoined(ctx context.Context, eventID, staffID uuid.UUID, status models.InviteStatus) {
+	e.stream.PublishEvent(ctx, models.StreamParticipant, eventID, models.ParticipantMessage{
+		StaffID: staffID,
+		Status:  status,
+	})
+}
+
+func newEventDomainEvent(eventType models.DomainEventType, event *models.Event,
+	status models.EventStatus) (*models.DomainEvent, error) {
+	return models.NewDomainEvent(eventType, event.OrganizationID, event.ID, models.EventData{
+		EventID:     event.ID,
+		Name:        event.Name,
+		Status:      status,
+		CreatedByID: event.CreatedByID,
+	})
 }
 
+// checkRegistration refuses registration in events that are not running or closed registration.
+func checkRegistration(event *models.Event) error {
+	if event.EventStatus != models.EventRunning {
+		return Conflict("event %s is %s; want: %s", event.ID, event.EventStatus, models.EventRunning)
+	}
+	if event.RegistrationClosed(time.Now()) {
+		return Conflict("registration in event %s is closed at %s", event.ID, event.RegistrationDeadline)
+	}
+	return nil
+}
+
+// AssignStaff invites staff to the event. Invites expire after the invitation ttl of the event.
 func (e *EventService) AssignStaff(ctx context.Context, events []models.StaffEvents, eventID uuid.UUID) error {
+	oldEvent, err := e.repo.GetEvent(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	if err := checkRegistration(oldEvent); err != nil {
+		return err
+	}
 	for _, event := range events {
 		event.ID = uuid.New()
 		event.EventID = eventID
 		if event.StaffRole == "" {
 			event.StaffRole = models.Default
 		}
-		oldEvent, err := e.repo.GetEvent(ctx, eventID)
+		if oldEvent.InvitationTTL != 0 {
+			expiresAt := time.Now().Add(time.Duration(oldEvent.InvitationTTL) * time.Second)
+			event.ExpiresAt = &expiresAt
+		}
+		if err := e.checkAccess(ctx, oldEvent, event.StaffID); err != nil {
+			return err
+		}
+
+		event.Status = models.InProgress
+		err = e.repo.AssignStaff(ctx, event)
 		if err != nil {
 			return err
 		}
-		switch oldEvent.EventType {
-		case "team-only":
-			inTeam := false
-			allStaff, err := e.repo.GetEvent(ctx, eventID)
-			if err != nil {
-				return err
-			}
-			for i := range allStaff.StaffEvents {
-				if allStaff.StaffEvents[i].StaffRole == models.Creator ||
-					allStaff.StaffEvents[i].StaffRole == models.Admin {
-
-					createdBy, err := e.repo.GetStaff(ctx, allStaff.StaffEvents[i].StaffID)
-					if err != nil {
-						return err
-					}
-					exists, err := e.repo.IsStaffInTeam(ctx, event.StaffID, createdBy.TeamID)
-					if err != nil {
-						return err
-					}
-					if exists {
-						inTeam = true
-						break
-					}
+		e.notify.Notify(ctx, models.Notification{
+			NotificationType: models.NotifyInvitation,
+			Message:          fmt.Sprintf("you are invited to event %s", oldEvent.Name),
+			EventID:          eventID,
+		}, event.StaffID)
+	}
+	return nil
+}
+
+// checkAccess refuses staff from outside the team of team-only events
+// and from outside the organization of private events.
+func (e *EventService) checkAccess(ctx context.Context, event *models.Event, staffID uuid.UUID) error {
+	switch event.EventType {
+	case "team-only":
+		inTeam := false
+		for i := range event.StaffEvents {
+			if event.StaffEvents[i].StaffRole == models.Creator ||
+				event.StaffEvents[i].StaffRole == models.Admin {
+
+				createdBy, err := e.repo.GetStaff(ctx, event.StaffEvents[i].StaffID)
+				if err != nil {
+					return err
+				}
+				exists, err := e.repo.IsStaffInTeam(ctx, staffID, createdBy.TeamID)
+				if err != nil {
+					return err
+				}
+				if exists {
+					inTeam = true
+					break
 				}
-			}
-			if !inTeam {
-				return fmt.Errorf("can not assign staff in event; not in this team")
-			}
-		case "private":
-			createdBy, err := e.repo.GetStaff(ctx, oldEvent.CreatedByID)
-			if err != nil {
-				return err
-			}
-			exists, err := e.repo.IsStaffInOrg(ctx, event.StaffID, createdBy.OrganizationID)
-			if err != nil {
-				return err
-			}
-			if !exists {
-				return fmt.Errorf("staff with this id: %s; not in this org", event.StaffID)
 			}
 		}
-
-		event.Status = models.InProgress
-		err = e.repo.AssignStaff(ctx, event)
+		if !inTeam {
+			return Forbidden("can not assign staff in event; not in this team")
+		}
+	case "private":
+		createdBy, err := e.repo.GetStaff(ctx, event.CreatedByID)
+		if err != nil {
+			return err
+		}
+		exists, err := e.repo.IsStaffInOrg(ctx, staffID, createdBy.OrganizationID)
 		if err != nil {
 			return err
 		}
+		if !exists {
+			return Forbidden("staff with this id: %s; not in this org", staffID)
+		}
 	}
 	return nil
 }
 
+// CreateEvent creates a running event, or a scheduled one when it starts in the future.
+// Draft events are not started until they are scheduled.
 func (e *EventService) CreateEvent(ctx context.Context, event *models.Event) error {
-	return e.repo.CreateEvent(ctx, event)
+	creationTime, endTime, err := eventTimes(event)
+	if err != nil {
+		return err
+	}
+	switch event.EventStatus {
+	case "":
+		event.EventStatus = models.EventRunning
+		if creationTime.After(time.Now()) {
+			event.EventStatus = models.EventScheduled
+		}
+	case models.EventDraft, models.EventScheduled, models.EventRunning:
+	default:
+		return InvalidField("event_status", "can not create event with status %s", event.EventStatus)
+	}
+	event.CreationDate = creationTime.Format(time.RFC3339)
+	event.EndDate = endTime.Format(time.RFC3339)
+	created, err := newEventDomainEvent(models.DomainEventCreated, event, event.EventStatus)
+	if err != nil {
+		return err
+	}
+	if err := e.repo.CreateEvent(ctx, event, created); err != nil {
+		return err
+	}
+	return e.scheduleLifecycle(ctx, event.ID, event.EventStatus, creationTime, endTime)
 }
 
 func (e *EventService) GetEvent(ctx context.Context, id uuid.UUID) (*models.Event, error) {
 	return e.repo.GetEvent(ctx, id)
 }
 
-func (e *EventService) GetEventsByTeamID(ctx context.Context, orgID uuid.UUID) ([]*models.Event, error) {
-	return e.repo.GetEventsByTeamID(ctx, orgID)
+func (e *EventService) GetEventsByTeamID(ctx context.Context, teamID uuid.UUID,
+	query models.ListQuery) ([]*models.Event, string, error) {
+	return e.repo.GetEventsByTeamID(ctx, teamID, query)
 }
 
 func (e *EventService) GetEventsByCommandID(ctx context.Context, commandID uuid.UUID) ([]*models.Event, error) {
@@ -108,22 +259,241 @@ func (e *EventService) GetEventsByCommandID(ctx context.Context, commandID uuid.
 }
 
 func (e *EventService) DeleteEvent(ctx context.Context, id uuid.UUID) error {
+	if err := e.jobs.CancelEventJobs(ctx, id); err != nil {
+		return err
+	}
 	return e.repo.DeleteEvent(ctx, id)
 }
 
-func (e *EventService) UpdateEvent(ctx context.Context, event *models.Event) error {
-	return e.repo.UpdateEvent(ctx, event)
+// UpdateEvent updates event info and moves its start and finish when dates are changed.
+// Status is changed only by ChangeEventStatus. The participants cap is changed only when
+// maxParticipants is set, 0 removes the cap.
+func (e *EventService) UpdateEvent(ctx context.Context, event *models.Event, maxParticipants *uint) error {
+	oldEvent, err := e.repo.GetEvent(ctx, event.ID)
+	if err != nil {
+		return err
+	}
+	if event.EventStatus != "" && event.EventStatus != oldEvent.EventStatus {
+		return InvalidField("event_status", "can not change event status in update; use event status change")
+	}
+	event.EventStatus = ""
+	if event.CreationDate == "" && event.EndDate == "" {
+		return e.update(ctx, oldEvent, event, maxParticipants)
+	}
+
+	merged := *oldEvent
+	if event.CreationDate != "" {
+		merged.CreationDate = event.CreationDate
+	}
+	if event.EndDate != "" {
+		merged.EndDate = event.EndDate
+	}
+	creationTime, endTime, err := eventTimes(&merged)
+	if err != nil {
+		return err
+	}
+	if oldEvent.EventStatus == models.EventRunning && event.CreationDate != "" {
+		return Conflict("can not change start of running event")
+	}
+	if oldEvent.EventStatus == models.EventFinished || oldEvent.EventStatus == models.EventCanceled {
+		return Conflict("can not change dates of %s event", oldEvent.EventStatus)
+	}
+	event.CreationDate = creationTime.Format(time.RFC3339)
+	event.EndDate = endTime.Format(time.RFC3339)
+	if err := e.update(ctx, oldEvent, event, maxParticipants); err != nil {
+		return err
+	}
+	if err := e.jobs.CancelEventJobs(ctx, event.ID, models.JobEventStart, models.JobEventFinish); err != nil {
+		return err
+	}
+	return e.scheduleLifecycle(ctx, event.ID, oldEvent.EventStatus, creationTime, endTime)
+}
+
+// update stores the event and its new participants cap. Places freed by a raised or
+// removed cap are filled from the waitlist.
+func (e *EventService) update(ctx context.Context, oldEvent, event *models.Event, maxParticipants *uint) error {
+	event.MaxParticipants = 0
+	if err := e.repo.UpdateEvent(ctx, event); err != nil {
+		return err
+	}
+	if maxParticipants == nil || *maxParticipants == oldEvent.MaxParticipants {
+		return nil
+	}
+	if err := e.repo.UpdateMaxParticipants(ctx, event.ID, *maxParticipants); err != nil {
+		return err
+	}
+	event.MaxParticipants = *maxParticipants
+	if oldEvent.MaxParticipants == 0 || *maxParticipants != 0 && *maxParticipants < oldEvent.MaxParticipants {
+		return nil
+	}
+	return e.promoteWaitlist(ctx, event.ID)
+}
+
+// ChangeEventStatus moves event through its lifecycle:
+// draft -> scheduled -> running -> finished, and any not final status -> canceled.
+// Open steps are closed when the event is finished or canceled.
+func (e *EventService) ChangeEventStatus(ctx context.Context, id uuid.UUID, status models.EventStatus) error {
+	status, err := models.NewEventStatus(string(status))
+	if err != nil {
+		return InvalidField("status", "%s", err)
+	}
+	event, err := e.repo.GetEvent(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !event.EventStatus.CanTransition(status) {
+		return Conflict("can not change event status from %s to %s", event.EventStatus, status)
+	}
+	var changed []*models.DomainEvent
+	if status == models.EventFinished {
+		finished, err := newEventDomainEvent(models.DomainEventFinished, event, status)
+		if err != nil {
+			return err
+		}
+		changed = append(changed, finished)
+	}
+	if err := e.repo.UpdateEventStatus(ctx, id, event.EventStatus, status, changed...); err != nil {
+		return err
+	}
+	e.notifyStatus(ctx, event, status)
+	e.stream.PublishEvent(ctx, models.StreamEventStatus, id, models.EventStatusMessage{Status: status})
+
+	switch status {
+	case models.EventFinished, models.EventCanceled:
+		if err := e.jobs.CancelEventJobs(ctx, id); err != nil {
+			return err
+		}
+		stepStatus := models.Finished
+		if status == models.EventCanceled {
+			stepStatus = models.Canceled
+		}
+		if err := e.repo.CloseEventSteps(ctx, id, stepStatus); err != nil {
+			return err
+		}
+		e.stream.PublishEvent(ctx, models.StreamStepStatus, id, models.StepStatusMessage{Status: stepStatus})
+		return nil
+	}
+	if err := e.jobs.CancelEventJobs(ctx, id, models.JobEventStart, models.JobEventFinish); err != nil {
+		return err
+	}
+	creationTime, endTime, err := eventTimes(event)
+	if err != nil {
+		return err
+	}
+	return e.scheduleLifecycle(ctx, id, status, creationTime, endTime)
+}
+
+// notifyStatus notifies participants of the event when it starts, finishes or is canceled.
+func (e *EventService) notifyStatus(ctx context.Context, event *models.Event, status models.EventStatus) {
+	notification := models.Notification{NotificationType: models.NotifyEventFinish, EventID: event.ID}
+	switch status {
+	case models.EventRunning:
+		notification.NotificationType = models.NotifyEventStart
+		notification.Message = fmt.Sprintf("event %s has started", event.Name)
+	case models.EventFinished:
+		notification.Message = fmt.Sprintf("event %s has finished", event.Name)
+	case models.EventCanceled:
+		notification.Message = fmt.Sprintf("event %s is canceled", event.Name)
+	default:
+		return
+	}
+	staffIDs := make([]uuid.UUID, 0, len(event.StaffEvents))
+	for _, staff := range event.StaffEvents {
+		if staff.Status == models.Accepted {
+			staffIDs = append(staffIDs, staff.StaffID)
+		}
+	}
+	e.notify.Notify(ctx, notification, staffIDs...)
+}
+
+// RunStartJob starts a scheduled event. Events moved out of scheduled are left as they are.
+func (e *EventService) RunStartJob(ctx context.Context, job *models.Job) error {
+	event, err := e.repo.GetEvent(ctx, job.EventID)
+	if err != nil {
+		return err
+	}
+	if event.EventStatus != models.EventScheduled {
+		return nil
+	}
+	return e.ChangeEventStatus(ctx, event.ID, models.EventRunning)
+}
+
+// RunFinishJob finishes a running event.
+func (e *EventService) RunFinishJob(ctx context.Context, job *models.Job) error {
+	event, err := e.repo.GetEvent(ctx, job.EventID)
+	if err != nil {
+		return err
+	}
+	if event.EventStatus != models.EventRunning {
+		return nil
+	}
+	return e.ChangeEventStatus(ctx, event.ID, models.EventFinished)
+}
+
+func (e *EventService) scheduleLifecycle(ctx context.Context, id uuid.UUID, status models.EventStatus,
+	creationTime, endTime time.Time) error {
+	jobs := make([]*models.Job, 0, 2)
+	switch status {
+	case models.EventScheduled:
+		jobs = append(jobs, newEventJob(id, models.JobEventStart, creationTime), newEventJob(id, models.JobEventFinish, endTime))
+	case models.EventRunning:
+		jobs = append(jobs, newEventJob(id, models.JobEventFinish, endTime))
+	}
+	for _, job := range jobs {
+		if err := e.jobs.CreateJob(ctx, job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newEventJob(eventID uuid.UUID, jobType models.JobType, runAt time.Time) *models.Job {
+	return &models.Job{
+		ID:      uuid.New(),
+		JobType: jobType,
+		EventID: eventID,
+		Status:  models.JobPending,
+		RunAt:   runAt,
+	}
+}
+
+func eventTimes(event *models.Event) (time.Time, time.Time, error) {
+	creationTime, err := parseTime(event.CreationDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, InvalidField("creation_date", "incorrent creation time: %s", err)
+	}
+	endTime, err := parseTime(event.EndDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, InvalidField("end_date", "incorrent end time: %s", err)
+	}
+	if !endTime.After(creationTime) {
+		return time.Time{}, time.Time{}, InvalidField("end_date", "incorrent end time and creation time: %s, %s", endTime, creationTime)
+	}
+	return creationTime, endTime, nil
+}
+
+// timeLayouts are formats of dates sent by clients and read back from the database.
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999", "2006-01-02T15:04:05.999999999", "2006-01-02"}
+
+func parseTime(value string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("can not parse time: %q", value)
 }
 
-func (e *EventService) GetStaffsEventsByRole(ctx context.Context, id uuid.UUID,
-	role string) ([]*models.Event, error) {
-	return e.repo.GetStaffsEventsByRole(ctx, id, role)
+func (e *EventService) GetStaffsEventsByRole(ctx context.Context, id uuid.UUID, role string,
+	query models.ListQuery) ([]*models.Event, string, error) {
+	return e.repo.GetStaffsEventsByRole(ctx, id, role, query)
 }
 
-func (e *EventService) GetStaffsEvents(ctx context.Context, id uuid.UUID) ([]*models.Event, error) {
-	return e.repo.GetStaffsEvents(ctx, id)
+func (e *EventService) GetStaffsEvents(ctx context.Context, id uuid.UUID, query models.ListQuery) ([]*models.Event, string, error) {
+	return e.repo.GetStaffsEvents(ctx, id, query)
 }
 
-func NewEventService(ctx context.Context, repo postgres.Event) *EventService {
-	return &EventService{repo: repo, ctx: ctx}
+func NewEventService(ctx context.Context, repo postgres.Event, jobs postgres.Job,
+	notify *NotificationService, stream *StreamService) *EventService {
+	return &EventService{repo: repo, jobs: jobs, notify: notify, stream: stream, ctx: ctx}
 }
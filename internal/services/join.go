@@ -0,0 +1,224 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"github.com/miprokop/fication/internal/persistence/postgres"
+	"time"
+)
+
+// joinCodeLength is the number of random bytes in a join code, it gives 16 characters.
+const joinCodeLength = 10
+
+// JoinService lets staff join events without an invite: public events directly,
+// any event with a join code, or with a join request approved by the event creator.
+// Every path applies the same team, organization and registration checks as invites.
+type JoinService struct {
+	repo   postgres.Join
+	events postgres.Event
+	event  *EventService
+	ctx    context.Context
+}
+
+// JoinEvent joins staff to a public event. Staff is waitlisted when the event is full.
+func (j *JoinService) JoinEvent(ctx context.Context, eventID, staffID uuid.UUID) (models.InviteStatus, error) {
+	event, err := j.joinable(ctx, eventID, staffID)
+	if err != nil {
+		return "", err
+	}
+	if event.EventType != "public" {
+		return "", Forbidden("event %s is %s; join it with a join code or a join request", event.ID, event.EventType)
+	}
+	status, err := j.repo.JoinEvent(ctx, newJoinInvite(eventID, staffID, models.InProgress), uuid.Nil)
+	if err != nil {
+		return "", err
+	}
+	j.event.participantJoined(ctx, eventID, staffID, status)
+	return status, nil
+}
+
+// JoinByCode joins staff to the event of the join code.
+func (j *JoinService) JoinByCode(ctx context.Context, code string, staffID uuid.UUID) (*models.StaffEvents, error) {
+	joinCode, err := j.repo.GetJoinCode(ctx, code)
+	if err != nil {
+		return nil, notFound(err, "no such join code")
+	}
+	if !joinCode.Valid(time.Now()) {
+		return nil, Conflict("join code %s is no longer valid", joinCode.ID)
+	}
+	if _, err := j.joinable(ctx, joinCode.EventID, staffID); err != nil {
+		return nil, err
+	}
+	invite := newJoinInvite(joinCode.EventID, staffID, models.InProgress)
+	invite.Status, err = j.repo.JoinEvent(ctx, invite, joinCode.ID)
+	if err != nil {
+		return nil, err
+	}
+	j.event.participantJoined(ctx, invite.EventID, staffID, invite.Status)
+	return invite, nil
+}
+
+// RequestJoin saves a request of staff to join the event, it waits for approval of the event creator.
+func (j *JoinService) RequestJoin(ctx context.Context, eventID, staffID uuid.UUID) (*models.StaffEvents, error) {
+	if _, err := j.joinable(ctx, eventID, staffID); err != nil {
+		return nil, err
+	}
+	invite := newJoinInvite(eventID, staffID, models.Requested)
+	if err := j.repo.CreateJoinRequest(ctx, invite); err != nil {
+		return nil, err
+	}
+	return invite, nil
+}
+
+// AnswerJoinRequest approves or declines a join request. Only creator and admins of the event can answer.
+// Approved staff is waitlisted when the event is full.
+func (j *JoinService) AnswerJoinRequest(ctx context.Context, id, staffID uuid.UUID,
+	status models.InviteStatus) (models.InviteStatus, error) {
+	request, err := j.repo.GetJoinRequest(ctx, id)
+	if err != nil {
+		return "", notFound(err, "no such join request %s", id)
+	}
+	event, err := j.managed(ctx, request.EventID, staffID)
+	if err != nil {
+		return "", err
+	}
+	switch status {
+	case models.Accepted:
+		if err := checkRegistration(event); err != nil {
+			return "", err
+		}
+		status, err = j.events.AcceptInvitation(ctx, request)
+		if err != nil {
+			return "", err
+		}
+		j.event.participantJoined(ctx, event.ID, request.StaffID, status)
+	case models.Declared:
+		request.Status = models.Declared
+		if err := j.events.AnswerInvitation(ctx, *request); err != nil {
+			return "", err
+		}
+	default:
+		return "", InvalidField("status", "incorrent join request answer: %s; want: %s or %s", status, models.Accepted, models.Declared)
+	}
+	j.event.notify.Notify(ctx, models.Notification{
+		NotificationType: models.NotifyInvitation,
+		Message:          fmt.Sprintf("your request to join event %s is %s", event.Name, status),
+		EventID:          event.ID,
+	}, request.StaffID)
+	return status, nil
+}
+
+func (j *JoinService) GetJoinRequests(ctx context.Context, eventID, staffID uuid.UUID) ([]*models.StaffEvents, error) {
+	if _, err := j.managed(ctx, eventID, staffID); err != nil {
+		return nil, err
+	}
+	return j.repo.GetJoinRequests(ctx, eventID)
+}
+
+// CreateJoinCode creates a random join code for the event.
+func (j *JoinService) CreateJoinCode(ctx context.Context, eventID, staffID uuid.UUID,
+	request models.JoinCodeRequest) (*models.JoinCode, error) {
+	if _, err := j.managed(ctx, eventID, staffID); err != nil {
+		return nil, err
+	}
+	code, err := newJoinCode()
+	if err != nil {
+		return nil, err
+	}
+	joinCode := &models.JoinCode{
+		ID:          uuid.New(),
+		EventID:     eventID,
+		Code:        code,
+		CreatedByID: staffID,
+		MaxUses:     request.MaxUses,
+	}
+	if request.TTL != 0 {
+		expiresAt := time.Now().Add(time.Duration(request.TTL) * time.Second)
+		joinCode.ExpiresAt = &expiresAt
+	}
+	if err := j.repo.CreateJoinCode(ctx, joinCode); err != nil {
+		return nil, err
+	}
+	return joinCode, nil
+}
+
+func (j *JoinService) GetJoinCodes(ctx context.Context, eventID, staffID uuid.UUID) ([]*models.JoinCode, error) {
+	if _, err := j.managed(ctx, eventID, staffID); err != nil {
+		return nil, err
+	}
+	return j.repo.GetJoinCodes(ctx, eventID)
+}
+
+func (j *JoinService) DeleteJoinCode(ctx context.Context, id, staffID uuid.UUID) error {
+	joinCode, err := j.repo.GetJoinCodeByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if _, err := j.managed(ctx, joinCode.EventID, staffID); err != nil {
+		return err
+	}
+	return j.repo.DeleteJoinCode(ctx, id)
+}
+
+// joinable returns the event when staff can register in it.
+func (j *JoinService) joinable(ctx context.Context, eventID, staffID uuid.UUID) (*models.Event, error) {
+	event, err := j.events.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkRegistration(event); err != nil {
+		return nil, err
+	}
+	if err := j.event.checkAccess(ctx, event, staffID); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// managed returns the event when staff is its creator or admin.
+func (j *JoinService) managed(ctx context.Context, eventID, staffID uuid.UUID) (*models.Event, error) {
+	return managedEvent(ctx, j.events, eventID, staffID)
+}
+
+// managedEvent returns the event when staff is its creator or admin.
+func managedEvent(ctx context.Context, events postgres.Event, eventID, staffID uuid.UUID) (*models.Event, error) {
+	event, err := events.GetEvent(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if event.CreatedByID == staffID {
+		return event, nil
+	}
+	for _, staff := range event.StaffEvents {
+		if staff.StaffID == staffID && (staff.StaffRole == models.Creator || staff.StaffRole == models.Admin) {
+			return event, nil
+		}
+	}
+	return nil, Forbidden("staff %s is not creator or admin of event %s", staffID, eventID)
+}
+
+func newJoinInvite(eventID, staffID uuid.UUID, status models.InviteStatus) *models.StaffEvents {
+	return &models.StaffEvents{
+		ID:        uuid.New(),
+		StaffID:   staffID,
+		EventID:   eventID,
+		Status:    status,
+		StaffRole: models.Default,
+	}
+}
+
+func newJoinCode() (string, error) {
+	b := make([]byte, joinCodeLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b), nil
+}
+
+func NewJoinService(ctx context.Context, repo postgres.Join, events postgres.Event, event *EventService) *JoinService {
+	return &JoinService{repo: repo, events: events, event: event, ctx: ctx}
+}
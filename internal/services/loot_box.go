@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	log "github.com/sirupsen/logrus"
+	"math/rand"
+)
+
+// DropTable maps a prize rarity to its weight in a loot box draw.
+type DropTable map[models.PrizeStatus]uint
+
+// NewDropTable builds a table from stored organization weights,
+// rarities that are not configured fall back to models.DefaultDropWeights.
+func NewDropTable(weights []models.DropWeight) DropTable {
+	table := make(DropTable, len(models.DefaultDropWeights))
+	for status, weight := range models.DefaultDropWeights {
+		table[status] = weight
+	}
+	for _, w := range weights {
+		table[w.PrizeStatus] = w.Weight
+	}
+	return table
+}
+
+// Draw picks a rarity by weight among the rarities that still have prizes in stock
+// and then a prize of that rarity uniformly. It returns the roll and the total weight
+// the roll was taken from, so the draw can be audited later.
+func (t DropTable) Draw(r *rand.Rand, items []*models.Prize) (*models.Prize, uint, uint, error) {
+	pool := make(map[models.PrizeStatus][]*models.Prize)
+	for _, item := range items {
+		if item.CurrentCount == 0 {
+			continue
+		}
+		pool[item.PrizeStatus] = append(pool[item.PrizeStatus], item)
+	}
+
+	var total uint
+	for _, status := range models.PrizeStatuses {
+		if len(pool[status]) != 0 {
+			total += t[status]
+		}
+	}
+	if total == 0 {
+		return nil, 0, 0, Conflict("can not draw prize; no prizes in stock for configured rarities")
+	}
+
+	roll := uint(r.Int63n(int64(total)))
+	var acc uint
+	for _, status := range models.PrizeStatuses {
+		if len(pool[status]) == 0 {
+			continue
+		}
+		acc += t[status]
+		if roll < acc {
+			prizes := pool[status]
+			return prizes[r.Intn(len(prizes))], roll, total, nil
+		}
+	}
+	return nil, 0, 0, fmt.Errorf("can not draw prize; roll %d is out of total weight %d", roll, total)
+}
+
+// checkLootBoxItems refuses items of a loot box that can not be drawn: loot boxes, archived prizes
+// and prizes of another organization than the one of the loot box creator.
+func (p *PrizeService) checkLootBoxItems(ctx context.Context, lootBox *models.Prize) error {
+	orgID, err := p.repo.GetStaffOrganization(ctx, lootBox.CreatedBy)
+	if err != nil {
+		return err
+	}
+	for _, id := range lootBox.Items {
+		item, err := p.repo.GetPrize(ctx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return InvalidField("items", "no such prize %s", id)
+		}
+		if err != nil {
+			return err
+		}
+		if item.PrizeType == models.LootBox {
+			return InvalidField("items", "prize %s is a loot box; loot boxes can not contain loot boxes", id)
+		}
+		if item.ArchivedAt != nil {
+			return InvalidField("items", "prize %s is archived", id)
+		}
+		itemOrgID, err := p.repo.GetPrizeOrganization(ctx, id)
+		if err != nil {
+			return err
+		}
+		if itemOrgID != orgID {
+			return InvalidField("items", "prize %s is not in this org", id)
+		}
+	}
+	return nil
+}
+
+func (p *PrizeService) GetDropTable(ctx context.Context, orgID uuid.UUID) (DropTable, error) {
+	weights, err := p.repo.GetDropWeights(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	return NewDropTable(weights), nil
+}
+
+func (p *PrizeService) UpdateDropTable(ctx context.Context, orgID uuid.UUID, table DropTable) error {
+	weights := make([]models.DropWeight, 0, len(table))
+	for status, weight := range table {
+		if !models.OneOf(status) {
+			return InvalidField("weights", "incorrent prize status: %s, want one of: %s, %s, %s, %s",
+				status, models.Common, models.Rare, models.Mith, models.Legendary)
+		}
+		weights = append(weights, models.DropWeight{
+			ID:             uuid.New(),
+			OrganizationID: orgID,
+			PrizeStatus:    status,
+			Weight:         weight,
+		})
+	}
+	return p.repo.SetDropWeights(ctx, weights)
+}
+
+func (p *PrizeService) OpenLootBox(ctx context.Context, staffID, lootBoxID uuid.UUID) (*models.LootBoxDraw, error) {
+	lootBox, err := p.repo.GetPrize(ctx, lootBoxID)
+	if err != nil {
+		return nil, err
+	}
+	if lootBox.PrizeType != models.LootBox {
+		return nil, Validation("can not open prize %s; it is not a loot box", lootBoxID)
+	}
+	staffPrize, err := p.repo.GetUnopenedLootBox(ctx, staffID, lootBoxID)
+	if err != nil {
+		return nil, notFound(err, "can not find unopened loot box %s for staff %s", lootBoxID, staffID)
+	}
+	orgID, err := p.repo.GetPrizeOrganization(ctx, lootBoxID)
+	if err != nil {
+		return nil, err
+	}
+	table, err := p.GetDropTable(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	items, err := p.repo.GetLootBoxItems(ctx, lootBoxID)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	prize, roll, total, err := table.Draw(p.rnd, items)
+	p.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	draw := &models.LootBoxDraw{
+		ID:           uuid.New(),
+		StaffPrizeID: staffPrize.ID,
+		LootBoxID:    lootBoxID,
+		StaffID:      staffID,
+		PrizeID:      prize.ID,
+		PrizeStatus:  prize.PrizeStatus,
+		Roll:         roll,
+		TotalWeight:  total,
+	}
+	awarded, err := models.NewDomainEvent(models.DomainPrizeAwarded, orgID, uuid.Nil, models.PrizeData{
+		PrizeID: prize.ID,
+		StaffID: staffID,
+		Name:    prize.Name,
+		LootBox: lootBox.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := p.repo.OpenLootBox(ctx, draw, awarded); err != nil {
+		return nil, err
+	}
+	log.WithFields(log.Fields{
+		"draw_id":      draw.ID,
+		"loot_box_id":  lootBoxID,
+		"staff_id":     staffID,
+		"prize_id":     prize.ID,
+		"prize_status": prize.PrizeStatus,
+		"roll":         roll,
+		"total_weight": total,
+	}).Info("loot box opened")
+	p.stream.PublishOrganization(ctx, models.StreamPrize, orgID, models.PrizeMessage{
+		StaffID: staffID,
+		PrizeID: prize.ID,
+		Name:    prize.Name,
+	})
+
+	draw.Prize = prize
+	return draw, nil
+}
+
+func (p *PrizeService) GetLootBoxDraws(ctx context.Context, lootBoxID uuid.UUID) ([]*models.LootBoxDraw, error) {
+	return p.repo.GetLootBoxDraws(ctx, lootBoxID)
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"math/rand"
+	"testing"
+)
+
+func lootBoxItems() []*models.Prize {
+	return []*models.Prize{
+		{ID: uuid.New(), Name: "sticker", PrizeStatus: models.Common, CurrentCount: 10},
+		{ID: uuid.New(), Name: "mug", PrizeStatus: models.Common, CurrentCount: 10},
+		{ID: uuid.New(), Name: "hoodie", PrizeStatus: models.Rare, CurrentCount: 10},
+		{ID: uuid.New(), Name: "day off", PrizeStatus: models.Legendary, CurrentCount: 1},
+	}
+}
+
+func TestDrawIsReproducibleWithSeed(t *testing.T) {
+	table := NewDropTable(nil)
+	items := lootBoxItems()
+	first, second := rand.New(rand.NewSource(42)), rand.New(rand.NewSource(42))
+	for i := 0; i < 100; i++ {
+		a, rollA, totalA, err := table.Draw(first, items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, rollB, totalB, err := table.Draw(second, items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a.ID != b.ID || rollA != rollB || totalA != totalB {
+			t.Fatalf("draw %d: got %s (roll %d of %d) and %s (roll %d of %d) with the same seed",
+				i, a.Name, rollA, totalA, b.Name, rollB, totalB)
+		}
+	}
+}
+
+func TestDrawSkipsOutOfStockRarities(t *testing.T) {
+	table := DropTable{models.Common: 1, models.Rare: 1000, models.Legendary: 1000}
+	items := lootBoxItems()
+	items[2].CurrentCount = 0
+	items[3].CurrentCount = 0
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		prize, roll, total, err := table.Draw(r, items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if prize.PrizeStatus != models.Common {
+			t.Fatalf("got %s prize %s; only common prizes are in stock", prize.PrizeStatus, prize.Name)
+		}
+		if total != 1 || roll != 0 {
+			t.Fatalf("got roll %d of %d; want roll 0 of 1", roll, total)
+		}
+	}
+}
+
+func TestDrawFollowsWeights(t *testing.T) {
+	table := DropTable{models.Common: 3, models.Rare: 1}
+	items := lootBoxItems()[:3]
+	r := rand.New(rand.NewSource(7))
+	counts := make(map[models.PrizeStatus]int)
+	const draws = 4000
+	for i := 0; i < draws; i++ {
+		prize, _, total, err := table.Draw(r, items)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != 4 {
+			t.Fatalf("got total weight %d; want 4", total)
+		}
+		counts[prize.PrizeStatus]++
+	}
+	if common := counts[models.Common]; common < draws*70/100 || common > draws*80/100 {
+		t.Fatalf("got %d common prizes of %d draws; want about 75%%", common, draws)
+	}
+}
+
+func TestDrawWithoutStock(t *testing.T) {
+	items := lootBoxItems()
+	for _, item := range items {
+		item.CurrentCount = 0
+	}
+	_, _, _, err := NewDropTable(nil).Draw(rand.New(rand.NewSource(1)), items)
+	if domain := ErrorOf(err); domain == nil || domain.Code != CodeConflict {
+		t.Fatalf("got error %v; want conflict", err)
+	}
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"github.com/miprokop/fication/internal/persistence/postgres"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+const (
+	notificationLimit    = 50
+	notificationMaxLimit = 200
+)
+
+// NotificationService stores notifications of staff about invitations, prizes,
+// step results, level-ups and event start and finish.
+type NotificationService struct {
+	repo      postgres.Notification
+	retention time.Duration
+	interval  time.Duration
+	ctx       context.Context
+}
+
+// Notify sends a copy of the notification to every staff who did not turn its type off.
+// Notifications are a side effect of the action that caused them, so errors are only logged.
+func (n *NotificationService) Notify(ctx context.Context, notification models.Notification, staffIDs ...uuid.UUID) {
+	if err := n.notify(ctx, notification, staffIDs...); err != nil {
+		log.WithFields(log.Fields{"type": notification.NotificationType}).Error(err)
+	}
+}
+
+// notify is Notify for callers that retry: it returns the error instead of logging it.
+// Notifications with a SourceID are created once, so retrying them does not notify anyone twice.
+func (n *NotificationService) notify(ctx context.Context, notification models.Notification, staffIDs ...uuid.UUID) error {
+	if len(staffIDs) == 0 {
+		return nil
+	}
+	muted, err := n.repo.GetMutedStaff(ctx, notification.NotificationType, staffIDs)
+	if err != nil {
+		return fmt.Errorf("can not get notification preferences: %s", err)
+	}
+	skip := make(map[uuid.UUID]bool, len(muted))
+	for _, id := range muted {
+		skip[id] = true
+	}
+	notifications := make([]*models.Notification, 0, len(staffIDs))
+	for _, staffID := range staffIDs {
+		if skip[staffID] {
+			continue
+		}
+		skip[staffID] = true
+		copied := notification
+		copied.ID = uuid.New()
+		copied.StaffID = staffID
+		notifications = append(notifications, &copied)
+	}
+	if len(notifications) == 0 {
+		return nil
+	}
+	if err := n.repo.CreateNotifications(ctx, notifications); err != nil {
+		return fmt.Errorf("can not create notifications: %s", err)
+	}
+	return nil
+}
+
+// HandleDomainEvent notifies staff about prizes it is awarded.
+func (n *NotificationService) HandleDomainEvent(ctx context.Context, event *models.DomainEvent) error {
+	if event.EventType != models.DomainPrizeAwarded {
+		return nil
+	}
+	var prize models.PrizeData
+	if err := json.Unmarshal(event.Data, &prize); err != nil {
+		return err
+	}
+	message := fmt.Sprintf("you are awarded prize %s", prize.Name)
+	if prize.LootBox != "" {
+		message = fmt.Sprintf("you got prize %s from loot box %s", prize.Name, prize.LootBox)
+	}
+	return n.notify(ctx, models.Notification{
+		NotificationType: models.NotifyPrize,
+		Message:          message,
+		StepID:           prize.StepID,
+		PrizeID:          prize.PrizeID,
+		SourceID:         event.ID,
+	}, prize.StaffID)
+}
+
+func (n *NotificationService) GetNotifications(ctx context.Context, staffID uuid.UUID,
+	filter models.NotificationFilter) ([]*models.Notification, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = notificationLimit
+	}
+	if filter.Limit > notificationMaxLimit {
+		filter.Limit = notificationMaxLimit
+	}
+	return n.repo.GetNotifications(ctx, staffID, filter)
+}
+
+func (n *NotificationService) CountUnread(ctx context.Context, staffID uuid.UUID) (int, error) {
+	return n.repo.CountUnread(ctx, staffID)
+}
+
+func (n *NotificationService) MarkRead(ctx context.Context, staffID uuid.UUID, ids []uuid.UUID) (int64, error) {
+	return n.repo.MarkRead(ctx, staffID, ids)
+}
+
+// GetPreferences returns preferences of staff for every notification type.
+func (n *NotificationService) GetPreferences(ctx context.Context, staffID uuid.UUID) ([]*models.NotificationPreference, error) {
+	saved, err := n.repo.GetPreferences(ctx, staffID)
+	if err != nil {
+		return nil, err
+	}
+	enabled := make(map[models.NotificationType]bool, len(saved))
+	for _, preference := range saved {
+		enabled[preference.NotificationType] = preference.Enabled
+	}
+	preferences := make([]*models.NotificationPreference, len(models.NotificationTypes))
+	for i, t := range models.NotificationTypes {
+		on, ok := enabled[t]
+		preferences[i] = &models.NotificationPreference{StaffID: staffID, NotificationType: t, Enabled: on || !ok}
+	}
+	return preferences, nil
+}
+
+func (n *NotificationService) UpdatePreferences(ctx context.Context, staffID uuid.UUID,
+	preferences []*models.NotificationPreference) ([]*models.NotificationPreference, error) {
+	for _, preference := range preferences {
+		t, err := models.NewNotificationType(string(preference.NotificationType))
+		if err != nil {
+			return nil, InvalidField("type", "%s", err)
+		}
+		preference.NotificationType = t
+		preference.StaffID = staffID
+	}
+	if len(preferences) != 0 {
+		if err := n.repo.SavePreferences(ctx, preferences); err != nil {
+			return nil, err
+		}
+	}
+	return n.GetPreferences(ctx, staffID)
+}
+
+// RunCleanup deletes notifications older than the retention period until ctx is done.
+// Every instance runs it, deleting is safe to repeat.
+func (n *NotificationService) RunCleanup(ctx context.Context) {
+	ticker := time.NewTicker(n.interval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := n.repo.DeleteNotificationsBefore(ctx, time.Now().Add(-n.retention))
+		if err != nil {
+			log.Errorf("can not delete old notifications: %s", err)
+		} else if deleted != 0 {
+			log.WithFields(log.Fields{"deleted": deleted}).Info("old notifications deleted")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func NewNotificationService(ctx context.Context, repo postgres.Notification, retention,
+	interval time.Duration) *NotificationService {
+	return &NotificationService{repo: repo, retention: retention, interval: interval, ctx: ctx}
+}
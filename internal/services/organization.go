@@ -2,7 +2,6 @@ package services
 
 import (
 	"context"
-	"fmt"
 	"github.com/google/uuid"
 	"github.com/miprokop/fication/internal/models"
 	"github.com/miprokop/fication/internal/persistence/postgres"
@@ -13,8 +12,8 @@ type OrganizationService struct {
 	ctx  context.Context
 }
 
-func (o *OrganizationService) GetOrganizations(ctx context.Context) ([]*models.Organization, error) {
-	return o.repo.GetOrganizations(ctx)
+func (o *OrganizationService) GetOrganizations(ctx context.Context, query models.ListQuery) ([]*models.Organization, string, error) {
+	return o.repo.GetOrganizations(ctx, query)
 }
 
 func (o *OrganizationService) GetOrganization(ctx context.Context, id uuid.UUID) (*models.Organization, error) {
@@ -42,7 +41,7 @@ func (o *OrganizationService) AddUsersToOrg(ctx context.Context, orgID uuid.UUID
 			}
 		}
 		if !hasPosition {
-			return fmt.Errorf("no such position in this org")
+			return InvalidField("position_id", "no such position in this org")
 		}
 		var hasTeam bool
 		for j := 0; j < len(org.Teams); j++ {
@@ -51,7 +50,7 @@ func (o *OrganizationService) AddUsersToOrg(ctx context.Context, orgID uuid.UUID
 			}
 		}
 		if !hasTeam {
-			return fmt.Errorf("no such team in this org")
+			return InvalidField("team_id", "no such team in this org")
 		}
 		staff := &models.Staff{
 			ID:             users[i].ID,
@@ -71,14 +70,16 @@ func (o *OrganizationService) DeleteOrganization(ctx context.Context, id uuid.UU
 	return o.repo.DeleteOrganization(ctx, id)
 }
 
-func (o *OrganizationService) GetOrganizationEvents(ctx context.Context, orgID, staffID uuid.UUID) ([]*models.Event, error) {
-	return o.repo.GetOrganizationEvents(ctx, orgID, staffID)
+func (o *OrganizationService) GetOrganizationEvents(ctx context.Context, orgID, staffID uuid.UUID,
+	query models.ListQuery) ([]*models.Event, string, error) {
+	return o.repo.GetOrganizationEvents(ctx, orgID, staffID, query)
 }
 
-func (o *OrganizationService) GetOrganizationStaff(ctx context.Context, orgID uuid.UUID) ([]models.StaffInfo, error) {
-	staff, err := o.repo.GetOrganizationStaff(ctx, orgID)
+func (o *OrganizationService) GetOrganizationStaff(ctx context.Context, orgID uuid.UUID,
+	query models.ListQuery) ([]models.StaffInfo, string, error) {
+	staff, next, err := o.repo.GetOrganizationStaff(ctx, orgID, query)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	var responseStaff = make([]models.StaffInfo, len(staff))
@@ -94,7 +95,7 @@ func (o *OrganizationService) GetOrganizationStaff(ctx context.Context, orgID uu
 		}
 	}
 
-	return responseStaff, err
+	return responseStaff, next, err
 }
 
 func (o *OrganizationService) CreateOrganizationType(ctx context.Context, orgType *models.OrganizationType) error {
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github.com/miprokop/fication/internal/models"
+	"github.com/miprokop/fication/internal/persistence/postgres"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+const (
+	outboxBatchSize   = 100
+	outboxMaxAttempts = 10
+	outboxRetryDelay  = 10 * time.Second
+	outboxLease       = time.Minute
+	// outboxCleanupInterval is how often dispatched domain events older than the retention period are deleted.
+	outboxCleanupInterval = time.Hour
+)
+
+type DomainEventHandler func(ctx context.Context, event *models.DomainEvent) error
+
+type domainEventSubscriber struct {
+	name    string
+	handler DomainEventHandler
+}
+
+// OutboxService dispatches domain events from the outbox to subscribers. A domain event is marked handled
+// by a subscriber after its handler returns, and only subscribers that failed get it again on the next attempt.
+// A domain event is dispatched when every subscriber has handled it. An instance can stop between a handler
+// and marking it handled, so handlers must be idempotent on the domain event id.
+type OutboxService struct {
+	repo      postgres.Outbox
+	interval  time.Duration
+	retention time.Duration
+	ctx       context.Context
+
+	mu          sync.RWMutex
+	subscribers []domainEventSubscriber
+}
+
+// Subscribe adds a subscriber to every domain event. Name identifies the subscriber in the outbox,
+// so it must not change once domain events are handed off to it.
+func (o *OutboxService) Subscribe(name string, handler DomainEventHandler) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.subscribers = append(o.subscribers, domainEventSubscriber{name: name, handler: handler})
+}
+
+// Run dispatches due domain events until ctx is done and deletes dispatched ones older than the retention period.
+// Every instance runs it, a domain event is claimed by one of them.
+func (o *OutboxService) Run(ctx context.Context) {
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+
+	var cleanedAt time.Time
+	for {
+		o.dispatchDue(ctx)
+		if time.Since(cleanedAt) > outboxCleanupInterval {
+			if _, err := o.repo.DeleteDispatchedBefore(ctx, time.Now().Add(-o.retention)); err != nil {
+				log.Errorf("can not delete dispatched domain events: %s", err)
+			}
+			cleanedAt = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (o *OutboxService) dispatchDue(ctx context.Context) {
+	for {
+		events, err := o.repo.ClaimDomainEvents(ctx, outboxBatchSize, outboxLease)
+		if err != nil {
+			log.Errorf("can not claim domain events: %s", err)
+			return
+		}
+		for _, event := range events {
+			o.dispatch(ctx, event)
+		}
+		if len(events) < outboxBatchSize {
+			return
+		}
+	}
+}
+
+// dispatch hands the domain event off to subscribers that have not handled it yet.
+func (o *OutboxService) dispatch(ctx context.Context, event *models.DomainEvent) {
+	o.mu.RLock()
+	subscribers := o.subscribers
+	o.mu.RUnlock()
+
+	var failed error
+	for _, subscriber := range subscribers {
+		if event.IsHandled(subscriber.name) {
+			continue
+		}
+		if err := subscriber.handler(ctx, event); err != nil {
+			failed = fmt.Errorf("%s: %s", subscriber.name, err)
+			log.WithFields(log.Fields{"domain_event": event.ID, "type": event.EventType, "attempt": event.Attempts}).
+				Warnf("can not hand off domain event to %s", failed)
+			continue
+		}
+		if err := o.repo.MarkHandled(ctx, event.ID, subscriber.name); err != nil {
+			log.Error(err)
+			failed = err
+		}
+	}
+
+	now := time.Now()
+	event.LastError = ""
+	switch {
+	case failed == nil:
+		event.DispatchedAt = &now
+	case event.Attempts >= outboxMaxAttempts:
+		log.WithFields(log.Fields{"domain_event": event.ID, "type": event.EventType}).
+			Errorf("domain event is dropped after %d attempts: %s", event.Attempts, failed)
+		event.LastError = failed.Error()
+		event.DispatchedAt = &now
+	default:
+		event.LastError = failed.Error()
+		event.NextAttemptAt = now.Add(outboxRetryDelay << (event.Attempts - 1))
+	}
+	if err := o.repo.FinishDomainEvent(ctx, event); err != nil {
+		log.Error(err)
+	}
+}
+
+// auditDomainEvent writes every domain event to the log as the audit trail of changes.
+func auditDomainEvent(ctx context.Context, event *models.DomainEvent) error {
+	log.WithFields(log.Fields{
+		"domain_event": event.ID,
+		"type":         event.EventType,
+		"organization": event.OrganizationID,
+		"event":        event.EventID,
+		"data":         string(event.Data),
+		"created_at":   event.CreatedAt,
+	}).Info("audit")
+	return nil
+}
+
+func NewOutboxService(ctx context.Context, repo postgres.Outbox, interval, retention time.Duration) *OutboxService {
+	return &OutboxService{repo: repo, interval: interval, retention: retention, ctx: ctx}
+}
@@ -0,0 +1,338 @@
+package services
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"sort"
+)
+
+// preparePrerequisites checks that required steps belong to the event of the step
+// and do not make a cycle with prerequisites of other steps.
+func preparePrerequisites(step *models.Step, steps []*models.Step, prerequisites []*models.StepPrerequisite) error {
+	inEvent := make(map[uuid.UUID]bool, len(steps))
+	for _, s := range steps {
+		inEvent[s.ID] = true
+	}
+	required := make(map[uuid.UUID]bool, len(step.Prerequisites))
+	for _, prerequisite := range step.Prerequisites {
+		if prerequisite.RequiredStepID == step.ID {
+			return InvalidField("prerequisites", "step can not require itself")
+		}
+		if !inEvent[prerequisite.RequiredStepID] {
+			return InvalidField("prerequisites", "required step %s is not in event %s", prerequisite.RequiredStepID, step.EventID)
+		}
+		switch prerequisite.Outcome {
+		case "":
+			prerequisite.Outcome = models.Done
+		case models.Done, models.Failed:
+		default:
+			return InvalidField("prerequisites", "incorrent prerequisite outcome: %s; want: %s or %s", prerequisite.Outcome,
+				models.Done, models.Failed)
+		}
+		if required[prerequisite.RequiredStepID] {
+			return InvalidField("prerequisites", "step %s is required twice", prerequisite.RequiredStepID)
+		}
+		required[prerequisite.RequiredStepID] = true
+		if prerequisite.ID == (uuid.UUID{}) {
+			prerequisite.ID = uuid.New()
+		}
+		prerequisite.StepID = step.ID
+	}
+
+	graph := make(map[uuid.UUID][]uuid.UUID)
+	for _, prerequisite := range prerequisites {
+		if prerequisite.StepID != step.ID {
+			graph[prerequisite.StepID] = append(graph[prerequisite.StepID], prerequisite.RequiredStepID)
+		}
+	}
+	for _, prerequisite := range step.Prerequisites {
+		graph[step.ID] = append(graph[step.ID], prerequisite.RequiredStepID)
+	}
+	if requires(graph, step.ID, step.ID, make(map[uuid.UUID]bool)) {
+		return InvalidField("prerequisites", "prerequisites of step %s make a cycle", step.ID)
+	}
+	return nil
+}
+
+// requires reports whether step from depends on step to through the prerequisite graph.
+func requires(graph map[uuid.UUID][]uuid.UUID, from, to uuid.UUID, seen map[uuid.UUID]bool) bool {
+	for _, next := range graph[from] {
+		if next == to {
+			return true
+		}
+		if !seen[next] {
+			seen[next] = true
+			if requires(graph, next, to, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// missingPrerequisites returns prerequisites that staff has not met: required steps
+// not done with enough score, or not failed for alternate steps.
+func missingPrerequisites(prerequisites []*models.StepPrerequisite,
+	staffSteps map[uuid.UUID]*models.StepStaff) []*models.StepPrerequisite {
+	var missing []*models.StepPrerequisite
+	for _, prerequisite := range prerequisites {
+		staffStep, ok := staffSteps[prerequisite.RequiredStepID]
+		switch {
+		case !ok:
+		case prerequisite.Outcome == models.Failed && staffStep.Accomplishment == models.Failed:
+			continue
+		case prerequisite.Outcome != models.Failed && staffStep.Accomplishment == models.Done &&
+			staffStep.Score >= prerequisite.MinScore:
+			continue
+		}
+		missing = append(missing, prerequisite)
+	}
+	return missing
+}
+
+func (s *StepService) eventStaffSteps(ctx context.Context, eventID, staffID uuid.UUID) (map[uuid.UUID]*models.StepStaff, error) {
+	staffSteps, err := s.repo.GetEventStaffSteps(ctx, eventID, staffID)
+	if err != nil {
+		return nil, err
+	}
+	byStep := make(map[uuid.UUID]*models.StepStaff, len(staffSteps))
+	for _, staffStep := range staffSteps {
+		byStep[staffStep.StepID] = staffStep
+	}
+	return byStep, nil
+}
+
+// checkUnlocked refuses to assign staff to a step with missing prerequisites.
+func (s *StepService) checkUnlocked(ctx context.Context, step *models.Step, staffID uuid.UUID) error {
+	if len(step.Prerequisites) == 0 {
+		return nil
+	}
+	staffSteps, err := s.eventStaffSteps(ctx, step.EventID, staffID)
+	if err != nil {
+		return err
+	}
+	if missing := missingPrerequisites(step.Prerequisites, staffSteps); len(missing) != 0 {
+		if missing[0].Outcome == models.Failed {
+			return Forbidden("step %s is locked; staff %s has not failed step %s", step.ID, staffID,
+				missing[0].RequiredStepID)
+		}
+		return Forbidden("step %s is locked; staff %s has not done step %s with score %d",
+			step.ID, staffID, missing[0].RequiredStepID, missing[0].MinScore)
+	}
+	return nil
+}
+
+// GetStepProgress returns steps of an event ordered by level with their state for staff.
+// Steps of branches not chosen by staff are skipped.
+func (s *StepService) GetStepProgress(ctx context.Context, eventID, staffID uuid.UUID) ([]*models.StepProgress, error) {
+	progress, _, err := s.stepProgress(ctx, eventID, staffID)
+	return progress, err
+}
+
+func (s *StepService) stepProgress(ctx context.Context, eventID, staffID uuid.UUID) ([]*models.StepProgress,
+	map[string]string, error) {
+	steps, err := s.repo.GetSteps(ctx, eventID)
+	if err != nil {
+		return nil, nil, err
+	}
+	prerequisites, err := s.repo.GetEventPrerequisites(ctx, eventID)
+	if err != nil {
+		return nil, nil, err
+	}
+	staffSteps, err := s.eventStaffSteps(ctx, eventID, staffID)
+	if err != nil {
+		return nil, nil, err
+	}
+	choices, err := s.branchChoices(ctx, eventID, staffID)
+	if err != nil {
+		return nil, nil, err
+	}
+	byStep := make(map[uuid.UUID][]*models.StepPrerequisite)
+	for _, prerequisite := range prerequisites {
+		byStep[prerequisite.StepID] = append(byStep[prerequisite.StepID], prerequisite)
+	}
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].Level < steps[j].Level })
+
+	progress := make([]*models.StepProgress, 0, len(steps))
+	for _, step := range steps {
+		step.Prerequisites = byStep[step.ID]
+		p := &models.StepProgress{Step: step, State: models.StepAvailable}
+		staffStep, assigned := staffSteps[step.ID]
+		if assigned {
+			p.Accomplishment = staffStep.Accomplishment
+			p.Score = staffStep.Score
+		}
+		switch {
+		case !step.OnPath(choices):
+			p.State = models.StepSkipped
+		case assigned && (staffStep.Accomplishment == models.InProcess || staffStep.Accomplishment == models.ReadyToCheck):
+			p.State = models.StepInProgress
+		case assigned:
+			p.State = models.StepDone
+		default:
+			if p.Missing = missingPrerequisites(step.Prerequisites, staffSteps); len(p.Missing) != 0 {
+				p.State = models.StepLocked
+			}
+		}
+		progress = append(progress, p)
+	}
+	return progress, choices, nil
+}
+
+// UpdatePrerequisites replaces prerequisites of a step. Staff already assigned to the step keeps it.
+func (s *StepService) UpdatePrerequisites(ctx context.Context, stepID uuid.UUID,
+	prerequisites []*models.StepPrerequisite) error {
+	step, err := s.repo.GetStep(ctx, stepID)
+	if err != nil {
+		return err
+	}
+	steps, err := s.repo.GetSteps(ctx, step.EventID)
+	if err != nil {
+		return err
+	}
+	existing, err := s.repo.GetEventPrerequisites(ctx, step.EventID)
+	if err != nil {
+		return err
+	}
+	step.Prerequisites = prerequisites
+	if err := preparePrerequisites(step, steps, existing); err != nil {
+		return err
+	}
+	return s.repo.ReplacePrerequisites(ctx, stepID, step.Prerequisites)
+}
+
+func (s *StepService) branchChoices(ctx context.Context, eventID, staffID uuid.UUID) (map[string]string, error) {
+	choices, err := s.repo.GetBranchChoices(ctx, eventID, staffID)
+	if err != nil {
+		return nil, err
+	}
+	branches := make(map[string]string, len(choices))
+	for _, choice := range choices {
+		branches[choice.Fork] = choice.Branch
+	}
+	return branches, nil
+}
+
+// prepareBranch checks that a step in a fork names its branch.
+func prepareBranch(step *models.Step) error {
+	if (step.Fork == "") != (step.Branch == "") {
+		return Validation("step in fork needs both fork and branch names; got fork %q and branch %q",
+			step.Fork, step.Branch)
+	}
+	return nil
+}
+
+// checkBranch refuses steps of a branch other than the one chosen by staff in the fork.
+// The first step taken in a fork chooses its branch.
+func (s *StepService) checkBranch(ctx context.Context, step *models.Step, staffID uuid.UUID) error {
+	if step.Fork == "" {
+		return nil
+	}
+	choices, err := s.branchChoices(ctx, step.EventID, staffID)
+	if err != nil {
+		return err
+	}
+	if branch, ok := choices[step.Fork]; ok {
+		if branch != step.Branch {
+			return Conflict("staff %s has chosen branch %s in fork %s", staffID, branch, step.Fork)
+		}
+		return nil
+	}
+	return s.repo.CreateBranchChoice(ctx, &models.BranchChoice{
+		ID:      uuid.New(),
+		EventID: step.EventID,
+		StaffID: staffID,
+		Fork:    step.Fork,
+		Branch:  step.Branch,
+	})
+}
+
+// ChooseBranch picks a branch of a fork for staff and assigns staff to the first
+// available step of the branch. The choice can not be changed.
+func (s *StepService) ChooseBranch(ctx context.Context, eventID, staffID uuid.UUID, fork, branch string) error {
+	if err := s.checkEventRunning(ctx, eventID); err != nil {
+		return err
+	}
+	progress, choices, err := s.stepProgress(ctx, eventID, staffID)
+	if err != nil {
+		return err
+	}
+	if chosen, ok := choices[fork]; ok {
+		return Conflict("branch %s is already chosen in fork %s", chosen, fork)
+	}
+	var first *models.StepProgress
+	exists := false
+	for _, p := range progress {
+		if p.Step.Fork != fork || p.Step.Branch != branch {
+			continue
+		}
+		exists = true
+		if first == nil && p.State == models.StepAvailable {
+			first = p
+		}
+	}
+	if !exists {
+		return NotFound("event %s has no branch %s in fork %s", eventID, branch, fork)
+	}
+	if first != nil {
+		return s.AssignStaff(ctx, staffID, first.Step.ID)
+	}
+	return s.repo.CreateBranchChoice(ctx, &models.BranchChoice{
+		ID:      uuid.New(),
+		EventID: eventID,
+		StaffID: staffID,
+		Fork:    fork,
+		Branch:  branch,
+	})
+}
+
+// GetStepGraph returns all steps of an event with prerequisites and forks.
+func (s *StepService) GetStepGraph(ctx context.Context, eventID uuid.UUID) (*models.StepGraph, error) {
+	steps, err := s.repo.GetSteps(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	prerequisites, err := s.repo.GetEventPrerequisites(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].Level < steps[j].Level })
+
+	graph := &models.StepGraph{Steps: steps, Prerequisites: prerequisites}
+	forks := make(map[string]*models.Fork)
+	for _, step := range steps {
+		if step.Fork == "" {
+			continue
+		}
+		fork, ok := forks[step.Fork]
+		if !ok {
+			fork = &models.Fork{Name: step.Fork}
+			forks[step.Fork] = fork
+			graph.Forks = append(graph.Forks, fork)
+		}
+		if !containsString(fork.Branches, step.Branch) {
+			fork.Branches = append(fork.Branches, step.Branch)
+		}
+	}
+	return graph, nil
+}
+
+// GetStaffPath returns branch choices of staff and the steps staff has taken on its path.
+func (s *StepService) GetStaffPath(ctx context.Context, eventID, staffID uuid.UUID) (*models.StaffPath, error) {
+	progress, _, err := s.stepProgress(ctx, eventID, staffID)
+	if err != nil {
+		return nil, err
+	}
+	choices, err := s.repo.GetBranchChoices(ctx, eventID, staffID)
+	if err != nil {
+		return nil, err
+	}
+	path := &models.StaffPath{StaffID: staffID, Choices: choices}
+	for _, p := range progress {
+		if p.State == models.StepInProgress || p.State == models.StepDone {
+			path.Steps = append(path.Steps, p)
+		}
+	}
+	return path, nil
+}
@@ -2,15 +2,21 @@ package services
 
 import (
 	"context"
-	"fmt"
 	"github.com/google/uuid"
 	"github.com/miprokop/fication/internal/models"
 	"github.com/miprokop/fication/internal/persistence/postgres"
+	"math/rand"
+	"sync"
 )
 
 type PrizeService struct {
-	repo postgres.Prize
-	ctx  context.Context
+	repo   postgres.Prize
+	notify *NotificationService
+	stream *StreamService
+	ctx    context.Context
+
+	mu  sync.Mutex
+	rnd *rand.Rand
 }
 
 func (p *PrizeService) GetPrizesByType(ctx context.Context, prizeType models.PrizeType) ([]*models.Prize, error) {
@@ -18,6 +24,11 @@ func (p *PrizeService) GetPrizesByType(ctx context.Context, prizeType models.Pri
 }
 
 func (p *PrizeService) CreatePrize(ctx context.Context, prize *models.Prize) error {
+	if prize.PrizeType == models.LootBox {
+		if err := p.checkLootBoxItems(ctx, prize); err != nil {
+			return err
+		}
+	}
 	return p.repo.CreatePrize(ctx, prize)
 }
 
@@ -25,18 +36,40 @@ func (p *PrizeService) GetPrize(ctx context.Context, id uuid.UUID) (*models.Priz
 	return p.repo.GetPrize(ctx, id)
 }
 
-func (p *PrizeService) GetPrizes(ctx context.Context, userID uuid.UUID) ([]*models.Prize, error) {
-	return p.repo.GetPrizes(ctx, userID)
+func (p *PrizeService) GetPrizes(ctx context.Context, userID uuid.UUID, query models.ListQuery) ([]*models.Prize, string, error) {
+	return p.repo.GetPrizes(ctx, userID, query)
+}
+
+func (p *PrizeService) GetAllPrizes(ctx context.Context, filter models.PrizeFilter,
+	query models.ListQuery) ([]*models.Prize, string, error) {
+	return p.repo.GetAllPrizes(ctx, filter, query)
 }
 
-func (p *PrizeService) GetAllPrizes(ctx context.Context) ([]*models.Prize, error) {
-	//TODO implement me
-	panic("implement me")
+// DeletePrize archives the prize of the organization, staff who already own it keep it.
+func (p *PrizeService) DeletePrize(ctx context.Context, id, orgID uuid.UUID) error {
+	if err := p.owned(ctx, id, orgID); err != nil {
+		return err
+	}
+	return p.repo.DeletePrize(ctx, id)
 }
 
-func (p *PrizeService) DeletePrize(ctx context.Context, id uuid.UUID) error {
-	//TODO implement me
-	panic("implement me")
+func (p *PrizeService) RestorePrize(ctx context.Context, id, orgID uuid.UUID) error {
+	if err := p.owned(ctx, id, orgID); err != nil {
+		return err
+	}
+	return p.repo.RestorePrize(ctx, id)
+}
+
+// owned checks that the prize is created in the organization.
+func (p *PrizeService) owned(ctx context.Context, id, orgID uuid.UUID) error {
+	prizeOrgID, err := p.repo.GetPrizeOrganization(ctx, id)
+	if err != nil {
+		return notFound(err, "can not find prize %s", id)
+	}
+	if prizeOrgID != orgID {
+		return Forbidden("prize %s is not in organization %s", id, orgID)
+	}
+	return nil
 }
 
 func (p *PrizeService) GivePrize(ctx context.Context, userID, prizeID uuid.UUID) error {
@@ -44,27 +77,49 @@ func (p *PrizeService) GivePrize(ctx context.Context, userID, prizeID uuid.UUID)
 	if err != nil {
 		return err
 	}
+	if prize.ArchivedAt != nil {
+		return Conflict("can not give prize to user; prize is archived")
+	}
 	if prize.CurrentCount == 0 {
-		return fmt.Errorf("can not give prize to user; current count of prizes is zero")
+		return Conflict("can not give prize to user; current count of prizes is zero")
 	}
-	prize.CurrentCount -= 1
-	err = p.repo.UpdatePrize(ctx, prize)
+	orgID, err := p.repo.GetPrizeOrganization(ctx, prize.ID)
+	if err != nil {
+		return err
+	}
+	awarded, err := models.NewDomainEvent(models.DomainPrizeAwarded, orgID, uuid.Nil, models.PrizeData{
+		PrizeID: prize.ID,
+		StaffID: userID,
+		StepID:  prize.StepID,
+		Name:    prize.Name,
+	})
 	if err != nil {
 		return err
 	}
-
 	staffPrize := &models.StaffPrize{
 		ID:      uuid.New(),
 		StaffID: userID,
 		PrizeID: prizeID,
 	}
-	return p.repo.GivePrize(ctx, staffPrize)
+	if err := p.repo.GivePrize(ctx, staffPrize, awarded); err != nil {
+		return err
+	}
+	message := models.PrizeMessage{StaffID: userID, PrizeID: prize.ID, Name: prize.Name}
+	if prize.StepID != uuid.Nil {
+		p.stream.PublishStep(ctx, models.StreamPrize, prize.StepID, message)
+		return nil
+	}
+	p.stream.PublishOrganization(ctx, models.StreamPrize, orgID, message)
+	return nil
 }
 
 func (p *PrizeService) UpdatePrize(ctx context.Context, prize *models.Prize) error {
 	return p.repo.UpdatePrize(ctx, prize)
 }
 
-func NewPrizeService(ctx context.Context, repo postgres.Prize) *PrizeService {
-	return &PrizeService{repo: repo, ctx: ctx}
+// NewPrizeService creates a prize service, src is the random source of loot box draws
+// and can be seeded to make the draws reproducible.
+func NewPrizeService(ctx context.Context, repo postgres.Prize, src rand.Source, notify *NotificationService,
+	stream *StreamService) *PrizeService {
+	return &PrizeService{repo: repo, notify: notify, stream: stream, ctx: ctx, rnd: rand.New(src)}
 }
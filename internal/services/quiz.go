@@ -0,0 +1,366 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// prepareQuiz checks quiz questions and their answer keys.
+// A step without max score gets the total score of its questions.
+func prepareQuiz(step *models.Step) error {
+	if step.StepType != models.QuizStep {
+		if len(step.Questions) != 0 {
+			return Validation("can not add questions to %s step", step.StepType)
+		}
+		return nil
+	}
+	if len(step.Rubric) != 0 {
+		return Validation("can not add rubric to quiz step; quiz is graded by answer key")
+	}
+	var total uint
+	for i, question := range step.Questions {
+		if err := checkQuestion(question); err != nil {
+			return InvalidField("questions", "can not create question %d: %s", i+1, err)
+		}
+		if question.ID == (uuid.UUID{}) {
+			question.ID = uuid.New()
+		}
+		question.StepID = step.ID
+		question.Position = uint(i)
+		total += question.Score
+	}
+	if step.MaxScore == 0 {
+		step.MaxScore = total
+	}
+	if total > step.MaxScore {
+		return InvalidField("questions", "can not create quiz; questions score %d is more than step max score %d", total, step.MaxScore)
+	}
+	if step.PassScore > step.MaxScore {
+		return InvalidField("pass_score", "can not create quiz; pass score %d is more than max score %d", step.PassScore, step.MaxScore)
+	}
+	return nil
+}
+
+func checkQuestion(question *models.QuizQuestion) error {
+	questionType, err := models.NewQuestionType(string(question.QuestionType))
+	if err != nil {
+		return err
+	}
+	question.QuestionType = questionType
+	if question.Text == "" {
+		return fmt.Errorf("question has no text")
+	}
+	if question.Score == 0 {
+		question.Score = 1
+	}
+	switch questionType {
+	case models.SingleChoice, models.MultipleChoice:
+		if len(question.Options) < 2 {
+			return fmt.Errorf("choice question needs at least 2 options")
+		}
+		if len(question.CorrectOptions) == 0 {
+			return fmt.Errorf("choice question has no correct options")
+		}
+		if questionType == models.SingleChoice && len(question.CorrectOptions) != 1 {
+			return fmt.Errorf("single choice question needs exactly 1 correct option")
+		}
+		for _, option := range question.CorrectOptions {
+			if option < 0 || option >= len(question.Options) {
+				return fmt.Errorf("correct option %d is out of options", option)
+			}
+		}
+	case models.Numeric:
+		if question.Tolerance < 0 {
+			return fmt.Errorf("numeric question has negative tolerance")
+		}
+	case models.ShortAnswer:
+		if len(question.TextAnswers) == 0 {
+			return fmt.Errorf("short answer question has no accepted answers")
+		}
+	}
+	return nil
+}
+
+// GradeAnswer reports whether the answer matches the question key.
+// Multiple choice answers are correct only when all correct options are chosen.
+func GradeAnswer(question *models.QuizQuestion, answer *models.QuizAnswer) bool {
+	switch question.QuestionType {
+	case models.SingleChoice, models.MultipleChoice:
+		return sameOptions(question.CorrectOptions, answer.Options)
+	case models.Numeric:
+		return answer.Number != nil && math.Abs(*answer.Number-question.NumericAnswer) <= question.Tolerance
+	case models.ShortAnswer:
+		text := strings.TrimSpace(answer.Text)
+		for _, accepted := range question.TextAnswers {
+			if strings.EqualFold(text, strings.TrimSpace(accepted)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sameOptions(want, got []int) bool {
+	set := make(map[int]bool, len(got))
+	for _, option := range got {
+		set[option] = true
+	}
+	if len(set) != len(want) {
+		return false
+	}
+	for _, option := range want {
+		if !set[option] {
+			return false
+		}
+	}
+	return true
+}
+
+// quizStaffStep checks that staff can take the quiz of the step.
+func (s *StepService) quizStaffStep(ctx context.Context, stepID, staffID uuid.UUID) (*models.Step, error) {
+	step, err := s.repo.GetStep(ctx, stepID)
+	if err != nil {
+		return nil, err
+	}
+	if step.StepType != models.QuizStep {
+		return nil, Validation("step %s is not a quiz", stepID)
+	}
+	if step.Status == models.Finished || step.Status == models.Canceled {
+		return nil, Conflict("can not take quiz; step status is %s", step.Status)
+	}
+	if err := s.checkEventRunning(ctx, step.EventID); err != nil {
+		return nil, err
+	}
+	staffStep, err := s.repo.GetStepStaff(ctx, stepID, staffID)
+	if err != nil {
+		return nil, Forbidden("staff %s is not assigned to step %s", staffID, stepID)
+	}
+	if staffStep.Accomplishment != models.InProcess {
+		return nil, Conflict("can not take quiz; step is already %s", staffStep.Accomplishment)
+	}
+	return step, nil
+}
+
+// StartQuiz returns the active quiz attempt of staff or starts a new one.
+// An expired attempt is closed with zero score and counts against the attempts limit.
+func (s *StepService) StartQuiz(ctx context.Context, stepID, staffID uuid.UUID) (*models.QuizSession, error) {
+	step, err := s.quizStaffStep(ctx, stepID, staffID)
+	if err != nil {
+		return nil, err
+	}
+	questions, err := s.repo.GetQuizQuestions(ctx, stepID)
+	if err != nil {
+		return nil, err
+	}
+	if len(questions) == 0 {
+		return nil, Conflict("quiz %s has no questions", stepID)
+	}
+	attempts, err := s.repo.GetQuizAttempts(ctx, stepID, staffID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if n := len(attempts); n != 0 && attempts[n-1].SubmittedAt == nil {
+		last := attempts[n-1]
+		if last.Active(now) {
+			return newQuizSession(step, last, questions, len(attempts)), nil
+		}
+		accomplishment, err := s.finishQuizAttempt(ctx, step, attempts, last, questions, nil, now)
+		if err != nil {
+			return nil, err
+		}
+		if accomplishment != models.InProcess {
+			return nil, Conflict("can not start quiz; last attempt expired and step is %s", accomplishment)
+		}
+	}
+	if step.AttemptsLimit != 0 && uint(len(attempts)) >= step.AttemptsLimit {
+		return nil, Conflict("can not start quiz; all %d attempts are used", step.AttemptsLimit)
+	}
+
+	order := make([]uuid.UUID, len(questions))
+	for i, question := range questions {
+		order[i] = question.ID
+	}
+	if step.ShuffleQuestions {
+		rnd := rand.New(rand.NewSource(now.UnixNano()))
+		rnd.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+	}
+	attempt := &models.QuizAttempt{
+		ID:            uuid.New(),
+		StepID:        stepID,
+		StaffID:       staffID,
+		QuestionOrder: order,
+		StartedAt:     now,
+	}
+	if step.TimeLimit != 0 {
+		deadline := now.Add(time.Duration(step.TimeLimit) * time.Second)
+		attempt.Deadline = &deadline
+	}
+	if err := s.repo.CreateQuizAttempt(ctx, attempt); err != nil {
+		return nil, err
+	}
+	return newQuizSession(step, attempt, questions, len(attempts)+1), nil
+}
+
+func newQuizSession(step *models.Step, attempt *models.QuizAttempt, questions []*models.QuizQuestion,
+	used int) *models.QuizSession {
+	byID := make(map[uuid.UUID]*models.QuizQuestion, len(questions))
+	for _, question := range questions {
+		byID[question.ID] = question
+	}
+	session := &models.QuizSession{Attempt: attempt, AttemptsLeft: -1}
+	for _, id := range attempt.QuestionOrder {
+		if question, ok := byID[id]; ok {
+			session.Questions = append(session.Questions, question.View())
+		}
+	}
+	if step.AttemptsLimit != 0 {
+		session.AttemptsLeft = int(step.AttemptsLimit) - used
+	}
+	return session
+}
+
+// SubmitQuiz grades answers of the active attempt. Answers after the time limit
+// are not graded and the attempt gets zero score.
+func (s *StepService) SubmitQuiz(ctx context.Context, stepID, staffID uuid.UUID,
+	answers []*models.QuizAnswer) (*models.QuizAttempt, error) {
+	step, err := s.quizStaffStep(ctx, stepID, staffID)
+	if err != nil {
+		return nil, err
+	}
+	attempts, err := s.repo.GetQuizAttempts(ctx, stepID, staffID)
+	if err != nil {
+		return nil, err
+	}
+	if len(attempts) == 0 || attempts[len(attempts)-1].SubmittedAt != nil {
+		return nil, Conflict("can not submit quiz; no started attempt")
+	}
+	questions, err := s.repo.GetQuizQuestions(ctx, stepID)
+	if err != nil {
+		return nil, err
+	}
+	attempt := attempts[len(attempts)-1]
+	_, err = s.finishQuizAttempt(ctx, step, attempts, attempt, questions, answers, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return attempt, nil
+}
+
+// finishQuizAttempt grades the attempt and updates the staff step with the best attempt score.
+// Staff passes with pass score, fails when no attempts are left, otherwise can try again.
+func (s *StepService) finishQuizAttempt(ctx context.Context, step *models.Step, attempts []*models.QuizAttempt,
+	attempt *models.QuizAttempt, questions []*models.QuizQuestion, answers []*models.QuizAnswer,
+	now time.Time) (models.Accomplishment, error) {
+	attempt.Expired = !attempt.Active(now)
+	attempt.SubmittedAt = &now
+	attempt.Score = 0
+	attempt.Answers = nil
+	if !attempt.Expired {
+		byID := make(map[uuid.UUID]*models.QuizQuestion, len(questions))
+		for _, question := range questions {
+			byID[question.ID] = question
+		}
+		for _, answer := range answers {
+			question, ok := byID[answer.QuestionID]
+			if !ok {
+				return "", InvalidField("answers", "question %s is not in quiz", answer.QuestionID)
+			}
+			delete(byID, answer.QuestionID)
+			answer.ID = uuid.New()
+			answer.AttemptID = attempt.ID
+			answer.Correct = GradeAnswer(question, answer)
+			answer.Score = 0
+			if answer.Correct {
+				answer.Score = question.Score
+			}
+			sort.Ints(answer.Options)
+			attempt.Score += answer.Score
+			attempt.Answers = append(attempt.Answers, answer)
+		}
+	}
+
+	best := attempt.Score
+	for _, previous := range attempts {
+		if previous.ID != attempt.ID && previous.SubmittedAt != nil && previous.Score > best {
+			best = previous.Score
+		}
+	}
+	// Without pass score staff passes by answering every question, questions can score
+	// less than the step max score.
+	passScore := step.PassScore
+	if passScore == 0 {
+		for _, question := range questions {
+			passScore += question.Score
+		}
+	}
+	accomplishment := models.InProcess
+	switch {
+	case best >= passScore:
+		accomplishment = models.Done
+	case step.AttemptsLimit != 0 && uint(len(attempts)) >= step.AttemptsLimit:
+		accomplishment = models.Failed
+	}
+
+	err := s.repo.FinishQuizAttempt(ctx, attempt, models.StepStaff{
+		StepID:         step.ID,
+		StaffID:        attempt.StaffID,
+		Accomplishment: accomplishment,
+		Score:          best,
+	})
+	if err != nil {
+		return "", err
+	}
+	if accomplishment != models.InProcess {
+		s.reportResult(ctx, step, attempt.StaffID, accomplishment, best)
+	}
+	if accomplishment == models.Done {
+		return accomplishment, s.assignNextStep(ctx, step, attempt.StaffID)
+	}
+	return accomplishment, nil
+}
+
+func (s *StepService) GetQuizAttempts(ctx context.Context, stepID, staffID uuid.UUID) ([]*models.QuizAttempt, error) {
+	return s.repo.GetQuizAttempts(ctx, stepID, staffID)
+}
+
+// GetQuizKey returns quiz questions with their answer key to creator or admin of the event.
+func (s *StepService) GetQuizKey(ctx context.Context, stepID, staffID uuid.UUID) ([]*models.QuizQuestion, error) {
+	if _, err := s.managedStep(ctx, stepID, staffID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetQuizQuestions(ctx, stepID)
+}
+
+// UpdateQuiz replaces quiz questions, only creator or admin of the event may do it.
+// Questions can not be changed after staff started the quiz.
+func (s *StepService) UpdateQuiz(ctx context.Context, stepID, staffID uuid.UUID, questions []*models.QuizQuestion) error {
+	step, err := s.managedStep(ctx, stepID, staffID)
+	if err != nil {
+		return err
+	}
+	if step.StepType != models.QuizStep {
+		return Validation("step %s is not a quiz", stepID)
+	}
+	started, err := s.repo.HasQuizAttempts(ctx, stepID)
+	if err != nil {
+		return err
+	}
+	if started {
+		return Conflict("can not update quiz; step %s already has attempts", stepID)
+	}
+	step.Questions = questions
+	step.MaxScore = 0
+	if err := prepareQuiz(step); err != nil {
+		return err
+	}
+	return s.repo.ReplaceQuizQuestions(ctx, stepID, step.Questions, step.MaxScore)
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"github.com/miprokop/fication/internal/persistence/postgres"
+	log "github.com/sirupsen/logrus"
+	"sort"
+)
+
+// AggregateScores combines reviewer scores into the final step score.
+// The trimmed mean drops the lowest and the highest score when there are at least three of them.
+func AggregateScores(strategy models.ScoreStrategy, scores []uint) uint {
+	if len(scores) == 0 {
+		return 0
+	}
+	sorted := make([]uint, len(scores))
+	copy(sorted, scores)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	switch strategy {
+	case models.Median:
+		mid := len(sorted) / 2
+		if len(sorted)%2 == 1 {
+			return sorted[mid]
+		}
+		return roundDiv(sorted[mid-1]+sorted[mid], 2)
+	case models.TrimmedMean:
+		if len(sorted) >= 3 {
+			sorted = sorted[1 : len(sorted)-1]
+		}
+	}
+
+	var sum uint
+	for _, score := range sorted {
+		sum += score
+	}
+	return roundDiv(sum, uint(len(sorted)))
+}
+
+func roundDiv(a, b uint) uint {
+	return (a + b/2) / b
+}
+
+// checkConflictOfInterest refuses reviewers that review their own work
+// or, unless the step allows it, work of their own team.
+func checkConflictOfInterest(step *models.Step, author, reviewer *models.Staff) error {
+	if author.ID == reviewer.ID {
+		return Forbidden("staff %s can not review own submission", reviewer.ID)
+	}
+	if !step.AllowSameTeam && author.TeamID != (uuid.UUID{}) &&
+		author.TeamID != models.DefaultTeam.ID && author.TeamID == reviewer.TeamID {
+		return Forbidden("staff %s can not review submission of own team", reviewer.ID)
+	}
+	return nil
+}
+
+// pickReviewers chooses the least loaded eligible reviewers for a submission,
+// fewer than ReviewersCount when not enough reviewers are eligible.
+func (s *StepService) pickReviewers(ctx context.Context, step *models.Step, author *models.Staff) ([]*models.Staff, error) {
+	var (
+		candidates []*models.Staff
+		err        error
+	)
+	if step.PeerReview {
+		candidates, err = s.repo.GetStepParticipants(ctx, step.ID)
+	} else {
+		candidates, err = s.repo.GetEventReviewers(ctx, step.EventID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	eligible := make([]*models.Staff, 0, len(candidates))
+	ids := make([]uuid.UUID, 0, len(candidates))
+	for _, candidate := range candidates {
+		if checkConflictOfInterest(step, author, candidate) == nil {
+			eligible = append(eligible, candidate)
+			ids = append(ids, candidate.ID)
+		}
+	}
+	load, err := s.repo.GetReviewerLoad(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(eligible, func(i, j int) bool {
+		if load[eligible[i].ID] != load[eligible[j].ID] {
+			return load[eligible[i].ID] < load[eligible[j].ID]
+		}
+		return eligible[i].ID.String() < eligible[j].ID.String()
+	})
+
+	n := int(step.ReviewersCount)
+	if n < 1 {
+		n = 1
+	}
+	if len(eligible) == 0 {
+		return nil, Conflict("can not submit work; step %s has no eligible reviewers", step.ID)
+	}
+	if len(eligible) < n {
+		log.Warnf("step %s needs %d reviewers; only %d eligible", step.ID, n, len(eligible))
+		n = len(eligible)
+	}
+	return eligible[:n], nil
+}
+
+func (s *StepService) AssignReviewer(ctx context.Context, submissionID, reviewerID uuid.UUID) error {
+	submission, err := s.pendingSubmission(ctx, submissionID)
+	if err != nil {
+		return err
+	}
+	for _, review := range submission.Reviews {
+		if review.ReviewerID == reviewerID {
+			return Conflict("staff %s is already reviewing submission %s", reviewerID, submissionID)
+		}
+	}
+	var reviewer *models.Staff
+	candidates, err := s.repo.GetEventReviewers(ctx, submission.Step.EventID)
+	if err != nil {
+		return err
+	}
+	if submission.Step.PeerReview {
+		participants, err := s.repo.GetStepParticipants(ctx, submission.StepID)
+		if err != nil {
+			return err
+		}
+		candidates = append(candidates, participants...)
+	}
+	for _, candidate := range candidates {
+		if candidate.ID == reviewerID {
+			reviewer = candidate
+			break
+		}
+	}
+	if reviewer == nil {
+		return Forbidden("staff %s can not review submissions of this step", reviewerID)
+	}
+	if err := checkConflictOfInterest(submission.Step, submission.Staff, reviewer); err != nil {
+		return err
+	}
+	return s.repo.CreateReviewAssignments(ctx, []*models.ReviewAssignment{{
+		ID:           uuid.New(),
+		SubmissionID: submissionID,
+		ReviewerID:   reviewerID,
+		Status:       models.ReviewAssigned,
+	}})
+}
+
+func (s *StepService) GetReviewerAssignments(ctx context.Context, reviewerID uuid.UUID) ([]*models.ReviewAssignment, error) {
+	return s.repo.GetReviewerAssignments(ctx, reviewerID)
+}
+
+// SubmitReview stores a reviewer score. When every required review is done
+// the scores are aggregated by the step strategy and the submission is approved.
+func (s *StepService) SubmitReview(ctx context.Context, assignmentID, reviewerID uuid.UUID, review models.SubmissionReview) error {
+	assignment, err := s.repo.GetReviewAssignment(ctx, assignmentID)
+	if err != nil {
+		return err
+	}
+	if assignment.ReviewerID != reviewerID {
+		return Forbidden("review %s is assigned to another staff", assignmentID)
+	}
+	submission, err := s.pendingSubmission(ctx, assignment.SubmissionID)
+	if err != nil {
+		return err
+	}
+	submission.Step.Rubric, err = s.repo.GetRubric(ctx, submission.StepID)
+	if err != nil {
+		return err
+	}
+	score, err := stepScore(submission.Step, review.Score, review.Criteria)
+	if err != nil {
+		return err
+	}
+
+	assignment.Status = models.ReviewDone
+	assignment.Score = score
+	assignment.Comment = review.Comment
+	assignment.Criteria = review.Criteria
+	if err := s.repo.CompleteReview(ctx, assignment); err != nil {
+		return err
+	}
+
+	return s.aggregateReviews(ctx, submission)
+}
+
+func (s *StepService) aggregateReviews(ctx context.Context, submission *models.Submission) error {
+	reviews, err := s.repo.GetReviewAssignments(ctx, submission.ID)
+	if err != nil {
+		return err
+	}
+	// Steps with few eligible reviewers get fewer reviews than ReviewersCount,
+	// so the submission is approved once every assigned review is done.
+	scores := make([]uint, 0, len(reviews))
+	for _, review := range reviews {
+		if review.Status != models.ReviewDone {
+			return nil
+		}
+		scores = append(scores, review.Score)
+	}
+	if len(scores) == 0 {
+		return nil
+	}
+
+	submission.Status = models.SubmissionApproved
+	submission.Score = AggregateScores(submission.Step.ScoreStrategy, scores)
+	submission.ReviewerID = uuid.UUID{}
+	if len(submission.Step.Rubric) != 0 {
+		criteria, err := s.repo.GetReviewCriterionScores(ctx, submission.ID)
+		if err != nil {
+			return err
+		}
+		if len(criteria) != 0 {
+			// The breakdown is aggregated per criterion, so the total follows it.
+			submission.Criteria = aggregateCriteria(submission.Step, criteria)
+			if submission.Score, err = RubricScore(submission.Step.Rubric, submission.Criteria); err != nil {
+				return err
+			}
+		}
+	}
+	// Reviews finished at the same time can both see every review done,
+	// only the one that approves the pending submission goes on.
+	err = s.repo.ReviewSubmission(ctx, submission, models.Done, nil)
+	var conflict *postgres.ConflictError
+	if errors.As(err, &conflict) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	s.reportResult(ctx, submission.Step, submission.StaffID, models.Done, submission.Score)
+	return s.assignNextStep(ctx, submission.Step, submission.StaffID)
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"math"
+)
+
+// prepareRubric fills criteria defaults and checks the rubric fits the step max score.
+// A step without max score gets the highest weighted total of its rubric.
+func prepareRubric(step *models.Step) error {
+	if len(step.Rubric) == 0 {
+		return nil
+	}
+	var total float64
+	for i, criterion := range step.Rubric {
+		if criterion.Name == "" {
+			return InvalidField("criteria", "can not create rubric; criterion %d has no name", i+1)
+		}
+		if criterion.MaxScore == 0 {
+			return InvalidField("criteria", "can not create rubric; criterion %s has no max score", criterion.Name)
+		}
+		if criterion.Weight < 0 {
+			return InvalidField("criteria", "can not create rubric; criterion %s has negative weight", criterion.Name)
+		}
+		if criterion.Weight == 0 {
+			criterion.Weight = 1
+		}
+		if criterion.ID == (uuid.UUID{}) {
+			criterion.ID = uuid.New()
+		}
+		criterion.StepID = step.ID
+		criterion.Position = uint(i)
+		total += float64(criterion.MaxScore) * criterion.Weight
+	}
+	weighted := uint(math.Round(total))
+	if step.MaxScore == 0 {
+		step.MaxScore = weighted
+	}
+	if weighted > step.MaxScore {
+		return InvalidField("criteria", "can not create rubric; weighted max %d is more than step max score %d", weighted, step.MaxScore)
+	}
+	return nil
+}
+
+// RubricScore checks that every rubric criterion is scored once within its max
+// and returns the weighted total of the scores.
+func RubricScore(rubric []*models.RubricCriterion, scores []*models.CriterionScore) (uint, error) {
+	criteria := make(map[uuid.UUID]*models.RubricCriterion, len(rubric))
+	for _, criterion := range rubric {
+		criteria[criterion.ID] = criterion
+	}
+	seen := make(map[uuid.UUID]bool, len(scores))
+	var total float64
+	for _, score := range scores {
+		criterion, ok := criteria[score.CriterionID]
+		if !ok {
+			return 0, InvalidField("scores", "criterion %s is not in step rubric", score.CriterionID)
+		}
+		if seen[score.CriterionID] {
+			return 0, InvalidField("scores", "criterion %s is scored twice", criterion.Name)
+		}
+		seen[score.CriterionID] = true
+		if score.Score > criterion.MaxScore {
+			return 0, InvalidField("scores", "can not give criterion %s score: %d; max: %d", criterion.Name, score.Score, criterion.MaxScore)
+		}
+		total += float64(score.Score) * criterion.Weight
+	}
+	if len(seen) != len(criteria) {
+		return 0, InvalidField("scores", "all %d rubric criteria must be scored; got %d", len(criteria), len(seen))
+	}
+	return uint(math.Round(total)), nil
+}
+
+// stepScore returns the score to give for a step. Steps with a rubric are scored
+// by criteria, the others by the plain score.
+func stepScore(step *models.Step, score uint, criteria []*models.CriterionScore) (uint, error) {
+	if len(step.Rubric) == 0 {
+		if len(criteria) != 0 {
+			return 0, Conflict("step %s has no rubric to score criteria", step.ID)
+		}
+	} else if len(criteria) != 0 || score != 0 {
+		var err error
+		score, err = RubricScore(step.Rubric, criteria)
+		if err != nil {
+			return 0, err
+		}
+	}
+	if step.MaxScore < score {
+		return 0, InvalidField("score", "can not give staff score: %d; max: %d", score, step.MaxScore)
+	}
+	return score, nil
+}
+
+// aggregateCriteria combines reviewer criterion scores by the step strategy.
+func aggregateCriteria(step *models.Step, scores []*models.CriterionScore) []*models.CriterionScore {
+	byCriterion := make(map[uuid.UUID][]uint, len(step.Rubric))
+	for _, score := range scores {
+		byCriterion[score.CriterionID] = append(byCriterion[score.CriterionID], score.Score)
+	}
+	result := make([]*models.CriterionScore, 0, len(step.Rubric))
+	for _, criterion := range step.Rubric {
+		result = append(result, &models.CriterionScore{
+			CriterionID: criterion.ID,
+			Score:       AggregateScores(step.ScoreStrategy, byCriterion[criterion.ID]),
+		})
+	}
+	return result
+}
+
+// UpdateRubric replaces step rubric. Rubric can not be changed after staff got criterion scores.
+func (s *StepService) UpdateRubric(ctx context.Context, stepID uuid.UUID, rubric []*models.RubricCriterion) error {
+	step, err := s.repo.GetStep(ctx, stepID)
+	if err != nil {
+		return err
+	}
+	scored, err := s.repo.HasCriterionScores(ctx, stepID)
+	if err != nil {
+		return err
+	}
+	if scored {
+		return Conflict("can not update rubric; step %s is already scored by it", stepID)
+	}
+	step.Rubric = rubric
+	if err := prepareRubric(step); err != nil {
+		return err
+	}
+	return s.repo.ReplaceRubric(ctx, stepID, step.Rubric)
+}
+
+// GetStepResult returns staff score in a step with per-criterion breakdown.
+func (s *StepService) GetStepResult(ctx context.Context, stepID, staffID uuid.UUID) (*models.StepResult, error) {
+	step, err := s.repo.GetStep(ctx, stepID)
+	if err != nil {
+		return nil, err
+	}
+	staffStep, err := s.repo.GetStepStaff(ctx, stepID, staffID)
+	if err != nil {
+		return nil, Forbidden("staff %s is not assigned to step %s", staffID, stepID)
+	}
+	criteria, err := s.repo.GetCriterionScores(ctx, stepID, staffID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.StepResult{
+		StepID:         stepID,
+		StaffID:        staffID,
+		Accomplishment: staffStep.Accomplishment,
+		Score:          staffStep.Score,
+		MaxScore:       step.MaxScore,
+		Criteria:       criteria,
+	}, nil
+}
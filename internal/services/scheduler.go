@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"github.com/miprokop/fication/internal/persistence/postgres"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+const (
+	// schedulerLockKey is the advisory lock key of the scheduler leader.
+	schedulerLockKey = 0x66696361
+	jobBatchSize     = 50
+	jobMaxAttempts   = 3
+	jobRetryDelay    = 30 * time.Second
+)
+
+type JobHandler func(ctx context.Context, job *models.Job) error
+
+// SchedulerService runs persisted jobs. Only the instance holding the advisory lock
+// polls the job table, the others wait to take over when the leader stops.
+type SchedulerService struct {
+	repo     postgres.Job
+	interval time.Duration
+	ctx      context.Context
+
+	mu       sync.RWMutex
+	handlers map[models.JobType]JobHandler
+}
+
+func (s *SchedulerService) Handle(jobType models.JobType, handler JobHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[jobType] = handler
+}
+
+func (s *SchedulerService) GetJob(ctx context.Context, id uuid.UUID) (*models.Job, error) {
+	return s.repo.GetJob(ctx, id)
+}
+
+func (s *SchedulerService) GetStepJobs(ctx context.Context, stepID uuid.UUID) ([]*models.Job, error) {
+	return s.repo.GetStepJobs(ctx, stepID)
+}
+
+// Run polls due jobs until ctx is done. A new leader first returns jobs left running
+// by the previous one to the queue, then catches up on every job that is already due.
+func (s *SchedulerService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	var lock *postgres.AdvisoryLock
+	defer func() {
+		if lock != nil {
+			if err := lock.Release(); err != nil {
+				log.Error(err)
+			}
+		}
+	}()
+
+	for {
+		if lock != nil && !lock.Held(ctx) {
+			log.Warn("scheduler lost leadership")
+			_ = lock.Release()
+			lock = nil
+		}
+		if lock == nil {
+			var err error
+			lock, err = s.repo.TryAdvisoryLock(ctx, schedulerLockKey)
+			if err != nil {
+				log.Errorf("can not take scheduler lock: %s", err)
+			}
+			if lock != nil {
+				log.Info("scheduler became leader")
+				if err := s.repo.ResetRunningJobs(ctx); err != nil {
+					log.Error(err)
+				}
+			}
+		}
+		if lock != nil {
+			s.runDueJobs(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *SchedulerService) runDueJobs(ctx context.Context) {
+	for {
+		jobs, err := s.repo.ClaimDueJobs(ctx, jobBatchSize)
+		if err != nil {
+			log.Errorf("can not claim jobs: %s", err)
+			return
+		}
+		for _, job := range jobs {
+			s.runJob(ctx, job)
+		}
+		if len(jobs) < jobBatchSize {
+			return
+		}
+	}
+}
+
+// runJob retries a failed job with a growing delay until it runs out of attempts.
+func (s *SchedulerService) runJob(ctx context.Context, job *models.Job) {
+	s.mu.RLock()
+	handler, ok := s.handlers[job.JobType]
+	s.mu.RUnlock()
+
+	err := fmt.Errorf("no handler for job type %s", job.JobType)
+	if ok {
+		err = handler(ctx, job)
+	}
+
+	now := time.Now()
+	job.Status = models.JobDone
+	job.LastError = ""
+	job.FinishedAt = &now
+	if err != nil {
+		log.WithFields(log.Fields{"job": job.ID, "type": job.JobType, "step": job.StepID}).
+			Errorf("job failed: %s", err)
+		job.LastError = err.Error()
+		job.Status = models.JobFailed
+		if ok && job.Attempts < jobMaxAttempts {
+			job.Status = models.JobPending
+			job.RunAt = now.Add(time.Duration(job.Attempts) * jobRetryDelay)
+			job.FinishedAt = nil
+		}
+	}
+	if err := s.repo.FinishJob(ctx, job); err != nil {
+		log.Error(err)
+	}
+}
+
+func NewSchedulerService(ctx context.Context, repo postgres.Job, interval time.Duration) *SchedulerService {
+	return &SchedulerService{
+		repo:     repo,
+		interval: interval,
+		ctx:      ctx,
+		handlers: make(map[models.JobType]JobHandler),
+	}
+}
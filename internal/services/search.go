@@ -0,0 +1,39 @@
+package services
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"github.com/miprokop/fication/internal/persistence/postgres"
+	"html"
+	"strings"
+)
+
+var searchHighlighter = strings.NewReplacer(models.SearchStartSel, "<mark>", models.SearchStopSel, "</mark>")
+
+type SearchService struct {
+	repo postgres.Search
+	ctx  context.Context
+}
+
+// Search finds events, steps, prizes and staff the staff may see, best ranked first.
+func (s *SearchService) Search(ctx context.Context, staffID uuid.UUID, query models.SearchQuery) ([]models.SearchResult, error) {
+	results, err := s.repo.Search(ctx, staffID, query)
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Title = highlight(results[i].Title)
+		results[i].Snippet = highlight(results[i].Snippet)
+	}
+	return results, nil
+}
+
+// highlight escapes text found by the database and marks up its matched words.
+func highlight(text string) string {
+	return searchHighlighter.Replace(html.EscapeString(text))
+}
+
+func NewSearchService(ctx context.Context, repo postgres.Search) *SearchService {
+	return &SearchService{repo: repo, ctx: ctx}
+}
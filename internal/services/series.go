@@ -0,0 +1,290 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"github.com/miprokop/fication/internal/persistence/postgres"
+	"strconv"
+	"time"
+)
+
+const defaultOccurrencesLimit = 10
+
+// SeriesService creates events of recurring series. Only the next occurrence of a series
+// has a job, the job creates the event and schedules the occurrence after it.
+type SeriesService struct {
+	repo     postgres.Series
+	events   postgres.Event
+	jobs     postgres.Job
+	template *TemplateService
+	ctx      context.Context
+}
+
+type occurrencePayload struct {
+	Index uint `json:"index"`
+}
+
+// CreateSeries creates a series of a template of the organization of staff, events are created in it.
+func (s *SeriesService) CreateSeries(ctx context.Context, series *models.EventSeries, orgID uuid.UUID) error {
+	frequency, err := models.NewFrequency(string(series.Frequency))
+	if err != nil {
+		return InvalidField("frequency", "%s", err)
+	}
+	series.Frequency = frequency
+	if series.Interval == 0 {
+		series.Interval = 1
+	}
+	if series.StartDate.Before(time.Now()) {
+		return InvalidField("start_date", "can not create series starting in the past: %s", series.StartDate)
+	}
+	if series.Until != nil && series.Until.Before(series.StartDate) {
+		return InvalidField("until", "incorrent until and start date: %s, %s", series.Until, series.StartDate)
+	}
+	template, err := s.template.GetTemplate(ctx, series.TemplateID, orgID)
+	if err != nil {
+		return err
+	}
+	if series.OrganizationID != (uuid.UUID{}) && series.OrganizationID != template.OrganizationID {
+		return Forbidden("can not create series of template %s in another organization", series.TemplateID)
+	}
+	series.OrganizationID = template.OrganizationID
+	if series.Name == "" {
+		series.Name = template.Name
+	}
+	if err := s.repo.CreateSeries(ctx, series); err != nil {
+		return err
+	}
+	return s.scheduleNext(ctx, series)
+}
+
+func (s *SeriesService) GetSeries(ctx context.Context, id, orgID uuid.UUID) (*models.EventSeries, error) {
+	return s.organizationSeries(ctx, id, orgID)
+}
+
+// organizationSeries returns the series when it belongs to the organization.
+func (s *SeriesService) organizationSeries(ctx context.Context, id, orgID uuid.UUID) (*models.EventSeries, error) {
+	series, err := s.repo.GetSeries(ctx, id)
+	if err != nil {
+		return nil, notFound(err, "no such series %s", id)
+	}
+	if series.OrganizationID != orgID {
+		return nil, Forbidden("series %s is not in organization %s", id, orgID)
+	}
+	return series, nil
+}
+
+func (s *SeriesService) GetOrganizationSeries(ctx context.Context, orgID uuid.UUID) ([]*models.EventSeries, error) {
+	return s.repo.GetOrganizationSeries(ctx, orgID)
+}
+
+// DeleteSeries stops the series. Events created by it are kept.
+func (s *SeriesService) DeleteSeries(ctx context.Context, id, orgID uuid.UUID) error {
+	if _, err := s.organizationSeries(ctx, id, orgID); err != nil {
+		return err
+	}
+	if err := s.jobs.CancelSeriesJobs(ctx, id); err != nil {
+		return err
+	}
+	return s.repo.DeleteSeries(ctx, id)
+}
+
+// GetOccurrences returns stored occurrences of the series and upcoming ones by the recurrence
+// rule until limit pending occurrences are listed.
+func (s *SeriesService) GetOccurrences(ctx context.Context, id, orgID uuid.UUID, limit int) ([]*models.EventOccurrence, error) {
+	series, err := s.organizationSeries(ctx, id, orgID)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := s.storedOccurrences(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultOccurrencesLimit
+	}
+	var (
+		occurrences []*models.EventOccurrence
+		pending     int
+	)
+	for n := uint(0); series.HasOccurrence(n); n++ {
+		occurrence := occurrenceOf(series, stored, n)
+		if occurrence.Status == models.OccurrencePending {
+			if pending == limit {
+				if _, ok := stored[n]; !ok && n > lastIndex(stored) {
+					break
+				}
+				continue
+			}
+			pending++
+		}
+		occurrences = append(occurrences, occurrence)
+	}
+	return occurrences, nil
+}
+
+// UpdateOccurrence skips, restores or edits one occurrence. Occurrences with a created event
+// can not be changed, the event itself is edited instead.
+func (s *SeriesService) UpdateOccurrence(ctx context.Context, id, orgID uuid.UUID,
+	request models.OccurrenceRequest) (*models.EventOccurrence, error) {
+	series, err := s.organizationSeries(ctx, id, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if !series.HasOccurrence(request.Index) {
+		return nil, NotFound("series %s has no occurrence %d", id, request.Index)
+	}
+	stored, err := s.storedOccurrences(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	occurrence := occurrenceOf(series, stored, request.Index)
+	if occurrence.Status == models.OccurrenceCreated {
+		return nil, Conflict("occurrence %d is already created; update event %s", request.Index, occurrence.EventID)
+	}
+	if occurrence.ID == (uuid.UUID{}) {
+		occurrence.ID = uuid.New()
+	}
+	if request.Skip != nil {
+		occurrence.Status = models.OccurrencePending
+		if *request.Skip {
+			occurrence.Status = models.OccurrenceSkipped
+		}
+	}
+	if request.StartDate != "" {
+		if occurrence.StartDate, err = parseTime(request.StartDate); err != nil {
+			return nil, InvalidField("start_date", "incorrent start date: %s", err)
+		}
+	}
+	if request.Name != "" {
+		occurrence.Name = request.Name
+	}
+	if err := s.repo.SaveOccurrence(ctx, occurrence); err != nil {
+		return nil, err
+	}
+	return occurrence, s.scheduleNext(ctx, series)
+}
+
+// RunOccurrenceJob creates the event of an occurrence and schedules the next one.
+// The event ID is made of the series and the occurrence index, so a job repeated after
+// the event was created finds the event instead of creating it twice.
+func (s *SeriesService) RunOccurrenceJob(ctx context.Context, job *models.Job) error {
+	series, err := s.repo.GetSeries(ctx, job.SeriesID)
+	if err != nil {
+		return err
+	}
+	var payload occurrencePayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return err
+	}
+	stored, err := s.storedOccurrences(ctx, series.ID)
+	if err != nil {
+		return err
+	}
+	occurrence := occurrenceOf(series, stored, payload.Index)
+	if occurrence.Status == models.OccurrencePending {
+		eventID := occurrenceEventID(series.ID, payload.Index)
+		_, err := s.events.GetEvent(ctx, eventID)
+		if errors.Is(err, sql.ErrNoRows) {
+			_, err = s.template.InstantiateTemplate(ctx, series.TemplateID, series.CreatedByID,
+				models.InstantiateRequest{
+					Name:           occurrence.Name,
+					StartDate:      occurrence.StartDate.Format(time.RFC3339),
+					OrganizationID: series.OrganizationID,
+					TeamID:         series.TeamID,
+					EventID:        eventID,
+				})
+		}
+		if err != nil {
+			return err
+		}
+		if occurrence.ID == (uuid.UUID{}) {
+			occurrence.ID = uuid.New()
+		}
+		occurrence.Status = models.OccurrenceCreated
+		occurrence.EventID = eventID
+		if err := s.repo.SaveOccurrence(ctx, occurrence); err != nil {
+			return err
+		}
+	}
+	return s.scheduleNext(ctx, series)
+}
+
+// occurrenceEventID is the ID of the event of the occurrence with the index.
+func occurrenceEventID(seriesID uuid.UUID, index uint) uuid.UUID {
+	return uuid.NewSHA1(seriesID, []byte(strconv.FormatUint(uint64(index), 10)))
+}
+
+// scheduleNext replaces the series job with a job for the first pending occurrence.
+func (s *SeriesService) scheduleNext(ctx context.Context, series *models.EventSeries) error {
+	if err := s.jobs.CancelSeriesJobs(ctx, series.ID); err != nil {
+		return err
+	}
+	stored, err := s.storedOccurrences(ctx, series.ID)
+	if err != nil {
+		return err
+	}
+	for n := uint(0); series.HasOccurrence(n); n++ {
+		occurrence := occurrenceOf(series, stored, n)
+		if occurrence.Status != models.OccurrencePending {
+			continue
+		}
+		payload, err := json.Marshal(occurrencePayload{Index: n})
+		if err != nil {
+			return err
+		}
+		return s.jobs.CreateJob(ctx, &models.Job{
+			ID:       uuid.New(),
+			JobType:  models.JobOccurrence,
+			SeriesID: series.ID,
+			Status:   models.JobPending,
+			RunAt:    occurrence.StartDate.Add(-time.Duration(series.LeadTime) * time.Second),
+			Payload:  payload,
+		})
+	}
+	return nil
+}
+
+func (s *SeriesService) storedOccurrences(ctx context.Context, seriesID uuid.UUID) (map[uint]*models.EventOccurrence, error) {
+	occurrences, err := s.repo.GetOccurrences(ctx, seriesID)
+	if err != nil {
+		return nil, err
+	}
+	byIndex := make(map[uint]*models.EventOccurrence, len(occurrences))
+	for _, occurrence := range occurrences {
+		byIndex[occurrence.Index] = occurrence
+	}
+	return byIndex, nil
+}
+
+// occurrenceOf returns the stored occurrence or the one made by the recurrence rule.
+func occurrenceOf(series *models.EventSeries, stored map[uint]*models.EventOccurrence, n uint) *models.EventOccurrence {
+	if occurrence, ok := stored[n]; ok {
+		return occurrence
+	}
+	return &models.EventOccurrence{
+		SeriesID:  series.ID,
+		Index:     n,
+		StartDate: series.OccurrenceDate(n),
+		Name:      series.Name,
+		Status:    models.OccurrencePending,
+	}
+}
+
+func lastIndex(stored map[uint]*models.EventOccurrence) uint {
+	var last uint
+	for n := range stored {
+		if n > last {
+			last = n
+		}
+	}
+	return last
+}
+
+func NewSeriesService(ctx context.Context, repo postgres.Series, events postgres.Event, jobs postgres.Job,
+	template *TemplateService) *SeriesService {
+	return &SeriesService{repo: repo, events: events, jobs: jobs, template: template, ctx: ctx}
+}
@@ -3,10 +3,18 @@ package services
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/chatops"
+	"github.com/miprokop/fication/internal/mail"
 	"github.com/miprokop/fication/internal/models"
 	"github.com/miprokop/fication/internal/persistence/postgres"
+	"github.com/miprokop/fication/internal/runner"
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+	"math/rand"
+	"net/http"
+	"runtime"
 	"time"
 )
 
@@ -18,6 +26,17 @@ type Service struct {
 	Prize        Prize
 	Step         Step
 	Event        Event
+	Scheduler    Scheduler
+	Template     Template
+	Series       Series
+	Join         Join
+	Notification Notification
+	Stream       Stream
+	Webhook      Webhook
+	Outbox       Outbox
+	Chat         Chat
+	Digest       Digest
+	Search       Search
 }
 
 type Auth interface {
@@ -35,7 +54,7 @@ type Staff interface {
 	UpdateStaff(ctx context.Context, staff *models.Staff) error
 	SetStaffRole(ctx context.Context, role models.StaffRole) error
 	GetInvites(ctx context.Context, id uuid.UUID) ([]models.StaffEvents, error)
-	GetStaffPrizes(ctx context.Context, id uuid.UUID) ([]models.Prize, error)
+	GetStaffPrizes(ctx context.Context, id uuid.UUID, query models.ListQuery) ([]models.Prize, string, error)
 	UploadImage(ctx context.Context, image models.StaffImage) error
 	GetPosition(ctx context.Context, id uuid.UUID) (*models.Position, error)
 	GetDefaultPosition(ctx context.Context, orgID uuid.UUID) (models.Position, error)
@@ -51,14 +70,15 @@ type Staff interface {
 }
 
 type Organization interface {
-	GetOrganizations(ctx context.Context) ([]*models.Organization, error)
+	GetOrganizations(ctx context.Context, query models.ListQuery) ([]*models.Organization, string, error)
 	GetOrganization(ctx context.Context, id uuid.UUID) (*models.Organization, error)
 	CreateOrganization(ctx context.Context, org *models.Organization, userID uuid.UUID) error
 	UpdateOrganization(ctx context.Context, org *models.Organization) error
 	AddUsersToOrg(ctx context.Context, orgID uuid.UUID, users []*models.StaffInsertion) error
 	DeleteOrganization(ctx context.Context, id uuid.UUID) error
-	GetOrganizationEvents(ctx context.Context, orgID, staffID uuid.UUID) ([]*models.Event, error)
-	GetOrganizationStaff(ctx context.Context, orgID uuid.UUID) ([]models.StaffInfo, error)
+	GetOrganizationEvents(ctx context.Context, orgID, staffID uuid.UUID,
+		query models.ListQuery) ([]*models.Event, string, error)
+	GetOrganizationStaff(ctx context.Context, orgID uuid.UUID, query models.ListQuery) ([]models.StaffInfo, string, error)
 
 	CreateOrganizationType(ctx context.Context, orgType *models.OrganizationType) error
 	GetOrganizationTypeByID(ctx context.Context, id uuid.UUID) (*models.OrganizationType, error)
@@ -80,40 +100,167 @@ type Team interface {
 type Prize interface {
 	CreatePrize(ctx context.Context, prize *models.Prize) error
 	GetPrize(ctx context.Context, id uuid.UUID) (*models.Prize, error)
-	GetPrizes(ctx context.Context, userID uuid.UUID) ([]*models.Prize, error)
-	GetAllPrizes(ctx context.Context) ([]*models.Prize, error)
+	GetPrizes(ctx context.Context, userID uuid.UUID, query models.ListQuery) ([]*models.Prize, string, error)
+	GetAllPrizes(ctx context.Context, filter models.PrizeFilter, query models.ListQuery) ([]*models.Prize, string, error)
 	GetPrizesByType(ctx context.Context, prizeType models.PrizeType) ([]*models.Prize, error)
-	DeletePrize(ctx context.Context, id uuid.UUID) error
+	DeletePrize(ctx context.Context, id, orgID uuid.UUID) error
+	RestorePrize(ctx context.Context, id, orgID uuid.UUID) error
 	GivePrize(ctx context.Context, userID, prizeID uuid.UUID) error
 	UpdatePrize(ctx context.Context, prize *models.Prize) error
+	GetDropTable(ctx context.Context, orgID uuid.UUID) (DropTable, error)
+	UpdateDropTable(ctx context.Context, orgID uuid.UUID, table DropTable) error
+	OpenLootBox(ctx context.Context, staffID, lootBoxID uuid.UUID) (*models.LootBoxDraw, error)
+	GetLootBoxDraws(ctx context.Context, lootBoxID uuid.UUID) ([]*models.LootBoxDraw, error)
 }
 
 type Step interface {
 	CreateStep(ctx context.Context, step *models.Step, creationTime, endTime time.Time) error
 	GetStep(ctx context.Context, id uuid.UUID) (*models.Step, error)
-	GetSteps(ctx context.Context, eventID uuid.UUID) ([]*models.Step, error)
+	GetSteps(ctx context.Context, eventID uuid.UUID, query models.ListQuery) ([]*models.Step, string, error)
 	DeleteStep(ctx context.Context, id uuid.UUID) error
 	GetStepPrizes(ctx context.Context, id uuid.UUID) ([]*models.Prize, error)
 	AssignStaff(ctx context.Context, staffID, stepID uuid.UUID) error
 	PassStaff(ctx context.Context, stepID, statusID uuid.UUID, status models.Accomplishment,
-		score uint) error
+		score uint, criteria []*models.CriterionScore) error
 	UpdateStep(ctx context.Context, step *models.Step) error
+	SubmitStep(ctx context.Context, submission *models.Submission) error
+	GetSubmission(ctx context.Context, id uuid.UUID) (*models.Submission, error)
+	GetSubmissionQueue(ctx context.Context, eventID uuid.UUID) ([]*models.Submission, error)
+	GetStaffSubmissions(ctx context.Context, stepID, staffID uuid.UUID) ([]*models.Submission, error)
+	ApproveSubmission(ctx context.Context, id, reviewerID uuid.UUID, review models.SubmissionReview) error
+	RejectSubmission(ctx context.Context, id, reviewerID uuid.UUID, review models.SubmissionReview) error
+	CommentSubmission(ctx context.Context, comment *models.SubmissionComment) error
+	AssignReviewer(ctx context.Context, submissionID, reviewerID uuid.UUID) error
+	GetReviewerAssignments(ctx context.Context, reviewerID uuid.UUID) ([]*models.ReviewAssignment, error)
+	SubmitReview(ctx context.Context, assignmentID, reviewerID uuid.UUID, review models.SubmissionReview) error
+	UpdateRubric(ctx context.Context, stepID uuid.UUID, rubric []*models.RubricCriterion) error
+	GetStepResult(ctx context.Context, stepID, staffID uuid.UUID) (*models.StepResult, error)
+	UpdatePrerequisites(ctx context.Context, stepID uuid.UUID, prerequisites []*models.StepPrerequisite) error
+	GetStepProgress(ctx context.Context, eventID, staffID uuid.UUID) ([]*models.StepProgress, error)
+	ChooseBranch(ctx context.Context, eventID, staffID uuid.UUID, fork, branch string) error
+	GetStepGraph(ctx context.Context, eventID uuid.UUID) (*models.StepGraph, error)
+	GetStaffPath(ctx context.Context, eventID, staffID uuid.UUID) (*models.StaffPath, error)
+	StartQuiz(ctx context.Context, stepID, staffID uuid.UUID) (*models.QuizSession, error)
+	SubmitQuiz(ctx context.Context, stepID, staffID uuid.UUID, answers []*models.QuizAnswer) (*models.QuizAttempt, error)
+	GetQuizAttempts(ctx context.Context, stepID, staffID uuid.UUID) ([]*models.QuizAttempt, error)
+	GetQuizKey(ctx context.Context, stepID, staffID uuid.UUID) ([]*models.QuizQuestion, error)
+	UpdateQuiz(ctx context.Context, stepID, staffID uuid.UUID, questions []*models.QuizQuestion) error
+	GetCodeLanguages() []string
+	SubmitCode(ctx context.Context, stepID, staffID uuid.UUID, input models.CodeSubmissionRequest) (*models.CodeSubmission, error)
+	GetCodeSubmissions(ctx context.Context, stepID, staffID uuid.UUID) ([]*models.CodeSubmission, error)
+	GetCodeTestCases(ctx context.Context, stepID, staffID uuid.UUID) ([]*models.CodeTestCase, error)
+	UpdateCodeTestCases(ctx context.Context, stepID, staffID uuid.UUID, tests []*models.CodeTestCase) error
+	RunCode(ctx context.Context)
+}
+
+type Scheduler interface {
+	Run(ctx context.Context)
+	GetJob(ctx context.Context, id uuid.UUID) (*models.Job, error)
+	GetStepJobs(ctx context.Context, stepID uuid.UUID) ([]*models.Job, error)
+}
+
+type Template interface {
+	SaveTemplate(ctx context.Context, eventID, staffID uuid.UUID, request models.TemplateRequest) (*models.EventTemplate, error)
+	GetTemplate(ctx context.Context, id, orgID uuid.UUID) (*models.EventTemplate, error)
+	GetTemplates(ctx context.Context, orgID uuid.UUID) ([]*models.EventTemplate, error)
+	DeleteTemplate(ctx context.Context, id, orgID uuid.UUID) error
+	InstantiateTemplate(ctx context.Context, id, staffID uuid.UUID, request models.InstantiateRequest) (uuid.UUID, error)
+	CloneEvent(ctx context.Context, eventID, staffID uuid.UUID, request models.InstantiateRequest) (uuid.UUID, error)
+}
+
+type Series interface {
+	CreateSeries(ctx context.Context, series *models.EventSeries, orgID uuid.UUID) error
+	GetSeries(ctx context.Context, id, orgID uuid.UUID) (*models.EventSeries, error)
+	GetOrganizationSeries(ctx context.Context, orgID uuid.UUID) ([]*models.EventSeries, error)
+	DeleteSeries(ctx context.Context, id, orgID uuid.UUID) error
+	GetOccurrences(ctx context.Context, id, orgID uuid.UUID, limit int) ([]*models.EventOccurrence, error)
+	UpdateOccurrence(ctx context.Context, id, orgID uuid.UUID, request models.OccurrenceRequest) (*models.EventOccurrence, error)
+}
+
+type Stream interface {
+	Run(ctx context.Context)
+	Subscribe(ctx context.Context, scope models.StreamScope, lastID int64) (*StreamSubscription, []*models.StreamMessage, error)
+	Unsubscribe(subscription *StreamSubscription)
+}
+
+type Webhook interface {
+	Run(ctx context.Context)
+	CreateWebhook(ctx context.Context, orgID, staffID uuid.UUID, request models.WebhookRequest) (*models.Webhook, string, error)
+	GetWebhook(ctx context.Context, id, orgID uuid.UUID) (*models.Webhook, error)
+	GetWebhooks(ctx context.Context, orgID uuid.UUID) ([]*models.Webhook, error)
+	UpdateWebhook(ctx context.Context, id, orgID uuid.UUID, request models.WebhookRequest) (*models.Webhook, error)
+	DeleteWebhook(ctx context.Context, id, orgID uuid.UUID) error
+	GetDeliveries(ctx context.Context, webhookID, orgID uuid.UUID, limit int) ([]*models.WebhookDelivery, error)
+	Redeliver(ctx context.Context, deliveryID, orgID uuid.UUID) (*models.WebhookDelivery, error)
+}
+
+type Outbox interface {
+	Run(ctx context.Context)
+}
+
+type Chat interface {
+	CreateChatIntegration(ctx context.Context, orgID, staffID uuid.UUID,
+		request models.ChatIntegrationRequest) (*models.ChatIntegration, error)
+	GetChatIntegration(ctx context.Context, id, orgID uuid.UUID) (*models.ChatIntegration, error)
+	GetChatIntegrations(ctx context.Context, orgID uuid.UUID) ([]*models.ChatIntegration, error)
+	UpdateChatIntegration(ctx context.Context, id, orgID uuid.UUID,
+		request models.ChatIntegrationRequest) (*models.ChatIntegration, error)
+	DeleteChatIntegration(ctx context.Context, id, orgID uuid.UUID) error
+	VerifyCommand(ctx context.Context, platform models.ChatPlatform, header http.Header,
+		body []byte) (*models.ChatIntegration, *models.ChatCommand, error)
+	RunCommand(ctx context.Context, integration *models.ChatIntegration, command *models.ChatCommand) models.ChatResponse
+	EncodeResponse(platform models.ChatPlatform, response models.ChatResponse) ([]byte, error)
+}
+
+type Digest interface {
+	Schedule(ctx context.Context) error
+	Unsubscribe(ctx context.Context, staffID uuid.UUID, kind models.DigestKind, token string) error
+	GetPreferences(ctx context.Context, staffID uuid.UUID) ([]*models.DigestPreference, error)
+	UpdatePreferences(ctx context.Context, staffID uuid.UUID,
+		preferences []*models.DigestPreference) ([]*models.DigestPreference, error)
+}
+
+type Search interface {
+	Search(ctx context.Context, staffID uuid.UUID, query models.SearchQuery) ([]models.SearchResult, error)
+}
+
+type Notification interface {
+	RunCleanup(ctx context.Context)
+	GetNotifications(ctx context.Context, staffID uuid.UUID, filter models.NotificationFilter) ([]*models.Notification, error)
+	CountUnread(ctx context.Context, staffID uuid.UUID) (int, error)
+	MarkRead(ctx context.Context, staffID uuid.UUID, ids []uuid.UUID) (int64, error)
+	GetPreferences(ctx context.Context, staffID uuid.UUID) ([]*models.NotificationPreference, error)
+	UpdatePreferences(ctx context.Context, staffID uuid.UUID,
+		preferences []*models.NotificationPreference) ([]*models.NotificationPreference, error)
+}
+
+type Join interface {
+	JoinEvent(ctx context.Context, eventID, staffID uuid.UUID) (models.InviteStatus, error)
+	JoinByCode(ctx context.Context, code string, staffID uuid.UUID) (*models.StaffEvents, error)
+	RequestJoin(ctx context.Context, eventID, staffID uuid.UUID) (*models.StaffEvents, error)
+	AnswerJoinRequest(ctx context.Context, id, staffID uuid.UUID, status models.InviteStatus) (models.InviteStatus, error)
+	GetJoinRequests(ctx context.Context, eventID, staffID uuid.UUID) ([]*models.StaffEvents, error)
+	CreateJoinCode(ctx context.Context, eventID, staffID uuid.UUID, request models.JoinCodeRequest) (*models.JoinCode, error)
+	GetJoinCodes(ctx context.Context, eventID, staffID uuid.UUID) ([]*models.JoinCode, error)
+	DeleteJoinCode(ctx context.Context, id, staffID uuid.UUID) error
 }
 
 type Event interface {
 	RemoveStaffFromEvent(ctx context.Context, events models.StaffEvents) error
-	GetInvites(ctx context.Context, staffID uuid.UUID) ([]*models.StaffEvents, error)
+	GetInvites(ctx context.Context, staffID uuid.UUID, query models.ListQuery) ([]*models.StaffEvents, string, error)
 	CreateEvent(ctx context.Context, event *models.Event) error
 	GetEvent(ctx context.Context, id uuid.UUID) (*models.Event, error)
-	GetEventsByTeamID(ctx context.Context, orgID uuid.UUID) ([]*models.Event, error)
+	GetEventsByTeamID(ctx context.Context, teamID uuid.UUID, query models.ListQuery) ([]*models.Event, string, error)
 	AssignStaff(ctx context.Context, events []models.StaffEvents, eventID uuid.UUID) error
 	GetEventsByCommandID(ctx context.Context, commandID uuid.UUID) ([]*models.Event, error)
-	AnswerInvitation(ctx context.Context, events models.StaffEvents) error
+	AnswerInvitation(ctx context.Context, events models.StaffEvents) (models.InviteStatus, error)
 	GetStaffScore(ctx context.Context, eventID, staffID uuid.UUID) (models.StaffScore, error)
 	DeleteEvent(ctx context.Context, id uuid.UUID) error
-	UpdateEvent(ctx context.Context, step *models.Event) error
-	GetStaffsEventsByRole(ctx context.Context, id uuid.UUID, role string) ([]*models.Event, error)
-	GetStaffsEvents(ctx context.Context, id uuid.UUID) ([]*models.Event, error)
+	UpdateEvent(ctx context.Context, step *models.Event, maxParticipants *uint) error
+	ChangeEventStatus(ctx context.Context, id uuid.UUID, status models.EventStatus) error
+	GetStaffsEventsByRole(ctx context.Context, id uuid.UUID, role string,
+		query models.ListQuery) ([]*models.Event, string, error)
+	GetStaffsEvents(ctx context.Context, id uuid.UUID, query models.ListQuery) ([]*models.Event, string, error)
 }
 
 func NewService(r *postgres.Repository) *Service {
@@ -140,13 +287,151 @@ func NewService(r *postgres.Repository) *Service {
 			panic(err)
 		}
 	}
+	seed := viper.GetInt64("lootBox.seed")
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	interval := viper.GetDuration("scheduler.interval")
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	retention := viper.GetDuration("notification.retention")
+	if retention <= 0 {
+		retention = 90 * 24 * time.Hour
+	}
+	cleanupInterval := viper.GetDuration("notification.cleanupInterval")
+	if cleanupInterval <= 0 {
+		cleanupInterval = time.Hour
+	}
+	notification := NewNotificationService(ctx, r.Notification, retention, cleanupInterval)
+	streamInterval := viper.GetDuration("stream.interval")
+	if streamInterval <= 0 {
+		streamInterval = time.Second
+	}
+	streamRetention := viper.GetDuration("stream.retention")
+	if streamRetention <= 0 {
+		streamRetention = 24 * time.Hour
+	}
+	stream := NewStreamService(ctx, r.Stream, streamInterval, streamRetention)
+	webhookInterval := viper.GetDuration("webhook.interval")
+	if webhookInterval <= 0 {
+		webhookInterval = 5 * time.Second
+	}
+	webhookTimeout := viper.GetDuration("webhook.timeout")
+	if webhookTimeout <= 0 {
+		webhookTimeout = 10 * time.Second
+	}
+	hooks := NewWebhookService(ctx, r.Webhook, NewWebhookClient(webhookTimeout), webhookInterval)
+	outboxInterval := viper.GetDuration("outbox.interval")
+	if outboxInterval <= 0 {
+		outboxInterval = time.Second
+	}
+	outboxRetention := viper.GetDuration("outbox.retention")
+	if outboxRetention <= 0 {
+		outboxRetention = 7 * 24 * time.Hour
+	}
+	outbox := NewOutboxService(ctx, r.Outbox, outboxInterval, outboxRetention)
+	outbox.Subscribe("notification", notification.HandleDomainEvent)
+	outbox.Subscribe("webhook", hooks.HandleDomainEvent)
+	chatTimeout := viper.GetDuration("chat.timeout")
+	if chatTimeout <= 0 {
+		chatTimeout = 10 * time.Second
+	}
+	chatClient := &http.Client{Timeout: chatTimeout}
+	chat := NewChatService(ctx, r.Chat, r.Event, notification, chatClient,
+		chatops.NewSlack(chatClient, chatops.SlackAPI), chatops.NewMattermost(chatClient))
+	outbox.Subscribe("chat", chat.HandleDomainEvent)
+	outbox.Subscribe("audit", auditDomainEvent)
+	scheduler := NewSchedulerService(ctx, r.Job, interval)
+	reminders := []time.Duration{24 * time.Hour, time.Hour}
+	if viper.IsSet("step.reminders") {
+		reminders = reminders[:0]
+		for _, reminder := range viper.GetStringSlice("step.reminders") {
+			before, err := time.ParseDuration(reminder)
+			if err != nil || before <= 0 {
+				panic(fmt.Errorf("incorrent step reminder: %s", reminder))
+			}
+			reminders = append(reminders, before)
+		}
+	}
+	sandbox := runner.DefaultSandbox
+	if path := viper.GetString("codeRunner.path"); path != "" {
+		sandbox.Path = path
+	}
+	sandbox.UID, sandbox.GID = viper.GetUint32("codeRunner.uid"), viper.GetUint32("codeRunner.gid")
+	if viper.IsSet("codeRunner.processes") {
+		sandbox.Processes = viper.GetUint64("codeRunner.processes")
+	}
+	if viper.IsSet("codeRunner.fileSize") {
+		sandbox.FileSize = viper.GetUint64("codeRunner.fileSize")
+	}
+	if sandbox.UID == 0 {
+		log.Warn("code runner runs programs as the server user; set codeRunner.uid to run them as another user")
+	}
+	codeWorkers := viper.GetInt("codeRunner.workers")
+	if codeWorkers <= 0 {
+		codeWorkers = runtime.NumCPU()
+	}
+	codeLease := viper.GetDuration("codeRunner.lease")
+	if codeLease <= 0 {
+		codeLease = 15 * time.Minute
+	}
+	step := NewStepService(ctx, r.Step, r.Event, r.Job,
+		runner.NewLocalRunner(viper.GetString("codeRunner.workDir"), nil, sandbox), codeWorkers, codeLease,
+		notification, stream, reminders)
+	scheduler.Handle(models.JobStepCreate, step.RunCreateJob)
+	scheduler.Handle(models.JobStepFinish, step.RunFinishJob)
+	scheduler.Handle(models.JobReminder, step.RunReminderJob)
+	event := NewEventService(ctx, r.Event, r.Job, notification, stream)
+	scheduler.Handle(models.JobEventStart, event.RunStartJob)
+	scheduler.Handle(models.JobEventFinish, event.RunFinishJob)
+	template := NewTemplateService(ctx, r.Template, r.Event, r.Step, r.Team, r.Staff, event, step)
+	series := NewSeriesService(ctx, r.Series, r.Event, r.Job, template)
+	scheduler.Handle(models.JobOccurrence, series.RunOccurrenceJob)
+	weekday, ok := parseWeekday(viper.GetString("digest.weekday"))
+	if !ok {
+		weekday = time.Monday
+	}
+	hour := viper.GetInt("digest.hour")
+	if !viper.IsSet("digest.hour") || hour < 0 || hour > 23 {
+		hour = 9
+	}
+	secret := viper.GetString("digest.secret")
+	if secret == "" {
+		secret = digestSecret(signingKey)
+	}
+	var sender mail.Sender = mail.LogSender{}
+	if host := viper.GetString("mail.host"); host != "" {
+		sender = mail.NewSMTPSender(host, viper.GetString("mail.port"), viper.GetString("mail.username"),
+			viper.GetString("mail.password"), viper.GetString("mail.from"))
+	}
+	templates, err := mail.NewTemplates()
+	if err != nil {
+		panic(err)
+	}
+	digest := NewDigestService(ctx, r.Digest, sender, templates, weekday, hour,
+		viper.GetString("digest.baseURL"), secret)
+	scheduler.Handle(models.JobDigest, digest.RunDigestJob)
+
 	return &Service{
 		Auth:         NewAuthService(ctx, r.Staff),
 		Staff:        NewStaffService(ctx, r.Staff),
 		Organization: NewOrganizationService(ctx, r.Organization),
 		Team:         NewTeamService(ctx, r.Team),
-		Prize:        NewPrizeService(ctx, r.Prize),
-		Step:         NewStepService(ctx, r.Step),
-		Event:        NewEventService(ctx, r.Event),
+		Prize:        NewPrizeService(ctx, r.Prize, rand.NewSource(seed), notification, stream),
+		Step:         step,
+		Event:        event,
+		Scheduler:    scheduler,
+		Template:     template,
+		Series:       series,
+		Join:         NewJoinService(ctx, r.Join, r.Event, event),
+		Notification: notification,
+		Stream:       stream,
+		Webhook:      hooks,
+		Outbox:       outbox,
+		Chat:         chat,
+		Digest:       digest,
+		Search:       NewSearchService(ctx, r.Search),
 	}
 }
@@ -2,7 +2,6 @@ package services
 
 import (
 	"context"
-	"fmt"
 	"github.com/google/uuid"
 	"github.com/miprokop/fication/internal/models"
 	"github.com/miprokop/fication/internal/persistence/postgres"
@@ -36,7 +35,7 @@ func (s *StaffService) UploadImage(ctx context.Context, image models.StaffImage)
 
 func (s *StaffService) CreateStaffUser(ctx context.Context, staff *models.StaffSignUp) error {
 	if !staff.Sex.IsCorrect(string(staff.Sex)) {
-		return fmt.Errorf("incorrect sex input: %s; want: %s, %s", staff.Sex,
+		return InvalidField("sex", "incorrect sex input: %s; want: %s, %s", staff.Sex,
 			models.Male, models.Female)
 	}
 	staff.Password = generatePasswordHash(staff.Password)
@@ -78,8 +77,9 @@ func (s *StaffService) GetInvites(ctx context.Context, id uuid.UUID) ([]models.S
 	return s.repo.GetInvites(ctx, id)
 }
 
-func (s *StaffService) GetStaffPrizes(ctx context.Context, id uuid.UUID) ([]models.Prize, error) {
-	return s.repo.GetStaffPrizes(ctx, id)
+func (s *StaffService) GetStaffPrizes(ctx context.Context, id uuid.UUID,
+	query models.ListQuery) ([]models.Prize, string, error) {
+	return s.repo.GetStaffPrizes(ctx, id, query)
 }
 
 func (s *StaffService) GetPosition(ctx context.Context, id uuid.UUID) (*models.Position, error) {
@@ -2,17 +2,37 @@ package services
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/miprokop/fication/internal/models"
 	"github.com/miprokop/fication/internal/persistence/postgres"
-	log "github.com/sirupsen/logrus"
+	"github.com/miprokop/fication/internal/runner"
+	"strings"
 	"time"
 )
 
 type StepService struct {
-	repo postgres.Step
-	ctx  context.Context
+	repo   postgres.Step
+	events postgres.Event
+	jobs   postgres.Job
+	runner runner.Runner
+	// codeRuns is the queue of code workers, runs older than codeLease are failed.
+	codeRuns    chan codeRun
+	codeWorkers int
+	codeLease   time.Duration
+	notify      *NotificationService
+	stream      *StreamService
+	// reminders are how long before the end of a step staff still doing it is reminded.
+	reminders []time.Duration
+	ctx       context.Context
+}
+
+type reminderPayload struct {
+	EndDate time.Time     `json:"end_date"`
+	Before  time.Duration `json:"before"`
 }
 
 func (s *StepService) GetStepPrizes(ctx context.Context, id uuid.UUID) ([]*models.Prize, error) {
@@ -21,37 +41,101 @@ func (s *StepService) GetStepPrizes(ctx context.Context, id uuid.UUID) ([]*model
 
 func (s *StepService) CreateStep(ctx context.Context, step *models.Step,
 	creationTime, endTime time.Time) error {
+	strategy, err := models.NewScoreStrategy(string(step.ScoreStrategy))
+	if err != nil {
+		return InvalidField("score_strategy", "%s", err)
+	}
+	step.ScoreStrategy = strategy
+	if step.ReviewersCount == 0 {
+		step.ReviewersCount = 1
+	}
+	step.StepType, err = models.NewStepType(string(step.StepType))
+	if err != nil {
+		return InvalidField("step_type", "%s", err)
+	}
+	if err := prepareRubric(step); err != nil {
+		return err
+	}
+	if err := prepareQuiz(step); err != nil {
+		return err
+	}
+	if err := s.prepareCode(step); err != nil {
+		return err
+	}
+	if err := prepareBranch(step); err != nil {
+		return err
+	}
+
 	if creationTime.Round(10*time.Minute) != time.Now().Round(10*time.Minute) {
-		s.createByTime(ctx, step, creationTime, endTime)
+		err = s.scheduleStepJob(ctx, models.JobStepCreate, step, creationTime)
 	} else {
-		steps, err := s.repo.GetSteps(ctx, step.EventID)
-		if err != nil {
-			return err
-		}
-		step.Level = uint(len(steps) + 1)
-		err = s.repo.CreateStep(ctx, step)
-		if err != nil {
-			return err
-		}
+		err = s.createStep(ctx, step)
+	}
+	if err != nil {
+		return err
 	}
 
-	s.updateByTime(ctx, step, creationTime, endTime)
-	return nil
+	if err := s.scheduleStepJob(ctx, models.JobStepFinish, step, endTime); err != nil {
+		return err
+	}
+	return s.scheduleReminders(ctx, step.ID, step.EventID, endTime)
+}
+
+func (s *StepService) createStep(ctx context.Context, step *models.Step) error {
+	steps, err := s.repo.GetSteps(ctx, step.EventID)
+	if err != nil {
+		return err
+	}
+	if err := preparePrerequisites(step, steps, nil); err != nil {
+		return err
+	}
+	step.Level = uint(len(steps) + 1)
+	created, err := newStepDomainEvent(models.DomainStepCreated, step, step.Status)
+	if err != nil {
+		return err
+	}
+	return s.repo.CreateStep(ctx, step, created)
+}
+
+func newStepDomainEvent(eventType models.DomainEventType, step *models.Step,
+	status models.StepStatus) (*models.DomainEvent, error) {
+	return models.NewDomainEvent(eventType, uuid.Nil, step.EventID, models.StepData{
+		StepID:  step.ID,
+		EventID: step.EventID,
+		Name:    step.Name,
+		Status:  status,
+	})
 }
 
 func (s *StepService) GetStep(ctx context.Context, id uuid.UUID) (*models.Step, error) {
 	return s.repo.GetStep(ctx, id)
 }
 
-func (s *StepService) GetSteps(ctx context.Context, eventID uuid.UUID) ([]*models.Step, error) {
-	return s.repo.GetSteps(ctx, eventID)
+func (s *StepService) GetSteps(ctx context.Context, eventID uuid.UUID, query models.ListQuery) ([]*models.Step, string, error) {
+	return s.repo.ListSteps(ctx, eventID, query)
 }
 
 func (s *StepService) DeleteStep(ctx context.Context, id uuid.UUID) error {
+	if err := s.jobs.CancelStepJobs(ctx, id); err != nil {
+		return err
+	}
 	return s.repo.DeleteStep(ctx, id)
 }
 
 func (s *StepService) AssignStaff(ctx context.Context, staffID, stepID uuid.UUID) error {
+	step, err := s.repo.GetStep(ctx, stepID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkEventRunning(ctx, step.EventID); err != nil {
+		return err
+	}
+	if err := s.checkUnlocked(ctx, step, staffID); err != nil {
+		return err
+	}
+	if err := s.checkBranch(ctx, step, staffID); err != nil {
+		return err
+	}
 	staffStep := models.StepStaff{
 		ID:             uuid.New(),
 		StepID:         stepID,
@@ -63,21 +147,111 @@ func (s *StepService) AssignStaff(ctx context.Context, staffID, stepID uuid.UUID
 }
 
 func (s *StepService) PassStaff(ctx context.Context, stepID, staffID uuid.UUID, status models.Accomplishment,
-	score uint) error {
+	score uint, criteria []*models.CriterionScore) error {
 	step, err := s.repo.GetStep(ctx, stepID)
 	if err != nil {
 		return err
 	}
-	if step.MaxScore < score {
-		return fmt.Errorf("can not give staff score: %d; max: %d", score, step.MaxScore)
+	if err := s.checkEventRunning(ctx, step.EventID); err != nil {
+		return err
+	}
+	if _, err := s.repo.GetStepStaff(ctx, stepID, staffID); errors.Is(err, sql.ErrNoRows) {
+		return Forbidden("staff %s is not assigned to step %s", staffID, stepID)
+	} else if err != nil {
+		return err
+	}
+	score, err = stepScore(step, score, criteria)
+	if err != nil {
+		return err
 	}
 	staffStep := models.StepStaff{
 		StepID:         stepID,
 		StaffID:        staffID,
 		Accomplishment: status,
 		Score:          score,
+		Criteria:       criteria,
 	}
-	return s.repo.PassStaff(ctx, staffStep)
+	result, err := models.NewDomainEvent(models.DomainStepResult, uuid.Nil, step.EventID, models.StepResultData{
+		StepID:         stepID,
+		EventID:        step.EventID,
+		StaffID:        staffID,
+		Accomplishment: status,
+		Score:          score,
+	})
+	if err != nil {
+		return err
+	}
+	err = s.repo.PassStaff(ctx, staffStep, result)
+	if err != nil {
+		return err
+	}
+	s.reportResult(ctx, step, staffID, status, score)
+	if status == models.Done {
+		return s.assignNextStep(ctx, step, staffID)
+	}
+	return nil
+}
+
+// checkEventRunning refuses changes of staff progress in events that are not running.
+func (s *StepService) checkEventRunning(ctx context.Context, eventID uuid.UUID) error {
+	status, err := s.repo.GetEventStatus(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	if status != models.EventRunning {
+		return Conflict("event %s is %s; want: %s", eventID, status, models.EventRunning)
+	}
+	return nil
+}
+
+// assignNextStep assigns staff to the first available step of the event with a higher level.
+// Steps that are still locked by prerequisites are skipped, as are forks where staff
+// has to choose a branch first.
+func (s *StepService) assignNextStep(ctx context.Context, step *models.Step, staffID uuid.UUID) error {
+	progress, choices, err := s.stepProgress(ctx, step.EventID, staffID)
+	if err != nil {
+		return err
+	}
+	for _, next := range progress {
+		if next.Step.Fork != "" && choices[next.Step.Fork] == "" {
+			continue
+		}
+		if next.Step.Level > step.Level && next.State == models.StepAvailable {
+			if err := s.AssignStaff(ctx, staffID, next.Step.ID); err != nil {
+				return err
+			}
+			s.notify.Notify(ctx, models.Notification{
+				NotificationType: models.NotifyLevelUp,
+				Message:          fmt.Sprintf("you reached level %d: %s", next.Step.Level, next.Step.Name),
+				EventID:          step.EventID,
+				StepID:           next.Step.ID,
+			}, staffID)
+			return nil
+		}
+	}
+	return nil
+}
+
+// reportResult notifies staff when its step is done, failed or returned to process by a reviewer,
+// and publishes the new score to the event stream.
+func (s *StepService) reportResult(ctx context.Context, step *models.Step, staffID uuid.UUID,
+	status models.Accomplishment, score uint) {
+	message := fmt.Sprintf("step %s is %s with score %d", step.Name, status, score)
+	if status == models.InProcess {
+		message = fmt.Sprintf("your submission to step %s is rejected, you can submit again", step.Name)
+	}
+	s.notify.Notify(ctx, models.Notification{
+		NotificationType: models.NotifyStepResult,
+		Message:          message,
+		EventID:          step.EventID,
+		StepID:           step.ID,
+	}, staffID)
+	s.stream.PublishEvent(ctx, models.StreamScore, step.EventID, models.ScoreMessage{
+		StaffID:        staffID,
+		StepID:         step.ID,
+		Accomplishment: status,
+		Score:          score,
+	})
 }
 
 func (s *StepService) UpdateStep(ctx context.Context, step *models.Step) error {
@@ -89,10 +263,19 @@ func (s *StepService) UpdateStep(ctx context.Context, step *models.Step) error {
 	var err error
 	var toUpdate bool
 
+	if step.ScoreStrategy != "" {
+		if _, err = models.NewScoreStrategy(string(step.ScoreStrategy)); err != nil {
+			return InvalidField("score_strategy", "%s", err)
+		}
+	}
+
 	oldStep, err := s.repo.GetStep(ctx, step.ID)
 	if err != nil {
 		return err
 	}
+	if step.StepType != "" && step.StepType != oldStep.StepType {
+		return InvalidField("step_type", "can not change step type from %s to %s", oldStep.StepType, step.StepType)
+	}
 	createTime, err = time.Parse(time.RFC3339, oldStep.CreationDate)
 	if err != nil {
 		return err
@@ -107,42 +290,191 @@ func (s *StepService) UpdateStep(ctx context.Context, step *models.Step) error {
 	}
 	if toUpdate {
 		if !endTime.After(createTime) {
-			return fmt.Errorf("incorrent end time and creation time: %s, %s", endTime,
+			return InvalidField("end_date", "incorrent end time and creation time: %s, %s", endTime,
 				createTime)
 		}
-		s.updateByTime(ctx, step, createTime, endTime)
+		if err := s.jobs.CancelStepJobs(ctx, step.ID, models.JobStepFinish, models.JobReminder); err != nil {
+			return err
+		}
+		if err := s.scheduleStepJob(ctx, models.JobStepFinish, step, endTime); err != nil {
+			return err
+		}
+		if err := s.scheduleReminders(ctx, step.ID, oldStep.EventID, endTime); err != nil {
+			return err
+		}
 	}
 
 	step.Status = models.Changed
 	err = s.repo.UpdateStep(ctx, step)
-
-	return err
+	if err != nil {
+		return err
+	}
+	s.stream.PublishEvent(ctx, models.StreamStepStatus, oldStep.EventID,
+		models.StepStatusMessage{StepID: step.ID, Status: models.Changed})
+	return nil
 }
 
-func (s *StepService) updateByTime(ctx context.Context, step *models.Step,
-	creationTime, endTime time.Time) {
-	time.AfterFunc(endTime.Sub(creationTime), func() {
-		updateStep := step
-		updateStep.Status = models.Finished
-		err := s.repo.UpdateStep(ctx, updateStep)
+// scheduleStepJob persists a step job. Create jobs carry the whole step,
+// it does not exist in the database until the job runs.
+func (s *StepService) scheduleStepJob(ctx context.Context, jobType models.JobType, step *models.Step,
+	runAt time.Time) error {
+	job := &models.Job{
+		ID:      uuid.New(),
+		JobType: jobType,
+		StepID:  step.ID,
+		EventID: step.EventID,
+		Status:  models.JobPending,
+		RunAt:   runAt,
+	}
+	if jobType == models.JobStepCreate {
+		payload, err := json.Marshal(step)
 		if err != nil {
-			log.Println(err)
-			return
+			return err
 		}
-	})
+		job.Payload = payload
+	}
+	return s.jobs.CreateJob(ctx, job)
 }
 
-func (s *StepService) createByTime(ctx context.Context, step *models.Step,
-	creationTime, endTime time.Time) {
-	time.AfterFunc(endTime.Sub(creationTime), func() {
-		err := s.repo.CreateStep(ctx, step)
+// scheduleReminders persists a reminder job for every reminder that is still ahead of the end of the step.
+func (s *StepService) scheduleReminders(ctx context.Context, stepID, eventID uuid.UUID, endTime time.Time) error {
+	now := time.Now()
+	for _, before := range s.reminders {
+		runAt := endTime.Add(-before)
+		if !runAt.After(now) {
+			continue
+		}
+		payload, err := json.Marshal(reminderPayload{EndDate: endTime, Before: before})
 		if err != nil {
-			log.Println(err)
-			return
+			return err
 		}
-	})
+		err = s.jobs.CreateJob(ctx, &models.Job{
+			ID:      uuid.New(),
+			JobType: models.JobReminder,
+			StepID:  stepID,
+			EventID: eventID,
+			Status:  models.JobPending,
+			RunAt:   runAt,
+			Payload: payload,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunReminderJob notifies staff still doing the step that it ends soon. Reminders are recorded
+// per end date after they are sent, so a repeated job reminds nobody twice and a moved end date
+// is reminded again. A job failed in between sends the same notifications, which are created once.
+func (s *StepService) RunReminderJob(ctx context.Context, job *models.Job) error {
+	var payload reminderPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return fmt.Errorf("can not parse reminder job payload: %s", err)
+	}
+	step, err := s.repo.GetStep(ctx, job.StepID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if step.Status == models.Finished || step.Status == models.Canceled {
+		return nil
+	}
+	endTime, err := time.Parse(time.RFC3339, step.EndDate)
+	if err != nil {
+		return err
+	}
+	if endTime.Unix() != payload.EndDate.Unix() {
+		return nil
+	}
+	staffIDs, err := s.repo.GetUnremindedStaff(ctx, step.ID, payload.EndDate, payload.Before)
+	if err != nil {
+		return err
+	}
+	err = s.notify.notify(ctx, models.Notification{
+		NotificationType: models.NotifyDeadline,
+		Message:          fmt.Sprintf("step %s ends in %s", step.Name, reminderText(payload.Before)),
+		EventID:          step.EventID,
+		StepID:           step.ID,
+		SourceID:         reminderSourceID(step.ID, payload),
+	}, staffIDs...)
+	if err != nil {
+		return err
+	}
+	return s.repo.SaveStepReminders(ctx, step.ID, payload.EndDate, payload.Before, staffIDs)
+}
+
+// reminderSourceID is the source of notifications of one reminder about one end date of the step.
+func reminderSourceID(stepID uuid.UUID, payload reminderPayload) uuid.UUID {
+	return uuid.NewSHA1(stepID, []byte(fmt.Sprintf("%d:%d", payload.EndDate.Unix(), int64(payload.Before/time.Second))))
+}
+
+// reminderText writes the time left without zero units, e.g. 24h, 1h30m or 45m.
+func reminderText(before time.Duration) string {
+	text := strings.TrimSuffix(before.Round(time.Minute).String(), "0s")
+	if strings.HasSuffix(text, "h0m") {
+		text = strings.TrimSuffix(text, "0m")
+	}
+	return text
+}
+
+// RunCreateJob creates a scheduled step. It does nothing when the step already exists,
+// so a job repeated after a crash does not create a duplicate.
+func (s *StepService) RunCreateJob(ctx context.Context, job *models.Job) error {
+	if _, err := s.repo.GetStep(ctx, job.StepID); err == nil {
+		return nil
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	step := new(models.Step)
+	if err := json.Unmarshal(job.Payload, step); err != nil {
+		return err
+	}
+	if err := s.createStep(ctx, step); err != nil {
+		return err
+	}
+	s.stream.PublishEvent(ctx, models.StreamStepStatus, step.EventID,
+		models.StepStatusMessage{StepID: step.ID, Status: models.Process})
+	return nil
+}
+
+func (s *StepService) RunFinishJob(ctx context.Context, job *models.Job) error {
+	step, err := s.repo.GetStep(ctx, job.StepID)
+	if err != nil {
+		return err
+	}
+	finished, err := newStepDomainEvent(models.DomainStepFinished, step, models.Finished)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdateStep(ctx, &models.Step{ID: job.StepID, Status: models.Finished}, finished); err != nil {
+		return err
+	}
+	if err := s.jobs.CancelStepJobs(ctx, job.StepID, models.JobReminder); err != nil {
+		return err
+	}
+	s.stream.PublishEvent(ctx, models.StreamStepStatus, step.EventID,
+		models.StepStatusMessage{StepID: job.StepID, Status: models.Finished})
+	return nil
+}
+
+// managedStep returns the step when staff is creator or admin of its event.
+func (s *StepService) managedStep(ctx context.Context, stepID, staffID uuid.UUID) (*models.Step, error) {
+	step, err := s.repo.GetStep(ctx, stepID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := managedEvent(ctx, s.events, step.EventID, staffID); err != nil {
+		return nil, err
+	}
+	return step, nil
 }
 
-func NewStepService(ctx context.Context, repo postgres.Step) *StepService {
-	return &StepService{repo: repo, ctx: ctx}
+func NewStepService(ctx context.Context, repo postgres.Step, events postgres.Event, jobs postgres.Job,
+	codeRunner runner.Runner, codeWorkers int, codeLease time.Duration, notify *NotificationService,
+	stream *StreamService, reminders []time.Duration) *StepService {
+	return &StepService{repo: repo, events: events, jobs: jobs, runner: codeRunner,
+		codeRuns: make(chan codeRun, codeWorkers*codeQueueSize), codeWorkers: codeWorkers, codeLease: codeLease,
+		notify: notify, stream: stream, reminders: reminders, ctx: ctx}
 }
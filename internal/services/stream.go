@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"github.com/miprokop/fication/internal/persistence/postgres"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+const (
+	streamBatchSize   = 500
+	streamReplayLimit = 500
+	streamBufferSize  = 64
+	// streamCleanupInterval is how often messages older than the retention period are deleted.
+	streamCleanupInterval = time.Hour
+	// streamGapTimeout is how long a missing message id is waited for. Ids are taken when messages
+	// are inserted, so a message of a slow transaction can be committed after messages with later
+	// ids; ids of rolled back inserts never come.
+	streamGapTimeout = time.Minute
+)
+
+// StreamSubscription receives messages of its scope. Messages is closed when
+// the subscriber does not keep up, the client has to reconnect with the last message id.
+type StreamSubscription struct {
+	Scope    models.StreamScope
+	Messages chan *models.StreamMessage
+}
+
+// StreamService publishes real-time updates of events and organizations.
+// Messages are stored, so every instance polls them for its own subscribers,
+// and a reconnecting client gets the messages it has missed.
+type StreamService struct {
+	repo      postgres.Stream
+	interval  time.Duration
+	retention time.Duration
+	ctx       context.Context
+
+	mu          sync.Mutex
+	subscribers map[*StreamSubscription]struct{}
+}
+
+// PublishEvent publishes a message to the event and to its organization.
+// Messages are a side effect of the action that caused them, so errors are only logged.
+func (s *StreamService) PublishEvent(ctx context.Context, messageType models.StreamMessageType,
+	eventID uuid.UUID, data interface{}) {
+	orgID, err := s.repo.GetEventOrganization(ctx, eventID)
+	if err != nil {
+		log.Errorf("can not get organization of event %s: %s", eventID, err)
+		return
+	}
+	s.publish(ctx, &models.StreamMessage{MessageType: messageType, EventID: eventID, OrganizationID: orgID}, data)
+}
+
+// PublishStep publishes a message to the event of the step.
+func (s *StreamService) PublishStep(ctx context.Context, messageType models.StreamMessageType,
+	stepID uuid.UUID, data interface{}) {
+	eventID, err := s.repo.GetStepEvent(ctx, stepID)
+	if err != nil {
+		log.Errorf("can not get event of step %s: %s", stepID, err)
+		return
+	}
+	s.PublishEvent(ctx, messageType, eventID, data)
+}
+
+// PublishOrganization publishes a message that is not about one event.
+func (s *StreamService) PublishOrganization(ctx context.Context, messageType models.StreamMessageType,
+	orgID uuid.UUID, data interface{}) {
+	s.publish(ctx, &models.StreamMessage{MessageType: messageType, OrganizationID: orgID}, data)
+}
+
+func (s *StreamService) publish(ctx context.Context, message *models.StreamMessage, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Errorf("can not marshal %s message: %s", message.MessageType, err)
+		return
+	}
+	message.Data = payload
+	if err := s.repo.CreateStreamMessage(ctx, message); err != nil {
+		log.WithFields(log.Fields{"type": message.MessageType, "event": message.EventID}).
+			Errorf("can not create stream message: %s", err)
+	}
+}
+
+// Subscribe subscribes to messages of the scope and returns messages after lastID the client has missed.
+// Replayed messages may also come through the subscription, clients skip ids they have seen.
+func (s *StreamService) Subscribe(ctx context.Context, scope models.StreamScope,
+	lastID int64) (*StreamSubscription, []*models.StreamMessage, error) {
+	subscription := &StreamSubscription{
+		Scope:    scope,
+		Messages: make(chan *models.StreamMessage, streamBufferSize),
+	}
+	s.mu.Lock()
+	s.subscribers[subscription] = struct{}{}
+	s.mu.Unlock()
+
+	if lastID == 0 {
+		return subscription, nil, nil
+	}
+	missed, err := s.repo.GetScopeMessages(ctx, scope, lastID, streamReplayLimit)
+	if err != nil {
+		s.Unsubscribe(subscription)
+		return nil, nil, err
+	}
+	return subscription, missed, nil
+}
+
+func (s *StreamService) Unsubscribe(subscription *StreamSubscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subscribers[subscription]; ok {
+		delete(s.subscribers, subscription)
+		close(subscription.Messages)
+	}
+}
+
+// Run polls new messages for subscribers of this instance until ctx is done
+// and deletes messages older than the retention period.
+func (s *StreamService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	var cursor *streamCursor
+	var cleanedAt time.Time
+	for {
+		if cursor == nil {
+			id, err := s.repo.GetLastStreamMessageID(ctx)
+			if err != nil {
+				log.Errorf("can not get last stream message: %s", err)
+			} else {
+				cursor = newStreamCursor(id)
+			}
+		}
+		if cursor != nil {
+			s.dispatch(ctx, cursor)
+		}
+		if time.Since(cleanedAt) > streamCleanupInterval {
+			if _, err := s.repo.DeleteStreamMessagesBefore(ctx, time.Now().Add(-s.retention)); err != nil {
+				log.Errorf("can not delete old stream messages: %s", err)
+			}
+			cleanedAt = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatch sends messages after the cursor to subscribers. Messages committed late with
+// ids lower than sent ones are sent too, every message is sent once.
+func (s *StreamService) dispatch(ctx context.Context, cursor *streamCursor) {
+	defer cursor.advance(time.Now())
+	afterID := cursor.after
+	for {
+		messages, err := s.repo.GetStreamMessages(ctx, afterID, streamBatchSize)
+		if err != nil {
+			log.Errorf("can not get stream messages: %s", err)
+			return
+		}
+		s.mu.Lock()
+		for _, message := range messages {
+			afterID = message.ID
+			if !cursor.add(message.ID, time.Now()) {
+				continue
+			}
+			for subscription := range s.subscribers {
+				if !subscription.Scope.Match(message) {
+					continue
+				}
+				select {
+				case subscription.Messages <- message:
+				default:
+					delete(s.subscribers, subscription)
+					close(subscription.Messages)
+				}
+			}
+		}
+		s.mu.Unlock()
+		if len(messages) < streamBatchSize {
+			return
+		}
+	}
+}
+
+// streamCursor tracks sent message ids. Every id up to after is sent or given up, ids after
+// it up to last are either sent or missing since the time in gaps.
+type streamCursor struct {
+	after int64
+	last  int64
+	sent  map[int64]struct{}
+	gaps  map[int64]time.Time
+}
+
+func newStreamCursor(after int64) *streamCursor {
+	return &streamCursor{after: after, last: after, sent: make(map[int64]struct{}), gaps: make(map[int64]time.Time)}
+}
+
+// add marks the id as sent and reports whether it was not sent before. Ids skipped
+// on the way to it are missing from now.
+func (c *streamCursor) add(id int64, now time.Time) bool {
+	if _, ok := c.sent[id]; ok || id <= c.after {
+		return false
+	}
+	for missing := c.last + 1; missing < id; missing++ {
+		c.gaps[missing] = now
+	}
+	if id > c.last {
+		c.last = id
+	}
+	delete(c.gaps, id)
+	c.sent[id] = struct{}{}
+	return true
+}
+
+// advance moves after over sent ids and ids missing for longer than streamGapTimeout.
+func (c *streamCursor) advance(now time.Time) {
+	for {
+		next := c.after + 1
+		if _, ok := c.sent[next]; ok {
+			delete(c.sent, next)
+		} else if since, ok := c.gaps[next]; ok && now.Sub(since) > streamGapTimeout {
+			delete(c.gaps, next)
+		} else {
+			return
+		}
+		c.after = next
+	}
+}
+
+func NewStreamService(ctx context.Context, repo postgres.Stream, interval, retention time.Duration) *StreamService {
+	return &StreamService{
+		repo:        repo,
+		interval:    interval,
+		retention:   retention,
+		ctx:         ctx,
+		subscribers: make(map[*StreamSubscription]struct{}),
+	}
+}
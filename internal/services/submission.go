@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+)
+
+// SubmitStep stores the staff work for a step and moves the staff step to ready-check.
+// Rejected work can be submitted again, pending or accepted work can not.
+func (s *StepService) SubmitStep(ctx context.Context, submission *models.Submission) error {
+	step, err := s.repo.GetStep(ctx, submission.StepID)
+	if err != nil {
+		return err
+	}
+	if step.Status == models.Finished || step.Status == models.Canceled {
+		return Conflict("can not submit work; step status is %s", step.Status)
+	}
+	if err := s.checkEventRunning(ctx, step.EventID); err != nil {
+		return err
+	}
+	if step.StepType == models.QuizStep || step.StepType == models.CodeStep {
+		return Validation("can not submit work; %s step is graded automatically", step.StepType)
+	}
+	staffStep, err := s.repo.GetStepStaff(ctx, submission.StepID, submission.StaffID)
+	if err != nil {
+		return Forbidden("can not submit work; staff %s is not assigned to step %s", submission.StaffID, submission.StepID)
+	}
+	switch staffStep.Accomplishment {
+	case models.ReadyToCheck:
+		return Conflict("can not submit work; previous submission is waiting for review")
+	case models.Done, models.Cheated:
+		return Conflict("can not submit work; step is already %s", staffStep.Accomplishment)
+	}
+	if submission.Text == "" && len(submission.Links) == 0 && len(submission.Attachments) == 0 {
+		return Validation("can not submit work; submission is empty")
+	}
+
+	submission.Status = models.SubmissionPending
+	for i := range submission.Attachments {
+		submission.Attachments[i].SubmissionID = submission.ID
+	}
+	if step.MultiReview() {
+		reviewers, err := s.pickReviewers(ctx, step, staffStep.Staff)
+		if err != nil {
+			return err
+		}
+		for _, reviewer := range reviewers {
+			submission.Reviews = append(submission.Reviews, &models.ReviewAssignment{
+				ID:           uuid.New(),
+				SubmissionID: submission.ID,
+				ReviewerID:   reviewer.ID,
+				Status:       models.ReviewAssigned,
+			})
+		}
+	}
+	return s.repo.CreateSubmission(ctx, submission)
+}
+
+func (s *StepService) GetSubmission(ctx context.Context, id uuid.UUID) (*models.Submission, error) {
+	return s.repo.GetSubmission(ctx, id)
+}
+
+func (s *StepService) GetSubmissionQueue(ctx context.Context, eventID uuid.UUID) ([]*models.Submission, error) {
+	return s.repo.GetSubmissionQueue(ctx, eventID)
+}
+
+func (s *StepService) GetStaffSubmissions(ctx context.Context, stepID, staffID uuid.UUID) ([]*models.Submission, error) {
+	return s.repo.GetStaffSubmissions(ctx, stepID, staffID)
+}
+
+// ApproveSubmission marks the staff step as done with the review score
+// and assigns the staff to the next step of the event.
+func (s *StepService) ApproveSubmission(ctx context.Context, id, reviewerID uuid.UUID, review models.SubmissionReview) error {
+	submission, err := s.pendingSubmission(ctx, id)
+	if err != nil {
+		return err
+	}
+	submission.Step.Rubric, err = s.repo.GetRubric(ctx, submission.StepID)
+	if err != nil {
+		return err
+	}
+	score, err := stepScore(submission.Step, review.Score, review.Criteria)
+	if err != nil {
+		return err
+	}
+	submission.Status = models.SubmissionApproved
+	submission.Score = score
+	submission.ReviewerID = reviewerID
+	submission.Criteria = review.Criteria
+
+	err = s.repo.ReviewSubmission(ctx, submission, models.Done, newSubmissionComment(id, reviewerID, review.Comment))
+	if err != nil {
+		return err
+	}
+	s.reportResult(ctx, submission.Step, submission.StaffID, models.Done, submission.Score)
+	return s.assignNextStep(ctx, submission.Step, submission.StaffID)
+}
+
+// RejectSubmission returns the staff step to process so the staff can submit again.
+func (s *StepService) RejectSubmission(ctx context.Context, id, reviewerID uuid.UUID, review models.SubmissionReview) error {
+	submission, err := s.pendingSubmission(ctx, id)
+	if err != nil {
+		return err
+	}
+	if review.Comment == "" {
+		return InvalidField("comment", "can not reject submission without comment")
+	}
+	submission.Status = models.SubmissionRejected
+	submission.Score = 0
+	submission.ReviewerID = reviewerID
+
+	err = s.repo.ReviewSubmission(ctx, submission, models.InProcess, newSubmissionComment(id, reviewerID, review.Comment))
+	if err != nil {
+		return err
+	}
+	s.reportResult(ctx, submission.Step, submission.StaffID, models.InProcess, 0)
+	return nil
+}
+
+func (s *StepService) CommentSubmission(ctx context.Context, comment *models.SubmissionComment) error {
+	if comment.Body == "" {
+		return InvalidField("comment", "can not add empty comment")
+	}
+	return s.repo.AddSubmissionComment(ctx, comment)
+}
+
+func (s *StepService) pendingSubmission(ctx context.Context, id uuid.UUID) (*models.Submission, error) {
+	submission, err := s.repo.GetSubmission(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if submission.Status != models.SubmissionPending {
+		return nil, Conflict("can not review submission; it is already %s", submission.Status)
+	}
+	if err := s.checkEventRunning(ctx, submission.Step.EventID); err != nil {
+		return nil, err
+	}
+	return submission, nil
+}
+
+func newSubmissionComment(submissionID, authorID uuid.UUID, body string) *models.SubmissionComment {
+	if body == "" {
+		return nil
+	}
+	return &models.SubmissionComment{
+		ID:           uuid.New(),
+		SubmissionID: submissionID,
+		AuthorID:     authorID,
+		Body:         body,
+	}
+}
@@ -0,0 +1,324 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"github.com/miprokop/fication/internal/persistence/postgres"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"time"
+)
+
+// TemplateService saves events as templates and creates events from templates
+// through event and step services, so new events are checked and scheduled as usual.
+type TemplateService struct {
+	repo   postgres.Template
+	events postgres.Event
+	steps  postgres.Step
+	teams  postgres.Team
+	staff  postgres.Staff
+	event  *EventService
+	step   *StepService
+	ctx    context.Context
+}
+
+// GetTemplate returns the template when it belongs to the organization.
+func (t *TemplateService) GetTemplate(ctx context.Context, id, orgID uuid.UUID) (*models.EventTemplate, error) {
+	template, err := t.repo.GetTemplate(ctx, id)
+	if err != nil {
+		return nil, notFound(err, "no such template %s", id)
+	}
+	if template.OrganizationID != orgID {
+		return nil, Forbidden("template %s is not in organization %s", id, orgID)
+	}
+	return template, nil
+}
+
+func (t *TemplateService) GetTemplates(ctx context.Context, orgID uuid.UUID) ([]*models.EventTemplate, error) {
+	return t.repo.GetTemplates(ctx, orgID)
+}
+
+func (t *TemplateService) DeleteTemplate(ctx context.Context, id, orgID uuid.UUID) error {
+	if _, err := t.GetTemplate(ctx, id, orgID); err != nil {
+		return err
+	}
+	return t.repo.DeleteTemplate(ctx, id)
+}
+
+// SaveTemplate saves the event with its steps, prizes, rubrics, quizzes and code tests as a template.
+// Only creator or admin of the event may save it.
+func (t *TemplateService) SaveTemplate(ctx context.Context, eventID, staffID uuid.UUID,
+	request models.TemplateRequest) (*models.EventTemplate, error) {
+	event, snapshot, err := t.snapshot(ctx, eventID, staffID)
+	if err != nil {
+		return nil, err
+	}
+	template := &models.EventTemplate{
+		ID:             uuid.New(),
+		Name:           request.Name,
+		Description:    request.Description,
+		OrganizationID: event.OrganizationID,
+		CreatedByID:    staffID,
+		Event:          snapshot,
+	}
+	if template.Name == "" {
+		template.Name = event.Name
+	}
+	if err := t.repo.CreateTemplate(ctx, template); err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+// InstantiateTemplate creates an event from the template starting at the requested date.
+// Staff must be of the template organization, the event is created in it.
+func (t *TemplateService) InstantiateTemplate(ctx context.Context, id, staffID uuid.UUID,
+	request models.InstantiateRequest) (uuid.UUID, error) {
+	template, err := t.repo.GetTemplate(ctx, id)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	staff, err := t.staff.GetStaff(ctx, staffID)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	if staff.OrganizationID != template.OrganizationID {
+		return uuid.UUID{}, Forbidden("staff %s is not of organization %s of template %s", staffID,
+			template.OrganizationID, id)
+	}
+	if request.OrganizationID != (uuid.UUID{}) && request.OrganizationID != template.OrganizationID {
+		return uuid.UUID{}, Forbidden("can not create event of template %s in another organization", id)
+	}
+	request.OrganizationID = template.OrganizationID
+	return t.instantiate(ctx, template.Event, staffID, request)
+}
+
+// CloneEvent creates a deep copy of the event in its organization, only creator or admin of
+// the event may clone it. The copy starts at the requested date, or at the start of the source
+// event when no date is passed.
+func (t *TemplateService) CloneEvent(ctx context.Context, eventID, staffID uuid.UUID,
+	request models.InstantiateRequest) (uuid.UUID, error) {
+	event, snapshot, err := t.snapshot(ctx, eventID, staffID)
+	if err != nil {
+		return uuid.UUID{}, err
+	}
+	if request.StartDate == "" {
+		request.StartDate = event.CreationDate
+	}
+	if request.OrganizationID != (uuid.UUID{}) && request.OrganizationID != event.OrganizationID {
+		return uuid.UUID{}, Forbidden("can not clone event %s to another organization", eventID)
+	}
+	request.OrganizationID = event.OrganizationID
+	return t.instantiate(ctx, snapshot, staffID, request)
+}
+
+// snapshot returns the event with its template when staff is creator or admin of the event.
+func (t *TemplateService) snapshot(ctx context.Context, eventID, staffID uuid.UUID) (*models.Event,
+	*models.TemplateEvent, error) {
+	event, err := managedEvent(ctx, t.events, eventID, staffID)
+	if err != nil {
+		return nil, nil, err
+	}
+	start, end, err := eventTimes(event)
+	if err != nil {
+		return nil, nil, err
+	}
+	snapshot := &models.TemplateEvent{
+		Name:        event.Name,
+		Description: event.Description,
+		ImagePath:   event.ImagePath,
+		EventType:   event.EventType,
+		Duration:    int64(end.Sub(start) / time.Second),
+	}
+	sort.SliceStable(event.Steps, func(i, j int) bool { return event.Steps[i].Level < event.Steps[j].Level })
+	for _, s := range event.Steps {
+		step, err := t.steps.GetStep(ctx, s.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if step.Questions, err = t.steps.GetQuizQuestions(ctx, s.ID); err != nil {
+			return nil, nil, err
+		}
+		if step.TestCases, err = t.steps.GetCodeTestCases(ctx, s.ID); err != nil {
+			return nil, nil, err
+		}
+		stepStart, err := parseTime(step.CreationDate)
+		if err != nil {
+			return nil, nil, InvalidField("steps", "incorrent creation time of step %s: %s", step.ID, err)
+		}
+		stepEnd, err := parseTime(step.EndDate)
+		if err != nil {
+			return nil, nil, InvalidField("steps", "incorrent end time of step %s: %s", step.ID, err)
+		}
+		step.ActiveStaff = nil
+		snapshot.Steps = append(snapshot.Steps, &models.TemplateStep{
+			Step:        step,
+			StartOffset: int64(stepStart.Sub(start) / time.Second),
+			Duration:    int64(stepEnd.Sub(stepStart) / time.Second),
+		})
+	}
+	return event, snapshot, nil
+}
+
+// instantiate creates the event and its steps with new IDs. References between steps
+// are moved to the new steps and step dates keep their offsets from the event start.
+// The event is deleted with the steps created so far when a step can not be created.
+func (t *TemplateService) instantiate(ctx context.Context, snapshot *models.TemplateEvent, staffID uuid.UUID,
+	request models.InstantiateRequest) (uuid.UUID, error) {
+	start := time.Now()
+	if request.StartDate != "" {
+		var err error
+		if start, err = parseTime(request.StartDate); err != nil {
+			return uuid.UUID{}, InvalidField("start_date", "incorrent start date: %s", err)
+		}
+	}
+	if request.EventID == (uuid.UUID{}) {
+		request.EventID = uuid.New()
+	}
+	event := &models.Event{
+		ID:             request.EventID,
+		Name:           snapshot.Name,
+		CreationDate:   start.Format(time.RFC3339),
+		EndDate:        start.Add(time.Duration(snapshot.Duration) * time.Second).Format(time.RFC3339),
+		Description:    snapshot.Description,
+		ImagePath:      snapshot.ImagePath,
+		CreatedByID:    staffID,
+		EventStatus:    models.EventStatus(request.EventStatus),
+		EventType:      snapshot.EventType,
+		OrganizationID: request.OrganizationID,
+	}
+	if request.Name != "" {
+		event.Name = request.Name
+	}
+	if event.OrganizationID == (uuid.UUID{}) {
+		event.OrganizationID = models.DefaultOrganization.ID
+	}
+	event.StaffEvents = append(event.StaffEvents, &models.StaffEvents{
+		ID:        uuid.New(),
+		StaffID:   staffID,
+		EventID:   event.ID,
+		Status:    models.Accepted,
+		StaffRole: models.Creator,
+	})
+	if request.TeamID != (uuid.UUID{}) {
+		team, err := t.teams.GetTeamByID(ctx, request.TeamID)
+		if err != nil {
+			return uuid.UUID{}, err
+		}
+		for _, staff := range team.Staff {
+			if staff.ID == staffID {
+				continue
+			}
+			event.StaffEvents = append(event.StaffEvents, &models.StaffEvents{
+				ID:        uuid.New(),
+				StaffID:   staff.ID,
+				EventID:   event.ID,
+				Status:    models.InProgress,
+				StaffRole: models.Default,
+			})
+		}
+	}
+	if err := t.event.CreateEvent(ctx, event); err != nil {
+		return uuid.UUID{}, err
+	}
+
+	stepIDs := make(map[uuid.UUID]uuid.UUID, len(snapshot.Steps))
+	for _, s := range snapshot.Steps {
+		stepIDs[s.Step.ID] = uuid.New()
+	}
+	created := make([]uuid.UUID, 0, len(snapshot.Steps))
+	for _, s := range snapshot.Steps {
+		step := copyStep(s.Step, stepIDs, event.ID, staffID)
+		creationTime := start.Add(time.Duration(s.StartOffset) * time.Second)
+		endTime := creationTime.Add(time.Duration(s.Duration) * time.Second)
+		step.CreationDate = creationTime.Format(time.RFC3339)
+		step.EndDate = endTime.Format(time.RFC3339)
+		if err := t.step.CreateStep(ctx, step, creationTime, endTime); err != nil {
+			t.discard(ctx, event.ID, created)
+			return uuid.UUID{}, fmt.Errorf("can not create step %s: %w", step.Name, err)
+		}
+		created = append(created, step.ID)
+	}
+	return event.ID, nil
+}
+
+// discard deletes a partly created event with its steps and their jobs.
+func (t *TemplateService) discard(ctx context.Context, eventID uuid.UUID, stepIDs []uuid.UUID) {
+	for _, id := range stepIDs {
+		if err := t.step.DeleteStep(ctx, id); err != nil {
+			log.WithFields(log.Fields{"event": eventID, "step": id}).Errorf("can not delete step of failed event: %s", err)
+		}
+	}
+	if err := t.event.DeleteEvent(ctx, eventID); err != nil {
+		log.WithFields(log.Fields{"event": eventID}).Errorf("can not delete failed event: %s", err)
+	}
+}
+
+// copyStep returns a copy of the template step with new IDs of the step and its parts.
+func copyStep(source *models.Step, stepIDs map[uuid.UUID]uuid.UUID, eventID, staffID uuid.UUID) *models.Step {
+	step := *source
+	step.ID = stepIDs[source.ID]
+	step.EventID = eventID
+	step.Status = models.Process
+	step.ActiveStaff = nil
+
+	step.Prizes = make([]*models.Prize, 0, len(source.Prizes))
+	for _, p := range source.Prizes {
+		prize := *p
+		prize.ID = uuid.New()
+		prize.StepID = step.ID
+		prize.Step = nil
+		prize.CreatedBy = staffID
+		prize.Staff = nil
+		prize.CurrentCount = prize.Count
+		prize.Prizes = nil
+		prize.ArchivedAt = nil
+		step.Prizes = append(step.Prizes, &prize)
+	}
+	step.Images = make([]*models.StepImage, 0, len(source.Images))
+	for _, i := range source.Images {
+		image := *i
+		image.ID = uuid.New()
+		image.StepID = step.ID
+		image.Step = nil
+		step.Images = append(step.Images, &image)
+	}
+	step.Rubric = make([]*models.RubricCriterion, 0, len(source.Rubric))
+	for _, c := range source.Rubric {
+		criterion := *c
+		criterion.ID = uuid.New()
+		criterion.StepID = step.ID
+		step.Rubric = append(step.Rubric, &criterion)
+	}
+	step.Questions = make([]*models.QuizQuestion, 0, len(source.Questions))
+	for _, q := range source.Questions {
+		question := *q
+		question.ID = uuid.New()
+		question.StepID = step.ID
+		step.Questions = append(step.Questions, &question)
+	}
+	step.TestCases = make([]*models.CodeTestCase, 0, len(source.TestCases))
+	for _, c := range source.TestCases {
+		test := *c
+		test.ID = uuid.New()
+		test.StepID = step.ID
+		step.TestCases = append(step.TestCases, &test)
+	}
+	step.Prerequisites = make([]*models.StepPrerequisite, 0, len(source.Prerequisites))
+	for _, p := range source.Prerequisites {
+		prerequisite := *p
+		prerequisite.ID = uuid.New()
+		prerequisite.StepID = step.ID
+		prerequisite.RequiredStepID = stepIDs[p.RequiredStepID]
+		step.Prerequisites = append(step.Prerequisites, &prerequisite)
+	}
+	return &step
+}
+
+func NewTemplateService(ctx context.Context, repo postgres.Template, events postgres.Event, steps postgres.Step,
+	teams postgres.Team, staff postgres.Staff, event *EventService, step *StepService) *TemplateService {
+	return &TemplateService{repo: repo, events: events, steps: steps, teams: teams, staff: staff, event: event,
+		step: step, ctx: ctx}
+}
@@ -0,0 +1,425 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"github.com/miprokop/fication/internal/persistence/postgres"
+	log "github.com/sirupsen/logrus"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	webhookBatchSize    = 20
+	webhookMaxAttempts  = 8
+	webhookRetryDelay   = 30 * time.Second
+	webhookLease        = 2 * time.Minute
+	webhookDisableAfter = 20
+	webhookSecretLength = 32
+	webhookDeliveryList = 50
+	// webhookErrorLength limits the response body kept as the error of a failed attempt.
+	webhookErrorLength = 512
+
+	WebhookSignatureHeader = "X-Acheer-Signature"
+	WebhookTimestampHeader = "X-Acheer-Timestamp"
+	WebhookEventHeader     = "X-Acheer-Event"
+	WebhookDeliveryHeader  = "X-Acheer-Delivery"
+)
+
+// WebhookService sends domain events to webhooks of organizations. Deliveries are stored
+// before they are sent and retried with exponential back-off, a webhook that keeps failing
+// is disabled until it is enabled again.
+type WebhookService struct {
+	repo     postgres.Webhook
+	client   *http.Client
+	interval time.Duration
+	ctx      context.Context
+}
+
+// HandleDomainEvent stores a delivery of the domain event for every webhook of the organization subscribed to it.
+// Deliveries of one domain event share its id, so receivers can drop repeats.
+func (w *WebhookService) HandleDomainEvent(ctx context.Context, event *models.DomainEvent) error {
+	eventType, err := models.NewWebhookEventType(string(event.EventType))
+	if err != nil {
+		// webhooks are not subscribed to every domain event
+		return nil
+	}
+	webhooks, err := w.repo.GetSubscribedWebhooks(ctx, event.OrganizationID, eventType)
+	if err != nil {
+		return err
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+	payload, err := json.Marshal(models.WebhookPayload{
+		ID:             event.ID,
+		Type:           eventType,
+		OrganizationID: event.OrganizationID,
+		CreatedAt:      event.CreatedAt.UTC(),
+		Data:           event.Data,
+	})
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	deliveries := make([]*models.WebhookDelivery, len(webhooks))
+	for i, webhook := range webhooks {
+		deliveries[i] = &models.WebhookDelivery{
+			ID:            uuid.New(),
+			WebhookID:     webhook.ID,
+			EventID:       event.ID,
+			EventType:     eventType,
+			Payload:       payload,
+			Status:        models.DeliveryPending,
+			NextAttemptAt: now,
+		}
+	}
+	return w.repo.CreateDeliveries(ctx, deliveries)
+}
+
+// CreateWebhook creates a webhook with a new signing secret and returns the secret.
+// The secret is not shown again.
+func (w *WebhookService) CreateWebhook(ctx context.Context, orgID, staffID uuid.UUID,
+	request models.WebhookRequest) (*models.Webhook, string, error) {
+	if err := checkWebhookRequest(&request); err != nil {
+		return nil, "", err
+	}
+	if request.URL == "" {
+		return nil, "", InvalidField("url", "can not create webhook without url")
+	}
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, "", err
+	}
+	webhook := &models.Webhook{
+		ID:             uuid.New(),
+		OrganizationID: orgID,
+		URL:            request.URL,
+		Secret:         secret,
+		Events:         request.Events,
+		Enabled:        request.Enabled == nil || *request.Enabled,
+		CreatedByID:    staffID,
+	}
+	if err := w.repo.CreateWebhook(ctx, webhook); err != nil {
+		return nil, "", err
+	}
+	return webhook, secret, nil
+}
+
+// GetWebhook returns the webhook when it belongs to the organization.
+func (w *WebhookService) GetWebhook(ctx context.Context, id, orgID uuid.UUID) (*models.Webhook, error) {
+	webhook, err := w.repo.GetWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if webhook.OrganizationID != orgID {
+		return nil, NotFound("webhook %s is not in organization %s", id, orgID)
+	}
+	return webhook, nil
+}
+
+func (w *WebhookService) GetWebhooks(ctx context.Context, orgID uuid.UUID) ([]*models.Webhook, error) {
+	return w.repo.GetWebhooks(ctx, orgID)
+}
+
+func (w *WebhookService) UpdateWebhook(ctx context.Context, id, orgID uuid.UUID,
+	request models.WebhookRequest) (*models.Webhook, error) {
+	webhook, err := w.GetWebhook(ctx, id, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkWebhookRequest(&request); err != nil {
+		return nil, err
+	}
+	if request.URL != "" {
+		webhook.URL = request.URL
+	}
+	if request.Events != nil {
+		webhook.Events = request.Events
+	}
+	if request.Enabled != nil {
+		if *request.Enabled && !webhook.Enabled {
+			webhook.FailureCount = 0
+			webhook.DisabledAt = nil
+		}
+		webhook.Enabled = *request.Enabled
+	}
+	if err := w.repo.UpdateWebhook(ctx, webhook); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (w *WebhookService) DeleteWebhook(ctx context.Context, id, orgID uuid.UUID) error {
+	if _, err := w.GetWebhook(ctx, id, orgID); err != nil {
+		return err
+	}
+	return w.repo.DeleteWebhook(ctx, id)
+}
+
+func (w *WebhookService) GetDeliveries(ctx context.Context, webhookID, orgID uuid.UUID, limit int) ([]*models.WebhookDelivery, error) {
+	if _, err := w.GetWebhook(ctx, webhookID, orgID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > webhookDeliveryList {
+		limit = webhookDeliveryList
+	}
+	return w.repo.GetDeliveries(ctx, webhookID, limit)
+}
+
+// Redeliver sends the payload of a delivery again as a new delivery with the same event id,
+// so receivers can tell it is a repeat.
+func (w *WebhookService) Redeliver(ctx context.Context, deliveryID, orgID uuid.UUID) (*models.WebhookDelivery, error) {
+	delivery, err := w.repo.GetDelivery(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	webhook, err := w.GetWebhook(ctx, delivery.WebhookID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if !webhook.Enabled {
+		return nil, Conflict("can not redeliver; webhook %s is disabled", webhook.ID)
+	}
+	redelivery := &models.WebhookDelivery{
+		ID:            uuid.New(),
+		WebhookID:     delivery.WebhookID,
+		EventID:       delivery.EventID,
+		EventType:     delivery.EventType,
+		Payload:       delivery.Payload,
+		Status:        models.DeliveryPending,
+		NextAttemptAt: time.Now(),
+		Redelivery:    true,
+	}
+	if err := w.repo.CreateDeliveries(ctx, []*models.WebhookDelivery{redelivery}); err != nil {
+		return nil, err
+	}
+	return redelivery, nil
+}
+
+// Run sends due deliveries until ctx is done. Every instance runs it, a delivery is claimed by one of them.
+func (w *WebhookService) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.sendDueDeliveries(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *WebhookService) sendDueDeliveries(ctx context.Context) {
+	for {
+		deliveries, err := w.repo.ClaimDueDeliveries(ctx, webhookBatchSize, webhookLease)
+		if err != nil {
+			log.Errorf("can not claim webhook deliveries: %s", err)
+			return
+		}
+		webhooks := make(map[uuid.UUID]*models.Webhook)
+		for _, delivery := range deliveries {
+			webhook, ok := webhooks[delivery.WebhookID]
+			if !ok {
+				webhook, err = w.repo.GetWebhook(ctx, delivery.WebhookID)
+				if err != nil {
+					log.Errorf("can not get webhook %s: %s", delivery.WebhookID, err)
+					continue
+				}
+				webhooks[webhook.ID] = webhook
+			}
+			if !webhook.Enabled {
+				// disabled by an earlier delivery of the batch, the delivery waits for it to be enabled
+				continue
+			}
+			w.attempt(ctx, webhook, delivery)
+		}
+		if len(deliveries) < webhookBatchSize {
+			return
+		}
+	}
+}
+
+// attempt sends the delivery once and schedules the next attempt when it fails.
+func (w *WebhookService) attempt(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) {
+	code, err := w.send(ctx, webhook, delivery)
+	now := time.Now()
+	delivery.ResponseCode = code
+	delivery.LastError = ""
+	if err == nil {
+		delivery.Status = models.DeliveryDelivered
+		delivery.DeliveredAt = &now
+	} else {
+		log.WithFields(log.Fields{"delivery": delivery.ID, "webhook": webhook.ID, "attempt": delivery.Attempts}).
+			Warnf("webhook delivery failed: %s", err)
+		delivery.LastError = err.Error()
+		delivery.Status = models.DeliveryFailed
+		if delivery.Attempts < webhookMaxAttempts {
+			delivery.Status = models.DeliveryPending
+			delivery.NextAttemptAt = now.Add(webhookBackoff(delivery.Attempts))
+		}
+	}
+	if err := w.repo.FinishDelivery(ctx, delivery); err != nil {
+		log.Error(err)
+	}
+	enabled, err := w.repo.RecordWebhookResult(ctx, webhook.ID, delivery.Status == models.DeliveryDelivered,
+		webhookDisableAfter)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+	if webhook.Enabled && !enabled {
+		log.WithFields(log.Fields{"webhook": webhook.ID}).Warnf("webhook disabled after %d failed deliveries",
+			webhookDisableAfter)
+	}
+	webhook.Enabled = enabled
+}
+
+// send posts the payload signed with the webhook secret. Any 2xx response is a success.
+func (w *WebhookService) send(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, string(delivery.EventType))
+	req.Header.Set(WebhookDeliveryHeader, delivery.EventID.String())
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, "sha256="+SignWebhook(webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, webhookErrorLength))
+		return resp.StatusCode, fmt.Errorf("webhook responded %d: %s", resp.StatusCode, body)
+	}
+	return resp.StatusCode, nil
+}
+
+// nonPublicNetworks are blocks not covered by the net.IP checks of publicIP.
+var nonPublicNetworks = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("192.0.0.0/24"),
+	mustParseCIDR("198.18.0.0/15"),
+}
+
+func mustParseCIDR(s string) *net.IPNet {
+	_, network, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return network
+}
+
+// publicIP reports whether the address is reachable from the internet, webhooks must not
+// reach the network of the server: loopback, private, link-local and cloud metadata addresses.
+func publicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// dialPublicOnly refuses connections to addresses that are not public. It runs after the host
+// is resolved, so a host name that resolves to a private address and redirects are refused too.
+func dialPublicOnly(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+		return fmt.Errorf("can not connect to %s; webhooks may connect only to public addresses", host)
+	}
+	return nil
+}
+
+// NewWebhookClient returns a client that connects only to public addresses, not through a proxy.
+func NewWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: dialPublicOnly}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// SignWebhook returns the hex HMAC-SHA256 of "timestamp.body" with the webhook secret.
+// Receivers compute it the same way to check a delivery.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff doubles the delay after every failed attempt.
+func webhookBackoff(attempts uint) time.Duration {
+	if attempts == 0 {
+		attempts = 1
+	}
+	return webhookRetryDelay << (attempts - 1)
+}
+
+func checkWebhookRequest(request *models.WebhookRequest) error {
+	if request.URL != "" {
+		u, err := url.Parse(request.URL)
+		if err != nil {
+			return InvalidField("url", "incorrent webhook url: %s", err)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return InvalidField("url", "incorrent webhook url: %s; want an http or https url", request.URL)
+		}
+		if ip := net.ParseIP(u.Hostname()); ip != nil && !publicIP(ip) || u.Hostname() == "localhost" {
+			return InvalidField("url", "incorrent webhook url: %s; want a public address", request.URL)
+		}
+	}
+	for i, t := range request.Events {
+		eventType, err := models.NewWebhookEventType(string(t))
+		if err != nil {
+			return InvalidField("events", "%s", err)
+		}
+		request.Events[i] = eventType
+	}
+	return nil
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, webhookSecretLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// NewWebhookService creates a webhook service, client sends the deliveries, see NewWebhookClient,
+// and can be replaced to send them to a test receiver.
+func NewWebhookService(ctx context.Context, repo postgres.Webhook, client *http.Client, interval time.Duration) *WebhookService {
+	return &WebhookService{repo: repo, client: client, interval: interval, ctx: ctx}
+}
@@ -0,0 +1,263 @@
+package services
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/miprokop/fication/internal/models"
+	"github.com/miprokop/fication/internal/persistence/postgres"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeWebhookRepo keeps webhooks and deliveries in memory the way the database does.
+type fakeWebhookRepo struct {
+	postgres.Webhook
+
+	mu         sync.Mutex
+	webhooks   map[uuid.UUID]*models.Webhook
+	deliveries []*models.WebhookDelivery
+}
+
+func newFakeWebhookRepo(webhooks ...*models.Webhook) *fakeWebhookRepo {
+	repo := &fakeWebhookRepo{webhooks: make(map[uuid.UUID]*models.Webhook)}
+	for _, webhook := range webhooks {
+		repo.webhooks[webhook.ID] = webhook
+	}
+	return repo
+}
+
+func (r *fakeWebhookRepo) GetWebhook(_ context.Context, id uuid.UUID) (*models.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.webhooks[id], nil
+}
+
+func (r *fakeWebhookRepo) ClaimDueDeliveries(_ context.Context, limit int, lease time.Duration) ([]*models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	var claimed []*models.WebhookDelivery
+	for _, delivery := range r.deliveries {
+		if len(claimed) == limit {
+			break
+		}
+		if delivery.Status != models.DeliveryPending || delivery.NextAttemptAt.After(now) ||
+			!r.webhooks[delivery.WebhookID].Enabled {
+			continue
+		}
+		delivery.Attempts++
+		delivery.NextAttemptAt = now.Add(lease)
+		claimed = append(claimed, delivery)
+	}
+	return claimed, nil
+}
+
+func (r *fakeWebhookRepo) FinishDelivery(context.Context, *models.WebhookDelivery) error {
+	return nil
+}
+
+func (r *fakeWebhookRepo) RecordWebhookResult(_ context.Context, id uuid.UUID, delivered bool,
+	disableAfter uint) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	webhook := r.webhooks[id]
+	if delivered {
+		webhook.FailureCount = 0
+		return webhook.Enabled, nil
+	}
+	webhook.FailureCount++
+	if webhook.Enabled && webhook.FailureCount >= disableAfter {
+		now := time.Now()
+		webhook.DisabledAt = &now
+	}
+	webhook.Enabled = webhook.Enabled && webhook.FailureCount < disableAfter
+	return webhook.Enabled, nil
+}
+
+func newTestDelivery(webhook *models.Webhook) *models.WebhookDelivery {
+	return &models.WebhookDelivery{
+		ID:            uuid.New(),
+		WebhookID:     webhook.ID,
+		EventID:       uuid.New(),
+		EventType:     models.WebhookEventFinished,
+		Payload:       []byte(`{"id":"1"}`),
+		Status:        models.DeliveryPending,
+		NextAttemptAt: time.Now(),
+	}
+}
+
+// newTestReceiver starts a receiver that answers every request with status and counts requests.
+func newTestReceiver(t *testing.T, status int, check func(r *http.Request, body []byte)) (*httptest.Server, *int) {
+	var mu sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if check != nil {
+			check(r, body)
+		}
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		w.WriteHeader(status)
+		w.Write([]byte("boom"))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestWebhookDeliveryIsSigned(t *testing.T) {
+	webhook := &models.Webhook{ID: uuid.New(), Secret: "secret", Enabled: true}
+	delivery := newTestDelivery(webhook)
+	server, requests := newTestReceiver(t, http.StatusNoContent, func(r *http.Request, body []byte) {
+		timestamp := r.Header.Get(WebhookTimestampHeader)
+		if want := "sha256=" + SignWebhook("secret", timestamp, body); r.Header.Get(WebhookSignatureHeader) != want {
+			t.Errorf("got signature %q; want %q", r.Header.Get(WebhookSignatureHeader), want)
+		}
+		if got := r.Header.Get(WebhookDeliveryHeader); got != delivery.EventID.String() {
+			t.Errorf("got delivery header %q; want event id %s", got, delivery.EventID)
+		}
+		if got := r.Header.Get(WebhookEventHeader); got != string(delivery.EventType) {
+			t.Errorf("got event header %q; want %s", got, delivery.EventType)
+		}
+		if string(body) != string(delivery.Payload) {
+			t.Errorf("got body %s; want %s", body, delivery.Payload)
+		}
+	})
+	webhook.URL = server.URL
+	repo := newFakeWebhookRepo(webhook)
+	repo.deliveries = []*models.WebhookDelivery{delivery}
+
+	NewWebhookService(context.Background(), repo, server.Client(), time.Second).sendDueDeliveries(context.Background())
+
+	if *requests != 1 {
+		t.Fatalf("got %d requests; want 1", *requests)
+	}
+	if delivery.Status != models.DeliveryDelivered || delivery.ResponseCode != http.StatusNoContent ||
+		delivery.DeliveredAt == nil {
+		t.Fatalf("got delivery %s with code %d; want delivered with code 204", delivery.Status, delivery.ResponseCode)
+	}
+}
+
+func TestWebhookRetriesWithBackoff(t *testing.T) {
+	webhook := &models.Webhook{ID: uuid.New(), Secret: "secret", Enabled: true}
+	server, requests := newTestReceiver(t, http.StatusInternalServerError, nil)
+	webhook.URL = server.URL
+	delivery := newTestDelivery(webhook)
+	repo := newFakeWebhookRepo(webhook)
+	repo.deliveries = []*models.WebhookDelivery{delivery}
+	service := NewWebhookService(context.Background(), repo, server.Client(), time.Second)
+
+	for attempt := uint(1); attempt <= webhookMaxAttempts; attempt++ {
+		delivery.NextAttemptAt = time.Now().Add(-time.Second)
+		before := time.Now()
+		service.sendDueDeliveries(context.Background())
+
+		if *requests != int(attempt) {
+			t.Fatalf("attempt %d: got %d requests", attempt, *requests)
+		}
+		if delivery.ResponseCode != http.StatusInternalServerError || !strings.Contains(delivery.LastError, "boom") {
+			t.Fatalf("attempt %d: got code %d and error %q; want the response of the receiver",
+				attempt, delivery.ResponseCode, delivery.LastError)
+		}
+		if attempt == webhookMaxAttempts {
+			if delivery.Status != models.DeliveryFailed {
+				t.Fatalf("got %s delivery after %d attempts; want failed", delivery.Status, attempt)
+			}
+			break
+		}
+		delay := webhookRetryDelay << (attempt - 1)
+		if delivery.Status != models.DeliveryPending || delivery.NextAttemptAt.Before(before.Add(delay)) ||
+			delivery.NextAttemptAt.After(time.Now().Add(delay)) {
+			t.Fatalf("attempt %d: got %s delivery retried at %s; want pending retried in %s",
+				attempt, delivery.Status, delivery.NextAttemptAt.Sub(before), delay)
+		}
+	}
+	if !webhook.Enabled || webhook.FailureCount != webhookMaxAttempts {
+		t.Fatalf("got webhook enabled %t with %d failures; want enabled with %d",
+			webhook.Enabled, webhook.FailureCount, webhookMaxAttempts)
+	}
+}
+
+func TestWebhookDisabledAfterFailures(t *testing.T) {
+	webhook := &models.Webhook{ID: uuid.New(), Secret: "secret", Enabled: true}
+	server, requests := newTestReceiver(t, http.StatusBadGateway, nil)
+	webhook.URL = server.URL
+	repo := newFakeWebhookRepo(webhook)
+	for i := 0; i < webhookDisableAfter+5; i++ {
+		repo.deliveries = append(repo.deliveries, newTestDelivery(webhook))
+	}
+
+	NewWebhookService(context.Background(), repo, server.Client(), time.Second).sendDueDeliveries(context.Background())
+
+	if *requests != webhookDisableAfter {
+		t.Fatalf("got %d requests; want %d before the webhook is disabled", *requests, webhookDisableAfter)
+	}
+	if webhook.Enabled || webhook.DisabledAt == nil {
+		t.Fatalf("got webhook enabled %t; want disabled", webhook.Enabled)
+	}
+	pending := 0
+	for _, delivery := range repo.deliveries {
+		if delivery.Status == models.DeliveryPending && delivery.ResponseCode == 0 {
+			pending++
+		}
+	}
+	if pending != 5 {
+		t.Fatalf("got %d unsent pending deliveries; want 5 waiting for the webhook to be enabled", pending)
+	}
+}
+
+func TestWebhookClientRefusesPrivateAddresses(t *testing.T) {
+	server, requests := newTestReceiver(t, http.StatusOK, nil)
+
+	_, err := NewWebhookClient(time.Second).Get(server.URL)
+	if err == nil || !strings.Contains(err.Error(), "public addresses") {
+		t.Fatalf("got error %v; want refused connection to %s", err, server.URL)
+	}
+	if *requests != 0 {
+		t.Fatalf("got %d requests; want none", *requests)
+	}
+}
+
+func TestPublicIP(t *testing.T) {
+	for address, want := range map[string]bool{
+		"93.184.216.34":    true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"::1":              false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"100.100.100.200":  false,
+		"0.0.0.0":          false,
+		"fe80::1":          false,
+		"fd00::1":          false,
+		"::ffff:127.0.0.1": false,
+	} {
+		if got := publicIP(net.ParseIP(address)); got != want {
+			t.Errorf("publicIP(%s) = %t; want %t", address, got, want)
+		}
+	}
+}
+
+func TestWebhookRequestRefusesPrivateURLs(t *testing.T) {
+	for _, u := range []string{"http://localhost:8080/hook", "http://127.0.0.1/hook", "http://[::1]/hook",
+		"http://169.254.169.254/latest/meta-data", "https://10.0.0.1/hook"} {
+		if err := checkWebhookRequest(&models.WebhookRequest{URL: u}); ErrorOf(err) == nil ||
+			ErrorOf(err).Code != CodeValidation {
+			t.Errorf("got error %v for %s; want validation error", err, u)
+		}
+	}
+	if err := checkWebhookRequest(&models.WebhookRequest{URL: "https://hooks.example.com/acheer"}); err != nil {
+		t.Errorf("got error %v for a public url", err)
+	}
+}